@@ -1,3 +1,17 @@
+// Package account signs Spectrum and Plasma Cash transactions.
+//
+// Keys of accounts can be kept in an encrypted JSON keystore. An account
+// signs while it is unlocked:
+//
+//	ks := account.NewKeyStore(dir, keystore.StandardScryptN,
+//		keystore.StandardScryptP)
+//	opts, err := ks.Transactor(address)
+//	err = ks.TimedUnlock(address, passphrase, time.Hour)
+//
+// Signing by a locked account returns ErrLocked, Lock removes
+// the decrypted key from memory. Hardware wallets and remote signing
+// services implement Signer, NewPlasmaTransactor creates transact options
+// which sign with any signer.
 package account
 
 import (
@@ -18,14 +32,20 @@ var (
 )
 
 // PlasmaSignerFn is a signer function callback when requires a method
-// to sign the Plasma cash transaction before submission.
-type PlasmaSignerFn func(address common.Address,
-	tx *transaction.Transaction) (*transaction.Transaction, error)
+// to sign the Plasma cash transaction for the domain before submission.
+type PlasmaSignerFn func(address common.Address, tx *transaction.Transaction,
+	domain transaction.Domain) (*transaction.Transaction, error)
+
+// OptInSignerFn is a signer function callback when requires a method
+// to sign the checkpoint opt-in for the domain before submission.
+type OptInSignerFn func(address common.Address, optIn *transaction.OptIn,
+	domain transaction.Domain) (*transaction.OptIn, error)
 
 // PlasmaTransactOpts is the collection of authorization data required
 // to create a valid Plasma Cash transaction.
 type PlasmaTransactOpts struct {
 	PlasmaSigner PlasmaSignerFn
+	OptInSigner  OptInSignerFn
 	*bind.TransactOpts
 }
 
@@ -73,11 +93,20 @@ func NewPlasmaKeyedTransactor(key *ecdsa.PrivateKey) *PlasmaTransactOpts {
 	return &PlasmaTransactOpts{
 		TransactOpts: bind.NewKeyedTransactor(key),
 		PlasmaSigner: func(address common.Address,
-			tx *transaction.Transaction) (*transaction.Transaction, error) {
+			tx *transaction.Transaction,
+			domain transaction.Domain) (*transaction.Transaction, error) {
+			if address != keyAddr {
+				return nil, ErrNotAuthorized
+			}
+			return tx.SignTx(key, domain)
+		},
+		OptInSigner: func(address common.Address,
+			optIn *transaction.OptIn,
+			domain transaction.Domain) (*transaction.OptIn, error) {
 			if address != keyAddr {
 				return nil, ErrNotAuthorized
 			}
-			return tx.SignTx(key)
+			return optIn.Sign(key, domain)
 		},
 	}
 }
@@ -0,0 +1,112 @@
+package account
+
+import (
+	"time"
+
+	"github.com/SmartMeshFoundation/Spectrum/accounts"
+	"github.com/SmartMeshFoundation/Spectrum/accounts/keystore"
+	"github.com/SmartMeshFoundation/Spectrum/common"
+	"github.com/pkg/errors"
+)
+
+// ErrUnknownAccount is returned if an account is not in the keystore.
+var ErrUnknownAccount = errors.New("unknown account")
+
+// ErrLocked is returned if an account of the keystore is locked.
+var ErrLocked = keystore.ErrLocked
+
+// KeyStore manages accounts in a directory of encrypted JSON key files.
+// An account signs transactions while it is unlocked.
+type KeyStore struct {
+	keys *keystore.KeyStore
+}
+
+// NewKeyStore opens the keystore in the directory. Arguments `scryptN`
+// and `scryptP` are parameters of key encryption, usually
+// keystore.StandardScryptN and keystore.StandardScryptP.
+func NewKeyStore(dir string, scryptN, scryptP int) *KeyStore {
+	return &KeyStore{keys: keystore.NewKeyStore(dir, scryptN, scryptP)}
+}
+
+// Accounts returns addresses of accounts in the keystore.
+func (ks *KeyStore) Accounts() []common.Address {
+	var addresses []common.Address
+	for _, acc := range ks.keys.Accounts() {
+		addresses = append(addresses, acc.Address)
+	}
+	return addresses
+}
+
+// NewAccount creates a new key encrypted with the passphrase.
+func (ks *KeyStore) NewAccount(passphrase string) (common.Address, error) {
+	acc, err := ks.keys.NewAccount(passphrase)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return acc.Address, nil
+}
+
+// Import imports an encrypted JSON key and encrypts it
+// with the new passphrase.
+func (ks *KeyStore) Import(keyJSON []byte,
+	passphrase, newPassphrase string) (common.Address, error) {
+	acc, err := ks.keys.Import(keyJSON, passphrase, newPassphrase)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return acc.Address, nil
+}
+
+// Unlock unlocks the account until it is locked.
+func (ks *KeyStore) Unlock(address common.Address, passphrase string) error {
+	return ks.TimedUnlock(address, passphrase, 0)
+}
+
+// TimedUnlock unlocks the account for the period of time.
+// Zero timeout unlocks the account until it is locked.
+func (ks *KeyStore) TimedUnlock(address common.Address, passphrase string,
+	timeout time.Duration) error {
+	acc, err := ks.find(address)
+	if err != nil {
+		return err
+	}
+	return ks.keys.TimedUnlock(acc, passphrase, timeout)
+}
+
+// Lock removes the decrypted key of the account from memory.
+func (ks *KeyStore) Lock(address common.Address) error {
+	return ks.keys.Lock(address)
+}
+
+// SignHash signs the hash by the unlocked account.
+func (ks *KeyStore) SignHash(address common.Address,
+	hash []byte) ([]byte, error) {
+	acc, err := ks.find(address)
+	if err != nil {
+		return nil, err
+	}
+	return ks.keys.SignHash(acc, hash)
+}
+
+// Transactor creates transact options which sign transactions
+// by the account. The account must be unlocked before signing.
+func (ks *KeyStore) Transactor(
+	address common.Address) (*PlasmaTransactOpts, error) {
+	if _, err := ks.find(address); err != nil {
+		return nil, err
+	}
+	return NewPlasmaTransactor(ks, address), nil
+}
+
+func (ks *KeyStore) find(address common.Address) (accounts.Account, error) {
+	// the keystore matches any account to the zero address
+	if (address == common.Address{}) {
+		return accounts.Account{}, ErrUnknownAccount
+	}
+
+	acc, err := ks.keys.Find(accounts.Account{Address: address})
+	if err != nil {
+		return accounts.Account{}, ErrUnknownAccount
+	}
+	return acc, nil
+}
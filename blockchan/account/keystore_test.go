@@ -0,0 +1,124 @@
+package account
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/SmartMeshFoundation/Spectrum/accounts/keystore"
+	"github.com/SmartMeshFoundation/Spectrum/common"
+	"github.com/SmartMeshFoundation/Spectrum/core/types"
+
+	"github.com/SmartMeshFoundation/SmartPlasma/blockchan/transaction"
+)
+
+func TestKeyStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "keystore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ks := NewKeyStore(dir, keystore.LightScryptN, keystore.LightScryptP)
+
+	address, err := ks.NewAccount("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if accounts := ks.Accounts(); len(accounts) != 1 ||
+		accounts[0] != address {
+		t.Fatal("wrong accounts")
+	}
+
+	if _, err := ks.Transactor(common.Address{}); err != ErrUnknownAccount {
+		t.Fatalf("expected %s, got %v", ErrUnknownAccount, err)
+	}
+
+	opts, err := ks.Transactor(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tx, err := transaction.NewTransaction(big.NewInt(1), big.NewInt(1),
+		big.NewInt(1), big.NewInt(1), address)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	domain := transaction.NewDomain(big.NewInt(1), address)
+
+	if _, err := opts.PlasmaSigner(address, tx, domain); err != ErrLocked {
+		t.Fatalf("expected %s, got %v", ErrLocked, err)
+	}
+
+	if err := ks.Unlock(address, "wrong"); err == nil {
+		t.Fatal("the account is unlocked with a wrong passphrase")
+	}
+
+	if err := ks.Unlock(address, "secret"); err != nil {
+		t.Fatal(err)
+	}
+
+	signed, err := opts.PlasmaSigner(address, tx, domain)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sender, err := transaction.Sender(signed, domain)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if sender != address {
+		t.Fatal("wrong sender")
+	}
+
+	ethTx := types.NewTransaction(0, address, big.NewInt(1),
+		big.NewInt(21000), big.NewInt(1), nil)
+	signer := types.HomesteadSigner{}
+
+	signedEthTx, err := opts.Signer(signer, address, ethTx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ethSender, err := types.Sender(signer, signedEthTx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ethSender != address {
+		t.Fatal("wrong sender")
+	}
+
+	if _, err := opts.PlasmaSigner(common.Address{}, tx,
+		domain); err != ErrNotAuthorized {
+		t.Fatalf("expected %s, got %v", ErrNotAuthorized, err)
+	}
+
+	if err := ks.Lock(address); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := opts.PlasmaSigner(address, tx, domain); err != ErrLocked {
+		t.Fatalf("expected %s, got %v", ErrLocked, err)
+	}
+
+	err = ks.TimedUnlock(address, "secret", 50*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := opts.PlasmaSigner(address, tx, domain); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+
+	if _, err := opts.PlasmaSigner(address, tx, domain); err != ErrLocked {
+		t.Fatalf("expected %s, got %v", ErrLocked, err)
+	}
+}
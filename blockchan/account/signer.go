@@ -0,0 +1,77 @@
+package account
+
+import (
+	"github.com/SmartMeshFoundation/Spectrum/accounts/abi/bind"
+	"github.com/SmartMeshFoundation/Spectrum/common"
+	"github.com/SmartMeshFoundation/Spectrum/core/types"
+
+	"github.com/SmartMeshFoundation/SmartPlasma/blockchan/transaction"
+)
+
+// Signer signs hashes by accounts which keys are kept outside
+// of the process memory: in an encrypted keystore, a hardware wallet
+// or a remote signing service.
+type Signer interface {
+	// SignHash returns a signature of the hash by the account
+	// in [R || S || V] format where V is 0 or 1.
+	SignHash(address common.Address, hash []byte) ([]byte, error)
+}
+
+// SignerFunc is an adapter to use a function as Signer.
+type SignerFunc func(address common.Address, hash []byte) ([]byte, error)
+
+// SignHash calls f(address, hash).
+func (f SignerFunc) SignHash(address common.Address,
+	hash []byte) ([]byte, error) {
+	return f(address, hash)
+}
+
+// NewPlasmaTransactor creates transact options which sign Spectrum
+// and Plasma Cash transactions of the account by the signer.
+// The options can be used by transport.Client and by sessions
+// of RootChain and Mediator contracts.
+func NewPlasmaTransactor(signer Signer,
+	address common.Address) *PlasmaTransactOpts {
+	return &PlasmaTransactOpts{
+		TransactOpts: &bind.TransactOpts{
+			From: address,
+			Signer: func(s types.Signer, from common.Address,
+				tx *types.Transaction) (*types.Transaction, error) {
+				if from != address {
+					return nil, ErrNotAuthorized
+				}
+
+				sig, err := signer.SignHash(from, s.Hash(tx).Bytes())
+				if err != nil {
+					return nil, err
+				}
+				return tx.WithSignature(s, sig)
+			},
+		},
+		PlasmaSigner: func(from common.Address, tx *transaction.Transaction,
+			domain transaction.Domain) (*transaction.Transaction, error) {
+			if from != address {
+				return nil, ErrNotAuthorized
+			}
+
+			sig, err := signer.SignHash(from, domain.SigHash(tx).Bytes())
+			if err != nil {
+				return nil, err
+			}
+			return tx.WithSignature(sig, domain)
+		},
+		OptInSigner: func(from common.Address, optIn *transaction.OptIn,
+			domain transaction.Domain) (*transaction.OptIn, error) {
+			if from != address {
+				return nil, ErrNotAuthorized
+			}
+
+			sig, err := signer.SignHash(from,
+				domain.OptInSigHash(optIn).Bytes())
+			if err != nil {
+				return nil, err
+			}
+			return optIn.WithSignature(sig), nil
+		},
+	}
+}
@@ -2,15 +2,18 @@ package backend
 
 import (
 	"context"
-	"log"
+	stdlog "log"
 	"time"
 
+	"github.com/SmartMeshFoundation/Spectrum"
 	"github.com/SmartMeshFoundation/Spectrum/accounts/abi/bind"
-	"github.com/SmartMeshFoundation/Spectrum/accounts/abi/bind/backends"
 	"github.com/SmartMeshFoundation/Spectrum/common"
 	"github.com/SmartMeshFoundation/Spectrum/core/types"
 	"github.com/SmartMeshFoundation/Spectrum/ethclient"
+	"github.com/SmartMeshFoundation/Spectrum/log"
 	"github.com/pkg/errors"
+
+	"github.com/SmartMeshFoundation/SmartPlasma/logger"
 )
 
 // Errors.
@@ -23,6 +26,9 @@ type Backend interface {
 	Connect() bind.ContractBackend
 	Mine(ctx context.Context, tx *types.Transaction) (*types.Receipt, error)
 	GoodTransaction(tx *types.Transaction) bool
+	FilterLogs(ctx context.Context,
+		query ethereum.FilterQuery) ([]types.Log, error)
+	SetLogger(logger log.Logger)
 }
 
 // Simulator interface.
@@ -33,20 +39,33 @@ type Simulator interface {
 
 type backend struct {
 	connect bind.ContractBackend
+	logger  log.Logger
 }
 
 // NewBackend makes new Backend.
 func NewBackend(url string) Backend {
+	l := logger.New("backend")
+
 	cli, err := ethclient.Dial(url)
 	if err != nil {
-		log.Fatal(err.Error())
+		l.Error("Failed to connect to Spectrum node",
+			"url", url, "err", err)
+		stdlog.Fatal(err.Error())
 	}
-	return &backend{connect: cli}
+	return &backend{connect: cli, logger: l}
 }
 
 // NewSimulatedBackend makes new backend simulator.
 func NewSimulatedBackend(accounts []common.Address) Backend {
-	return &backend{connect: newSimulator(accounts)}
+	return &backend{
+		connect: newSimulator(accounts),
+		logger:  logger.New("backend"),
+	}
+}
+
+// SetLogger sets the logger for the backend.
+func (back *backend) SetLogger(l log.Logger) {
+	back.logger = logger.Wrap(l)
 }
 
 // Connect gets connect to Ethereum backend.
@@ -57,20 +76,37 @@ func (back *backend) Connect() bind.ContractBackend {
 // Mine to wait mining.
 func (back *backend) Mine(ctx context.Context,
 	tx *types.Transaction) (*types.Receipt, error) {
+	var tr *types.Receipt
+	var err error
+
 	switch conn := back.connect.(type) {
 	case *ethclient.Client:
-		return bind.WaitMined(ctx, conn, tx)
-	case *backends.SimulatedBackend:
+		tr, err = bind.WaitMined(ctx, conn, tx)
+	case *simulator:
 		conn.Commit()
-		return bind.WaitMined(ctx, conn, tx)
+		tr, err = bind.WaitMined(ctx, conn, tx)
+	default:
+		return nil, ErrInvalidBackend
 	}
-	return nil, ErrInvalidBackend
+
+	l := logger.FromContext(ctx, back.logger)
+
+	if err != nil {
+		mineErrors.Inc()
+		l.Warn("Failed to mine transaction", "tx", tx.Hash(), "err", err)
+		return nil, err
+	}
+	observeReceipt(tx, tr)
+
+	l.Debug("Transaction mined", "tx", tx.Hash(),
+		"status", tr.Status, "gas", tr.GasUsed)
+	return tr, nil
 }
 
 // AdjustTime adds a time shift to the simulated clock.
 func (back *backend) AdjustTime(adjustment time.Duration) error {
 	switch conn := back.connect.(type) {
-	case *backends.SimulatedBackend:
+	case *simulator:
 		err := conn.AdjustTime(adjustment)
 		if err != nil {
 			return err
@@ -80,6 +116,19 @@ func (back *backend) AdjustTime(adjustment time.Duration) error {
 	return nil
 }
 
+// FilterLogs returns logs of mined transactions which match the query.
+func (back *backend) FilterLogs(ctx context.Context,
+	query ethereum.FilterQuery) ([]types.Log, error) {
+	filterer, ok := back.connect.(interface {
+		FilterLogs(ctx context.Context,
+			query ethereum.FilterQuery) ([]types.Log, error)
+	})
+	if !ok {
+		return nil, ErrInvalidBackend
+	}
+	return filterer.FilterLogs(ctx, query)
+}
+
 // GoodTransaction returns true if transaction status = 1.
 func (back *backend) GoodTransaction(tx *types.Transaction) bool {
 	tr, err := back.Mine(context.Background(), tx)
@@ -87,8 +136,6 @@ func (back *backend) GoodTransaction(tx *types.Transaction) bool {
 		return false
 	}
 
-	//fmt.Printf("gas: %d\n", tr.GasUsed)
-
 	if tr.Status != 1 {
 		return false
 	}
@@ -0,0 +1,45 @@
+package backend
+
+import (
+	"math/big"
+
+	"github.com/SmartMeshFoundation/Spectrum/core/types"
+
+	"github.com/SmartMeshFoundation/SmartPlasma/metrics"
+)
+
+// Backend metrics.
+var (
+	minedTransactions = metrics.DefaultRegistry.NewCounter(
+		"smartplasma_backend_mined_transactions_total",
+		"Number of mined Spectrum transactions.", "status")
+	mineGasUsed = metrics.DefaultRegistry.NewHistogram(
+		"smartplasma_backend_gas_used",
+		"Gas used by mined Spectrum transactions.",
+		metrics.ExponentialBuckets(21000, 2, 10))
+	mineCost = metrics.DefaultRegistry.NewCounter(
+		"smartplasma_backend_gas_cost_wei_total",
+		"Total cost of mined Spectrum transactions in wei.")
+	mineErrors = metrics.DefaultRegistry.NewCounter(
+		"smartplasma_backend_mine_errors_total",
+		"Number of transactions which failed to be mined.")
+)
+
+func observeReceipt(tx *types.Transaction, tr *types.Receipt) {
+	if tr.Status == types.ReceiptStatusFailed {
+		minedTransactions.Inc("failed")
+	} else {
+		minedTransactions.Inc("successful")
+	}
+
+	if tr.GasUsed == nil {
+		return
+	}
+
+	gas, _ := new(big.Float).SetInt(tr.GasUsed).Float64()
+	mineGasUsed.Observe(gas)
+
+	cost, _ := new(big.Float).SetInt(
+		new(big.Int).Mul(tr.GasUsed, tx.GasPrice())).Float64()
+	mineCost.Add(cost)
+}
@@ -1,15 +1,29 @@
 package backend
 
 import (
+	"context"
 	"fmt"
 	"math/big"
+	"sync"
 
+	"github.com/SmartMeshFoundation/Spectrum"
 	"github.com/SmartMeshFoundation/Spectrum/accounts/abi/bind/backends"
 	"github.com/SmartMeshFoundation/Spectrum/common"
 	"github.com/SmartMeshFoundation/Spectrum/core"
+	"github.com/SmartMeshFoundation/Spectrum/core/types"
 )
 
-func newSimulator(accounts []common.Address) *backends.SimulatedBackend {
+// simulator is the simulated backend which also filters logs.
+// The simulated backend of Spectrum does not index logs,
+// so logs are read from receipts of transactions sent to it.
+type simulator struct {
+	*backends.SimulatedBackend
+
+	mtx sync.Mutex
+	txs []common.Hash
+}
+
+func newSimulator(accounts []common.Address) *simulator {
 	alloc := make(core.GenesisAlloc)
 
 	balance := new(big.Int)
@@ -19,5 +33,94 @@ func newSimulator(accounts []common.Address) *backends.SimulatedBackend {
 		alloc[acc] = core.GenesisAccount{Balance: balance}
 	}
 
-	return backends.NewSimulatedBackend(alloc)
+	return &simulator{SimulatedBackend: backends.NewSimulatedBackend(alloc)}
+}
+
+// SendTransaction sends the transaction to the pending block
+// and remembers it for FilterLogs.
+func (sim *simulator) SendTransaction(ctx context.Context,
+	tx *types.Transaction) error {
+	if err := sim.SimulatedBackend.SendTransaction(ctx, tx); err != nil {
+		return err
+	}
+
+	sim.mtx.Lock()
+	defer sim.mtx.Unlock()
+	sim.txs = append(sim.txs, tx.Hash())
+	return nil
+}
+
+// FilterLogs returns logs of mined transactions which match the query.
+func (sim *simulator) FilterLogs(ctx context.Context,
+	query ethereum.FilterQuery) ([]types.Log, error) {
+	sim.mtx.Lock()
+	txs := append([]common.Hash{}, sim.txs...)
+	sim.mtx.Unlock()
+
+	var logs []types.Log
+	for _, hash := range txs {
+		tr, err := sim.TransactionReceipt(ctx, hash)
+		if err != nil {
+			return nil, err
+		}
+
+		// the transaction is not mined yet
+		if tr == nil {
+			continue
+		}
+
+		for _, l := range tr.Logs {
+			if matchLog(query, l) {
+				logs = append(logs, *l)
+			}
+		}
+	}
+	return logs, nil
+}
+
+// matchLog returns true if the log matches the filter query.
+func matchLog(query ethereum.FilterQuery, l *types.Log) bool {
+	number := new(big.Int).SetUint64(l.BlockNumber)
+	if query.FromBlock != nil && number.Cmp(query.FromBlock) < 0 {
+		return false
+	}
+
+	if query.ToBlock != nil && number.Cmp(query.ToBlock) > 0 {
+		return false
+	}
+
+	if len(query.Addresses) > 0 {
+		found := false
+		for _, addr := range query.Addresses {
+			if addr == l.Address {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if len(query.Topics) > len(l.Topics) {
+		return false
+	}
+
+	for i, alternatives := range query.Topics {
+		if len(alternatives) == 0 {
+			continue
+		}
+
+		found := false
+		for _, topic := range alternatives {
+			if topic == l.Topics[i] {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
 }
@@ -19,6 +19,7 @@ type CheckpointBlock interface {
 	AddCheckpoint(uid, number *big.Int) error
 	NumberOfCheckpoints() int64
 	GetNonce(uid *big.Int) *big.Int
+	Contains(uid *big.Int) bool
 }
 
 // Block is checkpoint block object.
@@ -28,7 +29,8 @@ type Block struct {
 	numbers map[string]common.Hash
 	tree    *merkle.Tree
 
-	built bool
+	built  bool
+	legacy bool
 }
 
 // NewBlock creates new Checkpoints block in memory.
@@ -39,6 +41,48 @@ func NewBlock() CheckpointBlock {
 	}
 }
 
+// NewLegacyBlock creates new Checkpoints block in memory which is built
+// with the legacy order of Merkle tree levels, see merkle.NewLegacyTree.
+// It is used to rebuild checkpoints published with the legacy order.
+func NewLegacyBlock() CheckpointBlock {
+	return &Block{
+		mtx:     sync.Mutex{},
+		numbers: make(map[string]common.Hash),
+		legacy:  true,
+	}
+}
+
+// MatchHash returns the block if it is built to the hash. Otherwise,
+// it returns the copy of the block built with the legacy order
+// of Merkle tree levels if the copy is built to the hash, checkpoints
+// published with the legacy order match their hashes only this way.
+// It returns nil if the block does not match the hash.
+func MatchHash(blk CheckpointBlock, hash common.Hash) (CheckpointBlock,
+	error) {
+	if blk.Hash() == hash {
+		return blk, nil
+	}
+
+	raw, err := blk.Marshal()
+	if err != nil {
+		return nil, err
+	}
+
+	legacy := NewLegacyBlock()
+	if err := legacy.Unmarshal(raw); err != nil {
+		return nil, err
+	}
+
+	if _, err := legacy.Build(); err != nil {
+		return nil, err
+	}
+
+	if legacy.Hash() != hash {
+		return nil, nil
+	}
+	return legacy, nil
+}
+
 // Hash returns block hash.
 func (bl *Block) Hash() common.Hash {
 	if !bl.built {
@@ -84,7 +128,12 @@ func (bl *Block) Build() (common.Hash, error) {
 		sort.Strings(bl.uIDs)
 	}
 
-	tree, err := merkle.NewTree(bl.numbers, merkle.Depth257)
+	newTree := merkle.NewTree
+	if bl.legacy {
+		newTree = merkle.NewLegacyTree
+	}
+
+	tree, err := newTree(bl.numbers, merkle.Depth257)
 	if err != nil {
 		return common.Hash{}, errors.Wrap(err, "failed to build block")
 	}
@@ -156,3 +205,13 @@ func (bl *Block) GetNonce(uid *big.Int) *big.Int {
 
 	return bl.tree.GetStructure()[0][uid.String()].Big()
 }
+
+// Contains returns true if the uid is in the block.
+// The block does not need to be built.
+func (bl *Block) Contains(uid *big.Int) bool {
+	bl.mtx.Lock()
+	defer bl.mtx.Unlock()
+
+	_, ok := bl.numbers[uid.String()]
+	return ok
+}
@@ -59,6 +59,16 @@ func TestAddCheckpoint(t *testing.T) {
 		t.Fatalf("tx number must be %d, got %d",
 			numberCheckpoints, len(bl.numbers))
 	}
+
+	for _, ch := range chs {
+		if !bl.Contains(ch.uid) {
+			t.Fatalf("uid %s is not in the block", ch.uid)
+		}
+	}
+
+	if bl.Contains(big.NewInt(-1)) {
+		t.Fatal("unknown uid is in the block")
+	}
 }
 
 func TestBlockBuild(t *testing.T) {
@@ -137,3 +147,55 @@ func TestBlockAddExistsTx(t *testing.T) {
 		t.Fatal("the transaction already exists in the block")
 	}
 }
+
+func TestMatchHash(t *testing.T) {
+	build := func(blk CheckpointBlock) common.Hash {
+		for _, uid := range []int64{10, 11, 100} {
+			err := blk.AddCheckpoint(big.NewInt(uid), big.NewInt(1))
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		hash, err := blk.Build()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return hash
+	}
+
+	bl := NewBlock()
+	hash := build(bl)
+	legacyHash := build(NewLegacyBlock())
+
+	if hash == legacyHash {
+		t.Fatal("legacy hash is not changed")
+	}
+
+	matched, err := MatchHash(bl, hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if matched != bl {
+		t.Fatal("block does not match its hash")
+	}
+
+	matched, err = MatchHash(bl, legacyHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if matched == nil || matched.Hash() != legacyHash {
+		t.Fatal("block does not match its legacy hash")
+	}
+
+	matched, err = MatchHash(bl, common.Hash{1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if matched != nil {
+		t.Fatal("block matches a wrong hash")
+	}
+}
@@ -0,0 +1,53 @@
+//go:build go1.18
+// +build go1.18
+
+package checkpoints
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/SmartMeshFoundation/Spectrum/common"
+
+	"github.com/SmartMeshFoundation/SmartPlasma/merkle"
+)
+
+// FuzzUnmarshal checks that malformed blocks are rejected with an error
+// and decoded blocks are built with valid proofs.
+func FuzzUnmarshal(f *testing.F) {
+	blk := NewBlock()
+	for _, cp := range generateCheckpoints(numberCheckpoints) {
+		if err := blk.AddCheckpoint(cp.uid, cp.nonce); err != nil {
+			f.Fatal(err)
+		}
+	}
+
+	raw, err := blk.Marshal()
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add(raw)
+	f.Add([]byte(`{"-1":"0x01"}`))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		decoded := NewBlock()
+		if err := decoded.Unmarshal(data); err != nil {
+			return
+		}
+
+		root, err := decoded.Build()
+		if err != nil {
+			return
+		}
+
+		for _, uid := range decoded.(*Block).uIDs {
+			id, _ := new(big.Int).SetString(uid, 10)
+			if !merkle.CheckMembership(id,
+				common.BigToHash(decoded.GetNonce(id)), root,
+				decoded.CreateProof(id)) {
+				t.Fatalf("invalid proof for uid %s", uid)
+			}
+		}
+		decoded.CreateProof(nil)
+	})
+}
@@ -29,16 +29,20 @@ type TxBlock interface {
 	NumberOfTX() int64
 	Transactions(ctx context.Context) <-chan *transaction.Transaction
 	GetTx(uid *big.Int) (*transaction.Transaction, error)
+	Legacy() bool
 }
 
-// Block is transactions block object.
+// Block is transactions block object. A split or a merge transaction
+// is included under uid of every coin it spends or creates.
 type Block struct {
 	mtx  sync.Mutex
 	uIDs []string
 	txs  map[string]*transaction.Transaction
 	tree *merkle.Tree
+	num  int64
 
-	built bool
+	built  bool
+	legacy bool
 }
 
 // NewBlock creates new Transactions block in memory.
@@ -49,6 +53,53 @@ func NewBlock() TxBlock {
 	}
 }
 
+// NewLegacyBlock creates new Transactions block in memory which is built
+// with the legacy order of Merkle tree levels, see merkle.NewLegacyTree.
+// It is used to rebuild blocks published with the legacy order.
+func NewLegacyBlock() TxBlock {
+	return &Block{
+		mtx:    sync.Mutex{},
+		txs:    make(map[string]*transaction.Transaction),
+		legacy: true,
+	}
+}
+
+// MatchRoot returns the block if it is built to the root. Otherwise,
+// it returns the copy of the block built with the legacy order
+// of Merkle tree levels if the copy is built to the root, blocks published
+// with the legacy order match their roots only this way. It returns nil
+// if the block does not match the root.
+func MatchRoot(blk TxBlock, root common.Hash) (TxBlock, error) {
+	if blk.Hash() == root {
+		return blk, nil
+	}
+
+	raw, err := blk.Marshal()
+	if err != nil {
+		return nil, err
+	}
+
+	legacy := NewLegacyBlock()
+	if err := legacy.Unmarshal(raw); err != nil {
+		return nil, err
+	}
+
+	if _, err := legacy.Build(); err != nil {
+		return nil, err
+	}
+
+	if legacy.Hash() != root {
+		return nil, nil
+	}
+	return legacy, nil
+}
+
+// Legacy returns true if the block is built with the legacy order
+// of Merkle tree levels.
+func (bl *Block) Legacy() bool {
+	return bl.legacy
+}
+
 // Hash returns block hash.
 func (bl *Block) Hash() common.Hash {
 	if !bl.built {
@@ -66,19 +117,32 @@ func (bl *Block) AddTx(tx *transaction.Transaction) error {
 	bl.mtx.Lock()
 	defer bl.mtx.Unlock()
 
-	if _, ok := bl.txs[tx.UID().String()]; ok {
-		return errors.Errorf("transaction for uid %s already"+
-			" exist in the block", tx.UID().String())
+	uids := tx.UIDs()
+	for _, uid := range uids {
+		if _, ok := bl.txs[uid.String()]; ok {
+			return errors.Errorf("transaction for uid %s already"+
+				" exist in the block", uid.String())
+		}
 	}
 
-	bl.uIDs = append(bl.uIDs, tx.UID().String())
-	bl.txs[tx.UID().String()] = tx
+	for _, uid := range uids {
+		bl.uIDs = append(bl.uIDs, uid.String())
+		bl.txs[uid.String()] = tx
+	}
+	bl.num++
 	return nil
 }
 
 // NumberOfTX returns number of transactions in the block.
 func (bl *Block) NumberOfTX() int64 {
-	return int64(len(bl.txs))
+	return bl.num
+}
+
+// primary returns true if the transaction is stored under the uid
+// for the first time, so every transaction is visited once.
+func primary(uid string, tx *transaction.Transaction) bool {
+	return tx.Type() == transaction.TransferType ||
+		tx.UIDs()[0].String() == uid
 }
 
 // Build finalizes the block.
@@ -100,7 +164,12 @@ func (bl *Block) Build() (common.Hash, error) {
 		leaves[uid] = bl.txs[uid].Hash()
 	}
 
-	tree, err := merkle.NewTree(leaves, merkle.Depth257)
+	newTree := merkle.NewTree
+	if bl.legacy {
+		newTree = merkle.NewLegacyTree
+	}
+
+	tree, err := newTree(leaves, merkle.Depth257)
 	if err != nil {
 		return common.Hash{}, errors.Wrap(err, "failed to build block")
 	}
@@ -132,6 +201,10 @@ func (bl *Block) Marshal() ([]byte, error) {
 	txs := make(map[string][]byte)
 
 	for uid, tx := range bl.txs {
+		if !primary(uid, tx) {
+			continue
+		}
+
 		var data []byte
 		buf := bytes.NewBuffer(data)
 
@@ -192,7 +265,11 @@ func (bl *Block) Transactions(
 		defer bl.mtx.Unlock()
 		defer close(result)
 
-		for _, tx := range bl.txs {
+		for uid, tx := range bl.txs {
+			if !primary(uid, tx) {
+				continue
+			}
+
 			select {
 			case result <- tx:
 			case <-ctx.Done():
@@ -32,7 +32,7 @@ func testAcc() *acc {
 	}
 }
 
-func testTX(t *testing.T, prevBlock *big.Int, newOwner common.Address,
+func testTX(t testing.TB, prevBlock *big.Int, newOwner common.Address,
 	key *ecdsa.PrivateKey, nonce *big.Int) *transaction.Transaction {
 	randKey := account.GenKey()
 
@@ -42,7 +42,7 @@ func testTX(t *testing.T, prevBlock *big.Int, newOwner common.Address,
 		t.Fatal(err)
 	}
 
-	tx, err := unsignedTx.SignTx(key)
+	tx, err := unsignedTx.SignTx(key, transaction.LegacyDomain)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -59,7 +59,7 @@ func testTXBench(b *testing.B, prevBlock *big.Int, newOwner common.Address,
 		b.Fatal(err)
 	}
 
-	tx, err := unsignedTx.SignTx(key)
+	tx, err := unsignedTx.SignTx(key, transaction.LegacyDomain)
 	if err != nil {
 		b.Fatal(err)
 	}
@@ -182,6 +182,94 @@ func TestBlockEncodeDecode(t *testing.T) {
 	validateTx(t, txs[0], root2.Bytes(), proof)
 }
 
+func TestBlockSplit(t *testing.T) {
+	owner := testAcc()
+	uid := big.NewInt(43)
+
+	unsignedTx, err := transaction.NewSplit(big.NewInt(1), uid,
+		big.NewInt(3), big.NewInt(1), uid, []transaction.Output{
+			{Amount: big.NewInt(1), Owner: owner.account.From},
+			{Amount: big.NewInt(2), Owner: owner.account.From},
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	split, err := unsignedTx.SignTx(owner.key, transaction.LegacyDomain)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	txs := generateTXs(t, numberTx, testPrevBlock)
+	bl := NewBlock().(*Block)
+
+	for _, tx := range append(txs, split) {
+		if err := bl.AddTx(tx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if bl.NumberOfTX() != numberTx+1 {
+		t.Fatalf("tx number must be %d, got %d",
+			numberTx+1, bl.NumberOfTX())
+	}
+
+	child, err := transaction.NewTransaction(big.NewInt(1),
+		transaction.ChildUID(uid, 1), big.NewInt(2), big.NewInt(1),
+		owner.account.From)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := bl.AddTx(child); err == nil {
+		t.Fatal("the sub-coin already exists in the block")
+	}
+
+	root1, err := bl.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err := bl.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reconstructed := NewBlock()
+	if err := reconstructed.Unmarshal(raw); err != nil {
+		t.Fatal(err)
+	}
+
+	if reconstructed.NumberOfTX() != numberTx+1 {
+		t.Fatal("wrong number of transactions")
+	}
+
+	root2, err := reconstructed.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if root1 != root2 {
+		t.Fatal("blocks not equal")
+	}
+
+	for _, uid := range split.UIDs() {
+		tx, err := reconstructed.GetTx(uid)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !merkle.CheckMembership(uid, split.Hash(), root2,
+			reconstructed.CreateProof(uid)) {
+			t.Fatal("the split is not included under the uid")
+		}
+
+		if tx.Hash() != split.Hash() {
+			t.Fatal("wrong transaction")
+		}
+	}
+}
+
 func TestBlockAddExistsTx(t *testing.T) {
 	txs := generateTXs(t, numberTx, testPrevBlock)
 
@@ -212,3 +300,68 @@ func BenchmarkTrBlock(b *testing.B) {
 		bl.Build()
 	}
 }
+
+func TestMatchRoot(t *testing.T) {
+	a := testAcc()
+
+	build := func(blk TxBlock) common.Hash {
+		for _, uid := range []int64{10, 11, 100} {
+			unsignedTx, err := transaction.NewTransaction(
+				big.NewInt(testPrevBlock), big.NewInt(uid), big.NewInt(1),
+				big.NewInt(0), a.account.From)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			tx, err := unsignedTx.SignTx(a.key, transaction.LegacyDomain)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := blk.AddTx(tx); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		root, err := blk.Build()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return root
+	}
+
+	bl := NewBlock()
+	root := build(bl)
+	legacyRoot := build(NewLegacyBlock())
+
+	if root == legacyRoot {
+		t.Fatal("legacy root is not changed")
+	}
+
+	matched, err := MatchRoot(bl, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if matched != bl {
+		t.Fatal("block does not match its root")
+	}
+
+	matched, err = MatchRoot(bl, legacyRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if matched == nil || matched.Hash() != legacyRoot {
+		t.Fatal("block does not match its legacy root")
+	}
+
+	matched, err = MatchRoot(bl, common.Hash{1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if matched != nil {
+		t.Fatal("block matches a wrong root")
+	}
+}
@@ -0,0 +1,59 @@
+//go:build go1.18
+// +build go1.18
+
+package transactions
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/SmartMeshFoundation/SmartPlasma/merkle"
+)
+
+// FuzzUnmarshal checks that malformed blocks are rejected with an error
+// and decoded blocks are built with valid proofs.
+func FuzzUnmarshal(f *testing.F) {
+	acc := testAcc()
+	blk := NewBlock()
+	for i := 0; i < numberTx; i++ {
+		tx := testTX(f, big.NewInt(testPrevBlock), acc.account.From,
+			acc.key, big.NewInt(int64(i)))
+		if err := blk.AddTx(tx); err != nil {
+			f.Fatal(err)
+		}
+	}
+
+	raw, err := blk.Marshal()
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add(raw)
+	f.Add([]byte(`{"1":"wA=="}`))
+	f.Add([]byte(`{"1":null}`))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		decoded := NewBlock()
+		if err := decoded.Unmarshal(data); err != nil {
+			return
+		}
+
+		root, err := decoded.Build()
+		if err != nil {
+			return
+		}
+
+		for _, uid := range decoded.(*Block).uIDs {
+			id, _ := new(big.Int).SetString(uid, 10)
+			tx, err := decoded.GetTx(id)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !merkle.CheckMembership(id, tx.Hash(), root,
+				decoded.CreateProof(id)) {
+				t.Fatalf("invalid proof for uid %s", uid)
+			}
+		}
+		decoded.CreateProof(nil)
+	})
+}
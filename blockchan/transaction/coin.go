@@ -0,0 +1,368 @@
+package transaction
+
+import (
+	"math/big"
+	"sort"
+
+	"github.com/SmartMeshFoundation/Spectrum/common"
+	"github.com/SmartMeshFoundation/Spectrum/crypto"
+)
+
+// Type is a type of Plasma transaction.
+type Type byte
+
+// Transaction types.
+const (
+	// TransferType moves a whole coin to a new owner.
+	TransferType Type = iota
+	// SplitType spends a coin and creates sub-coins with parts
+	// of its amount.
+	SplitType
+	// MergeType spends several sub-coins of one deposit
+	// and creates a coin with their total amount.
+	MergeType
+	// SwapType exchanges two coins between their owners atomically.
+	SwapType
+)
+
+// Limits of split and merge transactions.
+const (
+	MaxSplitOutputs = 64
+	MaxMergeInputs  = 64
+)
+
+// Coin is a coin created by a transaction.
+type Coin struct {
+	UID    *big.Int
+	Amount *big.Int
+	Owner  common.Address
+	Nonce  *big.Int
+}
+
+// Input is a coin spent by a transaction. Nonce is the nonce
+// of the spending transaction, it is the nonce of the coin plus one.
+type Input struct {
+	PrevBlock *big.Int
+	UID       *big.Int
+	Amount    *big.Int
+	Nonce     *big.Int
+}
+
+// Output is a sub-coin created by a split transaction.
+type Output struct {
+	Amount *big.Int
+	Owner  common.Address
+}
+
+type splitData struct {
+	PrevBlock *big.Int
+	UID       *big.Int
+	Amount    *big.Int
+	Nonce     *big.Int
+	Root      *big.Int
+	Outputs   []Output
+	Sig       []byte
+}
+
+type mergeData struct {
+	Root     *big.Int
+	Inputs   []Input
+	NewOwner common.Address
+	Sig      []byte
+}
+
+// ChildUID returns uid of a sub-coin created by a split of the parent coin.
+func ChildUID(parent *big.Int, index int) *big.Int {
+	return new(big.Int).SetBytes(crypto.Keccak256(
+		common.BigToHash(parent).Bytes(),
+		common.BigToHash(big.NewInt(int64(index))).Bytes()))
+}
+
+// MergeUID returns uid of a coin created by a merge of sub-coins
+// of the root deposit.
+func MergeUID(root *big.Int, uids []*big.Int) *big.Int {
+	sorted := make([]*big.Int, len(uids))
+	copy(sorted, uids)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Cmp(sorted[j]) < 0
+	})
+
+	data := [][]byte{common.BigToHash(root).Bytes()}
+	for _, uid := range sorted {
+		data = append(data, common.BigToHash(uid).Bytes())
+	}
+	return new(big.Int).SetBytes(crypto.Keccak256(data...))
+}
+
+// NewSplit creates new unsigned split transaction. The transaction spends
+// the coin `uid` and creates sub-coins with uids ChildUID(uid, i).
+// Argument `root` is uid of the deposit the coin belongs to.
+func NewSplit(prevBlock, uid, amount, nonce, root *big.Int,
+	outputs []Output) (*Transaction, error) {
+	if prevBlock == nil || uid == nil || amount == nil ||
+		nonce == nil || root == nil {
+		return nil, ErrInvalidArguments
+	}
+
+	data := &splitData{
+		PrevBlock: prevBlock,
+		UID:       uid,
+		Amount:    amount,
+		Nonce:     nonce,
+		Root:      root,
+		Outputs:   outputs,
+	}
+	if err := data.validate(); err != nil {
+		return nil, err
+	}
+	return &Transaction{typ: SplitType, split: data}, nil
+}
+
+// NewMerge creates new unsigned merge transaction. The transaction spends
+// sub-coins of the deposit `root` and creates the coin
+// with uid MergeUID(root, uids of inputs).
+func NewMerge(root *big.Int, inputs []Input,
+	newOwner common.Address) (*Transaction, error) {
+	if root == nil {
+		return nil, ErrInvalidArguments
+	}
+
+	data := &mergeData{
+		Root:     root,
+		Inputs:   inputs,
+		NewOwner: newOwner,
+	}
+	if err := data.validate(); err != nil {
+		return nil, err
+	}
+	return &Transaction{typ: MergeType, merge: data}, nil
+}
+
+func (d *splitData) validate() error {
+	if d.PrevBlock == nil || d.UID == nil || d.Amount == nil ||
+		d.Nonce == nil || d.Root == nil {
+		return ErrInvalidArguments
+	}
+
+	if d.PrevBlock.Sign() < 0 {
+		return ErrInvalidPreviousBlock
+	}
+
+	if d.UID.Sign() == 0 || d.Root.Sign() == 0 {
+		return ErrInvalidUID
+	}
+
+	if len(d.Outputs) < 2 || len(d.Outputs) > MaxSplitOutputs {
+		return ErrInvalidOutputs
+	}
+
+	total := new(big.Int)
+	for _, out := range d.Outputs {
+		if out.Amount == nil || out.Amount.Sign() <= 0 {
+			return ErrInvalidAmount
+		}
+
+		if (out.Owner == common.Address{}) {
+			return ErrInvalidNewOwner
+		}
+		total.Add(total, out.Amount)
+	}
+
+	if total.Cmp(d.Amount) != 0 {
+		return ErrInvalidAmount
+	}
+	return nil
+}
+
+func (d *mergeData) validate() error {
+	if d.Root == nil {
+		return ErrInvalidArguments
+	}
+
+	if d.Root.Sign() == 0 {
+		return ErrInvalidUID
+	}
+
+	if (d.NewOwner == common.Address{}) {
+		return ErrInvalidNewOwner
+	}
+
+	if len(d.Inputs) < 2 || len(d.Inputs) > MaxMergeInputs {
+		return ErrInvalidInputs
+	}
+
+	uids := make(map[string]bool)
+	for _, in := range d.Inputs {
+		if in.PrevBlock == nil || in.UID == nil ||
+			in.Amount == nil || in.Nonce == nil {
+			return ErrInvalidArguments
+		}
+
+		if in.PrevBlock.Sign() < 0 {
+			return ErrInvalidPreviousBlock
+		}
+
+		if in.UID.Sign() == 0 || uids[in.UID.String()] {
+			return ErrInvalidUID
+		}
+		uids[in.UID.String()] = true
+
+		if in.Amount.Sign() <= 0 {
+			return ErrInvalidAmount
+		}
+	}
+	return nil
+}
+
+func (d *splitData) hash() common.Hash {
+	return rlpHash([]interface{}{
+		SplitType,
+		d.PrevBlock,
+		d.UID,
+		d.Amount,
+		d.Nonce,
+		d.Root,
+		d.Outputs,
+	})
+}
+
+func (d *mergeData) hash() common.Hash {
+	return rlpHash([]interface{}{
+		MergeType,
+		d.Root,
+		d.Inputs,
+		d.NewOwner,
+	})
+}
+
+func (d *mergeData) uid() *big.Int {
+	uids := make([]*big.Int, len(d.Inputs))
+	for i, in := range d.Inputs {
+		uids[i] = in.UID
+	}
+	return MergeUID(d.Root, uids)
+}
+
+func (d *mergeData) amount() *big.Int {
+	total := new(big.Int)
+	for _, in := range d.Inputs {
+		total.Add(total, in.Amount)
+	}
+	return total
+}
+
+// Type returns type of the transaction.
+func (tx *Transaction) Type() Type {
+	return tx.typ
+}
+
+// Root returns uid of the deposit which sub-coins are split or merged.
+// It is nil for transfers.
+func (tx *Transaction) Root() *big.Int {
+	switch tx.typ {
+	case SplitType:
+		return tx.split.Root
+	case MergeType:
+		return tx.merge.Root
+	}
+	return nil
+}
+
+// Outputs returns sub-coins created by a split transaction.
+func (tx *Transaction) Outputs() []Output {
+	if tx.typ != SplitType {
+		return nil
+	}
+	return tx.split.Outputs
+}
+
+// UIDs returns uids of all coins spent or created by the transaction.
+// The transaction is included in a block under each of them.
+func (tx *Transaction) UIDs() []*big.Int {
+	switch tx.typ {
+	case SplitType:
+		uids := []*big.Int{tx.split.UID}
+		for i := range tx.split.Outputs {
+			uids = append(uids, ChildUID(tx.split.UID, i))
+		}
+		return uids
+	case MergeType:
+		var uids []*big.Int
+		for _, in := range tx.merge.Inputs {
+			uids = append(uids, in.UID)
+		}
+		return append(uids, tx.merge.uid())
+	case SwapType:
+		return tx.swap.uids()
+	}
+	return []*big.Int{tx.data.UID}
+}
+
+// Inputs returns coins spent by the transaction.
+func (tx *Transaction) Inputs() []Input {
+	switch tx.typ {
+	case SplitType:
+		return []Input{{
+			PrevBlock: tx.split.PrevBlock,
+			UID:       tx.split.UID,
+			Amount:    tx.split.Amount,
+			Nonce:     tx.split.Nonce,
+		}}
+	case MergeType:
+		return tx.merge.Inputs
+	case SwapType:
+		return tx.swap.Inputs
+	}
+	return []Input{{
+		PrevBlock: tx.data.PrevBlock,
+		UID:       tx.data.UID,
+		Amount:    tx.data.Amount,
+		Nonce:     tx.data.Nonce,
+	}}
+}
+
+// Coins returns coins created by the transaction. A transfer creates
+// the state of the same coin with the new owner, sub-coins created
+// by a split or a merge start with zero nonce.
+func (tx *Transaction) Coins() []Coin {
+	switch tx.typ {
+	case SplitType:
+		var coins []Coin
+		for i, out := range tx.split.Outputs {
+			coins = append(coins, Coin{
+				UID:    ChildUID(tx.split.UID, i),
+				Amount: out.Amount,
+				Owner:  out.Owner,
+				Nonce:  new(big.Int),
+			})
+		}
+		return coins
+	case MergeType:
+		return []Coin{{
+			UID:    tx.merge.uid(),
+			Amount: tx.merge.amount(),
+			Owner:  tx.merge.NewOwner,
+			Nonce:  new(big.Int),
+		}}
+	case SwapType:
+		return tx.swap.coins()
+	}
+	return []Coin{{
+		UID:    tx.data.UID,
+		Amount: tx.data.Amount,
+		Owner:  tx.data.NewOwner,
+		Nonce:  tx.data.Nonce,
+	}}
+}
+
+// Coin returns the coin with the uid created by the transaction.
+// It returns false if the transaction spends the coin
+// or does not touch it.
+func (tx *Transaction) Coin(uid *big.Int) (Coin, bool) {
+	for _, coin := range tx.Coins() {
+		if coin.UID.Cmp(uid) == 0 {
+			return coin, true
+		}
+	}
+	return Coin{}, false
+}
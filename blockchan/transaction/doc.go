@@ -0,0 +1,51 @@
+// Package transaction implements transactions of Plasma Cash.
+//
+// A deposit is split into sub-coins with NewSplit and sub-coins of one
+// deposit are merged back with NewMerge. Sub-coins have UIDs
+// ChildUID(parent, i) and MergeUID(root, uids), they are transferred with
+// ordinary transactions starting from nonce 1. A split or a merge is
+// included in a block under the UID of every coin it spends or creates,
+// so the usual proofs of inclusion are used for sub-coins.
+//
+// Splits and merges are off-chain only. RootChain contract decodes
+// transfers only and accepts exits of whole deposits, so sub-coins can not
+// exit and a split can not challenge an exit of the split coin.
+//
+// Two owners exchange coins with a swap created by NewSwap and signed
+// by both of them. The swap is included in a block under both UIDs
+// or is not included. Like splits, swaps are off-chain only: a coin
+// needs two transfers after a swap before it can exit and a swap can not
+// challenge an exit of a previous owner.
+//
+// Transactions are typed. The envelope of a transaction is the type byte
+// followed by RLP encoded payload of the type, it is returned
+// by MarshalBinary and decoded by UnmarshalBinary. Decoding rejects
+// unknown types with ErrUnknownType and validates the payload. Hashes
+// of typed payloads start with the type, so a signature of one type
+// is never valid for another type. Transfers are version 0 and keep their
+// encoding as RLP list of six fields, which is expected by RootChain
+// contract. EncodeRLP writes transfers in this form and other types
+// as RLP string with the envelope, DecodeRLP accepts both forms.
+//
+// Signatures are bound to a Domain, the chain ID and the address
+// of RootChain contract. A transaction signed for one domain has another
+// sender in other domains, so it is rejected there:
+//
+//	domain := transaction.NewDomain(chainID, rootChainAddress)
+//	signed, err := tx.SignTx(key, domain)
+//	sender, err := transaction.Sender(signed, domain)
+//
+// LegacyDomain signs the hash of a transaction without the separator.
+//
+// Transactions and opt-ins are signed as raw hashes, Domain.SigHash and
+// Domain.OptInSigHash, because RootChain contract recovers signers of raw
+// hashes only. EIP-191 and typed data signatures of wallets are not
+// supported until the contract verifies them.
+//
+// Transactions have canonical JSON form with the type name, decimal
+// numbers, hex addresses and signatures and the hash. json.Marshal omits
+// the sender because it depends on the domain, MarshalJSONDomain adds
+// the sender of the domain. json.Unmarshal validates the transaction
+// and rejects it if the hash does not match. String returns a short
+// human-readable form for logs.
+package transaction
@@ -0,0 +1,55 @@
+package transaction
+
+import (
+	"math/big"
+
+	"github.com/SmartMeshFoundation/Spectrum/common"
+	"github.com/SmartMeshFoundation/Spectrum/crypto"
+)
+
+// Domain binds signatures of Plasma transactions to one deployment
+// of RootChain contract. A transaction signed for one domain has another
+// sender in other domains, so it can not be replayed there.
+type Domain struct {
+	ChainID   *big.Int
+	RootChain common.Address
+}
+
+// LegacyDomain signs the hash of a transaction without a domain
+// separator. RootChain contract verifies signatures of transfers
+// in this domain only.
+var LegacyDomain = Domain{}
+
+// NewDomain creates the domain of RootChain contract
+// deployed in the chain.
+func NewDomain(chainID *big.Int, rootChain common.Address) Domain {
+	return Domain{ChainID: chainID, RootChain: rootChain}
+}
+
+// Legacy returns true for the legacy domain.
+func (d Domain) Legacy() bool {
+	return (d.ChainID == nil || d.ChainID.Sign() == 0) &&
+		d.RootChain == common.Address{}
+}
+
+// Separator returns the domain separator.
+func (d Domain) Separator() common.Hash {
+	chainID := d.ChainID
+	if chainID == nil {
+		chainID = new(big.Int)
+	}
+	return crypto.Keccak256Hash(
+		common.BigToHash(chainID).Bytes(), d.RootChain.Bytes())
+}
+
+// SigHash returns the hash which is signed by owners of the transaction.
+func (d Domain) SigHash(tx *Transaction) common.Hash {
+	return d.sigHash(tx.Hash())
+}
+
+func (d Domain) sigHash(hash common.Hash) common.Hash {
+	if d.Legacy() {
+		return hash
+	}
+	return crypto.Keccak256Hash(d.Separator().Bytes(), hash.Bytes())
+}
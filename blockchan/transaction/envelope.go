@@ -0,0 +1,111 @@
+package transaction
+
+import (
+	"github.com/SmartMeshFoundation/Spectrum/common"
+	"github.com/SmartMeshFoundation/Spectrum/rlp"
+)
+
+// A transaction envelope is the type byte followed by RLP encoded
+// payload of the type. Hashes of typed payloads start with the type,
+// so a signature of one type can not be used for another type.
+//
+// Transfers are version 0 of the envelope. EncodeRLP keeps them
+// as RLP list of six fields, so blocks and transactions sent
+// to RootChain contract are byte-identical to transactions created
+// before typed transactions. DecodeRLP accepts both forms.
+
+// payload is the body of a transaction of a particular type.
+type payload interface {
+	validate() error
+	hash() common.Hash
+}
+
+func (d *txData) validate() error {
+	if d.PrevBlock == nil || d.UID == nil ||
+		d.Amount == nil || d.Nonce == nil {
+		return ErrInvalidArguments
+	}
+
+	if d.PrevBlock.Sign() < 0 {
+		return ErrInvalidPreviousBlock
+	}
+
+	if (d.NewOwner == common.Address{}) {
+		return ErrInvalidNewOwner
+	}
+
+	if d.UID.Sign() == 0 {
+		return ErrInvalidUID
+	}
+	return nil
+}
+
+func (d *txData) hash() common.Hash {
+	return rlpHash([]interface{}{
+		d.PrevBlock,
+		d.UID,
+		d.Amount,
+		d.NewOwner,
+		d.Nonce,
+	})
+}
+
+func (tx *Transaction) payload() payload {
+	switch tx.typ {
+	case SplitType:
+		return tx.split
+	case MergeType:
+		return tx.merge
+	case SwapType:
+		return tx.swap
+	}
+	return &tx.data
+}
+
+// MarshalBinary returns the envelope of the transaction.
+// Unlike EncodeRLP, a transfer is encoded with the type byte too.
+func (tx *Transaction) MarshalBinary() ([]byte, error) {
+	switch tx.typ {
+	case TransferType, SplitType, MergeType, SwapType:
+	default:
+		return nil, ErrUnknownType
+	}
+
+	raw, err := rlp.EncodeToBytes(tx.payload())
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{byte(tx.typ)}, raw...), nil
+}
+
+// UnmarshalBinary decodes the envelope of the transaction.
+// It returns ErrUnknownType if the type is not supported.
+func (tx *Transaction) UnmarshalBinary(raw []byte) error {
+	if len(raw) == 0 {
+		return ErrInvalidTx
+	}
+
+	decoded := Transaction{typ: Type(raw[0])}
+	switch decoded.typ {
+	case TransferType:
+	case SplitType:
+		decoded.split = &splitData{}
+	case MergeType:
+		decoded.merge = &mergeData{}
+	case SwapType:
+		decoded.swap = &swapData{}
+	default:
+		return ErrUnknownType
+	}
+
+	data := decoded.payload()
+	if err := rlp.DecodeBytes(raw[1:], data); err != nil {
+		return err
+	}
+
+	if err := data.validate(); err != nil {
+		return err
+	}
+	*tx = decoded
+	return nil
+}
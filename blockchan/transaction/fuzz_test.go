@@ -0,0 +1,150 @@
+//go:build go1.18
+// +build go1.18
+
+// Fuzz tests of decoding, signature recovery and Merkle proofs check
+// that malformed input is rejected with an error, a fuzzer fails
+// on a panic. Inputs which failed are kept in testdata/fuzz and run
+// by go test.
+
+package transaction
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/SmartMeshFoundation/Spectrum/common"
+	"github.com/SmartMeshFoundation/Spectrum/crypto"
+)
+
+func fuzzSeeds(f *testing.F) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		f.Fatal(err)
+	}
+
+	unsigned, err := NewTransaction(big.NewInt(1), big.NewInt(43),
+		big.NewInt(1), big.NewInt(1), common.HexToAddress("0x01"))
+	if err != nil {
+		f.Fatal(err)
+	}
+
+	tx, err := unsigned.SignTx(key, LegacyDomain)
+	if err != nil {
+		f.Fatal(err)
+	}
+
+	split, err := NewSplit(big.NewInt(1), big.NewInt(43), big.NewInt(3),
+		big.NewInt(1), big.NewInt(43), []Output{
+			{Amount: big.NewInt(1), Owner: common.HexToAddress("0x01")},
+			{Amount: big.NewInt(2), Owner: common.HexToAddress("0x02")},
+		})
+	if err != nil {
+		f.Fatal(err)
+	}
+
+	for _, seed := range []*Transaction{unsigned, tx, split} {
+		buf := bytes.NewBuffer(nil)
+		if err := seed.EncodeRLP(buf); err != nil {
+			f.Fatal(err)
+		}
+		f.Add(buf.Bytes())
+	}
+	f.Add([]byte{0x82, 0x7f, 0xc0})
+	f.Add([]byte{0xc0})
+	f.Add([]byte{})
+}
+
+// FuzzDecodeRLP checks that malformed transactions are rejected
+// with an error and decoded transactions can be used and encoded back.
+func FuzzDecodeRLP(f *testing.F) {
+	fuzzSeeds(f)
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		tx := &Transaction{}
+		if err := DecodeRLP(bytes.NewReader(data), tx); err != nil {
+			return
+		}
+
+		hash := tx.Hash()
+		Sender(tx, LegacyDomain)
+		Signers(tx, NewDomain(big.NewInt(1), common.Address{}))
+		tx.UIDs()
+		tx.Inputs()
+		tx.Coins()
+		_ = tx.String()
+
+		if _, err := json.Marshal(tx); err != nil {
+			return
+		}
+
+		buf := bytes.NewBuffer(nil)
+		if err := tx.EncodeRLP(buf); err != nil {
+			t.Fatalf("failed to encode decoded transaction: %s", err)
+		}
+
+		tx2 := &Transaction{}
+		if err := DecodeRLP(buf, tx2); err != nil {
+			t.Fatalf("failed to decode encoded transaction: %s", err)
+		}
+
+		if tx2.Hash() != hash {
+			t.Fatal("hashes not equal")
+		}
+	})
+}
+
+// FuzzUnmarshalJSON checks that malformed JSON is rejected with an error.
+func FuzzUnmarshalJSON(f *testing.F) {
+	f.Add([]byte(`{"type":"transfer","prevBlock":"1","uid":"43",` +
+		`"amount":"1","newOwner":"0x0000000000000000000000000000000000000001",` +
+		`"nonce":"1"}`))
+	f.Add([]byte(`{"type":"swap","inputs":[{}],"owners":[]}`))
+	f.Add([]byte(`{"type":"merge","root":"-1"}`))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		tx := &Transaction{}
+		if err := json.Unmarshal(data, tx); err != nil {
+			return
+		}
+
+		raw, err := json.Marshal(tx)
+		if err != nil {
+			t.Fatalf("failed to encode decoded transaction: %s", err)
+		}
+
+		tx2 := &Transaction{}
+		if err := json.Unmarshal(raw, tx2); err != nil {
+			t.Fatalf("failed to decode encoded transaction: %s", err)
+		}
+	})
+}
+
+// FuzzSignature checks that recovery of malformed signatures
+// returns an error.
+func FuzzSignature(f *testing.F) {
+	f.Add(make([]byte, 65), []byte{})
+	f.Add(bytes.Repeat([]byte{0xff}, 65), []byte{0xff})
+
+	f.Fuzz(func(t *testing.T, sig []byte, hash []byte) {
+		tx, err := NewTransaction(big.NewInt(1),
+			new(big.Int).SetBytes(append([]byte{1}, hash...)),
+			big.NewInt(1), big.NewInt(1), common.HexToAddress("0x01"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if r, s, v, err := SignatureValues(sig); err == nil {
+			recoverPlain(tx.Hash(), r, s, v)
+		}
+
+		signed, err := tx.WithSignature(sig, LegacyDomain)
+		if err != nil {
+			return
+		}
+
+		Sender(signed, LegacyDomain)
+		Sender(signed, NewDomain(big.NewInt(1), common.Address{}))
+	})
+}
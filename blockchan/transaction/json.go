@@ -0,0 +1,302 @@
+package transaction
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/SmartMeshFoundation/Spectrum/common"
+	"github.com/SmartMeshFoundation/Spectrum/common/hexutil"
+)
+
+var typeNames = map[Type]string{
+	TransferType: "transfer",
+	SplitType:    "split",
+	MergeType:    "merge",
+	SwapType:     "swap",
+}
+
+// String returns the name of the type.
+func (t Type) String() string {
+	if name, ok := typeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("unknown(%d)", byte(t))
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (t Type) MarshalText() ([]byte, error) {
+	if _, ok := typeNames[t]; !ok {
+		return nil, ErrUnknownType
+	}
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (t *Type) UnmarshalText(text []byte) error {
+	for typ, name := range typeNames {
+		if name == string(text) {
+			*t = typ
+			return nil
+		}
+	}
+	return ErrUnknownType
+}
+
+// decimal is big.Int encoded as decimal string in JSON.
+type decimal big.Int
+
+func newDecimal(x *big.Int) *decimal {
+	return (*decimal)(x)
+}
+
+func (d *decimal) int() *big.Int {
+	return (*big.Int)(d)
+}
+
+func (d *decimal) MarshalText() ([]byte, error) {
+	return []byte(d.int().String()), nil
+}
+
+func (d *decimal) UnmarshalText(text []byte) error {
+	if _, ok := d.int().SetString(string(text), 10); !ok {
+		return ErrInvalidArguments
+	}
+	return nil
+}
+
+type inputJSON struct {
+	PrevBlock *decimal `json:"prevBlock"`
+	UID       *decimal `json:"uid"`
+	Amount    *decimal `json:"amount"`
+	Nonce     *decimal `json:"nonce"`
+}
+
+type outputJSON struct {
+	Amount *decimal       `json:"amount"`
+	Owner  common.Address `json:"owner"`
+}
+
+// txJSON is canonical JSON form of a transaction. Fields which are
+// not used by the type are omitted, hash and sender are computed.
+type txJSON struct {
+	Type      Type             `json:"type"`
+	PrevBlock *decimal         `json:"prevBlock,omitempty"`
+	UID       *decimal         `json:"uid,omitempty"`
+	Amount    *decimal         `json:"amount,omitempty"`
+	NewOwner  *common.Address  `json:"newOwner,omitempty"`
+	Nonce     *decimal         `json:"nonce,omitempty"`
+	Root      *decimal         `json:"root,omitempty"`
+	Inputs    []inputJSON      `json:"inputs,omitempty"`
+	Outputs   []outputJSON     `json:"outputs,omitempty"`
+	Owners    []common.Address `json:"owners,omitempty"`
+	Sig       hexutil.Bytes    `json:"sig,omitempty"`
+	Sigs      []hexutil.Bytes  `json:"sigs,omitempty"`
+	Hash      common.Hash      `json:"hash"`
+	Sender    *common.Address  `json:"sender,omitempty"`
+}
+
+func inputsJSON(inputs []Input) []inputJSON {
+	result := make([]inputJSON, len(inputs))
+	for i, in := range inputs {
+		result[i] = inputJSON{
+			PrevBlock: newDecimal(in.PrevBlock),
+			UID:       newDecimal(in.UID),
+			Amount:    newDecimal(in.Amount),
+			Nonce:     newDecimal(in.Nonce),
+		}
+	}
+	return result
+}
+
+func inputsFromJSON(inputs []inputJSON) []Input {
+	result := make([]Input, len(inputs))
+	for i, in := range inputs {
+		result[i] = Input{
+			PrevBlock: in.PrevBlock.int(),
+			UID:       in.UID.int(),
+			Amount:    in.Amount.int(),
+			Nonce:     in.Nonce.int(),
+		}
+	}
+	return result
+}
+
+// MarshalJSON implements json.Marshaler. The sender depends
+// on the domain, so it is omitted, MarshalJSONDomain adds it.
+func (tx *Transaction) MarshalJSON() ([]byte, error) {
+	return tx.marshalJSON(nil)
+}
+
+// MarshalJSONDomain returns canonical JSON of the transaction
+// with the sender recovered in the domain. The sender is omitted
+// if the transaction is not signed.
+func (tx *Transaction) MarshalJSONDomain(domain Domain) ([]byte, error) {
+	return tx.marshalJSON(&domain)
+}
+
+func (tx *Transaction) marshalJSON(domain *Domain) ([]byte, error) {
+	enc := txJSON{Type: tx.typ, Hash: tx.Hash()}
+
+	switch tx.typ {
+	case TransferType:
+		enc.PrevBlock = newDecimal(tx.data.PrevBlock)
+		enc.UID = newDecimal(tx.data.UID)
+		enc.Amount = newDecimal(tx.data.Amount)
+		enc.NewOwner = &tx.data.NewOwner
+		enc.Nonce = newDecimal(tx.data.Nonce)
+		enc.Sig = tx.data.Sig
+	case SplitType:
+		enc.PrevBlock = newDecimal(tx.split.PrevBlock)
+		enc.UID = newDecimal(tx.split.UID)
+		enc.Amount = newDecimal(tx.split.Amount)
+		enc.Nonce = newDecimal(tx.split.Nonce)
+		enc.Root = newDecimal(tx.split.Root)
+		for _, out := range tx.split.Outputs {
+			enc.Outputs = append(enc.Outputs, outputJSON{
+				Amount: newDecimal(out.Amount),
+				Owner:  out.Owner,
+			})
+		}
+		enc.Sig = tx.split.Sig
+	case MergeType:
+		enc.Root = newDecimal(tx.merge.Root)
+		enc.Inputs = inputsJSON(tx.merge.Inputs)
+		enc.NewOwner = &tx.merge.NewOwner
+		enc.Sig = tx.merge.Sig
+	case SwapType:
+		enc.Inputs = inputsJSON(tx.swap.Inputs)
+		enc.Owners = tx.swap.Owners
+		for _, sig := range tx.swap.Sigs {
+			enc.Sigs = append(enc.Sigs, sig)
+		}
+	default:
+		return nil, ErrUnknownType
+	}
+
+	if domain != nil && len(tx.sig()) != 0 {
+		if sender, err := Sender(tx, *domain); err == nil {
+			enc.Sender = &sender
+		}
+	}
+	return json.Marshal(&enc)
+}
+
+// UnmarshalJSON implements json.Unmarshaler. The transaction is validated
+// like a decoded envelope, the hash must be equal to the computed hash
+// if it is present. The sender is ignored.
+func (tx *Transaction) UnmarshalJSON(input []byte) error {
+	var dec txJSON
+	if err := json.Unmarshal(input, &dec); err != nil {
+		return err
+	}
+
+	decoded := Transaction{typ: dec.Type}
+	switch dec.Type {
+	case TransferType:
+		decoded.data = txData{
+			PrevBlock: dec.PrevBlock.int(),
+			UID:       dec.UID.int(),
+			Amount:    dec.Amount.int(),
+			Nonce:     dec.Nonce.int(),
+			Sig:       dec.Sig,
+		}
+		if dec.NewOwner != nil {
+			decoded.data.NewOwner = *dec.NewOwner
+		}
+	case SplitType:
+		decoded.split = &splitData{
+			PrevBlock: dec.PrevBlock.int(),
+			UID:       dec.UID.int(),
+			Amount:    dec.Amount.int(),
+			Nonce:     dec.Nonce.int(),
+			Root:      dec.Root.int(),
+			Sig:       dec.Sig,
+		}
+		for _, out := range dec.Outputs {
+			decoded.split.Outputs = append(decoded.split.Outputs, Output{
+				Amount: out.Amount.int(),
+				Owner:  out.Owner,
+			})
+		}
+	case MergeType:
+		decoded.merge = &mergeData{
+			Root:   dec.Root.int(),
+			Inputs: inputsFromJSON(dec.Inputs),
+			Sig:    dec.Sig,
+		}
+		if dec.NewOwner != nil {
+			decoded.merge.NewOwner = *dec.NewOwner
+		}
+	case SwapType:
+		decoded.swap = &swapData{
+			Inputs: inputsFromJSON(dec.Inputs),
+			Owners: dec.Owners,
+			Sigs:   make([][]byte, len(dec.Sigs)),
+		}
+		for i, sig := range dec.Sigs {
+			decoded.swap.Sigs[i] = sig
+		}
+	default:
+		return ErrUnknownType
+	}
+
+	if err := decoded.payload().validate(); err != nil {
+		return err
+	}
+
+	if (dec.Hash != common.Hash{}) && dec.Hash != decoded.Hash() {
+		return ErrHashMismatch
+	}
+	*tx = decoded
+	return nil
+}
+
+// String returns human-readable representation of the transaction.
+func (tx *Transaction) String() string {
+	var fields []string
+	add := func(name string, value interface{}) {
+		fields = append(fields, fmt.Sprintf("%s: %v", name, value))
+	}
+
+	switch tx.typ {
+	case TransferType:
+		add("uid", tx.data.UID)
+		add("prevBlock", tx.data.PrevBlock)
+		add("amount", tx.data.Amount)
+		add("nonce", tx.data.Nonce)
+		add("newOwner", tx.data.NewOwner.Hex())
+	case SplitType:
+		add("uid", tx.split.UID)
+		add("prevBlock", tx.split.PrevBlock)
+		add("amount", tx.split.Amount)
+		add("nonce", tx.split.Nonce)
+		add("root", tx.split.Root)
+		for i, out := range tx.split.Outputs {
+			add(fmt.Sprintf("output%d", i),
+				fmt.Sprintf("%v -> %s", out.Amount, out.Owner.Hex()))
+		}
+	case MergeType:
+		add("uid", tx.merge.uid())
+		add("root", tx.merge.Root)
+		for i, in := range tx.merge.Inputs {
+			add(fmt.Sprintf("input%d", i), fmt.Sprintf("%v@%v nonce %v",
+				in.UID, in.PrevBlock, in.Nonce))
+		}
+		add("amount", tx.merge.amount())
+		add("newOwner", tx.merge.NewOwner.Hex())
+	case SwapType:
+		for i, in := range tx.swap.Inputs {
+			add(fmt.Sprintf("input%d", i), fmt.Sprintf(
+				"%v@%v nonce %v -> %s", in.UID, in.PrevBlock, in.Nonce,
+				tx.swap.Owners[1-i].Hex()))
+		}
+		add("complete", tx.Complete())
+	default:
+		return fmt.Sprintf("%s()", tx.typ)
+	}
+
+	add("hash", tx.Hash().Hex())
+	return fmt.Sprintf("%s(%s)", tx.typ, strings.Join(fields, ", "))
+}
@@ -0,0 +1,99 @@
+package transaction
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+
+	"github.com/SmartMeshFoundation/Spectrum/common"
+	"github.com/SmartMeshFoundation/Spectrum/crypto"
+)
+
+// optInTag separates hashes of opt-ins from hashes of transactions.
+const optInTag = "checkpoint"
+
+// OptIn is consent of the owner of a coin to fix the coin in a checkpoint
+// (Plasma XT). The coin is fixed with the nonce of the transaction
+// in the block which gave the coin to the owner.
+type OptIn struct {
+	UID   *big.Int
+	Nonce *big.Int
+	Block uint64
+	Sig   []byte
+}
+
+// NewOptIn creates new unsigned opt-in.
+func NewOptIn(uid, nonce *big.Int, block uint64) (*OptIn, error) {
+	o := &OptIn{UID: uid, Nonce: nonce, Block: block}
+	if err := o.validate(); err != nil {
+		return nil, err
+	}
+
+	return &OptIn{
+		UID:   new(big.Int).Set(uid),
+		Nonce: new(big.Int).Set(nonce),
+		Block: block,
+	}, nil
+}
+
+func (o *OptIn) validate() error {
+	if o.UID == nil || o.Nonce == nil {
+		return ErrInvalidArguments
+	}
+
+	if o.UID.Sign() <= 0 {
+		return ErrInvalidUID
+	}
+
+	if o.Nonce.Sign() < 0 {
+		return ErrInvalidArguments
+	}
+	return nil
+}
+
+func (o *OptIn) hash() common.Hash {
+	return rlpHash([]interface{}{optInTag, o.UID, o.Nonce, o.Block})
+}
+
+// Hash returns hash of the opt-in without the signature.
+func (o *OptIn) Hash() common.Hash {
+	return o.hash()
+}
+
+// OptInSigHash returns the hash which is signed by the owner
+// of the coin in the opt-in.
+func (d Domain) OptInSigHash(o *OptIn) common.Hash {
+	return d.sigHash(o.hash())
+}
+
+// Sign signs the opt-in for the domain using a private key.
+func (o *OptIn) Sign(key *ecdsa.PrivateKey, domain Domain) (*OptIn, error) {
+	if key == nil {
+		return nil, ErrInvalidPrivateKey
+	}
+
+	sig, err := crypto.Sign(domain.OptInSigHash(o).Bytes(), key)
+	if err != nil {
+		return nil, err
+	}
+	return o.WithSignature(sig), nil
+}
+
+// WithSignature returns a new opt-in with the given signature.
+func (o *OptIn) WithSignature(sig []byte) *OptIn {
+	cpy := *o
+	cpy.Sig = append([]byte{}, sig...)
+	return &cpy
+}
+
+// Signer returns the address which signed the opt-in for the domain.
+func (o *OptIn) Signer(domain Domain) (common.Address, error) {
+	if err := o.validate(); err != nil {
+		return common.Address{}, err
+	}
+
+	r, s, v, err := SignatureValues(o.Sig)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return recoverPlain(domain.OptInSigHash(o), r, s, v)
+}
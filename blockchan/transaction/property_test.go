@@ -0,0 +1,55 @@
+package transaction
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+	"testing/quick"
+
+	"github.com/SmartMeshFoundation/Spectrum/common"
+	"github.com/SmartMeshFoundation/Spectrum/crypto"
+)
+
+// TestEncodeDecodeProperty checks that any valid transfer
+// is decoded to the transaction with the same hash and sender.
+func TestEncodeDecodeProperty(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	owner := crypto.PubkeyToAddress(key.PublicKey)
+
+	property := func(prevBlock, uid, amount, nonce uint64,
+		newOwner common.Address, chainID uint64) bool {
+		tx, err := NewTransaction(new(big.Int).SetUint64(prevBlock),
+			new(big.Int).SetUint64(uid|1), new(big.Int).SetUint64(amount),
+			new(big.Int).SetUint64(nonce), newOwner)
+		if err != nil {
+			return (newOwner == common.Address{})
+		}
+
+		domain := NewDomain(new(big.Int).SetUint64(chainID), newOwner)
+		signed, err := tx.SignTx(key, domain)
+		if err != nil {
+			return false
+		}
+
+		buf := bytes.NewBuffer(nil)
+		if err := signed.EncodeRLP(buf); err != nil {
+			return false
+		}
+
+		decoded := &Transaction{}
+		if err := DecodeRLP(buf, decoded); err != nil {
+			return false
+		}
+
+		sender, err := Sender(decoded, domain)
+		return err == nil && sender == owner &&
+			decoded.Hash() == tx.Hash()
+	}
+
+	if err := quick.Check(property, nil); err != nil {
+		t.Fatal(err)
+	}
+}
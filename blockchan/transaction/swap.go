@@ -0,0 +1,184 @@
+package transaction
+
+import (
+	"math/big"
+
+	"github.com/SmartMeshFoundation/Spectrum/common"
+)
+
+type swapData struct {
+	Inputs []Input
+	Owners []common.Address
+	Sigs   [][]byte
+}
+
+// NewSwap creates new unsigned swap transaction. The coin `a` owned
+// by `ownerA` goes to `ownerB` and the coin `b` goes to `ownerA`.
+// The transaction is valid when it is signed by both owners.
+func NewSwap(a, b Input, ownerA, ownerB common.Address) (*Transaction, error) {
+	data := &swapData{
+		Inputs: []Input{a, b},
+		Owners: []common.Address{ownerA, ownerB},
+		Sigs:   make([][]byte, 2),
+	}
+	if err := data.validate(); err != nil {
+		return nil, err
+	}
+	return &Transaction{typ: SwapType, swap: data}, nil
+}
+
+func (d *swapData) validate() error {
+	if len(d.Inputs) != 2 || len(d.Owners) != 2 || len(d.Sigs) != 2 {
+		return ErrInvalidInputs
+	}
+
+	for _, in := range d.Inputs {
+		if in.PrevBlock == nil || in.UID == nil ||
+			in.Amount == nil || in.Nonce == nil {
+			return ErrInvalidArguments
+		}
+
+		if in.PrevBlock.Sign() < 0 {
+			return ErrInvalidPreviousBlock
+		}
+
+		if in.UID.Sign() == 0 {
+			return ErrInvalidUID
+		}
+
+		if in.Amount.Sign() <= 0 {
+			return ErrInvalidAmount
+		}
+	}
+
+	if d.Inputs[0].UID.Cmp(d.Inputs[1].UID) == 0 {
+		return ErrInvalidUID
+	}
+
+	if (d.Owners[0] == common.Address{}) ||
+		(d.Owners[1] == common.Address{}) || d.Owners[0] == d.Owners[1] {
+		return ErrInvalidNewOwner
+	}
+	return nil
+}
+
+func (d *swapData) hash() common.Hash {
+	return rlpHash([]interface{}{
+		SwapType,
+		d.Inputs,
+		d.Owners,
+	})
+}
+
+// withSignature puts the signature to the place of the owner who made it.
+func (d *swapData) withSignature(sig []byte,
+	domain Domain) (*swapData, error) {
+	r, s, v, err := SignatureValues(sig)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, err := recoverPlain(domain.sigHash(d.hash()), r, s, v)
+	if err != nil {
+		return nil, err
+	}
+
+	cpy := *d
+	cpy.Sigs = make([][]byte, len(d.Sigs))
+	copy(cpy.Sigs, d.Sigs)
+
+	for i, owner := range d.Owners {
+		if owner == signer {
+			cpy.Sigs[i] = sig
+			return &cpy, nil
+		}
+	}
+	return nil, ErrInvalidSig
+}
+
+// Signatures returns signatures of the transaction. A swap has
+// a signature of each owner, it is empty until the owner signs.
+func (tx *Transaction) Signatures() [][]byte {
+	if tx.typ == SwapType {
+		return tx.swap.Sigs
+	}
+	return [][]byte{tx.sig()}
+}
+
+// Owners returns owners of inputs of a swap, one owner for each input,
+// it returns nil for other types.
+func (tx *Transaction) Owners() []common.Address {
+	if tx.typ != SwapType {
+		return nil
+	}
+	return tx.swap.Owners
+}
+
+// Complete returns false if a swap is not signed by both owners.
+func (tx *Transaction) Complete() bool {
+	for _, sig := range tx.Signatures() {
+		if len(sig) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Signers returns addresses which signed inputs of the transaction
+// for the domain, one address for each input.
+func Signers(tx *Transaction, domain Domain) ([]common.Address, error) {
+	if tx == nil {
+		return nil, ErrInvalidTx
+	}
+
+	if tx.typ != SwapType {
+		sender, err := Sender(tx, domain)
+		if err != nil {
+			return nil, err
+		}
+
+		signers := make([]common.Address, len(tx.Inputs()))
+		for i := range signers {
+			signers[i] = sender
+		}
+		return signers, nil
+	}
+
+	hash := domain.SigHash(tx)
+	signers := make([]common.Address, len(tx.swap.Sigs))
+	for i, sig := range tx.swap.Sigs {
+		r, s, v, err := SignatureValues(sig)
+		if err != nil {
+			return nil, err
+		}
+
+		signer, err := recoverPlain(hash, r, s, v)
+		if err != nil {
+			return nil, err
+		}
+
+		if signer != tx.swap.Owners[i] {
+			return nil, ErrInvalidSig
+		}
+		signers[i] = signer
+	}
+	return signers, nil
+}
+
+func (d *swapData) coins() []Coin {
+	return []Coin{{
+		UID:    d.Inputs[0].UID,
+		Amount: d.Inputs[0].Amount,
+		Owner:  d.Owners[1],
+		Nonce:  d.Inputs[0].Nonce,
+	}, {
+		UID:    d.Inputs[1].UID,
+		Amount: d.Inputs[1].Amount,
+		Owner:  d.Owners[0],
+		Nonce:  d.Inputs[1].Nonce,
+	}}
+}
+
+func (d *swapData) uids() []*big.Int {
+	return []*big.Int{d.Inputs[0].UID, d.Inputs[1].UID}
+}
@@ -23,11 +23,20 @@ var (
 	ErrInvalidPrivateKey    = errors.New("invalid private key")
 	ErrInvalidPublicKey     = errors.New("invalid public key")
 	ErrInvalidTx            = errors.New("invalid transaction")
+	ErrInvalidAmount        = errors.New("invalid amount")
+	ErrInvalidOutputs       = errors.New("invalid split outputs")
+	ErrInvalidInputs        = errors.New("invalid merge inputs")
+	ErrUnknownType          = errors.New("unknown transaction type")
+	ErrHashMismatch         = errors.New("transaction hash mismatch")
 )
 
 // Transaction structure.
 type Transaction struct {
-	data txData
+	typ   Type
+	data  txData
+	split *splitData
+	merge *mergeData
+	swap  *swapData
 }
 
 type txData struct {
@@ -53,7 +62,7 @@ func NewTransaction(prevBlock, uid, amount, nonce *big.Int,
 		return nil, ErrInvalidNewOwner
 	}
 
-	if uid == nil || uid.Int64() == 0 {
+	if uid == nil || uid.Sign() == 0 {
 		return nil, ErrInvalidUID
 	}
 
@@ -77,40 +86,100 @@ func rlpHash(x interface{}) (h common.Hash) {
 
 // Hash returns hash of transaction.
 func (tx *Transaction) Hash() common.Hash {
-	return rlpHash([]interface{}{
-		tx.data.PrevBlock,
-		tx.data.UID,
-		tx.data.Amount,
-		tx.data.NewOwner,
-		tx.data.Nonce,
-	})
+	return tx.payload().hash()
 }
 
 // PrevBlock returns previous block from the transaction.
+// For a split it is the previous block of the split coin,
+// for a swap it is the previous block of the first coin,
+// a merge has no single previous block and returns nil.
 func (tx *Transaction) PrevBlock() *big.Int {
+	switch tx.typ {
+	case SplitType:
+		return tx.split.PrevBlock
+	case MergeType:
+		return nil
+	case SwapType:
+		return tx.swap.Inputs[0].PrevBlock
+	}
 	return tx.data.PrevBlock
 }
 
 // UID returns uid from the transaction.
+// For a split it is uid of the split coin,
+// for a merge it is uid of the created coin,
+// for a swap it is uid of the first coin.
 func (tx *Transaction) UID() *big.Int {
+	switch tx.typ {
+	case SplitType:
+		return tx.split.UID
+	case MergeType:
+		return tx.merge.uid()
+	case SwapType:
+		return tx.swap.Inputs[0].UID
+	}
 	return tx.data.UID
 }
 
 // Amount returns amount from the transaction.
 func (tx *Transaction) Amount() *big.Int {
+	switch tx.typ {
+	case SplitType:
+		return tx.split.Amount
+	case MergeType:
+		return tx.merge.amount()
+	case SwapType:
+		return tx.swap.Inputs[0].Amount
+	}
 	return tx.data.Amount
 }
 
 // NewOwner returns new owner from the transaction.
+// A split has owners of outputs instead and returns zero address,
+// for a swap it is the new owner of the first coin.
 func (tx *Transaction) NewOwner() common.Address {
+	switch tx.typ {
+	case SplitType:
+		return common.Address{}
+	case MergeType:
+		return tx.merge.NewOwner
+	case SwapType:
+		return tx.swap.Owners[1]
+	}
 	return tx.data.NewOwner
 }
 
 // Nonce returns nonce from the transaction.
+// For a merge it is zero nonce of the created coin.
 func (tx *Transaction) Nonce() *big.Int {
+	switch tx.typ {
+	case SplitType:
+		return tx.split.Nonce
+	case MergeType:
+		return new(big.Int)
+	case SwapType:
+		return tx.swap.Inputs[0].Nonce
+	}
 	return tx.data.Nonce
 }
 
+func (tx *Transaction) sig() []byte {
+	switch tx.typ {
+	case SplitType:
+		return tx.split.Sig
+	case MergeType:
+		return tx.merge.Sig
+	case SwapType:
+		for _, sig := range tx.swap.Sigs {
+			if len(sig) != 0 {
+				return sig
+			}
+		}
+		return nil
+	}
+	return tx.data.Sig
+}
+
 // SignatureValues returns signature values.
 func SignatureValues(sig []byte) (r, s, v *big.Int, err error) {
 	if len(sig) != 65 {
@@ -122,52 +191,105 @@ func SignatureValues(sig []byte) (r, s, v *big.Int, err error) {
 	return r, s, v, nil
 }
 
-// SignTx signs the transaction using and a private key.
-func (tx *Transaction) SignTx(key *ecdsa.PrivateKey) (*Transaction, error) {
+// SignTx signs the transaction for the domain using a private key.
+func (tx *Transaction) SignTx(key *ecdsa.PrivateKey,
+	domain Domain) (*Transaction, error) {
 	if key == nil {
 		return nil, ErrInvalidPrivateKey
 	}
 
-	sig, err := crypto.Sign(tx.Hash().Bytes(), key)
+	sig, err := crypto.Sign(domain.SigHash(tx).Bytes(), key)
 	if err != nil {
 		return nil, err
 	}
-	return tx.WithSignature(sig)
+	return tx.WithSignature(sig, domain)
 }
 
-// WithSignature returns a new transaction with the given signature.
-func (tx *Transaction) WithSignature(sig []byte) (*Transaction, error) {
-	cpy := &Transaction{data: tx.data}
-	cpy.data.Sig = sig
+// WithSignature returns a new transaction with the given signature
+// made for the domain. A signature of a swap is put to the place
+// of the owner who made it.
+func (tx *Transaction) WithSignature(sig []byte,
+	domain Domain) (*Transaction, error) {
+	cpy := &Transaction{typ: tx.typ, data: tx.data}
+	switch tx.typ {
+	case SwapType:
+		swap, err := tx.swap.withSignature(sig, domain)
+		if err != nil {
+			return nil, err
+		}
+		cpy.swap = swap
+	case SplitType:
+		split := *tx.split
+		split.Sig = sig
+		cpy.split = &split
+	case MergeType:
+		merge := *tx.merge
+		merge.Sig = sig
+		cpy.merge = &merge
+	default:
+		cpy.data.Sig = sig
+	}
 	return cpy, nil
 }
 
-// EncodeRLP implements rlp.Encoder
+// EncodeRLP implements rlp.Encoder. A transfer is encoded as RLP list,
+// other types are encoded as RLP string with the envelope
+// of the transaction.
 func (tx *Transaction) EncodeRLP(w io.Writer) error {
-	return rlp.Encode(w, &tx.data)
+	if tx.typ == TransferType {
+		return rlp.Encode(w, &tx.data)
+	}
+
+	raw, err := tx.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	return rlp.Encode(w, raw)
 }
 
+// maxEncodedSize limits the size of an encoded transaction, so a malformed
+// length prefix does not allocate an arbitrary amount of memory.
+const maxEncodedSize = 64 * 1024
+
 // DecodeRLP implements rlp.Decoder
 func DecodeRLP(r io.Reader, tx *Transaction) error {
 	if tx == nil {
 		return ErrInvalidTx
 	}
-	return rlp.Decode(r, &tx.data)
+
+	s := rlp.NewStream(r, maxEncodedSize)
+	kind, _, err := s.Kind()
+	if err != nil {
+		return err
+	}
+
+	if kind == rlp.List {
+		*tx = Transaction{}
+		return s.Decode(&tx.data)
+	}
+
+	raw, err := s.Bytes()
+	if err != nil {
+		return err
+	}
+	return tx.UnmarshalBinary(raw)
 }
 
 // Sender returns the address derived from the signature (V, R, S) using
 // secp256k1 elliptic curve and an error if it failed deriving
-// or upon an incorrect signature.
-func Sender(tx *Transaction) (common.Address, error) {
+// or upon an incorrect signature. For a swap it is the address
+// of the first owner who signed it. A transaction signed for another
+// domain has another sender.
+func Sender(tx *Transaction, domain Domain) (common.Address, error) {
 	if tx == nil {
 		return common.Address{}, ErrInvalidTx
 	}
 
-	r, s, v, err := SignatureValues(tx.data.Sig)
+	r, s, v, err := SignatureValues(tx.sig())
 	if err != nil {
 		return common.Address{}, err
 	}
-	return recoverPlain(tx.Hash(), r, s, v)
+	return recoverPlain(domain.SigHash(tx), r, s, v)
 }
 
 func recoverPlain(sighash common.Hash, R, S,
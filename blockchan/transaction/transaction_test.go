@@ -3,11 +3,15 @@ package transaction
 import (
 	"bytes"
 	"crypto/ecdsa"
+	"encoding/json"
 	"math/big"
+	"strings"
 	"testing"
 
 	"github.com/SmartMeshFoundation/Spectrum/accounts/abi/bind"
+	"github.com/SmartMeshFoundation/Spectrum/common"
 	"github.com/SmartMeshFoundation/Spectrum/crypto"
+	"github.com/SmartMeshFoundation/Spectrum/rlp"
 )
 
 func testKey(t *testing.T) *ecdsa.PrivateKey {
@@ -37,7 +41,7 @@ func TestTransaction(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	tx, err := unsignedTx.SignTx(key1)
+	tx, err := unsignedTx.SignTx(key1, LegacyDomain)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -46,7 +50,7 @@ func TestTransaction(t *testing.T) {
 		t.Fatal("hashes not equal")
 	}
 
-	addr, err := Sender(tx)
+	addr, err := Sender(tx, LegacyDomain)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -70,7 +74,7 @@ func TestEncodeDecodeRLP(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	tx, err := unsignedTx.SignTx(key1)
+	tx, err := unsignedTx.SignTx(key1, LegacyDomain)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -79,7 +83,7 @@ func TestEncodeDecodeRLP(t *testing.T) {
 		t.Fatal("hashes not equal")
 	}
 
-	addr, err := Sender(tx)
+	addr, err := Sender(tx, LegacyDomain)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -106,7 +110,7 @@ func TestEncodeDecodeRLP(t *testing.T) {
 		t.Fatal("hashes not equal")
 	}
 
-	addr2, err := Sender(tx2)
+	addr2, err := Sender(tx2, LegacyDomain)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -115,3 +119,577 @@ func TestEncodeDecodeRLP(t *testing.T) {
 		t.Fatal("addresses not equal")
 	}
 }
+
+func encodeDecode(t *testing.T, tx *Transaction) *Transaction {
+	buf := bytes.NewBuffer(nil)
+	if err := tx.EncodeRLP(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	tx2 := &Transaction{}
+	if err := DecodeRLP(buf, tx2); err != nil {
+		t.Fatal(err)
+	}
+	return tx2
+}
+
+func TestSplit(t *testing.T) {
+	key := testKey(t)
+	owner := testAccount(key)
+	newOwner := testAccount(testKey(t))
+
+	uid := big.NewInt(43)
+	outputs := []Output{
+		{Amount: big.NewInt(1), Owner: owner.From},
+		{Amount: big.NewInt(2), Owner: newOwner.From},
+	}
+
+	_, err := NewSplit(big.NewInt(1), uid, big.NewInt(4),
+		big.NewInt(1), uid, outputs)
+	if err != ErrInvalidAmount {
+		t.Fatalf("expected %s, got %v", ErrInvalidAmount, err)
+	}
+
+	_, err = NewSplit(big.NewInt(1), uid, big.NewInt(1),
+		big.NewInt(1), uid, outputs[:1])
+	if err != ErrInvalidOutputs {
+		t.Fatalf("expected %s, got %v", ErrInvalidOutputs, err)
+	}
+
+	unsignedTx, err := NewSplit(big.NewInt(1), uid, big.NewInt(3),
+		big.NewInt(1), uid, outputs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tx, err := unsignedTx.SignTx(key, LegacyDomain)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tx2 := encodeDecode(t, tx)
+
+	if tx2.Type() != SplitType || tx2.Hash() != tx.Hash() {
+		t.Fatal("wrong transaction")
+	}
+
+	addr, err := Sender(tx2, LegacyDomain)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if addr != owner.From {
+		t.Fatal("addresses not equal")
+	}
+
+	uids := tx2.UIDs()
+	if len(uids) != 3 || uids[0].Cmp(uid) != 0 ||
+		uids[2].Cmp(ChildUID(uid, 1)) != 0 {
+		t.Fatal("wrong uids")
+	}
+
+	if _, ok := tx2.Coin(uid); ok {
+		t.Fatal("the split coin must be spent")
+	}
+
+	coin, ok := tx2.Coin(ChildUID(uid, 1))
+	if !ok || coin.Owner != newOwner.From ||
+		coin.Amount.Int64() != 2 || coin.Nonce.Sign() != 0 {
+		t.Fatal("wrong sub-coin")
+	}
+
+	transfer, err := NewTransaction(big.NewInt(1), uid, big.NewInt(3),
+		big.NewInt(1), newOwner.From)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if transfer.Hash() == unsignedTx.Hash() {
+		t.Fatal("hashes of different types are equal")
+	}
+}
+
+func TestMerge(t *testing.T) {
+	key := testKey(t)
+	owner := testAccount(key)
+
+	root := big.NewInt(43)
+	inputs := []Input{
+		{PrevBlock: big.NewInt(1), UID: ChildUID(root, 0),
+			Amount: big.NewInt(1), Nonce: big.NewInt(1)},
+		{PrevBlock: big.NewInt(2), UID: ChildUID(root, 1),
+			Amount: big.NewInt(2), Nonce: big.NewInt(3)},
+	}
+
+	_, err := NewMerge(root, []Input{inputs[0], inputs[0]}, owner.From)
+	if err != ErrInvalidUID {
+		t.Fatalf("expected %s, got %v", ErrInvalidUID, err)
+	}
+
+	unsignedTx, err := NewMerge(root, inputs, owner.From)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tx, err := unsignedTx.SignTx(key, LegacyDomain)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tx2 := encodeDecode(t, tx)
+
+	if tx2.Type() != MergeType || tx2.Hash() != tx.Hash() {
+		t.Fatal("wrong transaction")
+	}
+
+	addr, err := Sender(tx2, LegacyDomain)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if addr != owner.From {
+		t.Fatal("addresses not equal")
+	}
+
+	merged := MergeUID(root, []*big.Int{inputs[1].UID, inputs[0].UID})
+	if tx2.UID().Cmp(merged) != 0 || tx2.Amount().Int64() != 3 {
+		t.Fatal("wrong merged coin")
+	}
+
+	if len(tx2.UIDs()) != 3 || len(tx2.Inputs()) != 2 {
+		t.Fatal("wrong uids")
+	}
+}
+
+func TestDecodeUnknownType(t *testing.T) {
+	buf := bytes.NewBuffer([]byte{0x82, 0x7f, 0xc0})
+
+	if err := DecodeRLP(buf, &Transaction{}); err != ErrUnknownType {
+		t.Fatalf("expected %s, got %v", ErrUnknownType, err)
+	}
+}
+
+func TestEnvelope(t *testing.T) {
+	key := testKey(t)
+	newOwner := testAccount(testKey(t))
+
+	unsignedTx, err := NewTransaction(big.NewInt(2), big.NewInt(43),
+		big.NewInt(1), big.NewInt(3), newOwner.From)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tx, err := unsignedTx.SignTx(key, LegacyDomain)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// version 0 keeps the encoding of six fields
+	legacy, err := rlp.EncodeToBytes([]interface{}{
+		big.NewInt(2), big.NewInt(43), big.NewInt(1),
+		newOwner.From, big.NewInt(3), tx.sig(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf := bytes.NewBuffer(nil)
+	if err := tx.EncodeRLP(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(buf.Bytes(), legacy) {
+		t.Fatal("transfer encoding is changed")
+	}
+
+	unsigned, err := rlp.EncodeToBytes([]interface{}{
+		big.NewInt(2), big.NewInt(43), big.NewInt(1),
+		newOwner.From, big.NewInt(3),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tx.Hash() != crypto.Keccak256Hash(unsigned) {
+		t.Fatal("transfer hash is changed")
+	}
+
+	raw, err := tx.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if Type(raw[0]) != TransferType {
+		t.Fatal("wrong type byte")
+	}
+
+	// the envelope of a transfer is accepted by DecodeRLP
+	// and encoded back in the legacy form
+	enveloped, err := rlp.EncodeToBytes(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tx2 := &Transaction{}
+	if err := DecodeRLP(bytes.NewReader(enveloped), tx2); err != nil {
+		t.Fatal(err)
+	}
+
+	if tx2.Hash() != tx.Hash() {
+		t.Fatal("hashes not equal")
+	}
+
+	if !bytes.Equal(encodeDecode(t, tx2).sig(), tx.sig()) {
+		t.Fatal("signatures not equal")
+	}
+
+	if err := tx2.UnmarshalBinary([]byte{0x7f, 0xc0}); err != ErrUnknownType {
+		t.Fatalf("expected %s, got %v", ErrUnknownType, err)
+	}
+
+	if err := tx2.UnmarshalBinary(nil); err != ErrInvalidTx {
+		t.Fatalf("expected %s, got %v", ErrInvalidTx, err)
+	}
+
+	zeroOwner, err := rlp.EncodeToBytes([]interface{}{
+		big.NewInt(2), big.NewInt(43), big.NewInt(1),
+		common.Address{}, big.NewInt(3), tx.sig(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = tx2.UnmarshalBinary(append([]byte{byte(TransferType)},
+		zeroOwner...))
+	if err != ErrInvalidNewOwner {
+		t.Fatalf("expected %s, got %v", ErrInvalidNewOwner, err)
+	}
+}
+
+func TestSwap(t *testing.T) {
+	keyA := testKey(t)
+	keyB := testKey(t)
+	ownerA := testAccount(keyA)
+	ownerB := testAccount(keyB)
+
+	a := Input{PrevBlock: big.NewInt(1), UID: big.NewInt(43),
+		Amount: big.NewInt(1), Nonce: big.NewInt(1)}
+	b := Input{PrevBlock: big.NewInt(2), UID: big.NewInt(44),
+		Amount: big.NewInt(5), Nonce: big.NewInt(3)}
+
+	_, err := NewSwap(a, a, ownerA.From, ownerB.From)
+	if err != ErrInvalidUID {
+		t.Fatalf("expected %s, got %v", ErrInvalidUID, err)
+	}
+
+	unsignedTx, err := NewSwap(a, b, ownerA.From, ownerB.From)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = unsignedTx.SignTx(testKey(t), LegacyDomain)
+	if err != ErrInvalidSig {
+		t.Fatalf("expected %s, got %v", ErrInvalidSig, err)
+	}
+
+	txB, err := unsignedTx.SignTx(keyB, LegacyDomain)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if txB.Complete() {
+		t.Fatal("the swap is signed by one owner")
+	}
+
+	if _, err := Signers(txB, LegacyDomain); err == nil {
+		t.Fatal("error is expected")
+	}
+
+	tx, err := encodeDecode(t, txB).SignTx(keyA, LegacyDomain)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tx2 := encodeDecode(t, tx)
+
+	if !tx2.Complete() || tx2.Hash() != unsignedTx.Hash() {
+		t.Fatal("wrong transaction")
+	}
+
+	signers, err := Signers(tx2, LegacyDomain)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if signers[0] != ownerA.From || signers[1] != ownerB.From {
+		t.Fatal("wrong signers")
+	}
+
+	coinA, ok := tx2.Coin(a.UID)
+	if !ok || coinA.Owner != ownerB.From || coinA.Nonce.Int64() != 1 {
+		t.Fatal("wrong coin")
+	}
+
+	coinB, ok := tx2.Coin(b.UID)
+	if !ok || coinB.Owner != ownerA.From || coinB.Amount.Int64() != 5 {
+		t.Fatal("wrong coin")
+	}
+}
+
+func TestDomain(t *testing.T) {
+	key := testKey(t)
+	owner := testAccount(key)
+	newOwner := testAccount(testKey(t))
+
+	domain := NewDomain(big.NewInt(1), common.HexToAddress("0x01"))
+	other := NewDomain(big.NewInt(2), common.HexToAddress("0x01"))
+
+	if !LegacyDomain.Legacy() || domain.Legacy() {
+		t.Fatal("wrong legacy domain")
+	}
+
+	if domain.Separator() == other.Separator() {
+		t.Fatal("separators of different domains are equal")
+	}
+
+	unsignedTx, err := NewTransaction(big.NewInt(1), big.NewInt(43),
+		big.NewInt(1), big.NewInt(1), newOwner.From)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if LegacyDomain.SigHash(unsignedTx) != unsignedTx.Hash() {
+		t.Fatal("legacy domain changes the signed hash")
+	}
+
+	tx := encodeDecode(t, signTestTx(t, unsignedTx, key, domain))
+
+	sender, err := Sender(tx, domain)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if sender != owner.From {
+		t.Fatal("wrong sender")
+	}
+
+	// the transaction is not valid in other domains
+	for _, d := range []Domain{other, LegacyDomain} {
+		sender, err := Sender(tx, d)
+		if err == nil && sender == owner.From {
+			t.Fatal("the transaction is replayed in another domain")
+		}
+	}
+
+	// a signature of a swap made for another domain is not accepted
+	a := Input{PrevBlock: big.NewInt(1), UID: big.NewInt(1),
+		Amount: big.NewInt(1), Nonce: big.NewInt(1)}
+	b := Input{PrevBlock: big.NewInt(1), UID: big.NewInt(2),
+		Amount: big.NewInt(1), Nonce: big.NewInt(1)}
+
+	swap, err := NewSwap(a, b, owner.From, newOwner.From)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signed := signTestTx(t, swap, key, other)
+	if _, err := swap.WithSignature(
+		signed.Signatures()[0], domain); err != ErrInvalidSig {
+		t.Fatalf("expected %s, got %v", ErrInvalidSig, err)
+	}
+}
+
+func signTestTx(t *testing.T, tx *Transaction, key *ecdsa.PrivateKey,
+	domain Domain) *Transaction {
+	signed, err := tx.SignTx(key, domain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestJSON(t *testing.T) {
+	key := testKey(t)
+	owner := testAccount(key)
+	newOwner := testAccount(testKey(t))
+
+	domain := NewDomain(big.NewInt(1), common.HexToAddress("0x01"))
+
+	transfer, err := NewTransaction(big.NewInt(1), big.NewInt(43),
+		big.NewInt(10), big.NewInt(2), newOwner.From)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	split, err := NewSplit(big.NewInt(1), big.NewInt(43), big.NewInt(10),
+		big.NewInt(2), big.NewInt(43), []Output{
+			{Amount: big.NewInt(4), Owner: owner.From},
+			{Amount: big.NewInt(6), Owner: newOwner.From},
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inputs := []Input{
+		{PrevBlock: big.NewInt(1), UID: ChildUID(big.NewInt(43), 0),
+			Amount: big.NewInt(4), Nonce: big.NewInt(1)},
+		{PrevBlock: big.NewInt(2), UID: ChildUID(big.NewInt(43), 1),
+			Amount: big.NewInt(6), Nonce: big.NewInt(1)},
+	}
+
+	merge, err := NewMerge(big.NewInt(43), inputs, owner.From)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	swap, err := NewSwap(inputs[0], inputs[1], owner.From, newOwner.From)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, unsigned := range []*Transaction{transfer, split, merge, swap} {
+		tx := signTestTx(t, unsigned, key, domain)
+
+		raw, err := tx.MarshalJSONDomain(domain)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var fields map[string]interface{}
+		if err := json.Unmarshal(raw, &fields); err != nil {
+			t.Fatal(err)
+		}
+
+		if fields["type"] != tx.Type().String() ||
+			fields["hash"] != tx.Hash().Hex() ||
+			fields["sender"] != strings.ToLower(owner.From.Hex()) {
+			t.Fatalf("wrong JSON %s", raw)
+		}
+
+		tx2 := &Transaction{}
+		if err := json.Unmarshal(raw, tx2); err != nil {
+			t.Fatal(err)
+		}
+
+		if tx2.Hash() != tx.Hash() {
+			t.Fatal("hashes not equal")
+		}
+
+		sender, err := Sender(encodeDecode(t, tx2), domain)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if sender != owner.From {
+			t.Fatal("wrong sender")
+		}
+
+		buf1, buf2 := bytes.NewBuffer(nil), bytes.NewBuffer(nil)
+		if err := tx.EncodeRLP(buf1); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := tx2.EncodeRLP(buf2); err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.Equal(buf1.Bytes(), buf2.Bytes()) {
+			t.Fatal("RLP encodings not equal")
+		}
+
+		if !strings.HasPrefix(tx.String(), tx.Type().String()+"(") {
+			t.Fatalf("wrong string %s", tx)
+		}
+	}
+
+	// the sender is omitted without the domain
+	raw, err := json.Marshal(signTestTx(t, transfer, key, domain))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(string(raw), `"uid":"43"`) ||
+		strings.Contains(string(raw), `"sender"`) {
+		t.Fatalf("wrong JSON %s", raw)
+	}
+
+	tampered := strings.Replace(string(raw), `"amount":"10"`,
+		`"amount":"11"`, 1)
+	if err := json.Unmarshal(
+		[]byte(tampered), &Transaction{}); err != ErrHashMismatch {
+		t.Fatalf("expected %s, got %v", ErrHashMismatch, err)
+	}
+
+	if err := json.Unmarshal(
+		[]byte(`{"type":"unknown"}`), &Transaction{}); err != ErrUnknownType {
+		t.Fatalf("expected %s, got %v", ErrUnknownType, err)
+	}
+
+	if err := json.Unmarshal(
+		[]byte(`{"type":"split"}`), &Transaction{}); err == nil {
+		t.Fatal("error is expected")
+	}
+}
+
+func TestOptIn(t *testing.T) {
+	key := testKey(t)
+	owner := testAccount(key)
+
+	if _, err := NewOptIn(big.NewInt(0), big.NewInt(1), 1); err != ErrInvalidUID {
+		t.Fatalf("expected %s, got %v", ErrInvalidUID, err)
+	}
+
+	if _, err := NewOptIn(big.NewInt(1), big.NewInt(-1),
+		1); err != ErrInvalidArguments {
+		t.Fatalf("expected %s, got %v", ErrInvalidArguments, err)
+	}
+
+	optIn, err := NewOptIn(big.NewInt(43), big.NewInt(2), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tx, err := NewTransaction(big.NewInt(3), big.NewInt(43),
+		big.NewInt(1), big.NewInt(2), owner.From)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if optIn.Hash() == tx.Hash() {
+		t.Fatal("opt-in hash is equal to transaction hash")
+	}
+
+	domain := NewDomain(big.NewInt(1), common.HexToAddress("0x01"))
+	signed, err := optIn.Sign(key, domain)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err := rlp.EncodeToBytes(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded := &OptIn{}
+	if err := rlp.DecodeBytes(raw, decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	signer, err := decoded.Signer(domain)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if signer != owner.From {
+		t.Fatal("wrong signer")
+	}
+
+	// the signature does not consent to another nonce or domain
+	decoded.Nonce = big.NewInt(3)
+	if signer, _ := decoded.Signer(domain); signer == owner.From {
+		t.Fatal("signature is valid for another nonce")
+	}
+
+	if signer, _ := signed.Signer(LegacyDomain); signer == owner.From {
+		t.Fatal("signature is valid in another domain")
+	}
+}
@@ -0,0 +1,251 @@
+// Command massexit exits all coins of wallets in the keystore when
+// the operator fails. The history of every wallet is read from the bolt
+// database named by the address of the wallet in the histories
+// directory.
+//
+// Usage:
+//
+//	massexit [flags] start     prepares and starts exits of all coins
+//	massexit [flags] withdraw  withdraws coins of exits which are over
+//	massexit [flags] status    prints results of exits
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/SmartMeshFoundation/Spectrum/accounts/keystore"
+	"github.com/SmartMeshFoundation/Spectrum/common"
+
+	"github.com/SmartMeshFoundation/SmartPlasma/blockchan/account"
+	"github.com/SmartMeshFoundation/SmartPlasma/blockchan/backend"
+	"github.com/SmartMeshFoundation/SmartPlasma/database/bolt"
+	"github.com/SmartMeshFoundation/SmartPlasma/massexit"
+	"github.com/SmartMeshFoundation/SmartPlasma/watcher"
+)
+
+var (
+	node = flag.String("node", "http://127.0.0.1:8545",
+		"URL of Spectrum node")
+	rootChain = flag.String("rootchain", "",
+		"address of RootChain contract")
+	mediatorAddr = flag.String("mediator", "",
+		"address of Mediator contract")
+	keys = flag.String("keystore", "keystore",
+		"keystore directory of wallets")
+	password = flag.String("password", "",
+		"file with the passphrase of wallets")
+	histories = flag.String("histories", "histories",
+		"directory of wallet histories")
+	bucket = flag.String("bucket", "history",
+		"bucket of wallet histories")
+	results = flag.String("db", "massexit.db",
+		"database of exit results")
+	gasPrice = flag.String("gasprice", "",
+		"initial gas price in wei, suggested by the node if empty")
+	maxGasPrice = flag.String("maxgasprice", "",
+		"limit of the escalated gas price in wei")
+	gasLimit = flag.String("gaslimit", "",
+		"gas limit of transactions, estimated if empty")
+	escalation = flag.Uint64("escalation",
+		massexit.DefaultConfig().Escalation,
+		"percent of gas price escalation")
+	retries = flag.Int("retries", massexit.DefaultConfig().Retries,
+		"number of resends of a transaction")
+	batch = flag.Int("batch", massexit.DefaultConfig().BatchSize,
+		"number of transactions sent before waiting for them")
+	timeout = flag.Duration("timeout", massexit.DefaultConfig().Timeout,
+		"time to wait for a transaction before replacing it")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(),
+			"Usage: %s [flags] start|withdraw|status\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := run(flag.Arg(0)); err != nil {
+		fmt.Fprintln(os.Stderr, "massexit:", err)
+		os.Exit(1)
+	}
+}
+
+func run(command string) error {
+	cfg, err := config()
+	if err != nil {
+		return err
+	}
+
+	if !common.IsHexAddress(*rootChain) || !common.IsHexAddress(*mediatorAddr) {
+		return fmt.Errorf("addresses of contracts are required")
+	}
+
+	wallets, closeAll, err := openWallets()
+	if err != nil {
+		return err
+	}
+	defer closeAll()
+
+	db, err := bolt.NewDB(*results, "massexit", nil)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	m, err := massexit.New(backend.NewBackend(*node),
+		common.HexToAddress(*rootChain), common.HexToAddress(*mediatorAddr),
+		wallets, db, cfg)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := interruptContext()
+	defer cancel()
+
+	switch command {
+	case "start":
+		if _, err := m.Prepare(); err != nil {
+			return err
+		}
+		err = m.Start(ctx)
+	case "withdraw":
+		err = m.Withdraw(ctx)
+	case "status":
+	default:
+		return fmt.Errorf("unknown command %q", command)
+	}
+
+	if printErr := printResults(m); err == nil {
+		err = printErr
+	}
+	return err
+}
+
+// interruptContext returns the context which is canceled on interrupt.
+func interruptContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, os.Interrupt)
+	go func() {
+		select {
+		case <-ch:
+			cancel()
+		case <-ctx.Done():
+		}
+		signal.Stop(ch)
+	}()
+	return ctx, cancel
+}
+
+func config() (massexit.Config, error) {
+	cfg := massexit.Config{
+		Escalation: *escalation,
+		Retries:    *retries,
+		BatchSize:  *batch,
+		Timeout:    *timeout,
+	}
+
+	for _, v := range []struct {
+		name  string
+		value string
+		dst   **big.Int
+	}{
+		{"gasprice", *gasPrice, &cfg.GasPrice},
+		{"maxgasprice", *maxGasPrice, &cfg.MaxGasPrice},
+		{"gaslimit", *gasLimit, &cfg.GasLimit},
+	} {
+		if v.value == "" {
+			continue
+		}
+
+		n, ok := new(big.Int).SetString(v.value, 10)
+		if !ok || n.Sign() <= 0 {
+			return cfg, fmt.Errorf("invalid %s %q", v.name, v.value)
+		}
+		*v.dst = n
+	}
+	return cfg, nil
+}
+
+// openWallets unlocks accounts of the keystore and opens their
+// histories. Accounts without histories are skipped.
+func openWallets() ([]massexit.Wallet, func(), error) {
+	raw, err := ioutil.ReadFile(*password)
+	if err != nil {
+		return nil, nil, err
+	}
+	passphrase := strings.TrimRight(string(raw), "\r\n")
+
+	ks := account.NewKeyStore(*keys, keystore.StandardScryptN,
+		keystore.StandardScryptP)
+
+	var wallets []massexit.Wallet
+	var dbs []*bolt.DB
+	closeAll := func() {
+		for _, db := range dbs {
+			db.Close()
+		}
+	}
+
+	for _, addr := range ks.Accounts() {
+		path := filepath.Join(*histories, addr.Hex())
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			continue
+		}
+
+		if err := ks.Unlock(addr, passphrase); err != nil {
+			closeAll()
+			return nil, nil, fmt.Errorf("failed to unlock %s: %v",
+				addr.Hex(), err)
+		}
+
+		opts, err := ks.Transactor(addr)
+		if err != nil {
+			closeAll()
+			return nil, nil, err
+		}
+
+		db, err := bolt.NewDB(path, *bucket, nil)
+		if err != nil {
+			closeAll()
+			return nil, nil, err
+		}
+		dbs = append(dbs, db)
+
+		wallets = append(wallets, massexit.Wallet{
+			Opts:    opts,
+			History: watcher.NewHistory(db),
+		})
+	}
+	return wallets, closeAll, nil
+}
+
+func printResults(m *massexit.MassExit) error {
+	results, err := m.Results()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "WALLET\tUID\tSTAGE\tEXIT TIME\tGAS PRICE\tERROR")
+	for _, r := range results {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\n", r.Wallet.Hex(), r.UID,
+			r.Stage, r.ExitTime, r.GasPrice, r.Error)
+	}
+	return w.Flush()
+}
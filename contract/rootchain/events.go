@@ -0,0 +1,148 @@
+package rootchain
+
+import (
+	"context"
+	"math/big"
+	"strings"
+
+	"github.com/SmartMeshFoundation/Spectrum"
+	"github.com/SmartMeshFoundation/Spectrum/accounts/abi"
+	"github.com/SmartMeshFoundation/Spectrum/common"
+	"github.com/SmartMeshFoundation/Spectrum/core/types"
+	"github.com/pkg/errors"
+
+	"github.com/SmartMeshFoundation/SmartPlasma/blockchan/backend"
+)
+
+// Names of events of RootChain contract.
+const (
+	NewBlockEvent            = "NewBlock"
+	NewCheckpointEvent       = "NewCheckpoint"
+	ChallengeExitEvent       = "ChallengeExit"
+	ChallengeCheckpointEvent = "ChallengeCheckpoint"
+)
+
+// ErrUnknownEvent is returned for events which RootChain contract
+// does not have.
+var ErrUnknownEvent = errors.New("unknown RootChain event")
+
+var rootChainABI, errRootChainABI = abi.JSON(strings.NewReader(RootChainABI))
+
+// RootChainNewBlock represents a NewBlock event
+// raised by the RootChain contract.
+type RootChainNewBlock struct {
+	Hash [32]byte
+	Raw  types.Log
+}
+
+// RootChainNewCheckpoint represents a NewCheckpoint event
+// raised by the RootChain contract.
+type RootChainNewCheckpoint struct {
+	Hash [32]byte
+	Raw  types.Log
+}
+
+// RootChainChallengeExit represents a ChallengeExit event
+// raised by the RootChain contract.
+type RootChainChallengeExit struct {
+	Uid *big.Int
+	Raw types.Log
+}
+
+// RootChainChallengeCheckpoint represents a ChallengeCheckpoint event
+// raised by the RootChain contract.
+type RootChainChallengeCheckpoint struct {
+	Uid        *big.Int
+	Checkpoint [32]byte
+	Raw        types.Log
+}
+
+// EventTopic returns the topic of the event of RootChain contract.
+func EventTopic(name string) (common.Hash, error) {
+	if errRootChainABI != nil {
+		return common.Hash{}, errRootChainABI
+	}
+
+	event, ok := rootChainABI.Events[name]
+	if !ok {
+		return common.Hash{}, ErrUnknownEvent
+	}
+	return event.Id(), nil
+}
+
+// FilterEvents returns logs of the events of RootChain contract
+// in Spectrum blocks starting with the block from.
+func FilterEvents(ctx context.Context, server backend.Backend,
+	contract common.Address, from uint64,
+	names ...string) ([]types.Log, error) {
+	topics := make([]common.Hash, len(names))
+	for i, name := range names {
+		topic, err := EventTopic(name)
+		if err != nil {
+			return nil, err
+		}
+		topics[i] = topic
+	}
+
+	return server.FilterLogs(ctx, ethereum.FilterQuery{
+		FromBlock: new(big.Int).SetUint64(from),
+		Addresses: []common.Address{contract},
+		Topics:    [][]common.Hash{topics},
+	})
+}
+
+// UnpackEvent unpacks the log of the event of RootChain contract into out.
+// Out is the event structure if the event has several arguments,
+// otherwise it is a pointer to the only argument.
+func UnpackEvent(out interface{}, name string, log types.Log) error {
+	topic, err := EventTopic(name)
+	if err != nil {
+		return err
+	}
+
+	if len(log.Topics) == 0 || log.Topics[0] != topic {
+		return errors.Errorf("log is not %s event", name)
+	}
+	return rootChainABI.Unpack(out, name, log.Data)
+}
+
+// ParseNewBlock parses the log of NewBlock event.
+func ParseNewBlock(log types.Log) (*RootChainNewBlock, error) {
+	event := &RootChainNewBlock{Raw: log}
+	err := UnpackEvent(&event.Hash, NewBlockEvent, log)
+	if err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+// ParseNewCheckpoint parses the log of NewCheckpoint event.
+func ParseNewCheckpoint(log types.Log) (*RootChainNewCheckpoint, error) {
+	event := &RootChainNewCheckpoint{Raw: log}
+	err := UnpackEvent(&event.Hash, NewCheckpointEvent, log)
+	if err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+// ParseChallengeExit parses the log of ChallengeExit event.
+func ParseChallengeExit(log types.Log) (*RootChainChallengeExit, error) {
+	event := &RootChainChallengeExit{Raw: log}
+	err := UnpackEvent(&event.Uid, ChallengeExitEvent, log)
+	if err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+// ParseChallengeCheckpoint parses the log of ChallengeCheckpoint event.
+func ParseChallengeCheckpoint(
+	log types.Log) (*RootChainChallengeCheckpoint, error) {
+	event := &RootChainChallengeCheckpoint{Raw: log}
+	err := UnpackEvent(event, ChallengeCheckpointEvent, log)
+	if err != nil {
+		return nil, err
+	}
+	return event, nil
+}
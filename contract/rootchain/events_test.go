@@ -0,0 +1,202 @@
+package rootchain
+
+import (
+	"context"
+	"testing"
+
+	"github.com/SmartMeshFoundation/Spectrum/common"
+
+	"github.com/SmartMeshFoundation/SmartPlasma/blockchan/block/checkpoints"
+)
+
+func TestFilterEvents(t *testing.T) {
+	i := newInstance(t)
+	other := newInstance(t)
+	ctx := context.Background()
+
+	hashes := []common.Hash{{1}, {2}}
+	for _, hash := range hashes {
+		tx, err := i.rootOwnerSession.NewCheckpoint(hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !server.GoodTransaction(tx) {
+			t.Fatal("failed to create new checkpoint")
+		}
+	}
+
+	// events of other contracts are not returned
+	tx, err := other.rootOwnerSession.NewCheckpoint(common.Hash{3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !server.GoodTransaction(tx) {
+		t.Fatal("failed to create new checkpoint")
+	}
+
+	logs, err := FilterEvents(ctx, server, i.rootChainAddr, 0,
+		NewCheckpointEvent)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(logs) != len(hashes) {
+		t.Fatalf("expected %d events, got %d", len(hashes), len(logs))
+	}
+
+	for n, l := range logs {
+		event, err := ParseNewCheckpoint(l)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if event.Hash != hashes[n] {
+			t.Fatalf("expected checkpoint %s, got %s",
+				hashes[n].Hex(), common.Hash(event.Hash).Hex())
+		}
+	}
+
+	logs2, err := FilterEvents(ctx, server, i.rootChainAddr,
+		logs[1].BlockNumber, NewCheckpointEvent)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(logs2) != 1 || logs2[0].TxHash != logs[1].TxHash {
+		t.Fatal("events before the start block are returned")
+	}
+
+	if _, err := FilterEvents(ctx, server, i.rootChainAddr, 0,
+		"Unknown"); err != ErrUnknownEvent {
+		t.Fatalf("expected error %s, got %v", ErrUnknownEvent, err)
+	}
+}
+
+func TestParseChallengeEvents(t *testing.T) {
+	i := newInstance(t)
+	ctx := context.Background()
+
+	uid := testDeposit(t, i)
+
+	tx1 := newPlasmaTestTx(t, i, zero, uid, one, zero, user2.From, user1)
+	tx2 := newPlasmaTestTx(t, i, one, uid, one, one, user1.From, user2)
+	tx3 := newPlasmaTestTx(t, i, two, uid, one, two, user2.From, user1)
+
+	ethTx, err := i.rootUser2Session.StartExit(tx2.rawTx, tx2.proof, two,
+		tx3.rawTx, tx3.proof, three)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !server.GoodTransaction(ethTx) {
+		t.Fatal("failed to start exit")
+	}
+
+	ethTx, err = i.rootUser1Session.ChallengeExit(uid, tx1.rawTx,
+		tx1.proof, one)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !server.GoodTransaction(ethTx) {
+		t.Fatal("failed to challenge exit")
+	}
+
+	chpt := checkpoints.NewBlock()
+	if err := chpt.AddCheckpoint(uid, three); err != nil {
+		t.Fatal(err)
+	}
+
+	chptHash, err := chpt.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ethTx, err = i.rootOwnerSession.NewCheckpoint(chptHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !server.GoodTransaction(ethTx) {
+		t.Fatal("failed to create new checkpoint")
+	}
+
+	ethTx, err = i.rootUser2Session.ChallengeCheckpoint(uid, chptHash,
+		chpt.CreateProof(uid), three, tx3.rawTx, tx3.proof, three)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !server.GoodTransaction(ethTx) {
+		t.Fatal("failed to challenge checkpoint")
+	}
+
+	logs, err := FilterEvents(ctx, server, i.rootChainAddr, 0,
+		ChallengeExitEvent, ChallengeCheckpointEvent)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(logs) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(logs))
+	}
+
+	exit, err := ParseChallengeExit(logs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if exit.Uid.Cmp(uid) != 0 {
+		t.Fatalf("expected uid %s, got %s", uid, exit.Uid)
+	}
+
+	challenge, err := ParseChallengeCheckpoint(logs[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if challenge.Uid.Cmp(uid) != 0 || challenge.Checkpoint != chptHash {
+		t.Fatal("wrong checkpoint challenge event")
+	}
+
+	if _, err := ParseChallengeExit(logs[1]); err == nil {
+		t.Fatal("ChallengeCheckpoint event is parsed as ChallengeExit")
+	}
+}
+
+func TestParseNewBlock(t *testing.T) {
+	i := newInstance(t)
+	ctx := context.Background()
+
+	hash := common.Hash{1}
+	tx, err := i.rootOwnerSession.NewBlock(hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !server.GoodTransaction(tx) {
+		t.Fatal("failed to create new block")
+	}
+
+	logs, err := FilterEvents(ctx, server, i.rootChainAddr, 0,
+		NewBlockEvent)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(logs) != 1 {
+		t.Fatalf("expected 1 event, got %d", len(logs))
+	}
+
+	event, err := ParseNewBlock(logs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if event.Hash != hash {
+		t.Fatalf("expected block %s, got %s", hash.Hex(),
+			common.Hash(event.Hash).Hex())
+	}
+}
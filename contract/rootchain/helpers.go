@@ -1,11 +1,14 @@
 package rootchain
 
 import (
+	"context"
+	"math/big"
+
+	"github.com/SmartMeshFoundation/Spectrum"
 	"github.com/SmartMeshFoundation/Spectrum/accounts/abi/bind"
 	"github.com/SmartMeshFoundation/Spectrum/common"
 	"github.com/SmartMeshFoundation/Spectrum/crypto"
 	"github.com/pkg/errors"
-	"math/big"
 
 	"github.com/SmartMeshFoundation/SmartPlasma/blockchan/backend"
 )
@@ -65,3 +68,24 @@ func CopySession(session *RootChainSession) *RootChainSession {
 	*newSession = *session
 	return newSession
 }
+
+// EstimateGas estimates gas used by the call of the method of RootChain
+// contract from the account. It returns an error if the call fails.
+func EstimateGas(ctx context.Context, server backend.Backend,
+	from, contract common.Address, method string,
+	args ...interface{}) (*big.Int, error) {
+	if errRootChainABI != nil {
+		return nil, errRootChainABI
+	}
+
+	data, err := rootChainABI.Pack(method, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return server.Connect().EstimateGas(ctx, ethereum.CallMsg{
+		From: from,
+		To:   &contract,
+		Data: data,
+	})
+}
@@ -129,12 +129,13 @@ func testTx(t *testing.T, prevBlock, uid,
 		t.Fatal(err)
 	}
 
-	tx, err := signer.PlasmaSigner(signer.From, unsignedTx)
+	tx, err := signer.PlasmaSigner(signer.From, unsignedTx,
+		transaction.LegacyDomain)
 	if err != nil {
 		t.Fatalf("failed to sign transaction %s", err)
 	}
 
-	addr, err := transaction.Sender(tx)
+	addr, err := transaction.Sender(tx, transaction.LegacyDomain)
 	if err != nil {
 		t.Fatal(err)
 	}
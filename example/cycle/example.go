@@ -46,6 +46,9 @@ var (
 	cycles      = 3
 )
 
+// operatorToken allows clients to call operator methods of the server.
+const operatorToken = "operator"
+
 type user struct {
 	acc      *account.PlasmaTransactOpts
 	uids     map[string]uint64
@@ -114,7 +117,11 @@ func newServer(owner account.PlasmaTransactOpts, rootChainAddr,
 		rootChainContract, mediatorContract, false)
 
 	// create new RPC server for communication with PlasmaCash clients.
-	return transport.NewServer(100, port, s)
+	srv := transport.NewServer(100, port, s)
+
+	// only clients with the token can call operator methods.
+	srv.SetAdminTokens(operatorToken)
+	return srv
 }
 
 // newDirectClient the client that interacts with the blockchain directly.
@@ -269,6 +276,10 @@ func increaseApproval(session *erc20token.ExampleTokenSession,
 func (u *user) deposit(env *environment) {
 	cli1 := newDirectClient(u.acc, env.rootChainAddress,
 		env.mediatorAddress, env.backend)
+	if u == env.accounts[0] {
+		// the first user builds blocks.
+		cli1.SetAuthToken(operatorToken)
+	}
 	err := cli1.Connect("", env.port)
 	if err != nil {
 		panic(err)
@@ -309,7 +320,8 @@ func (u *user) transact(newOwner *user) {
 			panic(err)
 		}
 		// sign Plasma Cash transaction
-		tx1, err := u.acc.PlasmaSigner(u.acc.From, tx)
+		tx1, err := u.acc.PlasmaSigner(u.acc.From, tx,
+			transaction.LegacyDomain)
 		if err != nil {
 			panic(err)
 		}
@@ -28,6 +28,9 @@ import (
 	"github.com/SmartMeshFoundation/SmartPlasma/transport"
 )
 
+// operatorToken allows clients to call operator methods of the server.
+const operatorToken = "operator"
+
 type environment struct {
 	dir              string
 	accounts         []*account.PlasmaTransactOpts
@@ -89,7 +92,11 @@ func newServer(owner account.PlasmaTransactOpts, rootChainAddr,
 		rootChainContract, mediatorContract, false)
 
 	// create new RPC server for communication with PlasmaCash clients.
-	return transport.NewServer(100, port, s)
+	srv := transport.NewServer(100, port, s)
+
+	// only clients with the token can call operator methods.
+	srv.SetAdminTokens(operatorToken)
+	return srv
 }
 
 // newDirectClient the client that interacts with the blockchain directly.
@@ -288,6 +295,7 @@ func main() {
 
 	cli0 := newDirectClient(owner, env.rootChainAddress,
 		env.mediatorAddress, env.backend)
+	cli0.SetAuthToken(operatorToken)
 
 	err := cli0.Connect("", port)
 	if err != nil {
@@ -296,6 +304,7 @@ func main() {
 
 	cli1 := newDirectClient(user1, env.rootChainAddress,
 		env.mediatorAddress, env.backend)
+	cli1.SetAuthToken(operatorToken)
 
 	err = cli1.Connect("", port)
 	if err != nil {
@@ -334,7 +343,8 @@ func main() {
 		big.NewInt(1), big.NewInt(0), user1.From)
 
 	// sign Plasma Cash transaction
-	tx1, err := user1.PlasmaSigner(user1.From, unTx1)
+	tx1, err := user1.PlasmaSigner(user1.From, unTx1,
+		transaction.LegacyDomain)
 	if err != nil {
 		panic(err)
 	}
@@ -415,7 +425,8 @@ func main() {
 		big.NewInt(1), big.NewInt(1), user2.From)
 
 	// sign Plasma Cash transaction #2
-	tx2, err := user1.PlasmaSigner(user1.From, unTx2)
+	tx2, err := user1.PlasmaSigner(user1.From, unTx2,
+		transaction.LegacyDomain)
 	if err != nil {
 		panic(err)
 	}
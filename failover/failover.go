@@ -0,0 +1,275 @@
+// Package failover runs the operator on several nodes. Only the node
+// which holds the shared lock, the leader, publishes blocks, other nodes
+// are replicas of the leader and take over block production
+// when the leader loses the lock:
+//
+//	lock := failover.NewFileLock("/shared/operator.lock")
+//	o := failover.New("node-a", lock, svc, replica)
+//	o.SetTTL(15 * time.Second)
+//	o.SetBlockInterval(10 * time.Second)
+//	go o.Run(ctx)
+//
+// Lock is a lease which the leader renews three times per time-to-live.
+// FileLock keeps the lease in a file shared by nodes, MemoryLock keeps it
+// in memory for nodes in one process. A leader stops publishing when
+// its lease is over and resigns when it is stopped.
+//
+// A lease does not stop a leader which is paused past its end, so
+// the leader publishes with a fencing token: it reads the number of the
+// next block from RootChain contract when it acquires the lock and
+// Service.PublishBlockAt sends the root only if the block gets this
+// number. A leader which finds a block it did not expect resigns. A new
+// leader mirrors blocks of the previous leader before it publishes its
+// first block, so numbers are never reused.
+package failover
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/SmartMeshFoundation/Spectrum/log"
+	"github.com/pkg/errors"
+
+	"github.com/SmartMeshFoundation/SmartPlasma/logger"
+	"github.com/SmartMeshFoundation/SmartPlasma/replica"
+	"github.com/SmartMeshFoundation/SmartPlasma/service"
+)
+
+// Defaults of the operator.
+const (
+	DefaultTTL           = 15 * time.Second
+	DefaultBlockInterval = 10 * time.Second
+)
+
+// ErrNotLeader is returned when the operator which does not hold
+// the lock publishes a block.
+var ErrNotLeader = errors.New("operator is not the leader")
+
+// Operator is the operator node. The leader renews the lock three times
+// per time-to-live of the lock and publishes blocks of its service
+// with the interval. A standby node mirrors blocks of the leader
+// with the replica and tries to acquire the lock.
+//
+// A new leader publishes blocks only after it mirrors all published
+// blocks, so numbers of blocks are never reused, and it stops publishing
+// when its lease is over, so blocks are not published by two leaders.
+// Blocks of the leader are published with the context which is done
+// at the end of its lease. The number of the next block is the fencing
+// token of the leader: it is read from RootChain contract when the lock
+// is acquired and the root is sent only if the block gets this number,
+// so a leader which lost its lock without noticing it does not publish
+// after blocks of the new leader.
+type Operator struct {
+	id       string
+	lock     Lock
+	service  *service.Service
+	replica  *replica.Replica
+	ttl      time.Duration
+	interval time.Duration
+	logger   log.Logger
+	now      func() time.Time
+
+	mtx     sync.Mutex
+	expires time.Time
+	// next is the number of the next block of the leader,
+	// it is zero until the leader reads it
+	next uint64
+}
+
+// New creates the operator node with the identifier. The service
+// publishes blocks of the node, the replica mirrors blocks
+// of the leader to databases of the service.
+func New(id string, lock Lock, svc *service.Service,
+	r *replica.Replica) *Operator {
+	return &Operator{
+		id:       id,
+		lock:     lock,
+		service:  svc,
+		replica:  r,
+		ttl:      DefaultTTL,
+		interval: DefaultBlockInterval,
+		logger:   logger.New("failover").New("id", id),
+		now:      time.Now,
+	}
+}
+
+// SetTTL sets the time-to-live of the lock.
+func (o *Operator) SetTTL(ttl time.Duration) {
+	o.ttl = ttl
+}
+
+// SetBlockInterval sets the period between blocks of the leader.
+func (o *Operator) SetBlockInterval(interval time.Duration) {
+	o.interval = interval
+}
+
+// SetLogger sets the logger of the operator.
+func (o *Operator) SetLogger(l log.Logger) {
+	o.logger = logger.Wrap(l).New("id", o.id)
+}
+
+// IsLeader returns true if the lease of the operator is not over.
+func (o *Operator) IsLeader() bool {
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+	return o.now().Before(o.expires)
+}
+
+// Campaign acquires or renews the lock. It returns true
+// if the operator is the leader.
+func (o *Operator) Campaign(ctx context.Context) (bool, error) {
+	leader := o.IsLeader()
+
+	// the lease is counted from the time before the lock is acquired
+	start := o.now()
+	acquired, err := o.lock.TryLock(ctx, o.id, o.ttl)
+	if err != nil {
+		return leader, err
+	}
+
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+
+	if !acquired {
+		if leader {
+			o.logger.Warn("Leadership lost")
+		}
+		o.expires = time.Time{}
+		return false, nil
+	}
+
+	if !leader {
+		o.logger.Info("Leadership acquired", "ttl", o.ttl)
+		o.next = 0
+	}
+	o.expires = start.Add(o.ttl)
+	return true, nil
+}
+
+// Resign releases the lock.
+func (o *Operator) Resign(ctx context.Context) error {
+	o.mtx.Lock()
+	o.expires = time.Time{}
+	o.next = 0
+	o.mtx.Unlock()
+
+	o.logger.Info("Leadership resigned")
+	return o.lock.Unlock(ctx, o.id)
+}
+
+// PublishBlock publishes the current block of the service if the
+// operator is the leader. Blocks published by the previous leader are
+// mirrored before. An operator which finds its block number taken
+// by another operator resigns. It returns the number of the published
+// block or zero if the current block is empty.
+func (o *Operator) PublishBlock(ctx context.Context) (uint64, error) {
+	o.mtx.Lock()
+	expires, next := o.expires, o.next
+	o.mtx.Unlock()
+
+	if !o.now().Before(expires) {
+		return 0, ErrNotLeader
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, expires.Sub(o.now()))
+	defer cancel()
+
+	if next == 0 {
+		// the new leader mirrors blocks of the previous one
+		if err := o.replica.Poll(ctx); err != nil {
+			return 0, err
+		}
+
+		last, err := o.service.LastBlockNumber(ctx)
+		if err != nil {
+			return 0, err
+		}
+		next = last.Uint64() + 1
+	}
+
+	number, err := o.service.PublishBlockAt(ctx, next)
+	if errors.Cause(err) == service.ErrBlocksNotStored {
+		o.logger.Info("Mirroring published blocks", "err", err)
+		if err := o.replica.Poll(ctx); err != nil {
+			return 0, err
+		}
+		number, err = o.service.PublishBlockAt(ctx, next)
+	}
+
+	if errors.Cause(err) == service.ErrBlockNumberTaken {
+		o.logger.Error("Another operator publishes blocks", "err", err)
+		if err := o.Resign(ctx); err != nil {
+			o.logger.Error("Failed to resign", "err", err)
+		}
+	}
+
+	if err != nil {
+		return number, err
+	}
+
+	if number != 0 {
+		next = number + 1
+	}
+
+	o.mtx.Lock()
+	o.next = next
+	o.mtx.Unlock()
+	return number, nil
+}
+
+// Run campaigns for the lock and publishes blocks while the operator
+// is the leader until the context is done. A standby operator mirrors
+// blocks of the leader between campaigns. The leader resigns
+// when the context is done.
+func (o *Operator) Run(ctx context.Context) error {
+	o.logger.Info("Operator started", "ttl", o.ttl, "interval", o.interval)
+
+	renew := time.NewTicker(o.ttl / 3)
+	defer renew.Stop()
+
+	blocks := time.NewTicker(o.interval)
+	defer blocks.Stop()
+
+	o.campaign(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			if o.IsLeader() {
+				if err := o.Resign(context.Background()); err != nil {
+					o.logger.Error("Failed to resign", "err", err)
+				}
+			}
+			o.logger.Info("Operator stopped")
+			return ctx.Err()
+		case <-renew.C:
+			o.campaign(ctx)
+		case <-blocks.C:
+			if !o.IsLeader() {
+				continue
+			}
+
+			_, err := o.PublishBlock(ctx)
+			if err != nil && ctx.Err() == nil {
+				o.logger.Error("Failed to publish block", "err", err)
+			}
+		}
+	}
+}
+
+// campaign campaigns for the lock, a standby operator
+// mirrors blocks of the leader.
+func (o *Operator) campaign(ctx context.Context) {
+	leader, err := o.Campaign(ctx)
+	if err != nil && ctx.Err() == nil {
+		o.logger.Error("Failed to campaign", "err", err)
+	}
+
+	if leader {
+		return
+	}
+
+	if err := o.replica.Poll(ctx); err != nil && ctx.Err() == nil {
+		o.logger.Warn("Failed to mirror leader", "err", err)
+	}
+}
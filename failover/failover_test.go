@@ -0,0 +1,403 @@
+package failover
+
+import (
+	"context"
+	"io/ioutil"
+	"math/big"
+	"net/http/httptest"
+	"net/rpc"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/SmartMeshFoundation/Spectrum/accounts/abi/bind"
+	"github.com/SmartMeshFoundation/Spectrum/common"
+	"github.com/pkg/errors"
+
+	"github.com/SmartMeshFoundation/SmartPlasma/blockchan/account"
+	"github.com/SmartMeshFoundation/SmartPlasma/blockchan/backend"
+	"github.com/SmartMeshFoundation/SmartPlasma/blockchan/transaction"
+	"github.com/SmartMeshFoundation/SmartPlasma/contract/mediator"
+	"github.com/SmartMeshFoundation/SmartPlasma/contract/rootchain"
+	"github.com/SmartMeshFoundation/SmartPlasma/database/bolt"
+	"github.com/SmartMeshFoundation/SmartPlasma/replica"
+	"github.com/SmartMeshFoundation/SmartPlasma/service"
+	"github.com/SmartMeshFoundation/SmartPlasma/transport"
+	"github.com/SmartMeshFoundation/SmartPlasma/transport/handlers"
+)
+
+var (
+	zero = big.NewInt(0)
+	one  = big.NewInt(1)
+	two  = big.NewInt(2)
+)
+
+type instance struct {
+	backend   backend.Backend
+	accounts  []*account.PlasmaTransactOpts
+	rootChain common.Address
+	session   *rootchain.RootChainSession
+	lock      *MemoryLock
+	clock     *clock
+	dir       string
+	closers   []func()
+}
+
+type node struct {
+	operator *Operator
+	service  *service.Service
+	server   *httptest.Server
+}
+
+func newInstance(t *testing.T, numberAcc int) *instance {
+	accounts := account.GenAccounts(numberAcc)
+	owner := accounts[0]
+
+	server := backend.NewSimulatedBackend(account.Addresses(accounts))
+
+	_, med, err := mediator.Deploy(owner.TransactOpts, server)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rootChain, err := med.RootChain(&bind.CallOptsWithNumber{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	session, err := rootchain.NewRootChainSession(
+		*owner.TransactOpts, rootChain, server)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := ioutil.TempDir("", "failover")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := newClock()
+	lock := NewMemoryLock()
+	lock.now = c.now
+
+	return &instance{
+		backend:   server,
+		accounts:  accounts,
+		rootChain: rootChain,
+		session:   session,
+		lock:      lock,
+		clock:     c,
+		dir:       dir,
+	}
+}
+
+// newDB opens new database in the directory of the instance,
+// it is closed with the instance.
+func (i *instance) newDB(t *testing.T, bucket string) *bolt.DB {
+	dir, err := ioutil.TempDir(i.dir, bucket)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := bolt.NewDB(filepath.Join(dir, bucket), bucket, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	i.closers = append(i.closers, func() { db.Close() })
+	return db
+}
+
+// Close closes clients, servers, services and databases of the instance
+// in reverse order and removes its directory.
+func (i *instance) Close() {
+	for j := len(i.closers) - 1; j >= 0; j-- {
+		i.closers[j]()
+	}
+	os.RemoveAll(i.dir)
+}
+
+// nodes creates two operator nodes which are replicas of each other.
+func (i *instance) nodes(t *testing.T) (*node, *node) {
+	a, b := i.node(t), i.node(t)
+	a.operator = i.operator(t, "a", a, b)
+	b.operator = i.operator(t, "b", b, a)
+	return a, b
+}
+
+func (i *instance) node(t *testing.T) *node {
+	s := service.NewService(i.session, i.backend,
+		i.newDB(t, bolt.BlocksBucket), i.newDB(t, bolt.CheckpointsBucket),
+		nil, nil, false)
+	i.closers = append(i.closers, func() { s.Close() })
+
+	rpcServer := rpc.NewServer()
+	rpcServer.RegisterName("SmartPlasma", handlers.NewSmartPlasma(100, s))
+
+	server := httptest.NewServer(rpcServer)
+	i.closers = append(i.closers, server.Close)
+	return &node{service: s, server: server}
+}
+
+func (i *instance) operator(t *testing.T, id string,
+	n, leader *node) *Operator {
+	cli := transport.NewClient(100, i.accounts[0])
+	if err := cli.ConnectString(leader.server.URL[7:]); err != nil {
+		t.Fatal(err)
+	}
+	i.closers = append(i.closers, func() { cli.Close() })
+
+	r := replica.New(i.backend, i.rootChain, cli, n.service,
+		i.newDB(t, "replica"))
+
+	o := New(id, i.lock, n.service, r)
+	o.SetTTL(time.Minute)
+	o.now = i.clock.now
+	return o
+}
+
+func testTx(t *testing.T, prevBlock, uid, nonce *big.Int,
+	newOwner common.Address,
+	signer *account.PlasmaTransactOpts) *transaction.Transaction {
+	unsignedTx, err := transaction.NewTransaction(
+		prevBlock, uid, one, nonce, newOwner)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tx, err := signer.PlasmaSigner(signer.From, unsignedTx,
+		transaction.LegacyDomain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tx
+}
+
+func campaign(t *testing.T, n *node, expected bool) {
+	leader, err := n.operator.Campaign(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if leader != expected {
+		t.Fatalf("expected leadership %v, got %v", expected, leader)
+	}
+}
+
+// publish publishes the block with the transaction
+// and returns the number of the block.
+func publish(t *testing.T, n *node, tx *transaction.Transaction) uint64 {
+	if err := n.service.AcceptTransaction(tx); err != nil {
+		t.Fatal(err)
+	}
+
+	number, err := n.operator.PublishBlock(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return number
+}
+
+func (i *instance) lastBlock(t *testing.T) uint64 {
+	last, err := i.session.BlockNumber()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return last.Uint64()
+}
+
+func (i *instance) stored(t *testing.T, n *node, number uint64) {
+	hash, err := n.service.BlockHash(number)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root, err := i.session.ChildChain(new(big.Int).SetUint64(number))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if hash != common.Hash(root) {
+		t.Fatalf("block %d is not stored", number)
+	}
+}
+
+func TestFailover(t *testing.T) {
+	i := newInstance(t, 3)
+	defer i.Close()
+
+	user1, user2 := i.accounts[1], i.accounts[2]
+	uid := big.NewInt(7)
+	a, b := i.nodes(t)
+
+	campaign(t, a, true)
+	campaign(t, b, false)
+
+	if publish(t, a, testTx(t, zero, uid, zero, user2.From, user1)) != 1 {
+		t.Fatal("wrong block number")
+	}
+
+	// the standby publishes nothing
+	err := b.service.AcceptTransaction(
+		testTx(t, one, big.NewInt(8), zero, user2.From, user1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := b.operator.PublishBlock(
+		context.Background()); err != ErrNotLeader {
+		t.Fatalf("expected error %s, got %v", ErrNotLeader, err)
+	}
+
+	if publish(t, a, testTx(t, one, uid, one, user1.From, user2)) != 2 {
+		t.Fatal("wrong block number")
+	}
+
+	// the leader stops renewing its lease
+	i.clock.add(time.Minute)
+
+	if _, err := a.operator.PublishBlock(
+		context.Background()); err != ErrNotLeader {
+		t.Fatalf("expected error %s, got %v", ErrNotLeader, err)
+	}
+
+	// the standby mirrors blocks of the leader and resumes after them
+	campaign(t, b, true)
+	campaign(t, a, false)
+
+	number, err := b.operator.PublishBlock(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if number != 3 || i.lastBlock(t) != 3 {
+		t.Fatal("block number is reused")
+	}
+
+	for n := uint64(1); n <= 3; n++ {
+		i.stored(t, b, n)
+	}
+
+	// the old leader mirrors blocks of the new one
+	if err := a.operator.replica.Poll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	i.stored(t, a, 3)
+
+	if err := b.operator.Resign(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	campaign(t, a, true)
+
+	if publish(t, a, testTx(t, two, uid, two, user2.From, user1)) != 4 {
+		t.Fatal("wrong block number")
+	}
+}
+
+func TestFailoverUnavailableBlocks(t *testing.T) {
+	i := newInstance(t, 3)
+	defer i.Close()
+
+	user1, user2 := i.accounts[1], i.accounts[2]
+	uid := big.NewInt(7)
+	a, b := i.nodes(t)
+
+	campaign(t, a, true)
+	publish(t, a, testTx(t, zero, uid, zero, user2.From, user1))
+
+	// the leader publishes a root without its block and fails
+	tx, err := i.session.NewBlock(common.Hash{1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !i.backend.GoodTransaction(tx) {
+		t.Fatal("failed to publish block")
+	}
+	i.clock.add(time.Minute)
+
+	campaign(t, b, true)
+	err = b.service.AcceptTransaction(
+		testTx(t, one, uid, one, user1.From, user2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := b.operator.PublishBlock(context.Background()); errors.Cause(
+		err) != replica.ErrBlockUnavailable {
+		t.Fatalf("expected error %s, got %v", replica.ErrBlockUnavailable,
+			err)
+	}
+
+	if i.lastBlock(t) != 2 {
+		t.Fatal("block is published after the unavailable block")
+	}
+}
+
+func TestFailoverFencing(t *testing.T) {
+	i := newInstance(t, 3)
+	defer i.Close()
+
+	user1, user2 := i.accounts[1], i.accounts[2]
+	uid := big.NewInt(7)
+	a, b := i.nodes(t)
+
+	campaign(t, a, true)
+	publish(t, a, testTx(t, zero, uid, zero, user2.From, user1))
+
+	if err := b.operator.replica.Poll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// the other operator publishes a block without the lock
+	err := b.service.AcceptTransaction(
+		testTx(t, one, big.NewInt(8), zero, user2.From, user1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	number, err := b.service.PublishBlock(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if number != 2 {
+		t.Fatal("wrong block number")
+	}
+
+	// the leader does not publish after a block it does not expect
+	err = a.service.AcceptTransaction(
+		testTx(t, one, uid, one, user1.From, user2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := a.operator.PublishBlock(context.Background()); errors.Cause(
+		err) != service.ErrBlockNumberTaken {
+		t.Fatalf("expected error %s, got %v", service.ErrBlockNumberTaken,
+			err)
+	}
+
+	if a.operator.IsLeader() || i.lastBlock(t) != 2 {
+		t.Fatal("the fenced leader publishes")
+	}
+
+	// the leader mirrors the block when it acquires the lock again
+	campaign(t, a, true)
+
+	number, err = a.operator.PublishBlock(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if number != 3 {
+		t.Fatal("block number is reused")
+	}
+
+	i.stored(t, a, 2)
+	i.stored(t, a, 3)
+
+	if i.lastBlock(t) != 3 {
+		t.Fatal("wrong number of blocks")
+	}
+}
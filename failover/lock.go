@@ -0,0 +1,289 @@
+package failover
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Lock is a lock shared by operators. The lock is a lease, the holder
+// has the lock until the time-to-live passes and renews it
+// by acquiring it again.
+type Lock interface {
+	// TryLock acquires or renews the lock for the holder.
+	// It returns false if another holder has the lock.
+	TryLock(ctx context.Context, holder string,
+		ttl time.Duration) (bool, error)
+	// Unlock releases the lock if the holder has it.
+	Unlock(ctx context.Context, holder string) error
+	// Holder returns the holder of the lock or an empty string
+	// if the lock is free.
+	Holder(ctx context.Context) (string, error)
+}
+
+// lease is the state of a lock.
+type lease struct {
+	Holder  string    `json:"holder"`
+	Expires time.Time `json:"expires"`
+}
+
+// free returns true if the lease may be taken by the holder.
+func (l lease) free(holder string, now time.Time) bool {
+	return l.Holder == "" || l.Holder == holder || !now.Before(l.Expires)
+}
+
+// MemoryLock is a lock in memory of the process,
+// it is used by operators which run in one process.
+type MemoryLock struct {
+	mtx   sync.Mutex
+	lease lease
+	now   func() time.Time
+}
+
+// NewMemoryLock creates a free lock in memory.
+func NewMemoryLock() *MemoryLock {
+	return &MemoryLock{now: time.Now}
+}
+
+// TryLock acquires or renews the lock for the holder.
+func (l *MemoryLock) TryLock(ctx context.Context, holder string,
+	ttl time.Duration) (bool, error) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	now := l.now()
+	if !l.lease.free(holder, now) {
+		return false, nil
+	}
+	l.lease = lease{Holder: holder, Expires: now.Add(ttl)}
+	return true, nil
+}
+
+// Unlock releases the lock if the holder has it.
+func (l *MemoryLock) Unlock(ctx context.Context, holder string) error {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	if l.lease.Holder == holder {
+		l.lease = lease{}
+	}
+	return nil
+}
+
+// Holder returns the holder of the lock.
+func (l *MemoryLock) Holder(ctx context.Context) (string, error) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	if l.lease.free("", l.now()) {
+		return "", nil
+	}
+	return l.lease.Holder, nil
+}
+
+// guardTimeout is the time after which the guard file
+// of a crashed process is removed.
+const guardTimeout = 10 * time.Second
+
+// FileLock is a lock in a file shared by operators, for example
+// on a network file system. The lease is written to the file,
+// changes of the lease are guarded by the file with the ".guard"
+// suffix which is created exclusively. The guard of a crashed process
+// is taken over by renaming it, so only one process removes it.
+type FileLock struct {
+	path string
+	now  func() time.Time
+}
+
+// NewFileLock creates a lock in the file.
+func NewFileLock(path string) *FileLock {
+	return &FileLock{path: path, now: time.Now}
+}
+
+// TryLock acquires or renews the lock for the holder.
+func (l *FileLock) TryLock(ctx context.Context, holder string,
+	ttl time.Duration) (acquired bool, err error) {
+	err = l.guarded(ctx, func() error {
+		current, err := l.read()
+		if err != nil {
+			return err
+		}
+
+		now := l.now()
+		if !current.free(holder, now) {
+			return nil
+		}
+
+		acquired = true
+		return l.write(lease{Holder: holder, Expires: now.Add(ttl)})
+	})
+	return acquired, err
+}
+
+// Unlock releases the lock if the holder has it.
+func (l *FileLock) Unlock(ctx context.Context, holder string) error {
+	return l.guarded(ctx, func() error {
+		current, err := l.read()
+		if err != nil {
+			return err
+		}
+
+		if current.Holder != holder {
+			return nil
+		}
+		return l.write(lease{})
+	})
+}
+
+// Holder returns the holder of the lock.
+func (l *FileLock) Holder(ctx context.Context) (string, error) {
+	current, err := l.read()
+	if err != nil {
+		return "", err
+	}
+
+	if current.free("", l.now()) {
+		return "", nil
+	}
+	return current.Holder, nil
+}
+
+// guarded calls the function while the process has the guard file.
+// The guard contains a random token of the process, so the process
+// removes only its own guard.
+func (l *FileLock) guarded(ctx context.Context, fn func() error) error {
+	guard := l.path + ".guard"
+
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return err
+	}
+	token := []byte(hex.EncodeToString(raw))
+
+	for {
+		f, err := os.OpenFile(guard, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			_, err = f.Write(token)
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+
+			if err != nil {
+				os.Remove(guard)
+				return err
+			}
+			break
+		}
+
+		if !os.IsExist(err) {
+			return err
+		}
+
+		if takeOver(guard, string(token)) {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	defer release(guard, token)
+
+	return fn()
+}
+
+// takeOver removes the guard of a crashed process. The guard is renamed
+// to a name of the process, a rename is atomic, so only one process
+// takes the guard. A guard which was created after the stale guard
+// was checked is renamed back unless another guard is created.
+// It returns true if the stale guard is removed.
+func takeOver(guard, token string) bool {
+	if !stale(guard) {
+		return false
+	}
+
+	taken := guard + "." + token
+	if err := os.Rename(guard, taken); err != nil {
+		return false
+	}
+	defer os.Remove(taken)
+
+	if stale(taken) {
+		return true
+	}
+
+	// the link fails if the guard exists
+	os.Link(taken, guard)
+	return false
+}
+
+// stale returns true if the guard is older than the guard timeout.
+func stale(guard string) bool {
+	info, err := os.Stat(guard)
+	return err == nil && time.Since(info.ModTime()) > guardTimeout
+}
+
+// release removes the guard if it contains the token.
+func release(guard string, token []byte) {
+	current, err := ioutil.ReadFile(guard)
+	if err == nil && bytes.Equal(current, token) {
+		os.Remove(guard)
+	}
+}
+
+func (l *FileLock) read() (lease, error) {
+	var current lease
+
+	raw, err := ioutil.ReadFile(l.path)
+	if os.IsNotExist(err) {
+		return current, nil
+	} else if err != nil {
+		return current, err
+	}
+
+	if len(raw) == 0 {
+		return current, nil
+	}
+
+	if err := json.Unmarshal(raw, &current); err != nil {
+		return current, errors.Wrapf(err, "failed to read lock %s", l.path)
+	}
+	return current, nil
+}
+
+// write replaces the lease in the file,
+// readers never see a partially written lease.
+func (l *FileLock) write(next lease) error {
+	raw, err := json.Marshal(next)
+	if err != nil {
+		return err
+	}
+
+	f, err := ioutil.TempFile(filepath.Dir(l.path), filepath.Base(l.path))
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(raw); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), l.path)
+}
@@ -0,0 +1,169 @@
+package failover
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// clock is a manual clock of tests.
+type clock struct {
+	t time.Time
+}
+
+func newClock() *clock {
+	return &clock{t: time.Unix(1000, 0)}
+}
+
+func (c *clock) now() time.Time {
+	return c.t
+}
+
+func (c *clock) add(d time.Duration) {
+	c.t = c.t.Add(d)
+}
+
+func testLock(t *testing.T, lock Lock, c *clock) {
+	ctx := context.Background()
+
+	holder := func(expected string) {
+		h, err := lock.Holder(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if h != expected {
+			t.Fatalf("expected holder %q, got %q", expected, h)
+		}
+	}
+
+	tryLock := func(holder string, expected bool) {
+		ok, err := lock.TryLock(ctx, holder, time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if ok != expected {
+			t.Fatalf("expected %v for %s, got %v", expected, holder, ok)
+		}
+	}
+
+	holder("")
+	tryLock("a", true)
+	tryLock("b", false)
+	holder("a")
+
+	// the holder renews the lock
+	c.add(50 * time.Second)
+	tryLock("a", true)
+	c.add(50 * time.Second)
+	tryLock("b", false)
+
+	// the lock is free after the time-to-live
+	c.add(10 * time.Second)
+	holder("")
+	tryLock("b", true)
+	tryLock("a", false)
+
+	// only the holder unlocks the lock
+	if err := lock.Unlock(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+	holder("b")
+
+	if err := lock.Unlock(ctx, "b"); err != nil {
+		t.Fatal(err)
+	}
+	holder("")
+	tryLock("a", true)
+}
+
+func TestMemoryLock(t *testing.T) {
+	c := newClock()
+	lock := NewMemoryLock()
+	lock.now = c.now
+	testLock(t, lock, c)
+}
+
+func TestFileLock(t *testing.T) {
+	c := newClock()
+	dir, err := ioutil.TempDir("", "failover")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "operator.lock")
+
+	lock := NewFileLock(path)
+	lock.now = c.now
+	testLock(t, lock, c)
+
+	// the lease is shared by locks of the file
+	other := NewFileLock(path)
+	other.now = c.now
+
+	ok, err := other.TryLock(context.Background(), "b", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ok {
+		t.Fatal("lock is acquired by two holders")
+	}
+}
+
+func TestFileLockGuard(t *testing.T) {
+	c := newClock()
+	dir, err := ioutil.TempDir("", "failover")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "operator.lock")
+	guard := path + ".guard"
+
+	lock := NewFileLock(path)
+	lock.now = c.now
+
+	// the guard of a live process is not taken over
+	if err := ioutil.WriteFile(guard, []byte("live"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(
+		context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	if _, err := lock.TryLock(ctx, "a", time.Minute); err != ctx.Err() {
+		t.Fatalf("expected %s, got %v", context.DeadlineExceeded, err)
+	}
+
+	// the guard of a crashed process is taken over
+	old := time.Now().Add(-2 * guardTimeout)
+	if err := os.Chtimes(guard, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	ok, err := lock.TryLock(context.Background(), "a", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !ok {
+		t.Fatal("lock is not acquired")
+	}
+
+	files, err := filepath.Glob(guard + "*")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(files) != 0 {
+		t.Fatalf("guard files are left: %v", files)
+	}
+}
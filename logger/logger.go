@@ -0,0 +1,131 @@
+// Package logger configures the leveled structured logger of Spectrum
+// for SmartPlasma packages. Nothing is written until a handler is set
+// on the root logger:
+//
+//	log.Root().SetHandler(log.LvlFilterHandler(log.LvlInfo,
+//		log.StreamHandler(os.Stderr, log.LogfmtFormat())))
+//
+// A custom logger can be injected with SetLogger of service.Service,
+// transport.Server, handlers.SmartPlasma and backend.Backend. Every RPC
+// call gets a request identifier (reqid), it is stored in the context
+// used by the handlers and the Spectrum calls. Values of sensitive
+// fields (signatures, keys, tokens) are redacted.
+package logger
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"strings"
+
+	"github.com/SmartMeshFoundation/Spectrum/log"
+	"github.com/pborman/uuid"
+)
+
+// Context keys.
+const (
+	ModuleKey    = "module"
+	RequestIDKey = "reqid"
+)
+
+// Redacted replaces values of sensitive fields in log records.
+const Redacted = "[redacted]"
+
+// sensitiveKeys are the log keys whose values are never written.
+var sensitiveKeys = []string{
+	"sig",
+	"signature",
+	"key",
+	"privkey",
+	"passphrase",
+	"password",
+	"token",
+	"secret",
+	"authorization",
+}
+
+type requestIDKey struct{}
+
+// New creates a logger for a module. The logger writes
+// to the root handler and redacts sensitive fields.
+func New(module string) log.Logger {
+	return Wrap(log.New(ModuleKey, module))
+}
+
+// Wrap returns a child of the logger that redacts sensitive fields.
+func Wrap(l log.Logger) log.Logger {
+	child := l.New()
+	child.SetHandler(RedactHandler(child.GetHandler()))
+	return child
+}
+
+// NewRequestID generates new request identifier.
+func NewRequestID() string {
+	return uuid.NewRandom().String()
+}
+
+// WithRequestID returns a copy of the context with the request identifier.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request identifier from the context.
+func RequestID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// FromContext returns a logger which includes
+// the request identifier from the context.
+func FromContext(ctx context.Context, l log.Logger) log.Logger {
+	if id := RequestID(ctx); id != "" {
+		return l.New(RequestIDKey, id)
+	}
+	return l
+}
+
+// IsSensitive returns true if values of the log key must not be written.
+func IsSensitive(key string) bool {
+	key = strings.ToLower(key)
+	for _, s := range sensitiveKeys {
+		if key == s || strings.HasSuffix(key, "_"+s) ||
+			strings.HasSuffix(key, s+"s") {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactHandler replaces values of sensitive fields
+// and private keys before passing a record to the handler.
+func RedactHandler(h log.Handler) log.Handler {
+	return log.FuncHandler(func(r *log.Record) error {
+		ctx := make([]interface{}, len(r.Ctx))
+		copy(ctx, r.Ctx)
+
+		for i := 0; i+1 < len(ctx); i += 2 {
+			if _, ok := ctx[i+1].(*ecdsa.PrivateKey); ok {
+				ctx[i+1] = Redacted
+				continue
+			}
+			if IsSensitive(fmt.Sprint(ctx[i])) {
+				ctx[i+1] = Redacted
+			}
+		}
+
+		cpy := *r
+		cpy.Ctx = ctx
+		return h.Log(&cpy)
+	})
+}
+
+// Secret wraps sensitive bytes, only their length is printed.
+type Secret []byte
+
+// String implements fmt.Stringer.
+func (s Secret) String() string {
+	return fmt.Sprintf("%s(%d bytes)", Redacted, len(s))
+}
@@ -0,0 +1,87 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/SmartMeshFoundation/Spectrum/crypto"
+	"github.com/SmartMeshFoundation/Spectrum/log"
+)
+
+func testLogger() (log.Logger, *bytes.Buffer) {
+	buf := bytes.NewBuffer(nil)
+	l := log.New()
+	l.SetHandler(log.StreamHandler(buf, log.LogfmtFormat()))
+	return Wrap(l), buf
+}
+
+func TestRequestID(t *testing.T) {
+	ctx := context.Background()
+
+	if RequestID(ctx) != "" {
+		t.Fatal("unexpected request id")
+	}
+
+	id := NewRequestID()
+	if id == "" || id == NewRequestID() {
+		t.Fatal("wrong request id")
+	}
+
+	ctx = WithRequestID(ctx, id)
+	if RequestID(ctx) != id {
+		t.Fatal("request id is not stored in the context")
+	}
+
+	l, buf := testLogger()
+	FromContext(ctx, l).Info("call")
+
+	if !strings.Contains(buf.String(), RequestIDKey+"="+id) {
+		t.Fatalf("request id is not logged: %s", buf.String())
+	}
+}
+
+func TestRedact(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l, buf := testLogger()
+	l.Info("sign", "sig", "0xdeadbeef", "private", key,
+		"auth_token", "qwerty", "raw", Secret([]byte{1, 2, 3}),
+		"uid", 1)
+
+	out := buf.String()
+	for _, secret := range []string{"deadbeef", "qwerty", "raw=\"\\x01"} {
+		if strings.Contains(out, secret) {
+			t.Fatalf("secret %s is logged: %s", secret, out)
+		}
+	}
+
+	if !strings.Contains(out, "uid=1") {
+		t.Fatalf("non sensitive field is redacted: %s", out)
+	}
+
+	if !strings.Contains(out, "(3 bytes)") {
+		t.Fatalf("secret length is not logged: %s", out)
+	}
+}
+
+func TestIsSensitive(t *testing.T) {
+	for key, expected := range map[string]bool{
+		"sig":       true,
+		"Signature": true,
+		"keys":      true,
+		"admin_key": true,
+		"token":     true,
+		"uid":       false,
+		"hash":      false,
+		"reqid":     false,
+	} {
+		if IsSensitive(key) != expected {
+			t.Fatalf("wrong result for key %s", key)
+		}
+	}
+}
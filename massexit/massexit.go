@@ -0,0 +1,483 @@
+// Package massexit exits all coins of a set of wallets when the operator
+// fails. Exits of all wallets are started together with managed nonces
+// and escalating gas prices, their results are saved to a database,
+// and coins are withdrawn after the challenge period.
+//
+// Prepare saves exits of coins whose last transaction in the wallet
+// history gives the coin to the wallet. Start sends StartExit
+// transactions in batches: nonces of wallets are assigned in order,
+// an underpriced transaction or a transaction which is not mined in time
+// is sent again with the same nonce and the gas price raised
+// by Config.Escalation percent up to Config.MaxGasPrice. Withdraw updates
+// exits with RootChain contract and withdraws coins of exits which are
+// over. Failed exits keep their errors and are sent again by the next
+// call.
+package massexit
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/SmartMeshFoundation/Spectrum/accounts/abi/bind"
+	"github.com/SmartMeshFoundation/Spectrum/common"
+	"github.com/SmartMeshFoundation/Spectrum/core/types"
+	"github.com/SmartMeshFoundation/Spectrum/log"
+	"github.com/SmartMeshFoundation/Spectrum/rlp"
+	"github.com/pkg/errors"
+
+	"github.com/SmartMeshFoundation/SmartPlasma/blockchan/account"
+	"github.com/SmartMeshFoundation/SmartPlasma/blockchan/backend"
+	"github.com/SmartMeshFoundation/SmartPlasma/contract/mediator"
+	"github.com/SmartMeshFoundation/SmartPlasma/contract/rootchain"
+	"github.com/SmartMeshFoundation/SmartPlasma/database"
+	"github.com/SmartMeshFoundation/SmartPlasma/logger"
+	"github.com/SmartMeshFoundation/SmartPlasma/watcher"
+)
+
+// Mass exit errors.
+var (
+	ErrNoWallets     = errors.New("no wallets to exit")
+	ErrUnknownWallet = errors.New("exit of unknown wallet")
+	ErrGasPriceLimit = errors.New("gas price reached the limit")
+	ErrNotMined      = errors.New("transaction is not mined")
+)
+
+// exitsKey is the key of exits in the database.
+var exitsKey = []byte("massexit")
+
+// Wallet is a wallet which coins are exited.
+type Wallet struct {
+	Opts    *account.PlasmaTransactOpts
+	History *watcher.History
+}
+
+// Config is the configuration of sending transactions.
+type Config struct {
+	// GasPrice is the initial gas price,
+	// the price suggested by the backend is used if it is nil.
+	GasPrice *big.Int
+	// MaxGasPrice limits the escalated gas price, nil means no limit.
+	MaxGasPrice *big.Int
+	// GasLimit is the gas limit of transactions, the limit
+	// is estimated for every transaction if it is nil.
+	GasLimit *big.Int
+	// Escalation is the percent the gas price is raised by when
+	// a transaction is underpriced or is not mined in time.
+	Escalation uint64
+	// Retries is the number of times a transaction is sent again
+	// with a raised gas price.
+	Retries int
+	// BatchSize is the number of transactions sent before waiting
+	// for them to be mined.
+	BatchSize int
+	// Timeout is the time a transaction is waited for
+	// before it is replaced.
+	Timeout time.Duration
+}
+
+// DefaultConfig returns the default configuration.
+func DefaultConfig() Config {
+	return Config{
+		Escalation: 25,
+		Retries:    5,
+		BatchSize:  10,
+		Timeout:    time.Minute,
+	}
+}
+
+// Result is the result of the exit of a coin.
+type Result struct {
+	Wallet common.Address
+	UID    *big.Int
+	Stage  watcher.ExitStage
+	// ExitTime is the unix time after which the coin can be withdrawn.
+	ExitTime   uint64
+	StartTx    common.Hash
+	WithdrawTx common.Hash
+	// GasPrice is the gas price of the last transaction of the exit.
+	GasPrice *big.Int
+	// Error is the last error of the exit.
+	Error string
+}
+
+// exit is the exit of a coin saved to the database.
+type exit struct {
+	Result
+	Prev watcher.CoinRecord
+	Last watcher.CoinRecord
+}
+
+type wallet struct {
+	history   *watcher.History
+	rootChain *rootchain.RootChainSession
+	mediator  *mediator.MediatorSession
+}
+
+// MassExit exits coins of wallets.
+type MassExit struct {
+	backend backend.Backend
+	wallets map[common.Address]*wallet
+	order   []common.Address
+	db      database.Database
+	cfg     Config
+	logger  log.Logger
+
+	// gasPrice is the current gas price of transactions,
+	// it is escalated for all wallets together.
+	gasPrice *big.Int
+
+	// now returns the current time, coins are withdrawn
+	// after exit times.
+	now func() time.Time
+
+	mtx sync.Mutex
+}
+
+// New creates a mass exit of coins of the wallets. Exits are started
+// in RootChain contract, coins are withdrawn from Mediator contract,
+// results are saved to the database.
+func New(server backend.Backend, rootChain, mediatorAddr common.Address,
+	wallets []Wallet, db database.Database, cfg Config) (*MassExit, error) {
+	if len(wallets) == 0 {
+		return nil, ErrNoWallets
+	}
+
+	defaults := DefaultConfig()
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaults.BatchSize
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaults.Timeout
+	}
+
+	m := &MassExit{
+		backend: server,
+		wallets: make(map[common.Address]*wallet),
+		db:      db,
+		cfg:     cfg,
+		logger:  logger.New("massexit"),
+		now:     time.Now,
+	}
+
+	if cfg.GasPrice != nil {
+		m.gasPrice = new(big.Int).Set(cfg.GasPrice)
+	}
+
+	for _, w := range wallets {
+		session, err := rootchain.NewRootChainSession(
+			*w.Opts.TransactOpts, rootChain, server)
+		if err != nil {
+			return nil, err
+		}
+
+		mediatorSession, err := mediator.NewMediatorSession(
+			*w.Opts.TransactOpts, mediatorAddr, server)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, ok := m.wallets[w.Opts.From]; !ok {
+			m.order = append(m.order, w.Opts.From)
+		}
+		m.wallets[w.Opts.From] = &wallet{
+			history:   w.History,
+			rootChain: session,
+			mediator:  mediatorSession,
+		}
+	}
+	return m, nil
+}
+
+// SetLogger sets the logger of the mass exit.
+func (m *MassExit) SetLogger(l log.Logger) {
+	m.logger = logger.Wrap(l)
+}
+
+// Prepare adds exits of coins owned by the wallets to the database.
+// Coins with exits in the database and coins which histories have
+// no transactions to exit with are skipped. It returns the number
+// of added exits.
+func (m *MassExit) Prepare() (int, error) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	exits, err := m.exits()
+	if err != nil {
+		return 0, err
+	}
+
+	added := 0
+	for _, addr := range m.order {
+		uids, err := m.wallets[addr].history.UIDs()
+		if err != nil {
+			return 0, err
+		}
+
+		for _, uid := range uids {
+			if findExit(exits, addr, uid) != nil {
+				continue
+			}
+
+			prev, last, err := m.wallets[addr].history.ExitRecords(uid, addr)
+			switch errors.Cause(err) {
+			case nil:
+			case watcher.ErrNotCoinOwner, watcher.ErrNoExitHistory:
+				m.logger.Debug("Coin skipped", "wallet", addr.Hex(),
+					"uid", uid, "err", err)
+				continue
+			default:
+				return 0, err
+			}
+
+			exits = append(exits, &exit{
+				Result: Result{
+					Wallet: addr,
+					UID:    uid,
+					Stage:  watcher.ExitPending,
+				},
+				Prev: *prev,
+				Last: *last,
+			})
+			added++
+		}
+	}
+
+	m.logger.Info("Exits prepared", "added", added, "total", len(exits))
+	return added, m.save(exits)
+}
+
+// Start starts pending exits. Exits which failed to start stay pending
+// with the error in their results and are started by the next call.
+// It returns the first error.
+func (m *MassExit) Start(ctx context.Context) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	exits, err := m.exits()
+	if err != nil {
+		return err
+	}
+
+	var jobs []*job
+	for _, e := range exits {
+		if e.Stage != watcher.ExitPending {
+			continue
+		}
+
+		w, ok := m.wallets[e.Wallet]
+		if !ok {
+			return errors.Wrapf(ErrUnknownWallet, "wallet %s", e.Wallet.Hex())
+		}
+
+		e := e
+		jobs = append(jobs, &job{
+			from: e.Wallet,
+			send: func(opts *bind.TransactOpts) (*types.Transaction, error) {
+				return w.rootChain.Contract.StartExit(opts, e.Prev.Tx,
+					e.Prev.Proof, new(big.Int).SetUint64(e.Prev.Block),
+					e.Last.Tx, e.Last.Proof,
+					new(big.Int).SetUint64(e.Last.Block))
+			},
+			done: func(j *job) {
+				e.GasPrice = j.gasPrice
+				if j.err != nil {
+					e.Error = j.err.Error()
+					return
+				}
+				e.Stage, e.StartTx, e.Error = watcher.ExitStarted, j.mined, ""
+			},
+			uid: e.UID,
+		})
+	}
+
+	m.logger.Info("Starting exits", "exits", len(jobs))
+	return m.run(ctx, "start", jobs, exits)
+}
+
+// Withdraw updates started exits with states of RootChain contract
+// and withdraws coins of exits whose exit time has passed
+// and which have no challenges. Exits which are not over are
+// withdrawn by the next call. It returns the first error.
+func (m *MassExit) Withdraw(ctx context.Context) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	exits, err := m.exits()
+	if err != nil {
+		return err
+	}
+
+	var first error
+	var jobs []*job
+	for _, e := range exits {
+		if e.Stage == watcher.ExitPending || e.Stage.Final() {
+			continue
+		}
+
+		w, ok := m.wallets[e.Wallet]
+		if !ok {
+			return errors.Wrapf(ErrUnknownWallet, "wallet %s", e.Wallet.Hex())
+		}
+
+		due, err := m.update(ctx, w, e)
+		if err != nil {
+			e.Error = err.Error()
+			if first == nil {
+				first = errors.Wrapf(err, "uid %s", e.UID)
+			}
+			continue
+		}
+
+		if !due {
+			continue
+		}
+
+		e := e
+		jobs = append(jobs, &job{
+			from: e.Wallet,
+			send: func(opts *bind.TransactOpts) (*types.Transaction, error) {
+				return w.mediator.Contract.Withdraw(opts, e.Prev.Tx,
+					e.Prev.Proof, new(big.Int).SetUint64(e.Prev.Block),
+					e.Last.Tx, e.Last.Proof,
+					new(big.Int).SetUint64(e.Last.Block))
+			},
+			done: func(j *job) {
+				e.GasPrice = j.gasPrice
+				if j.err != nil {
+					e.Error = j.err.Error()
+					return
+				}
+				e.Stage, e.WithdrawTx, e.Error = watcher.ExitWithdrawn,
+					j.mined, ""
+			},
+			uid: e.UID,
+		})
+	}
+
+	m.logger.Info("Withdrawing coins", "exits", len(jobs))
+	if err := m.run(ctx, "withdraw", jobs, exits); first == nil {
+		first = err
+	}
+	return first
+}
+
+// Results returns results of all exits in the order they were prepared.
+func (m *MassExit) Results() ([]Result, error) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	exits, err := m.exits()
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]Result, len(exits))
+	for i, e := range exits {
+		results[i] = e.Result
+	}
+	return results, nil
+}
+
+// update updates the exit with its state in RootChain contract.
+// It returns true if the coin of the exit can be withdrawn.
+func (m *MassExit) update(ctx context.Context, w *wallet,
+	e *exit) (bool, error) {
+	session := rootchain.CopySession(w.rootChain)
+	session.CallOpts.Context = ctx
+
+	state, err := session.Exits(e.UID)
+	if err != nil {
+		return false, err
+	}
+
+	length, err := session.ChallengesLength(e.UID)
+	if err != nil {
+		return false, err
+	}
+
+	switch state.State.Int64() {
+	case watcher.ExitStateNone:
+		e.Stage = watcher.ExitCanceled
+		m.logger.Warn("Exit canceled by challenge", "uid", e.UID)
+	case watcher.ExitStateChallenged:
+		e.Stage = watcher.ExitChallenged
+	case watcher.ExitStateActive:
+		e.Stage = watcher.ExitStarted
+		e.ExitTime = state.ExitTime.Uint64()
+		return m.now().Unix() > state.ExitTime.Int64() &&
+			length.Sign() == 0, nil
+	case watcher.ExitStateFinished:
+		e.Stage = watcher.ExitWithdrawn
+	}
+	return false, nil
+}
+
+// run sends transactions of the jobs in batches and saves exits
+// after each batch. It returns the first error of the jobs.
+func (m *MassExit) run(ctx context.Context, kind string, jobs []*job,
+	exits []*exit) error {
+	var first error
+	for start := 0; start < len(jobs); start += m.cfg.BatchSize {
+		end := start + m.cfg.BatchSize
+		if end > len(jobs) {
+			end = len(jobs)
+		}
+
+		batch := jobs[start:end]
+		m.batch(ctx, batch)
+
+		for _, j := range batch {
+			j.done(j)
+			transactions.Inc(kind, resultLabel(j.err))
+
+			if j.err != nil {
+				m.logger.Warn("Exit transaction failed", "kind", kind,
+					"uid", j.uid, "err", j.err)
+				if first == nil {
+					first = errors.Wrapf(j.err, "uid %s", j.uid)
+				}
+			}
+		}
+
+		if err := m.save(exits); err != nil {
+			return err
+		}
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+	return first
+}
+
+func (m *MassExit) exits() ([]*exit, error) {
+	raw, err := m.db.Get(exitsKey)
+	if err != nil || len(raw) == 0 {
+		return nil, err
+	}
+
+	var exits []*exit
+	if err := rlp.DecodeBytes(raw, &exits); err != nil {
+		return nil, err
+	}
+	return exits, nil
+}
+
+func (m *MassExit) save(exits []*exit) error {
+	raw, err := rlp.EncodeToBytes(exits)
+	if err != nil {
+		return err
+	}
+	return m.db.Set(exitsKey, raw)
+}
+
+func findExit(exits []*exit, addr common.Address, uid *big.Int) *exit {
+	for _, e := range exits {
+		if e.Wallet == addr && e.UID.Cmp(uid) == 0 {
+			return e
+		}
+	}
+	return nil
+}
@@ -0,0 +1,546 @@
+package massexit
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/SmartMeshFoundation/Spectrum/accounts/abi/bind"
+	"github.com/SmartMeshFoundation/Spectrum/common"
+	"github.com/SmartMeshFoundation/Spectrum/core"
+	"github.com/SmartMeshFoundation/Spectrum/core/types"
+	"github.com/pkg/errors"
+
+	"github.com/SmartMeshFoundation/SmartPlasma/blockchan/account"
+	"github.com/SmartMeshFoundation/SmartPlasma/blockchan/backend"
+	"github.com/SmartMeshFoundation/SmartPlasma/blockchan/transaction"
+	"github.com/SmartMeshFoundation/SmartPlasma/contract/erc20token"
+	"github.com/SmartMeshFoundation/SmartPlasma/contract/mediator"
+	"github.com/SmartMeshFoundation/SmartPlasma/contract/rootchain"
+	"github.com/SmartMeshFoundation/SmartPlasma/database/bolt"
+	"github.com/SmartMeshFoundation/SmartPlasma/service"
+	"github.com/SmartMeshFoundation/SmartPlasma/watcher"
+)
+
+var (
+	zero = big.NewInt(0)
+	one  = big.NewInt(1)
+)
+
+type instance struct {
+	backend   backend.Backend
+	accounts  []*account.PlasmaTransactOpts
+	rootChain common.Address
+	mediator  common.Address
+	token     common.Address
+	session   *rootchain.RootChainSession
+	service   *service.Service
+	blocks    uint64
+	dir       string
+	closers   []func()
+}
+
+func newInstance(t *testing.T, numberAcc int) *instance {
+	accounts := account.GenAccounts(numberAcc)
+	owner := accounts[0]
+
+	server := backend.NewSimulatedBackend(account.Addresses(accounts))
+
+	mediatorAddr, med, err := mediator.Deploy(owner.TransactOpts, server)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rootChain, err := med.RootChain(&bind.CallOptsWithNumber{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, _, err := erc20token.Deploy(owner.TransactOpts, server)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	session, err := rootchain.NewRootChainSession(
+		*owner.TransactOpts, rootChain, server)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := ioutil.TempDir("", "massexit")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	i := &instance{
+		backend:   server,
+		accounts:  accounts,
+		rootChain: rootChain,
+		mediator:  mediatorAddr,
+		token:     token,
+		session:   session,
+		dir:       dir,
+	}
+
+	i.service = service.NewService(session, server,
+		i.newDB(t, bolt.BlocksBucket), i.newDB(t, bolt.CheckpointsBucket),
+		nil, nil, false)
+	i.closers = append(i.closers, func() { i.service.Close() })
+	return i
+}
+
+// newDB opens new database in the directory of the instance,
+// it is closed with the instance.
+func (i *instance) newDB(t *testing.T, bucket string) *bolt.DB {
+	dir, err := ioutil.TempDir(i.dir, bucket)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := bolt.NewDB(filepath.Join(dir, bucket), bucket, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	i.closers = append(i.closers, func() { db.Close() })
+	return db
+}
+
+// Close closes the service and databases of the instance in reverse
+// order and removes its directory.
+func (i *instance) Close() {
+	for j := len(i.closers) - 1; j >= 0; j-- {
+		i.closers[j]()
+	}
+	os.RemoveAll(i.dir)
+}
+
+func (i *instance) mustSucceed(t *testing.T, tx *types.Transaction,
+	err error, msg string) {
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !i.backend.GoodTransaction(tx) {
+		t.Fatal(msg)
+	}
+}
+
+// deposit deposits coins of one token from the account
+// and returns UIDs of the coins.
+func (i *instance) deposit(t *testing.T, user *account.PlasmaTransactOpts,
+	coins int) []*big.Int {
+	ownerToken, err := erc20token.NewExampleTokenSession(
+		*i.accounts[0].TransactOpts, i.token, i.backend)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	userToken, err := erc20token.NewExampleTokenSession(
+		*user.TransactOpts, i.token, i.backend)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	med, err := mediator.NewMediatorSession(
+		*user.TransactOpts, i.mediator, i.backend)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	amount := big.NewInt(int64(coins))
+	tx, err := ownerToken.Mint(user.From, amount)
+	i.mustSucceed(t, tx, err, "failed to mint tokens")
+
+	tx, err = userToken.IncreaseApproval(i.mediator, amount)
+	i.mustSucceed(t, tx, err, "failed to approve tokens")
+
+	uids := make([]*big.Int, coins)
+	for n := range uids {
+		uids[n], err = rootchain.GenerateNextUID(i.session, user.From, i.token)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		tx, err = med.Deposit(i.token, one)
+		i.mustSucceed(t, tx, err, "failed to deposit")
+	}
+	return uids
+}
+
+// publishBlock publishes the block with transactions of the coins
+// of the user with the nonce, adds them to the history and returns
+// the number of the block.
+func (i *instance) publishBlock(t *testing.T,
+	user *account.PlasmaTransactOpts, uids []*big.Int, prevBlock,
+	nonce *big.Int, history *watcher.History) *big.Int {
+	txs := make([]*transaction.Transaction, len(uids))
+	for n, uid := range uids {
+		unsignedTx, err := transaction.NewTransaction(
+			prevBlock, uid, one, nonce, user.From)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		txs[n], err = user.PlasmaSigner(user.From, unsignedTx,
+			transaction.LegacyDomain)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := i.service.AcceptTransaction(txs[n]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	hash, err := i.service.BuildBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ethTx, err := i.service.SendBlockHash(context.Background(), hash)
+	i.mustSucceed(t, ethTx, err, "failed to publish block")
+
+	i.blocks++
+	err = i.service.SaveBlockToDB(i.blocks, i.service.CurrentBlock())
+	if err != nil {
+		t.Fatal(err)
+	}
+	i.service.InitBlock()
+
+	for n, uid := range uids {
+		proof, err := i.service.CreateProof(uid, i.blocks)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		buf := bytes.NewBuffer(nil)
+		if err := txs[n].EncodeRLP(buf); err != nil {
+			t.Fatal(err)
+		}
+
+		err = history.Add(uid, watcher.CoinRecord{
+			Tx: buf.Bytes(), Proof: proof, Block: i.blocks})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return new(big.Int).SetUint64(i.blocks)
+}
+
+// wallets deposits coins of the users, publishes two transactions
+// of every coin in two blocks and returns wallets of the users.
+func (i *instance) wallets(t *testing.T, users []*account.PlasmaTransactOpts,
+	coins int) []Wallet {
+	wallets := make([]Wallet, len(users))
+	uids := make([][]*big.Int, len(users))
+	for n, user := range users {
+		uids[n] = i.deposit(t, user, coins)
+		wallets[n] = Wallet{
+			Opts:    user,
+			History: watcher.NewHistory(i.newDB(t, "history")),
+		}
+	}
+
+	blocks := make([]*big.Int, len(users))
+	for n, user := range users {
+		blocks[n] = i.publishBlock(t, user, uids[n], zero, zero,
+			wallets[n].History)
+	}
+
+	for n, user := range users {
+		i.publishBlock(t, user, uids[n], blocks[n], one, wallets[n].History)
+	}
+	return wallets
+}
+
+// timeMachine moves the simulated clock after the challenge period.
+func (i *instance) timeMachine(t *testing.T) {
+	period, err := i.session.ChallengePeriod()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	adjustment := time.Duration(period.Int64()+1) * time.Second
+	err = i.backend.(backend.Simulator).AdjustTime(adjustment)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (i *instance) massExit(t *testing.T, wallets []Wallet,
+	cfg Config) *MassExit {
+	m, err := New(i.backend, i.rootChain, i.mediator, wallets,
+		i.newDB(t, "massexit"), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the simulated clock starts at the zero time
+	m.now = func() time.Time { return time.Unix(0, 0) }
+	return m
+}
+
+// testConfig returns the configuration with batches which fit
+// the gas limit of the simulated block.
+func testConfig() Config {
+	cfg := DefaultConfig()
+	cfg.BatchSize = 2
+	return cfg
+}
+
+func stages(t *testing.T, m *MassExit) map[watcher.ExitStage]int {
+	results, err := m.Results()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stages := make(map[watcher.ExitStage]int)
+	for _, r := range results {
+		stages[r.Stage]++
+	}
+	return stages
+}
+
+func TestMassExit(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping mass exit of hundreds of coins in short mode")
+	}
+
+	const users, coins = 4, 50
+
+	i := newInstance(t, users+1)
+	defer i.Close()
+
+	wallets := i.wallets(t, i.accounts[1:], coins)
+	// exits are not estimated to save time of the simulator
+	cfg := testConfig()
+	cfg.GasLimit = big.NewInt(2000000)
+	m := i.massExit(t, wallets, cfg)
+	ctx := context.Background()
+
+	added, err := m.Prepare()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if added != users*coins {
+		t.Fatalf("expected %d exits, got %d", users*coins, added)
+	}
+
+	// exits are not added twice
+	if added, err := m.Prepare(); err != nil || added != 0 {
+		t.Fatalf("exits are prepared again: %d, %v", added, err)
+	}
+
+	if err := m.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := stages(t, m)[watcher.ExitStarted]; n != users*coins {
+		t.Fatalf("expected %d started exits, got %d", users*coins, n)
+	}
+
+	// coins are not withdrawn before the end of the challenge period
+	if err := m.Withdraw(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := stages(t, m)[watcher.ExitStarted]; n != users*coins {
+		t.Fatal("coins are withdrawn before the end of the challenge period")
+	}
+
+	i.timeMachine(t)
+	m.now = time.Now
+
+	if err := m.Withdraw(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := m.Results()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, r := range results {
+		if r.Stage != watcher.ExitWithdrawn ||
+			r.StartTx == (common.Hash{}) || r.WithdrawTx == (common.Hash{}) {
+			t.Fatalf("coin %s is not withdrawn: %s %s", r.UID, r.Stage,
+				r.Error)
+		}
+	}
+
+	for _, w := range wallets {
+		for _, r := range results {
+			if r.Wallet != w.Opts.From {
+				continue
+			}
+
+			exit, err := i.session.Exits(r.UID)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if exit.State.Int64() != watcher.ExitStateFinished {
+				t.Fatalf("exit of coin %s is not finished", r.UID)
+			}
+		}
+	}
+}
+
+// pricedBackend rejects transactions with gas prices
+// below the minimum.
+type pricedBackend struct {
+	backend.Backend
+	min *big.Int
+}
+
+func (b *pricedBackend) Connect() bind.ContractBackend {
+	return &pricedConn{ContractBackend: b.Backend.Connect(), min: b.min}
+}
+
+type pricedConn struct {
+	bind.ContractBackend
+	min *big.Int
+}
+
+func (c *pricedConn) SendTransaction(ctx context.Context,
+	tx *types.Transaction) error {
+	if tx.GasPrice().Cmp(c.min) < 0 {
+		return core.ErrUnderpriced
+	}
+	return c.ContractBackend.SendTransaction(ctx, tx)
+}
+
+func TestMassExitGasPriceEscalation(t *testing.T) {
+	i := newInstance(t, 3)
+	defer i.Close()
+
+	wallets := i.wallets(t, i.accounts[1:], 3)
+	i.backend = &pricedBackend{Backend: i.backend, min: big.NewInt(150)}
+
+	cfg := testConfig()
+	cfg.GasPrice = big.NewInt(100)
+	m := i.massExit(t, wallets, cfg)
+
+	if _, err := m.Prepare(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := m.Results()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) != 6 {
+		t.Fatalf("expected 6 exits, got %d", len(results))
+	}
+
+	for _, r := range results {
+		if r.Stage != watcher.ExitStarted {
+			t.Fatalf("exit of coin %s is not started: %s", r.UID, r.Error)
+		}
+
+		if r.GasPrice.Cmp(big.NewInt(150)) < 0 {
+			t.Fatalf("exit of coin %s is underpriced", r.UID)
+		}
+	}
+}
+
+func TestMassExitGasPriceLimit(t *testing.T) {
+	i := newInstance(t, 2)
+	defer i.Close()
+
+	wallets := i.wallets(t, i.accounts[1:], 2)
+	i.backend = &pricedBackend{Backend: i.backend, min: big.NewInt(150)}
+
+	cfg := testConfig()
+	cfg.GasPrice = big.NewInt(100)
+	cfg.MaxGasPrice = big.NewInt(140)
+	m := i.massExit(t, wallets, cfg)
+
+	if _, err := m.Prepare(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Start(context.Background()); errors.Cause(
+		err) != ErrGasPriceLimit {
+		t.Fatalf("expected error %s, got %v", ErrGasPriceLimit, err)
+	}
+
+	// exits stay pending and are started with a higher limit
+	if n := stages(t, m)[watcher.ExitPending]; n != 2 {
+		t.Fatalf("expected 2 pending exits, got %d", n)
+	}
+
+	m.cfg.MaxGasPrice = big.NewInt(200)
+	if err := m.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := stages(t, m)[watcher.ExitStarted]; n != 2 {
+		t.Fatalf("expected 2 started exits, got %d", n)
+	}
+}
+
+func TestMassExitSkipsSpentCoins(t *testing.T) {
+	i := newInstance(t, 3)
+	defer i.Close()
+
+	user1, user2 := i.accounts[1], i.accounts[2]
+	wallets := i.wallets(t, []*account.PlasmaTransactOpts{user1}, 2)
+	history := wallets[0].History
+
+	uids, err := history.UIDs()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// user1 gives the first coin to user2
+	unsignedTx, err := transaction.NewTransaction(
+		new(big.Int).SetUint64(i.blocks), uids[0], one, big.NewInt(2),
+		user2.From)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tx, err := user1.PlasmaSigner(user1.From, unsignedTx,
+		transaction.LegacyDomain)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf := bytes.NewBuffer(nil)
+	if err := tx.EncodeRLP(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	err = history.Add(uids[0], watcher.CoinRecord{Tx: buf.Bytes(),
+		Block: i.blocks + 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := i.massExit(t, wallets, testConfig())
+	added, err := m.Prepare()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := m.Results()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if added != 1 || results[0].UID.Cmp(uids[1]) != 0 {
+		t.Fatal("spent coin is exited")
+	}
+}
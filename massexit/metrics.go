@@ -0,0 +1,23 @@
+package massexit
+
+import (
+	"github.com/SmartMeshFoundation/SmartPlasma/metrics"
+)
+
+// Mass exit metrics.
+var (
+	transactions = metrics.DefaultRegistry.NewCounter(
+		"smartplasma_massexit_transactions_total",
+		"Number of exit and withdrawal transactions sent by the mass exit.",
+		"kind", "result")
+	gasPriceEscalations = metrics.DefaultRegistry.NewCounter(
+		"smartplasma_massexit_gas_price_escalations_total",
+		"Number of gas price escalations of the mass exit.")
+)
+
+func resultLabel(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "ok"
+}
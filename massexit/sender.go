@@ -0,0 +1,206 @@
+package massexit
+
+import (
+	"context"
+	"math/big"
+	"strings"
+
+	"github.com/SmartMeshFoundation/Spectrum/accounts/abi/bind"
+	"github.com/SmartMeshFoundation/Spectrum/common"
+	"github.com/SmartMeshFoundation/Spectrum/core/types"
+
+	"github.com/SmartMeshFoundation/SmartPlasma/blockchan/backend"
+	"github.com/SmartMeshFoundation/SmartPlasma/watcher"
+)
+
+// job is a transaction of an exit sent by the mass exit.
+type job struct {
+	uid  *big.Int
+	from common.Address
+
+	// send sends the transaction with the options.
+	send func(opts *bind.TransactOpts) (*types.Transaction, error)
+	// done updates the exit with the result of the job.
+	done func(j *job)
+
+	nonce    uint64
+	gasPrice *big.Int
+	retries  int
+	// txs are transactions sent with the nonce, a transaction
+	// is replaced by the next one with a higher gas price.
+	txs   []*types.Transaction
+	mined common.Hash
+	err   error
+}
+
+// batch sends transactions of the jobs and waits for them to be mined.
+// Nonces of wallets are read from the backend once per batch
+// and are assigned to transactions in order, a transaction
+// which failed to be sent does not take a nonce.
+func (m *MassExit) batch(ctx context.Context, jobs []*job) {
+	nonces := make(map[common.Address]uint64)
+	for _, j := range jobs {
+		nonce, ok := nonces[j.from]
+		if !ok {
+			var err error
+			nonce, err = m.backend.Connect().PendingNonceAt(ctx, j.from)
+			if err != nil {
+				j.err = err
+				continue
+			}
+		}
+
+		j.nonce = nonce
+		if j.err = m.send(ctx, j); j.err != nil {
+			nonces[j.from] = nonce
+			continue
+		}
+		nonces[j.from] = nonce + 1
+	}
+
+	for _, j := range jobs {
+		if j.err == nil {
+			j.err = m.wait(ctx, j)
+		}
+	}
+}
+
+// send sends the transaction of the job with its nonce. Underpriced
+// transactions are sent again with an escalated gas price.
+func (m *MassExit) send(ctx context.Context, j *job) error {
+	for {
+		price, err := m.price(ctx)
+		if err != nil {
+			return err
+		}
+
+		opts := m.wallets[j.from].rootChain.TransactOpts
+		opts.Nonce = new(big.Int).SetUint64(j.nonce)
+		opts.GasPrice = price
+		opts.GasLimit = m.cfg.GasLimit
+		opts.Context = ctx
+
+		tx, err := j.send(&opts)
+		if err == nil {
+			j.txs = append(j.txs, tx)
+			j.gasPrice = price
+			return nil
+		}
+
+		if !underpriced(err) || j.retries >= m.cfg.Retries {
+			return err
+		}
+		j.retries++
+
+		if err := m.escalate(price); err != nil {
+			return err
+		}
+	}
+}
+
+// wait waits until a transaction of the job is mined. A transaction
+// which is not mined in time is replaced by the transaction with
+// the same nonce and an escalated gas price.
+func (m *MassExit) wait(ctx context.Context, j *job) error {
+	for {
+		last := j.txs[len(j.txs)-1]
+
+		waitCtx, cancel := context.WithTimeout(ctx, m.cfg.Timeout)
+		tr, err := m.backend.Mine(waitCtx, last)
+		cancel()
+
+		if err != nil && ctx.Err() == nil && waitCtx.Err() != nil {
+			// a replaced transaction may be mined instead of the last one
+			last, tr, err = m.replaced(ctx, j)
+			if err == nil && tr == nil {
+				if j.retries >= m.cfg.Retries {
+					return ErrNotMined
+				}
+				j.retries++
+
+				if err := m.escalate(j.gasPrice); err != nil {
+					return err
+				}
+
+				if err := m.send(ctx, j); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if tr.Status != types.ReceiptStatusSuccessful {
+			return watcher.ErrTransactionFailed
+		}
+		j.mined = last.Hash()
+		return nil
+	}
+}
+
+// replaced returns a mined transaction of the job with its receipt
+// or nil if the transactions are not mined.
+func (m *MassExit) replaced(ctx context.Context,
+	j *job) (*types.Transaction, *types.Receipt, error) {
+	reader, ok := m.backend.Connect().(bind.DeployBackend)
+	if !ok {
+		return nil, nil, backend.ErrInvalidBackend
+	}
+
+	for _, tx := range j.txs {
+		tr, err := reader.TransactionReceipt(ctx, tx.Hash())
+		if err == nil && tr != nil {
+			return tx, tr, nil
+		}
+	}
+	return nil, nil, nil
+}
+
+// price returns the current gas price, the initial price
+// is suggested by the backend.
+func (m *MassExit) price(ctx context.Context) (*big.Int, error) {
+	if m.gasPrice == nil {
+		price, err := m.backend.Connect().SuggestGasPrice(ctx)
+		if err != nil {
+			return nil, err
+		}
+		m.gasPrice = price
+	}
+	return new(big.Int).Set(m.gasPrice), nil
+}
+
+// escalate raises the current gas price if it is not raised
+// above the price yet.
+func (m *MassExit) escalate(price *big.Int) error {
+	if m.gasPrice.Cmp(price) > 0 {
+		return nil
+	}
+
+	next := new(big.Int).Mul(price, new(big.Int).SetUint64(
+		100+m.cfg.Escalation))
+	next.Div(next, big.NewInt(100))
+	if next.Cmp(price) <= 0 {
+		next.Add(price, big.NewInt(1))
+	}
+
+	if m.cfg.MaxGasPrice != nil && next.Cmp(m.cfg.MaxGasPrice) > 0 {
+		if price.Cmp(m.cfg.MaxGasPrice) >= 0 {
+			return ErrGasPriceLimit
+		}
+		next.Set(m.cfg.MaxGasPrice)
+	}
+
+	gasPriceEscalations.Inc()
+	m.logger.Info("Gas price escalated", "from", price, "to", next)
+	m.gasPrice = next
+	return nil
+}
+
+// underpriced returns true if the transaction is rejected
+// because of its gas price.
+func underpriced(err error) bool {
+	return strings.Contains(err.Error(), "underpriced")
+}
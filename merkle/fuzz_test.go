@@ -0,0 +1,35 @@
+//go:build go1.18
+// +build go1.18
+
+package merkle
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/SmartMeshFoundation/Spectrum/common"
+)
+
+// FuzzCheckMembership checks that malformed proofs are rejected
+// without a panic.
+func FuzzCheckMembership(f *testing.F) {
+	tree := testTree(f, map[string]common.Hash{
+		uid1.String(): dummyVal,
+	}, depth257)
+	f.Add(uid1.Bytes(), dummyVal.Bytes(), CreateProof(uid1, depth257,
+		tree.GetStructure(), tree.DefaultNodes), false)
+	f.Add([]byte{}, []byte{}, []byte{}, true)
+
+	f.Fuzz(func(t *testing.T, uid, leaf, proof []byte, negative bool) {
+		index := new(big.Int).SetBytes(uid)
+		if negative {
+			index.Neg(index)
+		}
+
+		ok := CheckMembership(index, common.BytesToHash(leaf), tree.Root(),
+			proof)
+		if ok && negative && index.Sign() != 0 {
+			t.Fatal("negative uid is member of the tree")
+		}
+	})
+}
@@ -1,3 +1,15 @@
+// Package merkle implements the sparse Merkle tree of Plasma Cash blocks.
+//
+// Indexes of every level of the tree are hashed in numeric order. Trees
+// were built in lexical order of decimal strings before, so a pair
+// of siblings was hashed without the left sibling if an index of another
+// length was ordered between them. Blocks published before keep their
+// roots in RootChain contract: the order is saved with every block,
+// and a block saved without the order is rebuilt with NewLegacyTree,
+// so proofs of its coins match its published root. Blocks and
+// checkpoints received from the operator are matched against their
+// published roots in both orders. Coins of broken pairs in legacy blocks
+// stay without valid proofs.
 package merkle
 
 import (
@@ -30,8 +42,25 @@ type Tree struct {
 	DefaultNodes map[string]common.Hash
 }
 
-// NewTree creates new Merkle tree.
+// NewTree creates new Merkle tree. Indexes of every level
+// are hashed in numeric order.
 func NewTree(leaves map[string]common.Hash, depth *big.Int) (*Tree, error) {
+	return newTree(leaves, depth, sortKeys)
+}
+
+// NewLegacyTree creates Merkle tree which indexes of every level are
+// hashed in lexical order of their decimal strings, as trees were built
+// before. Such a tree loses the left sibling of a pair if an index of
+// another length is between them, for example "100" between "10"
+// and "11", so its root differs from the root of NewTree. It is used
+// only to rebuild blocks published with the lexical order.
+func NewLegacyTree(leaves map[string]common.Hash,
+	depth *big.Int) (*Tree, error) {
+	return newTree(leaves, depth, sortLegacyKeys)
+}
+
+func newTree(leaves map[string]common.Hash, depth *big.Int,
+	sortFn func(map[string]common.Hash) []string) (*Tree, error) {
 	length := new(big.Int).SetInt64(int64(len(leaves)))
 	capacity := new(big.Int).Sub(new(big.Int).Exp(big.NewInt(2),
 		depth, nil), big.NewInt(1))
@@ -41,13 +70,21 @@ func NewTree(leaves map[string]common.Hash, depth *big.Int) (*Tree, error) {
 			" leaves", depth, len(leaves))
 	}
 
+	limit := new(big.Int).Exp(two, new(big.Int).Sub(depth, one), nil)
+	for key := range leaves {
+		index, ok := new(big.Int).SetString(key, 10)
+		if !ok || index.Sign() < 0 || index.Cmp(limit) >= 0 {
+			return nil, errors.Errorf("invalid leaf index %q", key)
+		}
+	}
+
 	tree := &Tree{depth: depth}
 
 	defaultNodes := createDefaultNodes(depth)
 	tree.DefaultNodes = defaultNodes
 
 	if leaves != nil {
-		tree.tree = create(leaves, depth, defaultNodes)
+		tree.tree = create(leaves, depth, defaultNodes, sortFn)
 		tree.root = tree.tree[len(tree.tree)-1]["0"]
 	} else {
 		tree.tree = []map[string]common.Hash{}
@@ -67,7 +104,8 @@ func (tr *Tree) GetStructure() []map[string]common.Hash {
 }
 
 func create(leaves map[string]common.Hash, depth *big.Int,
-	defaultNodes map[string]common.Hash) []map[string]common.Hash {
+	defaultNodes map[string]common.Hash,
+	sortFn func(map[string]common.Hash) []string) []map[string]common.Hash {
 	tree := []map[string]common.Hash{leaves}
 	treeLevel := leaves
 
@@ -75,7 +113,7 @@ func create(leaves map[string]common.Hash, depth *big.Int,
 		nextLevel := make(map[string]common.Hash)
 		prevIndex := big.NewInt(-1)
 
-		keys := sortKeys(treeLevel)
+		keys := sortFn(treeLevel)
 
 		for _, strUID := range keys {
 			index, _ := new(big.Int).SetString(strUID, 10)
@@ -120,6 +158,9 @@ func createDefaultNodes(depth *big.Int) map[string]common.Hash {
 // CreateProof creates merkle proof for particular uid.
 func CreateProof(uid, depth *big.Int, tree []map[string]common.Hash,
 	defaultNodes map[string]common.Hash) []byte {
+	if uid == nil || uid.Sign() < 0 {
+		return nil
+	}
 	index := new(big.Int).Set(uid)
 	var proof []byte
 
@@ -152,6 +193,10 @@ func CheckMembership(uid *big.Int, leaf, rootHash common.Hash,
 		return false
 	}
 
+	if uid == nil || uid.Sign() < 0 {
+		return false
+	}
+
 	index := new(big.Int).Set(uid)
 
 	computedHash := leaf.Bytes()
@@ -169,15 +214,30 @@ func CheckMembership(uid *big.Int, leaf, rootHash common.Hash,
 	return bytes.Equal(computedHash, rootHash.Bytes())
 }
 
+// sortKeys returns decimal indexes of the level in numeric order,
+// a shorter index is less than a longer one.
 func sortKeys(dict map[string]common.Hash) []string {
 	var keys []string
 
 	for k := range dict {
 		keys = append(keys, k)
 	}
-	sort.Strings(keys)
-	if !sort.StringsAreSorted(keys) {
-		panic("slice are not sorted")
+	sort.Slice(keys, func(i, j int) bool {
+		if len(keys[i]) != len(keys[j]) {
+			return len(keys[i]) < len(keys[j])
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+// sortLegacyKeys returns decimal indexes of the level in lexical order.
+func sortLegacyKeys(dict map[string]common.Hash) []string {
+	var keys []string
+
+	for k := range dict {
+		keys = append(keys, k)
 	}
+	sort.Strings(keys)
 	return keys
 }
@@ -25,7 +25,7 @@ var (
 	depth257 = big.NewInt(257)
 )
 
-func testTree(t *testing.T, leaves map[string]common.Hash,
+func testTree(t testing.TB, leaves map[string]common.Hash,
 	depth *big.Int) *Tree {
 	tree, err := NewTree(leaves, depth)
 	if err != nil {
@@ -168,3 +168,73 @@ func TestLimit(t *testing.T) {
 	}
 
 }
+
+func TestMembershipOfIndexesWithDifferentLengths(t *testing.T) {
+	// as strings "100" is ordered between siblings "10" and "11"
+	uids := []*big.Int{big.NewInt(8), big.NewInt(9), big.NewInt(10),
+		big.NewInt(11), big.NewInt(100)}
+
+	leaves := make(map[string]common.Hash)
+	for _, uid := range uids {
+		leaves[uid.String()] = dummyVal
+	}
+
+	tree := testTree(t, leaves, depth257)
+
+	for _, uid := range uids {
+		proof := CreateProof(uid, depth257, tree.GetStructure(),
+			tree.DefaultNodes)
+
+		if !CheckMembership(uid, dummyVal, tree.root, proof) {
+			t.Fatalf("membership of %s is not confirmed", uid)
+		}
+	}
+}
+
+func TestLegacyTree(t *testing.T) {
+	leaves := map[string]common.Hash{"8": dummyVal, "9": dummyVal}
+
+	legacy, err := NewLegacyTree(leaves, depth257)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// indexes of one length are ordered the same way
+	if legacy.Root() != testTree(t, leaves, depth257).Root() {
+		t.Fatal("roots of indexes of one length are different")
+	}
+
+	otherVal := common.BytesToHash(genHash("\x02"))
+	leaves = map[string]common.Hash{"10": dummyVal, "11": otherVal,
+		"100": dummyVal}
+
+	legacy, err = NewLegacyTree(leaves, depth257)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if legacy.Root() == testTree(t, leaves, depth257).Root() {
+		t.Fatal("legacy root is not changed")
+	}
+
+	// the pair of "10" and "11" is hashed without the left sibling,
+	// so proofs of both are not confirmed by the legacy root
+	cases := []struct {
+		uid    *big.Int
+		leaf   common.Hash
+		member bool
+	}{
+		{big.NewInt(10), dummyVal, false},
+		{big.NewInt(11), otherVal, false},
+		{big.NewInt(100), dummyVal, true},
+	}
+
+	for _, c := range cases {
+		proof := CreateProof(c.uid, depth257, legacy.GetStructure(),
+			legacy.DefaultNodes)
+
+		if CheckMembership(c.uid, c.leaf, legacy.Root(), proof) != c.member {
+			t.Fatalf("wrong membership of %s in legacy tree", c.uid)
+		}
+	}
+}
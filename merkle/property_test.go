@@ -0,0 +1,55 @@
+package merkle
+
+import (
+	"math/big"
+	"testing"
+	"testing/quick"
+
+	"github.com/SmartMeshFoundation/Spectrum/common"
+)
+
+// TestProofProperty checks that proofs of any set of uids
+// are verified against the root of the tree.
+func TestProofProperty(t *testing.T) {
+	property := func(uids []uint64, hi uint64) bool {
+		leaves := make(map[string]common.Hash)
+		for _, uid := range uids {
+			index := new(big.Int).SetUint64(uid)
+			index.Add(index, new(big.Int).Lsh(
+				new(big.Int).SetUint64(hi), 192))
+			leaves[index.String()] = common.BigToHash(index)
+		}
+
+		tree, err := NewTree(leaves, depth257)
+		if err != nil {
+			return false
+		}
+
+		for key, leaf := range leaves {
+			index, _ := new(big.Int).SetString(key, 10)
+			proof := CreateProof(index, depth257, tree.GetStructure(),
+				tree.DefaultNodes)
+			if !CheckMembership(index, leaf, tree.Root(), proof) {
+				return false
+			}
+
+			if CheckMembership(index, dummyVal, tree.Root(), proof) {
+				return false
+			}
+		}
+		return true
+	}
+
+	if err := quick.Check(property, &quick.Config{MaxCount: 20}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewTreeInvalidLeaves(t *testing.T) {
+	for _, key := range []string{"-1", "uid", "4"} {
+		if _, err := NewTree(map[string]common.Hash{
+			key: dummyVal}, depth3); err == nil {
+			t.Fatalf("leaf %s must be rejected", key)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package metrics
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Metric types.
+const (
+	CounterType   = "counter"
+	GaugeType     = "gauge"
+	HistogramType = "histogram"
+)
+
+// Errors.
+var (
+	ErrLabelCount = errors.New("wrong number of label values")
+)
+
+// DefBuckets are the default histogram buckets for latencies in seconds.
+var DefBuckets = []float64{
+	.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// ExponentialBuckets creates `count` buckets, where the lowest bucket
+// has an upper bound of `start` and each following bucket's upper bound
+// is `factor` times the previous bucket's upper bound.
+func ExponentialBuckets(start, factor float64, count int) []float64 {
+	buckets := make([]float64, count)
+	for i := range buckets {
+		buckets[i] = start
+		start *= factor
+	}
+	return buckets
+}
+
+// family is a set of series of one metric distinguished by label values.
+type family struct {
+	name    string
+	help    string
+	kind    string
+	labels  []string
+	buckets []float64
+
+	mtx    sync.Mutex
+	series map[string]*series
+}
+
+type series struct {
+	labels []string
+	value  float64
+	counts []uint64
+	count  uint64
+}
+
+func newFamily(name, help, kind string,
+	buckets []float64, labels []string) *family {
+	return &family{
+		name:    name,
+		help:    help,
+		kind:    kind,
+		labels:  labels,
+		buckets: buckets,
+		series:  make(map[string]*series),
+	}
+}
+
+// get returns the series for the label values, the family mutex
+// must be held.
+func (f *family) get(values []string) *series {
+	if len(values) != len(f.labels) {
+		panic(errors.Wrapf(ErrLabelCount, "metric %s", f.name))
+	}
+
+	key := strings.Join(values, "\xff")
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{
+			labels: append([]string(nil), values...),
+			counts: make([]uint64, len(f.buckets)),
+		}
+		f.series[key] = s
+	}
+	return s
+}
+
+// snapshot returns a sorted copy of all series.
+func (f *family) snapshot() []series {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	result := make([]series, 0, len(f.series))
+	for _, s := range f.series {
+		cpy := *s
+		cpy.counts = append([]uint64(nil), s.counts...)
+		result = append(result, cpy)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return strings.Join(result[i].labels, "\xff") <
+			strings.Join(result[j].labels, "\xff")
+	})
+	return result
+}
+
+// Counter is a monotonically increasing metric.
+type Counter struct {
+	*family
+}
+
+// Inc increments the counter by 1.
+func (c *Counter) Inc(labels ...string) {
+	c.Add(1, labels...)
+}
+
+// Add adds the given value to the counter. Negative values are ignored.
+func (c *Counter) Add(val float64, labels ...string) {
+	if val < 0 {
+		return
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.get(labels).value += val
+}
+
+// Gauge is a metric that can arbitrarily go up and down.
+type Gauge struct {
+	*family
+}
+
+// Set sets the gauge to the given value.
+func (g *Gauge) Set(val float64, labels ...string) {
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+
+	g.get(labels).value = val
+}
+
+// Add adds the given value to the gauge.
+func (g *Gauge) Add(val float64, labels ...string) {
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+
+	g.get(labels).value += val
+}
+
+// Histogram counts observations in configurable buckets.
+type Histogram struct {
+	*family
+}
+
+// Observe adds a single observation to the histogram.
+func (h *Histogram) Observe(val float64, labels ...string) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	s := h.get(labels)
+	for i, upper := range h.buckets {
+		if val <= upper {
+			s.counts[i]++
+		}
+	}
+	s.count++
+	s.value += val
+}
+
+// Since observes the time elapsed since `start` in seconds.
+func (h *Histogram) Since(start time.Time, labels ...string) {
+	h.Observe(time.Since(start).Seconds(), labels...)
+}
+
+func normalizeBuckets(buckets []float64) []float64 {
+	if len(buckets) == 0 {
+		buckets = DefBuckets
+	}
+
+	result := make([]float64, 0, len(buckets))
+	for _, b := range buckets {
+		if !math.IsInf(b, 1) {
+			result = append(result, b)
+		}
+	}
+	sort.Float64s(result)
+	return result
+}
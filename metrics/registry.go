@@ -0,0 +1,195 @@
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// ContentType is the content type of the Prometheus text format.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefaultRegistry is the registry used by the SmartPlasma packages.
+var DefaultRegistry = NewRegistry()
+
+// Registry holds a set of metrics.
+type Registry struct {
+	mtx      sync.Mutex
+	families map[string]*family
+}
+
+// NewRegistry creates new metrics registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		families: make(map[string]*family),
+	}
+}
+
+func (r *Registry) register(name, help, kind string,
+	buckets []float64, labels []string) *family {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if f, ok := r.families[name]; ok {
+		if f.kind != kind || len(f.labels) != len(labels) {
+			panic(errors.Errorf("metric %s is already"+
+				" registered with a different type", name))
+		}
+		return f
+	}
+
+	f := newFamily(name, help, kind, buckets, labels)
+	r.families[name] = f
+	return f
+}
+
+// NewCounter registers a counter or returns the already registered one.
+func (r *Registry) NewCounter(name, help string,
+	labels ...string) *Counter {
+	return &Counter{r.register(name, help, CounterType, nil, labels)}
+}
+
+// NewGauge registers a gauge or returns the already registered one.
+func (r *Registry) NewGauge(name, help string, labels ...string) *Gauge {
+	return &Gauge{r.register(name, help, GaugeType, nil, labels)}
+}
+
+// NewHistogram registers a histogram or returns the already registered one.
+// If `buckets` is empty, DefBuckets are used.
+func (r *Registry) NewHistogram(name, help string, buckets []float64,
+	labels ...string) *Histogram {
+	return &Histogram{r.register(name, help, HistogramType,
+		normalizeBuckets(buckets), labels)}
+}
+
+// WriteTo writes all metrics in the Prometheus text format.
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mtx.Lock()
+	names := make([]string, 0, len(r.families))
+	for name := range r.families {
+		names = append(names, name)
+	}
+	r.mtx.Unlock()
+
+	sort.Strings(names)
+
+	cw := &countWriter{w: bufio.NewWriter(w)}
+
+	for _, name := range names {
+		r.mtx.Lock()
+		f := r.families[name]
+		r.mtx.Unlock()
+
+		writeFamily(cw, f)
+	}
+
+	if cw.err != nil {
+		return cw.n, cw.err
+	}
+	return cw.n, cw.w.Flush()
+}
+
+// Handler returns HTTP handler which exposes metrics
+// in the Prometheus text format.
+func Handler(r *Registry) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", ContentType)
+		r.WriteTo(w)
+	})
+}
+
+func writeFamily(w *countWriter, f *family) {
+	all := f.snapshot()
+	if len(all) == 0 {
+		return
+	}
+
+	w.write("# HELP ", f.name, " ", escapeHelp(f.help), "\n")
+	w.write("# TYPE ", f.name, " ", f.kind, "\n")
+
+	for _, s := range all {
+		labels := formatLabels(f.labels, s.labels, "", "")
+
+		if f.kind != HistogramType {
+			w.write(f.name, labels, " ", formatValue(s.value), "\n")
+			continue
+		}
+
+		for i, upper := range f.buckets {
+			w.write(f.name, "_bucket",
+				formatLabels(f.labels, s.labels, "le", formatValue(upper)),
+				" ", strconv.FormatUint(s.counts[i], 10), "\n")
+		}
+		w.write(f.name, "_bucket",
+			formatLabels(f.labels, s.labels, "le", "+Inf"),
+			" ", strconv.FormatUint(s.count, 10), "\n")
+		w.write(f.name, "_sum", labels, " ", formatValue(s.value), "\n")
+		w.write(f.name, "_count", labels, " ",
+			strconv.FormatUint(s.count, 10), "\n")
+	}
+}
+
+func formatLabels(names, values []string, extraName,
+	extraValue string) string {
+	if len(names) == 0 && extraName == "" {
+		return ""
+	}
+
+	pairs := make([]string, 0, len(names)+1)
+	for i, name := range names {
+		pairs = append(pairs, name+`="`+escapeLabel(values[i])+`"`)
+	}
+	if extraName != "" {
+		pairs = append(pairs, extraName+`="`+extraValue+`"`)
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatValue(val float64) string {
+	switch {
+	case math.IsInf(val, 1):
+		return "+Inf"
+	case math.IsInf(val, -1):
+		return "-Inf"
+	case math.IsNaN(val):
+		return "NaN"
+	}
+	return strconv.FormatFloat(val, 'g', -1, 64)
+}
+
+var (
+	helpReplacer  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string {
+	return helpReplacer.Replace(s)
+}
+
+func escapeLabel(s string) string {
+	return labelReplacer.Replace(s)
+}
+
+type countWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (cw *countWriter) write(parts ...string) {
+	for _, p := range parts {
+		if cw.err != nil {
+			return
+		}
+		n, err := cw.w.WriteString(p)
+		cw.n += int64(n)
+		cw.err = err
+	}
+}
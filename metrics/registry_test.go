@@ -0,0 +1,138 @@
+package metrics
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func testOutput(t *testing.T, r *Registry) string {
+	buf := bytes.NewBuffer(nil)
+
+	if _, err := r.WriteTo(buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func mustContain(t *testing.T, output string, lines ...string) {
+	for _, line := range lines {
+		if !strings.Contains(output, line+"\n") {
+			t.Fatalf("line %q not found in output:\n%s", line, output)
+		}
+	}
+}
+
+func TestCounter(t *testing.T) {
+	r := NewRegistry()
+
+	c := r.NewCounter("test_requests_total", "Number of requests.",
+		"method", "code")
+	c.Inc("Build", "ok")
+	c.Inc("Build", "ok")
+	c.Add(3, "Save", "error")
+	c.Add(-1, "Save", "error")
+
+	mustContain(t, testOutput(t, r),
+		"# HELP test_requests_total Number of requests.",
+		"# TYPE test_requests_total counter",
+		`test_requests_total{method="Build",code="ok"} 2`,
+		`test_requests_total{method="Save",code="error"} 3`)
+}
+
+func TestGauge(t *testing.T) {
+	r := NewRegistry()
+
+	g := r.NewGauge("test_depth", "Depth.")
+	g.Set(10)
+	g.Add(-3)
+
+	mustContain(t, testOutput(t, r),
+		"# TYPE test_depth gauge",
+		"test_depth 7")
+}
+
+func TestHistogram(t *testing.T) {
+	r := NewRegistry()
+
+	h := r.NewHistogram("test_size", "Size.", []float64{1, 10, 100})
+	h.Observe(0.5)
+	h.Observe(5)
+	h.Observe(50)
+	h.Observe(500)
+
+	mustContain(t, testOutput(t, r),
+		"# TYPE test_size histogram",
+		`test_size_bucket{le="1"} 1`,
+		`test_size_bucket{le="10"} 2`,
+		`test_size_bucket{le="100"} 3`,
+		`test_size_bucket{le="+Inf"} 4`,
+		"test_size_sum 555.5",
+		"test_size_count 4")
+}
+
+func TestLabelEscaping(t *testing.T) {
+	r := NewRegistry()
+
+	c := r.NewCounter("test_escape", "Help with \\ and\nnewline.", "val")
+	c.Inc("a\"b\\c\nd")
+
+	mustContain(t, testOutput(t, r),
+		`# HELP test_escape Help with \\ and\nnewline.`,
+		`test_escape{val="a\"b\\c\nd"} 1`)
+}
+
+func TestRegisterTwice(t *testing.T) {
+	r := NewRegistry()
+
+	r.NewCounter("test_total", "Total.").Inc()
+	r.NewCounter("test_total", "Total.").Inc()
+
+	mustContain(t, testOutput(t, r), "test_total 2")
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected panic")
+		}
+	}()
+	r.NewGauge("test_total", "Total.")
+}
+
+func TestWrongLabels(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounter("test_total", "Total.", "method")
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected panic")
+		}
+	}()
+	c.Inc()
+}
+
+func TestHandler(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounter("test_total", "Total.").Inc()
+
+	srv := httptest.NewServer(Handler(r))
+	defer srv.Close()
+
+	resp, err := srv.Client().Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.Header.Get("Content-Type") != ContentType {
+		t.Fatal("wrong content type")
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mustContain(t, string(body), "test_total 1")
+}
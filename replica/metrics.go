@@ -0,0 +1,19 @@
+package replica
+
+import (
+	"github.com/SmartMeshFoundation/SmartPlasma/metrics"
+)
+
+// Replica metrics.
+var (
+	blocksMirrored = metrics.DefaultRegistry.NewCounter(
+		"smartplasma_replica_blocks_mirrored_total",
+		"Number of blocks pulled from the primary by the replica.", "result")
+	checkpointsMirrored = metrics.DefaultRegistry.NewCounter(
+		"smartplasma_replica_checkpoints_mirrored_total",
+		"Number of checkpoints pulled from the primary by the replica.",
+		"result")
+	lastBlock = metrics.DefaultRegistry.NewGauge(
+		"smartplasma_replica_last_block",
+		"Number of the last block mirrored by the replica.")
+)
@@ -0,0 +1,391 @@
+// Package replica implements a replica of the operator. The replica
+// follows blocks and checkpoints published in RootChain contract,
+// pulls them from the primary operator, verifies them against their
+// roots in the contract and stores them in its own databases, so
+// the read-only RPC methods of the replica are a fallback data source
+// for clients. The replica saves blocks with a service over its own
+// databases, which is served by a read-only server:
+//
+//	svc := service.NewService(session, server, replicaBlocks,
+//		replicaCheckpoints, rootChainWrapper, mediatorWrapper, false)
+//	r := replica.New(server, rootChainAddr, primary, svc, replicaDB)
+//	go r.Run(ctx)
+//
+//	srv := transport.NewServer(timeout, port, svc)
+//	srv.SetReadOnly(true)
+//	go srv.ListenAndServe()
+//
+// Blocks are numbered in the order of events, checkpoints are recorded
+// in the checkpoint registry as covering the last block published before
+// them. Opt-ins of owners are not mirrored. A block which the primary
+// does not serve yet stops the poll and is pulled again by the next one,
+// a block which does not match its root is never saved. The position
+// of the replica is saved to its database, so a restarted replica resumes
+// from it.
+package replica
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/SmartMeshFoundation/Spectrum/common"
+	"github.com/SmartMeshFoundation/Spectrum/core/types"
+	"github.com/SmartMeshFoundation/Spectrum/log"
+	"github.com/SmartMeshFoundation/Spectrum/rlp"
+	"github.com/pkg/errors"
+
+	"github.com/SmartMeshFoundation/SmartPlasma/blockchan/backend"
+	"github.com/SmartMeshFoundation/SmartPlasma/blockchan/block/checkpoints"
+	"github.com/SmartMeshFoundation/SmartPlasma/blockchan/block/transactions"
+	"github.com/SmartMeshFoundation/SmartPlasma/contract/rootchain"
+	"github.com/SmartMeshFoundation/SmartPlasma/database"
+	"github.com/SmartMeshFoundation/SmartPlasma/logger"
+	"github.com/SmartMeshFoundation/SmartPlasma/service"
+	"github.com/SmartMeshFoundation/SmartPlasma/transport"
+)
+
+// DefaultInterval is the default period between polls
+// of RootChain events.
+const DefaultInterval = 15 * time.Second
+
+// Replica errors.
+var (
+	ErrBlockUnavailable   = errors.New("primary does not serve the block")
+	ErrBlockMismatch      = errors.New("block does not match its root")
+	ErrCheckpointMismatch = errors.New("checkpoint does not match its hash")
+	ErrOutOfSync          = errors.New(
+		"replica is out of sync with RootChain contract")
+)
+
+// cursorKey is the key of the cursor in the database of the replica.
+var cursorKey = []byte("replica")
+
+// cursor is the position of the replica in RootChain events.
+type cursor struct {
+	// Next is the number of the Spectrum block
+	// the replica reads events from.
+	Next uint64
+	// Block is the number of the last Plasma block
+	// published before the Spectrum block.
+	Block uint64
+}
+
+// Replica mirrors blocks and checkpoints of the primary operator.
+// Blocks are saved to database of the service with their numbers
+// and checkpoints are recorded in its checkpoint registry, so the service
+// serves them the same way as the primary does. Opt-ins of owners are
+// not mirrored. The position of the replica is saved to the database,
+// a restarted replica resumes from it.
+type Replica struct {
+	service  *service.Service
+	backend  backend.Backend
+	contract common.Address
+	primary  *transport.Client
+	db       database.Database
+	interval time.Duration
+	logger   log.Logger
+
+	mtx    sync.Mutex
+	cursor *cursor
+}
+
+// New creates a replica of the primary operator. Blocks and checkpoints
+// are saved by the service, the service must use databases
+// of the replica and RootChain contract of the primary.
+// The database stores the position of the replica.
+func New(server backend.Backend, rootChain common.Address,
+	primary *transport.Client, svc *service.Service,
+	db database.Database) *Replica {
+	return &Replica{
+		service:  svc,
+		backend:  server,
+		contract: rootChain,
+		primary:  primary,
+		db:       db,
+		interval: DefaultInterval,
+		logger:   logger.New("replica"),
+	}
+}
+
+// SetInterval sets the period between polls of RootChain events.
+func (r *Replica) SetInterval(interval time.Duration) {
+	r.interval = interval
+}
+
+// SetStartBlock sets the number of the Spectrum block the replica
+// starts to read events from and the number of the last Plasma block
+// published before it. It overrides the saved position.
+func (r *Replica) SetStartBlock(number, published uint64) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	return r.save(&cursor{Next: number, Block: published})
+}
+
+// SetLogger sets the logger of the replica.
+func (r *Replica) SetLogger(l log.Logger) {
+	r.logger = logger.Wrap(l)
+}
+
+// LastBlock returns the number of the last Plasma block
+// mirrored by the replica.
+func (r *Replica) LastBlock() (uint64, error) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	cur, err := r.load()
+	if err != nil {
+		return 0, err
+	}
+	return cur.Block, nil
+}
+
+// Run polls RootChain events with the interval until the context
+// is done. Failed polls are logged and repeated at the next tick.
+func (r *Replica) Run(ctx context.Context) error {
+	r.logger.Info("Replica started", "interval", r.interval)
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.Poll(ctx); err != nil && ctx.Err() == nil {
+			r.logger.Error("Failed to mirror primary", "err", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			r.logger.Info("Replica stopped")
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll mirrors blocks and checkpoints published since the previous poll.
+// NewBlock events do not contain numbers of blocks, so blocks
+// are numbered in the order of events after the last block of the
+// position. Events are mirrored in order, the first failed event stops
+// the poll and events of its Spectrum block are mirrored again
+// by the next poll. It returns the error of the failed event.
+func (r *Replica) Poll(ctx context.Context) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	cur, err := r.load()
+	if err != nil {
+		return err
+	}
+
+	logs, err := rootchain.FilterEvents(ctx, r.backend, r.contract,
+		cur.Next, rootchain.NewBlockEvent, rootchain.NewCheckpointEvent)
+	if err != nil {
+		return err
+	}
+
+	if len(logs) == 0 {
+		return nil
+	}
+
+	blockTopic, err := rootchain.EventTopic(rootchain.NewBlockEvent)
+	if err != nil {
+		return err
+	}
+
+	// start is the position of the Spectrum block of the current event
+	start := *cur
+	number := cur.Block
+	for _, l := range logs {
+		if l.BlockNumber != start.Next {
+			start = cursor{Next: l.BlockNumber, Block: number}
+		}
+
+		if l.Topics[0] == blockTopic {
+			number++
+			err = r.mirrorBlock(ctx, number, l)
+		} else {
+			err = r.mirrorCheckpoint(ctx, number, l)
+		}
+
+		if err != nil {
+			if saveErr := r.save(&start); saveErr != nil {
+				return saveErr
+			}
+			return err
+		}
+	}
+
+	return r.save(&cursor{Next: logs[len(logs)-1].BlockNumber + 1,
+		Block: number})
+}
+
+// mirrorBlock pulls the block from the primary and saves it
+// if the block matches its root in RootChain contract.
+func (r *Replica) mirrorBlock(ctx context.Context, number uint64,
+	l types.Log) (err error) {
+	defer func() {
+		blocksMirrored.Inc(mirrorLabel(err))
+	}()
+
+	event, err := rootchain.ParseNewBlock(l)
+	if err != nil {
+		return err
+	}
+
+	root, err := r.service.ChildChain(ctx, new(big.Int).SetUint64(number))
+	if err != nil {
+		return err
+	}
+
+	if root != common.Hash(event.Hash) {
+		return errors.Wrapf(ErrOutOfSync, "block %d", number)
+	}
+
+	// a stored block is pulled again only if it does not match its root
+	stored, err := r.service.BlockHash(number)
+	if err != nil && err != service.ErrBlockNotFound {
+		return err
+	}
+
+	if stored == root {
+		return nil
+	}
+
+	blk, err := r.primary.GetTransactionsBlockContext(ctx, number)
+	if err == nil && blk.NumberOfTX() == 0 {
+		// the primary returns an empty block for unknown numbers
+		err = errors.New("empty block")
+	}
+
+	if err != nil {
+		return errors.Wrapf(errors.WithMessage(ErrBlockUnavailable,
+			err.Error()), "block %d", number)
+	}
+
+	// the client rebuilds the tree of the received block, a block
+	// published with the legacy order of Merkle tree levels matches
+	// its root with the legacy order
+	matched, err := transactions.MatchRoot(blk, root)
+	if err != nil {
+		return err
+	}
+
+	if matched == nil {
+		return errors.Wrapf(ErrBlockMismatch, "block %d", number)
+	}
+
+	// the block is saved with the order it matches its root with
+	if err := r.service.SaveBlockToDB(number, matched); err != nil {
+		return err
+	}
+	lastBlock.Set(float64(number))
+
+	r.logger.Info("Block mirrored", "number", number, "hash", root,
+		"txs", blk.NumberOfTX())
+	return nil
+}
+
+// mirrorCheckpoint pulls the checkpoint from the primary, saves it
+// if it matches its hash in RootChain contract and records it
+// in the checkpoint registry as covering the last published block.
+func (r *Replica) mirrorCheckpoint(ctx context.Context, covered uint64,
+	l types.Log) (err error) {
+	defer func() {
+		checkpointsMirrored.Inc(mirrorLabel(err))
+	}()
+
+	event, err := rootchain.ParseNewCheckpoint(l)
+	if err != nil {
+		return err
+	}
+	hash := common.Hash(event.Hash)
+
+	_, err = r.service.RegisteredCheckpoint(hash)
+	if err == nil {
+		return nil
+	} else if err != service.ErrCheckpointNotFound {
+		return err
+	}
+
+	chpt, err := r.primary.GetCheckpointsBlockContext(ctx, hash)
+	if err == nil && chpt.NumberOfCheckpoints() == 0 {
+		err = errors.New("empty checkpoint")
+	}
+
+	if err != nil {
+		return errors.Wrapf(errors.WithMessage(ErrBlockUnavailable,
+			err.Error()), "checkpoint %s", hash.Hex())
+	}
+
+	matched, err := checkpoints.MatchHash(chpt, hash)
+	if err != nil {
+		return err
+	}
+
+	if matched == nil {
+		return errors.Wrapf(ErrCheckpointMismatch,
+			"checkpoint %s", hash.Hex())
+	}
+
+	if err := r.service.SaveCheckpointToDB(chpt); err != nil {
+		return err
+	}
+
+	// the registry verifies that the checkpoint is published
+	_, err = r.service.RegisterCheckpoint(ctx, hash, l.TxHash, covered)
+	if err != nil {
+		return err
+	}
+
+	r.logger.Info("Checkpoint mirrored", "hash", hash, "block", covered,
+		"uids", chpt.NumberOfCheckpoints())
+	return nil
+}
+
+// load returns the position of the replica, the replica
+// starts from the first block if the position is not saved.
+func (r *Replica) load() (*cursor, error) {
+	if r.cursor != nil {
+		return r.cursor, nil
+	}
+
+	raw, err := r.db.Get(cursorKey)
+	if err != nil {
+		return nil, err
+	}
+
+	cur := new(cursor)
+	if len(raw) > 0 {
+		if err := rlp.DecodeBytes(raw, cur); err != nil {
+			return nil, err
+		}
+	}
+	r.cursor = cur
+	return cur, nil
+}
+
+func (r *Replica) save(cur *cursor) error {
+	raw, err := rlp.EncodeToBytes(cur)
+	if err != nil {
+		return err
+	}
+
+	if err := r.db.Set(cursorKey, raw); err != nil {
+		return err
+	}
+	r.cursor = cur
+	return nil
+}
+
+func mirrorLabel(err error) string {
+	switch errors.Cause(err) {
+	case nil:
+		return "ok"
+	case ErrBlockUnavailable:
+		return "unavailable"
+	case ErrBlockMismatch, ErrCheckpointMismatch:
+		return "mismatch"
+	}
+	return "error"
+}
@@ -0,0 +1,470 @@
+package replica
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"math/big"
+	"net/http/httptest"
+	"net/rpc"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/SmartMeshFoundation/Spectrum/accounts/abi/bind"
+	"github.com/SmartMeshFoundation/Spectrum/common"
+	"github.com/pkg/errors"
+
+	"github.com/SmartMeshFoundation/SmartPlasma/blockchan/account"
+	"github.com/SmartMeshFoundation/SmartPlasma/blockchan/backend"
+	"github.com/SmartMeshFoundation/SmartPlasma/blockchan/block/transactions"
+	"github.com/SmartMeshFoundation/SmartPlasma/blockchan/transaction"
+	"github.com/SmartMeshFoundation/SmartPlasma/contract/mediator"
+	"github.com/SmartMeshFoundation/SmartPlasma/contract/rootchain"
+	"github.com/SmartMeshFoundation/SmartPlasma/database/bolt"
+	"github.com/SmartMeshFoundation/SmartPlasma/service"
+	"github.com/SmartMeshFoundation/SmartPlasma/transport"
+	"github.com/SmartMeshFoundation/SmartPlasma/transport/handlers"
+)
+
+var (
+	zero = big.NewInt(0)
+	one  = big.NewInt(1)
+	two  = big.NewInt(2)
+)
+
+type instance struct {
+	backend   backend.Backend
+	accounts  []*account.PlasmaTransactOpts
+	rootChain common.Address
+	session   *rootchain.RootChainSession
+	primary   *service.Service
+	server    *httptest.Server
+	blocks    uint64
+	dir       string
+	closers   []func()
+}
+
+func newInstance(t *testing.T, numberAcc int) *instance {
+	accounts := account.GenAccounts(numberAcc)
+	owner := accounts[0]
+
+	server := backend.NewSimulatedBackend(account.Addresses(accounts))
+
+	_, med, err := mediator.Deploy(owner.TransactOpts, server)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rootChain, err := med.RootChain(&bind.CallOptsWithNumber{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	session, err := rootchain.NewRootChainSession(
+		*owner.TransactOpts, rootChain, server)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := ioutil.TempDir("", "replica")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	i := &instance{
+		backend:   server,
+		accounts:  accounts,
+		rootChain: rootChain,
+		session:   session,
+		dir:       dir,
+	}
+	i.primary, i.server = i.newService(t)
+	return i
+}
+
+// newService creates a service with its own databases
+// and serves it over RPC.
+func (i *instance) newService(
+	t *testing.T) (*service.Service, *httptest.Server) {
+	s := service.NewService(i.session, i.backend,
+		i.newDB(t, bolt.BlocksBucket), i.newDB(t, bolt.CheckpointsBucket),
+		nil, nil, false)
+	i.closers = append(i.closers, func() { s.Close() })
+
+	rpcServer := rpc.NewServer()
+	rpcServer.RegisterName("SmartPlasma", handlers.NewSmartPlasma(100, s))
+
+	server := httptest.NewServer(rpcServer)
+	i.closers = append(i.closers, server.Close)
+	return s, server
+}
+
+// newDB opens new database in the directory of the instance,
+// it is closed with the instance.
+func (i *instance) newDB(t *testing.T, bucket string) *bolt.DB {
+	dir, err := ioutil.TempDir(i.dir, bucket)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := bolt.NewDB(filepath.Join(dir, bucket), bucket, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	i.closers = append(i.closers, func() { db.Close() })
+	return db
+}
+
+// Close closes clients, servers, services and databases of the instance
+// in reverse order and removes its directory.
+func (i *instance) Close() {
+	for j := len(i.closers) - 1; j >= 0; j-- {
+		i.closers[j]()
+	}
+	os.RemoveAll(i.dir)
+}
+
+func (i *instance) client(t *testing.T,
+	server *httptest.Server) *transport.Client {
+	cli := transport.NewClient(100, i.accounts[0])
+	if err := cli.ConnectString(server.URL[7:]); err != nil {
+		t.Fatal(err)
+	}
+	i.closers = append(i.closers, func() { cli.Close() })
+	return cli
+}
+
+// replica creates a replica of the primary with its own service
+// and the database of its position.
+func (i *instance) replica(t *testing.T) (*Replica, *service.Service,
+	*httptest.Server) {
+	s, server := i.newService(t)
+	return New(i.backend, i.rootChain, i.client(t, i.server), s,
+		i.newDB(t, "replica")), s, server
+}
+
+func testTx(t *testing.T, prevBlock, uid, nonce *big.Int,
+	newOwner common.Address,
+	signer *account.PlasmaTransactOpts) *transaction.Transaction {
+	unsignedTx, err := transaction.NewTransaction(
+		prevBlock, uid, one, nonce, newOwner)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tx, err := signer.PlasmaSigner(signer.From, unsignedTx,
+		transaction.LegacyDomain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tx
+}
+
+// sendBlock builds the block with the transaction and publishes
+// its root without saving the block.
+func (i *instance) sendBlock(t *testing.T,
+	tx *transaction.Transaction) transactions.TxBlock {
+	if err := i.primary.AcceptTransaction(tx); err != nil {
+		t.Fatal(err)
+	}
+
+	hash, err := i.primary.BuildBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ethTx, err := i.primary.SendBlockHash(context.Background(), hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !i.backend.GoodTransaction(ethTx) {
+		t.Fatal("failed to publish block")
+	}
+
+	i.blocks++
+	blk := i.primary.CurrentBlock()
+	i.primary.InitBlock()
+	return blk
+}
+
+// publishTx publishes and saves the block with the transaction.
+func (i *instance) publishTx(t *testing.T, tx *transaction.Transaction) {
+	blk := i.sendBlock(t, tx)
+	if err := i.primary.SaveBlockToDB(i.blocks, blk); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// publishCheckpoint publishes and registers the checkpoint of the coin
+// with the nonce of the transaction in the block.
+func (i *instance) publishCheckpoint(t *testing.T, uid, nonce *big.Int,
+	block uint64) common.Hash {
+	if err := i.primary.AcceptUIDState(uid, nonce, block); err != nil {
+		t.Fatal(err)
+	}
+
+	hash, err := i.primary.BuildCheckpoint()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = i.primary.SaveCheckpointToDB(i.primary.CurrentCheckpoint())
+	if err != nil {
+		t.Fatal(err)
+	}
+	i.primary.InitCheckpoint()
+
+	tx, err := i.primary.SendChptHash(context.Background(), hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !i.backend.GoodTransaction(tx) {
+		t.Fatal("failed to publish checkpoint")
+	}
+
+	_, err = i.primary.RegisterCheckpoint(context.Background(), hash,
+		tx.Hash(), i.blocks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hash
+}
+
+func (i *instance) sameBlock(t *testing.T, replica *service.Service,
+	number uint64) {
+	expected, err := i.primary.RawBlockFromDB(number)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err := replica.RawBlockFromDB(number)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(raw) == 0 || !bytes.Equal(raw, expected) {
+		t.Fatalf("block %d is not mirrored", number)
+	}
+}
+
+func TestReplica(t *testing.T) {
+	i := newInstance(t, 3)
+	defer i.Close()
+
+	user1, user2 := i.accounts[1], i.accounts[2]
+	uid := big.NewInt(7)
+
+	i.publishTx(t, testTx(t, zero, uid, zero, user2.From, user1))
+	i.publishTx(t, testTx(t, one, uid, one, user1.From, user2))
+	chpt := i.publishCheckpoint(t, uid, one, 2)
+	i.publishTx(t, testTx(t, two, uid, two, user2.From, user1))
+
+	r, replica, server := i.replica(t)
+	if err := r.Poll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	for n := uint64(1); n <= i.blocks; n++ {
+		i.sameBlock(t, replica, n)
+	}
+
+	last, err := r.LastBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if last != i.blocks {
+		t.Fatalf("expected last block %d, got %d", i.blocks, last)
+	}
+
+	// the replica serves mirrored blocks and checkpoints
+	cli := i.client(t, server)
+	blk, err := cli.GetTransactionsBlock(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root, err := i.session.ChildChain(two)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if blk.Hash() != common.Hash(root) {
+		t.Fatal("replica serves wrong block")
+	}
+
+	infos, err := cli.ListCheckpoints(1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(infos) != 1 || infos[0].Hash != chpt || infos[0].Block != 2 {
+		t.Fatal("checkpoint is not mirrored")
+	}
+
+	info, err := cli.LatestCheckpointFor(uid)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Hash != chpt {
+		t.Fatal("wrong latest checkpoint")
+	}
+
+	// mirrored blocks and checkpoints are not pulled again
+	if err := r.Poll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	count, err := replica.CheckpointCount()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if count != 1 {
+		t.Fatal("checkpoint is registered again")
+	}
+}
+
+func TestReplicaUnavailableBlock(t *testing.T) {
+	i := newInstance(t, 3)
+	defer i.Close()
+
+	user1, user2 := i.accounts[1], i.accounts[2]
+	uid := big.NewInt(7)
+
+	i.publishTx(t, testTx(t, zero, uid, zero, user2.From, user1))
+
+	// the primary publishes the root before it saves the block
+	withheld := i.sendBlock(t, testTx(t, one, uid, one, user1.From, user2))
+	number := i.blocks
+	i.publishTx(t, testTx(t, two, uid, two, user2.From, user1))
+
+	s, _ := i.newService(t)
+	db := i.newDB(t, "replica")
+	r := New(i.backend, i.rootChain, i.client(t, i.server), s, db)
+
+	if err := r.Poll(context.Background()); errors.Cause(
+		err) != ErrBlockUnavailable {
+		t.Fatalf("expected error %s, got %v", ErrBlockUnavailable, err)
+	}
+
+	i.sameBlock(t, s, 1)
+
+	raw, err := s.RawBlockFromDB(i.blocks)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(raw) != 0 {
+		t.Fatal("block after the unavailable block is mirrored")
+	}
+
+	if err := i.primary.SaveBlockToDB(number, withheld); err != nil {
+		t.Fatal(err)
+	}
+
+	// a restarted replica resumes from the saved position
+	r = New(i.backend, i.rootChain, i.client(t, i.server), s, db)
+	if err := r.Poll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	for n := uint64(1); n <= i.blocks; n++ {
+		i.sameBlock(t, s, n)
+	}
+}
+
+func TestReplicaMismatch(t *testing.T) {
+	i := newInstance(t, 3)
+	defer i.Close()
+
+	user1, user2 := i.accounts[1], i.accounts[2]
+	uid := big.NewInt(7)
+
+	i.sendBlock(t, testTx(t, zero, uid, zero, user2.From, user1))
+
+	// the primary serves another block than the published one
+	blk := transactions.NewBlock()
+	err := blk.AddTx(testTx(t, zero, uid, zero, user1.From, user2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := blk.Build(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := i.primary.SaveBlockToDB(i.blocks, blk); err != nil {
+		t.Fatal(err)
+	}
+
+	r, replica, _ := i.replica(t)
+	if err := r.Poll(context.Background()); errors.Cause(
+		err) != ErrBlockMismatch {
+		t.Fatalf("expected error %s, got %v", ErrBlockMismatch, err)
+	}
+
+	raw, err := replica.RawBlockFromDB(i.blocks)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(raw) != 0 {
+		t.Fatal("inconsistent block is mirrored")
+	}
+}
+
+func TestReplicaStartBlock(t *testing.T) {
+	i := newInstance(t, 3)
+	defer i.Close()
+
+	user1, user2 := i.accounts[1], i.accounts[2]
+	uid := big.NewInt(7)
+
+	i.publishTx(t, testTx(t, zero, uid, zero, user2.From, user1))
+	i.publishTx(t, testTx(t, one, uid, one, user1.From, user2))
+
+	logs, err := rootchain.FilterEvents(context.Background(), i.backend,
+		i.rootChain, 0, rootchain.NewBlockEvent)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(logs) != 2 {
+		t.Fatal("wrong number of events")
+	}
+
+	r, replica, _ := i.replica(t)
+	if err := r.SetStartBlock(logs[1].BlockNumber, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.Poll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	i.sameBlock(t, replica, 2)
+
+	raw, err := replica.RawBlockFromDB(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(raw) != 0 {
+		t.Fatal("block before the start block is mirrored")
+	}
+
+	// a wrong number of published blocks is detected
+	if err := r.SetStartBlock(0, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.Poll(context.Background()); errors.Cause(
+		err) != ErrOutOfSync {
+		t.Fatalf("expected error %s, got %v", ErrOutOfSync, err)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/pkg/errors"
 
 	"github.com/SmartMeshFoundation/SmartPlasma/blockchan/block"
+	"github.com/SmartMeshFoundation/SmartPlasma/logger"
 )
 
 func (s *Service) mineTx(ctx context.Context, tx *types.Transaction) error {
@@ -61,7 +62,16 @@ func buildBlockFromBytes(blk block.Block, raw []byte) error {
 }
 
 func (s *Service) transact(ctx context.Context, tx *types.Transaction) error {
-	return s.backend.Connect().SendTransaction(ctx, tx)
+	l := logger.FromContext(ctx, s.logger)
+
+	err := s.backend.Connect().SendTransaction(ctx, tx)
+	if err != nil {
+		l.Warn("Failed to relay transaction", "tx", tx.Hash(),
+			"to", tx.To(), "err", err)
+		return err
+	}
+	l.Debug("Transaction relayed", "tx", tx.Hash(), "to", tx.To())
+	return nil
 }
 
 // PendingCodeAt returns the code of the given account in the pending state.
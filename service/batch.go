@@ -0,0 +1,168 @@
+package service
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/SmartMeshFoundation/Spectrum/common"
+	"github.com/pkg/errors"
+
+	"github.com/SmartMeshFoundation/SmartPlasma/blockchan/block/transactions"
+)
+
+// MaxBatchSize is the maximum number of items in one batch request.
+const MaxBatchSize = 1000
+
+// Errors.
+var (
+	ErrBatchTooLarge = errors.New("batch is too large")
+	ErrBlockNotFound = errors.New("block not found")
+)
+
+// ProofRequest is a request of a proof of a UID in a transactions block.
+type ProofRequest struct {
+	UID   *big.Int
+	Block uint64
+}
+
+// ProofResult is a proof of a UID in a transactions block
+// or the reason why the proof was not created.
+type ProofResult struct {
+	Proof []byte
+	Err   error
+}
+
+// UIDStateProof is a proof of a UID in a checkpoint block
+// and the nonce of the UID fixed by the checkpoint.
+type UIDStateProof struct {
+	Proof []byte
+	Nonce *big.Int
+}
+
+// CreateProofs creates merkle proofs for pairs of UID and block number.
+// Every block is read and rebuilt once for all its proofs.
+func (s *Service) CreateProofs(ctx context.Context,
+	reqs []ProofRequest) ([]ProofResult, error) {
+	if len(reqs) > MaxBatchSize {
+		return nil, ErrBatchTooLarge
+	}
+
+	type built struct {
+		blk transactions.TxBlock
+		err error
+	}
+	blocks := make(map[uint64]*built)
+
+	results := make([]ProofResult, len(reqs))
+	for i, req := range reqs {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		b, ok := blocks[req.Block]
+		if !ok {
+			blk, err := s.buildBlock(req.Block)
+			b = &built{blk: blk, err: err}
+			blocks[req.Block] = b
+		}
+
+		if b.err != nil {
+			results[i].Err = b.err
+			continue
+		}
+		results[i].Proof = b.blk.CreateProof(req.UID)
+	}
+
+	s.logger.Debug("Proofs created", "proofs", len(reqs),
+		"blocks", len(blocks))
+	return results, nil
+}
+
+// CreateUIDStateProofs creates merkle proofs for UIDs
+// in the checkpoint block. The checkpoint is rebuilt once.
+func (s *Service) CreateUIDStateProofs(ctx context.Context, uids []*big.Int,
+	chptHash common.Hash) ([]UIDStateProof, error) {
+	if len(uids) > MaxBatchSize {
+		return nil, ErrBatchTooLarge
+	}
+
+	raw, err := s.RawCheckpointFromDB(chptHash)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(raw) == 0 {
+		return nil, ErrBlockNotFound
+	}
+
+	blk, err := buildCheckpoint(chptHash, raw)
+	if err != nil {
+		return nil, err
+	}
+
+	proofs := make([]UIDStateProof, len(uids))
+	for i, uid := range uids {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		proofs[i] = UIDStateProof{
+			Proof: blk.CreateProof(uid),
+			Nonce: blk.GetNonce(uid),
+		}
+	}
+	return proofs, nil
+}
+
+// RawBlocksFromDB returns raw Plasma blocks with numbers
+// from `from` to `to` inclusive.
+func (s *Service) RawBlocksFromDB(ctx context.Context,
+	from, to uint64) ([][]byte, error) {
+	if from > to {
+		return nil, nil
+	}
+
+	if to-from >= MaxBatchSize {
+		return nil, ErrBatchTooLarge
+	}
+
+	blocks := make([][]byte, 0, to-from+1)
+	for number := from; number <= to; number++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		raw, err := s.RawBlockFromDB(number)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(raw) == 0 {
+			return nil, errors.Wrapf(ErrBlockNotFound, "block %d", number)
+		}
+		blocks = append(blocks, raw)
+	}
+	return blocks, nil
+}
+
+// buildBlock reads a Plasma block from database and builds it.
+func (s *Service) buildBlock(number uint64) (transactions.TxBlock, error) {
+	raw, err := s.RawBlockFromDB(number)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(raw) == 0 {
+		return nil, ErrBlockNotFound
+	}
+
+	blk, err := s.newBlock(number)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := buildBlockFromBytes(blk, raw); err != nil {
+		return nil, err
+	}
+	return blk, nil
+}
@@ -0,0 +1,160 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/SmartMeshFoundation/Spectrum/common"
+	"github.com/pkg/errors"
+)
+
+func TestCreateProofs(t *testing.T) {
+	i := newInstance(t)
+	tx := testTx(t, zero, one, two, three, owner.From, owner)
+
+	if err := i.service.AcceptTransaction(tx); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := i.service.BuildBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = i.service.SaveBlockToDB(one.Uint64(), i.service.CurrentBlock())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	proof, err := i.service.CreateProof(one, one.Uint64())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := i.service.CreateProofs(context.Background(),
+		[]ProofRequest{
+			{UID: one, Block: one.Uint64()},
+			{UID: two, Block: one.Uint64()},
+			{UID: one, Block: two.Uint64()},
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) != 3 {
+		t.Fatal("wrong number of results")
+	}
+
+	if results[0].Err != nil || !bytes.Equal(results[0].Proof, proof) {
+		t.Fatal("wrong proof")
+	}
+
+	if results[1].Err != nil || len(results[1].Proof) == 0 {
+		t.Fatal("empty proof")
+	}
+
+	if results[2].Err != ErrBlockNotFound {
+		t.Fatalf("expected %s, got %v", ErrBlockNotFound, results[2].Err)
+	}
+
+	_, err = i.service.CreateProofs(context.Background(),
+		make([]ProofRequest, MaxBatchSize+1))
+	if err != ErrBatchTooLarge {
+		t.Fatalf("expected %s, got %v", ErrBatchTooLarge, err)
+	}
+}
+
+func TestRawBlocksFromDB(t *testing.T) {
+	i := newInstance(t)
+	tx := testTx(t, zero, one, two, three, owner.From, owner)
+
+	if err := i.service.AcceptTransaction(tx); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := i.service.BuildBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = i.service.SaveBlockToDB(one.Uint64(), i.service.CurrentBlock())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	blocks, err := i.service.RawBlocksFromDB(context.Background(),
+		one.Uint64(), one.Uint64())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err := i.service.RawBlockFromDB(one.Uint64())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(blocks) != 1 || !bytes.Equal(blocks[0], raw) {
+		t.Fatal("wrong blocks")
+	}
+
+	_, err = i.service.RawBlocksFromDB(context.Background(),
+		one.Uint64(), two.Uint64())
+	if errors.Cause(err) != ErrBlockNotFound {
+		t.Fatalf("expected %s, got %v", ErrBlockNotFound, err)
+	}
+
+	_, err = i.service.RawBlocksFromDB(context.Background(),
+		0, MaxBatchSize)
+	if err != ErrBatchTooLarge {
+		t.Fatalf("expected %s, got %v", ErrBatchTooLarge, err)
+	}
+}
+
+func TestCreateUIDStateProofs(t *testing.T) {
+	i := newInstance(t)
+
+	err := i.service.currentChpt.AddCheckpoint(one, two)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = i.service.BuildCheckpoint()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chpt := i.service.CurrentCheckpoint()
+
+	err = i.service.SaveCheckpointToDB(chpt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	proofs, err := i.service.CreateUIDStateProofs(context.Background(),
+		[]*big.Int{one, three}, chpt.Hash())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(proofs) != 2 {
+		t.Fatal("wrong number of proofs")
+	}
+
+	proof, nonce, err := i.service.CreateUIDStateProof(one, chpt.Hash())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(proofs[0].Proof, proof) ||
+		proofs[0].Nonce.Cmp(nonce) != 0 {
+		t.Fatal("wrong proof")
+	}
+
+	_, err = i.service.CreateUIDStateProofs(context.Background(),
+		[]*big.Int{one}, common.Hash{})
+	if err != ErrBlockNotFound {
+		t.Fatalf("expected %s, got %v", ErrBlockNotFound, err)
+	}
+}
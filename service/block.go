@@ -5,6 +5,7 @@ import (
 	"context"
 	"math/big"
 	"strconv"
+	"time"
 
 	"github.com/SmartMeshFoundation/Spectrum/common"
 	"github.com/SmartMeshFoundation/Spectrum/core/types"
@@ -13,12 +14,99 @@ import (
 	"github.com/SmartMeshFoundation/SmartPlasma/blockchan/block/transactions"
 	"github.com/SmartMeshFoundation/SmartPlasma/blockchan/transaction"
 	"github.com/SmartMeshFoundation/SmartPlasma/contract/rootchain"
+	"github.com/SmartMeshFoundation/SmartPlasma/logger"
 	"github.com/SmartMeshFoundation/SmartPlasma/merkle"
 )
 
+// Errors of transactions and block publishing.
+var (
+	ErrBlocksNotStored  = errors.New("published blocks are not stored")
+	ErrBlockNumberTaken = errors.New("block number is taken by another block")
+	ErrSplitsDisabled   = errors.New(
+		"split and merge transactions are disabled")
+	ErrSwapsDisabled      = errors.New("swap transactions are disabled")
+	ErrDomainNotSupported = errors.New(
+		"domain is not supported by RootChain contract")
+)
+
+// orderPrefix is the prefix of keys of orders of Merkle tree levels
+// saved with blocks in the blocks database.
+var orderPrefix = []byte("order-")
+
+// Orders of Merkle tree levels of saved blocks.
+const (
+	legacyOrder  byte = 0
+	numericOrder byte = 1
+)
+
+// isSplit returns true if the transaction is a split or a merge.
+func isSplit(tx *transaction.Transaction) bool {
+	return tx.Type() == transaction.SplitType ||
+		tx.Type() == transaction.MergeType
+}
+
 // AcceptTransaction adds a transaction to current transactions block.
+// A swap signed by one owner waits for the signature of the other owner
+// until the block is published and is added to the block when it is
+// signed by both. Input coins of the swap must be owned by its owners.
+// Split and merge transactions are rejected unless they are enabled
+// by SetSplits, swaps are rejected unless they are enabled by SetSwaps.
+// Transactions wait while the current block is published, so they are
+// not added to the block which is already sent.
 func (s *Service) AcceptTransaction(tx *transaction.Transaction) error {
-	return s.currentBlock.AddTx(tx)
+	s.blockMtx.RLock()
+	defer s.blockMtx.RUnlock()
+
+	if isSplit(tx) && !s.splits {
+		acceptedTransactions.Inc(resultLabel(ErrSplitsDisabled))
+		s.logger.Debug("Transaction rejected", "uid", tx.UID(),
+			"tx", tx.Hash(), "err", ErrSplitsDisabled)
+		return ErrSplitsDisabled
+	}
+
+	if tx.Type() == transaction.SwapType && !s.swapsEnabled {
+		acceptedTransactions.Inc(resultLabel(ErrSwapsDisabled))
+		s.logger.Debug("Swap rejected", "tx", tx.Hash(),
+			"err", ErrSwapsDisabled)
+		return ErrSwapsDisabled
+	}
+
+	if tx.Type() == transaction.SwapType {
+		swap, err := s.acceptSwap(tx)
+		if err != nil {
+			acceptedTransactions.Inc(resultLabel(err))
+			s.logger.Debug("Swap rejected", "tx", tx.Hash(), "err", err)
+			return err
+		}
+
+		if swap == nil {
+			s.logger.Debug("Swap waits for signature", "tx", tx.Hash())
+			return nil
+		}
+		tx = swap
+	}
+
+	revert, err := s.fees.admit(tx, s.Domain())
+	if err != nil {
+		acceptedTransactions.Inc(resultLabel(err))
+		s.logger.Debug("Transaction rejected", "uid", tx.UID(),
+			"nonce", tx.Nonce(), "tx", tx.Hash(), "err", err)
+		return err
+	}
+
+	err = s.currentBlock.AddTx(tx)
+	acceptedTransactions.Inc(resultLabel(err))
+	mempoolDepth.Set(float64(s.currentBlock.NumberOfTX()))
+
+	if err != nil {
+		revert()
+		s.logger.Debug("Transaction rejected", "uid", tx.UID(),
+			"nonce", tx.Nonce(), "tx", tx.Hash(), "err", err)
+		return err
+	}
+	s.logger.Debug("Transaction accepted", "uid", tx.UID(),
+		"nonce", tx.Nonce(), "tx", tx.Hash())
+	return nil
 }
 
 // CreateProof creates merkle proof for particular uid.
@@ -29,7 +117,11 @@ func (s *Service) CreateProof(uid *big.Int, block uint64) ([]byte, error) {
 		return nil, err
 	}
 
-	blk := transactions.NewBlock()
+	blk, err := s.newBlock(block)
+	if err != nil {
+		return nil, err
+	}
+
 	err = buildBlockFromBytes(blk, raw)
 	if err != nil {
 		return nil, err
@@ -49,17 +141,75 @@ func (s *Service) VerifyTxProof(uid *big.Int, hash common.Hash,
 	return merkle.CheckMembership(uid, hash, root, proof), err
 }
 
+// newBlock creates the block with the number to read it from database.
+// The order of Merkle tree levels is saved with the block, blocks saved
+// without the order are saved by older versions and are built
+// with the legacy order.
+func (s *Service) newBlock(number uint64) (transactions.TxBlock, error) {
+	order, err := s.blockBase.Get(orderKey(number))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(order) == 0 || order[0] == legacyOrder {
+		return transactions.NewLegacyBlock(), nil
+	}
+	return transactions.NewBlock(), nil
+}
+
+// saveOrder saves the order of Merkle tree levels of the block.
+func (s *Service) saveOrder(number uint64, blk transactions.TxBlock) error {
+	order := numericOrder
+	if blk.Legacy() {
+		order = legacyOrder
+	}
+	return s.blockBase.Set(orderKey(number), []byte{order})
+}
+
+func orderKey(number uint64) []byte {
+	return strconv.AppendUint(append([]byte{}, orderPrefix...), number, 10)
+}
+
 // InitBlock initializes a new block.
 func (s *Service) InitBlock() {
+	s.blockMtx.Lock()
+	defer s.blockMtx.Unlock()
+	s.initBlock()
+}
+
+func (s *Service) initBlock() {
 	s.currentBlock = transactions.NewBlock()
+	s.fees.reset()
+	s.swaps.reset()
+	mempoolDepth.Set(0)
+	s.logger.Debug("New block initialized")
 }
 
 // BuildBlock build current Plasma block.
 func (s *Service) BuildBlock() (common.Hash, error) {
+	s.blockMtx.Lock()
+	defer s.blockMtx.Unlock()
+	return s.buildCurrentBlock()
+}
+
+func (s *Service) buildCurrentBlock() (common.Hash, error) {
 	if s.strongMode { // TODO: not used
-		s.ValidateBlock(context.Background()) // TODO: change to context with timeout
+		s.checkBlock(context.Background()) // TODO: change to context with timeout
+	}
+
+	start := time.Now()
+	hash, err := s.currentBlock.Build()
+	if err != nil {
+		blockBuildErrors.Inc()
+		s.logger.Error("Failed to build block", "err", err)
+		return hash, err
 	}
-	return s.currentBlock.Build()
+	blockBuildDuration.Since(start)
+	blockTransactions.Observe(float64(s.currentBlock.NumberOfTX()))
+
+	s.logger.Info("Block built", "hash", hash,
+		"txs", s.currentBlock.NumberOfTX(), "elapsed", time.Since(start))
+	return hash, nil
 }
 
 // RawBlockFromDB returns raw Plasma block from database.
@@ -70,11 +220,216 @@ func (s *Service) RawBlockFromDB(number uint64) ([]byte, error) {
 // SaveBlockToDB saves Plasma Block to database.
 func (s *Service) SaveBlockToDB(number uint64,
 	blk transactions.TxBlock) error {
+	start := time.Now()
+
 	raw, err := blk.Marshal()
 	if err != nil {
 		return err
 	}
-	return s.blockBase.Set(strconv.AppendUint(nil, number, 10), raw)
+
+	// the order is saved first, a block is never read with a wrong order
+	if err := s.saveOrder(number, blk); err != nil {
+		s.logger.Error("Failed to save block", "number", number, "err", err)
+		return err
+	}
+
+	err = s.blockBase.Set(strconv.AppendUint(nil, number, 10), raw)
+	if err != nil {
+		s.logger.Error("Failed to save block", "number", number, "err", err)
+		return err
+	}
+	blockSaveDuration.Since(start)
+	blockSaveBytes.Observe(float64(len(raw)))
+
+	fees, err := s.blockFees(blk)
+	if err != nil {
+		return err
+	}
+
+	if err := s.saveFees(number, fees); err != nil {
+		s.logger.Error("Failed to save fees", "number", number, "err", err)
+		return err
+	}
+	collected, _ := new(big.Float).SetInt(fees).Float64()
+	feesCollected.Add(collected)
+
+	s.logger.Info("Block saved", "number", number, "bytes", len(raw),
+		"fees", fees)
+	return nil
+}
+
+// BlockHash returns the root of the Plasma block stored in database.
+func (s *Service) BlockHash(number uint64) (common.Hash, error) {
+	blk, err := s.buildBlock(number)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return blk.Hash(), nil
+}
+
+// PublishBlock builds the current block, saves it with the number
+// after the last block in RootChain contract, publishes its root
+// and initializes a new block. It returns the number of the published
+// block or zero if the current block is empty.
+//
+// The block is published only if the last published block is stored
+// with the same root, so an operator which missed blocks of another
+// operator does not reuse their numbers. The block is saved before
+// its root is sent, so a published block is never lost. A block which
+// failed to be published is published again by the next call,
+// the sent transaction is waited for instead of sending another root.
+func (s *Service) PublishBlock(ctx context.Context) (uint64, error) {
+	return s.publishBlock(ctx, 0)
+}
+
+// PublishBlockAt is like PublishBlock but the root is sent only
+// if the block gets the expected number. The expected number is
+// the fencing token of an operator, an operator which lost its lock
+// without noticing it does not publish after blocks of the new leader.
+func (s *Service) PublishBlockAt(ctx context.Context,
+	expected uint64) (uint64, error) {
+	return s.publishBlock(ctx, expected)
+}
+
+// publishBlock publishes the current block, the root is sent only
+// if the block gets the expected number unless it is zero.
+func (s *Service) publishBlock(ctx context.Context,
+	expected uint64) (uint64, error) {
+	s.blockMtx.Lock()
+	defer s.blockMtx.Unlock()
+
+	if s.currentBlock.NumberOfTX() == 0 {
+		return 0, nil
+	}
+
+	l := logger.FromContext(ctx, s.logger)
+
+	last, err := s.LastBlockNumber(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	hash := s.currentBlock.Hash()
+	if !s.currentBlock.IsBuilt() {
+		if hash, err = s.buildCurrentBlock(); err != nil {
+			return 0, err
+		}
+	}
+
+	number := last.Uint64() + 1
+	if s.blockTx == nil {
+		if expected != 0 && number != expected {
+			return 0, errors.Wrapf(ErrBlockNumberTaken,
+				"block %d is expected, next block is %d", expected, number)
+		}
+
+		if err := s.checkStored(ctx, last.Uint64()); err != nil {
+			return 0, err
+		}
+
+		if err := s.SaveBlockToDB(number, s.currentBlock); err != nil {
+			return 0, err
+		}
+
+		if s.blockTx, err = s.SendBlockHash(ctx, hash); err != nil {
+			return 0, err
+		}
+	} else if root, err := s.ChildChain(ctx, last); err != nil {
+		return 0, err
+	} else if root == hash {
+		// the root sent by the previous call is already mined
+		number = last.Uint64()
+	}
+
+	tr, err := s.backend.Mine(ctx, s.blockTx)
+	if err == nil && tr.Status == types.ReceiptStatusFailed {
+		// the root is not published, it is sent again by the next call
+		s.blockTx = nil
+		err = errors.New("transaction execution failed")
+	}
+
+	if err != nil {
+		l.Error("Failed to publish block", "number", number,
+			"hash", hash, "err", err)
+		return 0, err
+	}
+	s.blockTx = nil
+
+	root, err := s.ChildChain(ctx, new(big.Int).SetUint64(number))
+	if err != nil {
+		return 0, err
+	}
+
+	if root != hash {
+		// another operator published a block with the number,
+		// the block is saved with the number it is published with
+		published, err := s.publishedNumber(ctx, hash, number+1)
+		if err != nil {
+			return 0, err
+		}
+
+		if published != 0 {
+			err = s.SaveBlockToDB(published, s.currentBlock)
+			if err != nil {
+				return 0, err
+			}
+		}
+
+		l.Error("Block number is taken", "number", number, "hash", hash,
+			"root", root, "published", published)
+		s.initBlock()
+		return published, errors.Wrapf(ErrBlockNumberTaken,
+			"block %d", number)
+	}
+
+	s.initBlock()
+	l.Info("Block published", "number", number, "hash", hash)
+	return number, nil
+}
+
+// publishedNumber returns the number of the block with the root
+// starting with the number from or zero if the root is not published.
+func (s *Service) publishedNumber(ctx context.Context, hash common.Hash,
+	from uint64) (uint64, error) {
+	last, err := s.LastBlockNumber(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	for number := from; number <= last.Uint64(); number++ {
+		root, err := s.ChildChain(ctx, new(big.Int).SetUint64(number))
+		if err != nil {
+			return 0, err
+		}
+
+		if root == hash {
+			return number, nil
+		}
+	}
+	return 0, nil
+}
+
+// checkStored returns an error if the published block
+// is not stored with its root.
+func (s *Service) checkStored(ctx context.Context, number uint64) error {
+	if number == 0 {
+		return nil
+	}
+
+	root, err := s.ChildChain(ctx, new(big.Int).SetUint64(number))
+	if err != nil {
+		return err
+	}
+
+	hash, err := s.BlockHash(number)
+	if err != nil && err != ErrBlockNotFound {
+		return err
+	}
+
+	if hash != root {
+		return errors.Wrapf(ErrBlocksNotStored, "block %d", number)
+	}
+	return nil
 }
 
 // SendBlockHash sends a Plasma block hash to the blockchain.
@@ -83,7 +438,19 @@ func (s *Service) SendBlockHash(
 	session := rootchain.CopySession(s.session)
 	session.TransactOpts.Context = ctx
 
-	return session.NewBlock(hash)
+	l := logger.FromContext(ctx, s.logger)
+
+	start := time.Now()
+	tx, err := session.NewBlock(hash)
+	blockHashSent.Inc(resultLabel(err))
+	if err != nil {
+		l.Error("Failed to send block hash", "hash", hash, "err", err)
+		return nil, err
+	}
+	blockHashSendDuration.Since(start)
+
+	l.Info("Block hash sent", "hash", hash, "tx", tx.Hash())
+	return tx, nil
 }
 
 // LastBlockNumber gets last block number from blockchain.
@@ -95,38 +462,98 @@ func (s *Service) LastBlockNumber(ctx context.Context) (*big.Int, error) {
 
 // CurrentBlock returns current Plasma block.
 func (s *Service) CurrentBlock() transactions.TxBlock {
+	s.blockMtx.RLock()
+	defer s.blockMtx.RUnlock()
 	return s.currentBlock
 }
 
 // ValidateBlock returns checked transactions.
 func (s *Service) ValidateBlock(ctx context.Context) error {
+	s.blockMtx.Lock()
+	defer s.blockMtx.Unlock()
+	return s.checkBlock(ctx)
+}
+
+func (s *Service) checkBlock(ctx context.Context) error {
+	start := time.Now()
+
+	err := s.validateBlock(ctx)
+	if err != nil {
+		blockValidateErrors.Inc()
+		logger.FromContext(ctx, s.logger).Error(
+			"Failed to validate block", "err", err)
+		return err
+	}
+	blockValidateDuration.Since(start)
+	return nil
+}
+
+func (s *Service) validateBlock(ctx context.Context) error {
+	l := logger.FromContext(ctx, s.logger)
+
+	before := s.currentBlock.NumberOfTX()
 	ch := s.currentBlock.Transactions(ctx)
 
 	txs := make(map[string]*transaction.Transaction)
 
+	reject := func(tx *transaction.Transaction, reason string) {
+		l.Debug("Transaction removed from block",
+			"uid", tx.UID(), "nonce", tx.Nonce(), "reason", reason)
+	}
+
 	lastBlock, err := s.session.BlockNumber()
 	if err != nil {
 		return err
 	}
 
 	for tx := range ch {
+		if tx.Type() != transaction.TransferType {
+			if lastBlock.Uint64() == 0 {
+				reject(tx, "coins are not published")
+				continue
+			}
+
+			reason, err := s.checkCoins(tx)
+			if err != nil {
+				return err
+			}
+
+			if reason != "" {
+				reject(tx, reason)
+				continue
+			}
+			txs[tx.UID().String()] = tx
+			continue
+		}
+
 		storedAmount, err := s.session.Wallet(common.BigToHash(tx.UID()))
 		if err != nil {
 			return err
 		}
 
 		// if storedAmount = 0 then the deposit does not exist
-		if storedAmount.Int64() == 0 {
-			continue
+		// or the coin is a sub-coin of a deposit
+		if storedAmount.Sign() == 0 {
+			exists, err := s.subCoinExists(tx, lastBlock.Uint64())
+			if err != nil {
+				return err
+			}
+
+			if !exists {
+				reject(tx, "deposit does not exist")
+				continue
+			}
 		}
 
 		if lastBlock.Uint64() == 0 {
-			sender, err := transaction.Sender(tx)
+			sender, err := transaction.Sender(tx, s.Domain())
 			if err != nil {
+				reject(tx, "invalid signature")
 				continue
 			}
 
 			if tx.NewOwner().String() != sender.String() {
+				reject(tx, "first transaction is not to the owner")
 				continue
 			}
 
@@ -139,18 +566,21 @@ func (s *Service) ValidateBlock(ctx context.Context) error {
 			return err
 		}
 
-		sender, err := transaction.Sender(tx)
+		sender, err := transaction.Sender(tx, s.Domain())
 		if err != nil {
+			reject(tx, "invalid signature")
 			continue
 		}
 
 		// if first transaction
-		if tx.Nonce().Int64() == 0 {
+		if tx.Nonce().Sign() == 0 {
 			if tx.NewOwner().String() != sender.String() {
+				reject(tx, "first transaction is not to the owner")
 				continue
 			}
 
 			if startBlock.Uint64() > lastBlock.Uint64() {
+				reject(tx, "deposit is not published")
 				continue
 			}
 
@@ -172,6 +602,7 @@ func (s *Service) ValidateBlock(ctx context.Context) error {
 						return
 					}
 
+					// the order of the tree is not used here
 					block := transactions.NewBlock()
 					err = block.Unmarshal(rawBlock)
 					if err != nil {
@@ -209,58 +640,26 @@ func (s *Service) ValidateBlock(ctx context.Context) error {
 				}
 				if !result.found {
 					txs[tx.UID().String()] = tx
+				} else {
+					reject(tx, "first transaction already spent")
 				}
 			case <-ctx.Done():
 				close(testTxChan)
 				return errors.New("timeout")
 			}
-		} else if tx.Nonce().Int64() > 0 {
+		} else if tx.Nonce().Sign() > 0 {
 			if tx.NewOwner().String() == sender.String() {
+				reject(tx, "transfer to the sender")
 				continue
 			}
 
-			rawBlock, err := s.RawBlockFromDB(tx.PrevBlock().Uint64())
-			if err != nil {
-				return err
-			}
-
-			prevBlock := transactions.NewBlock()
-			err = prevBlock.Unmarshal(rawBlock)
-			if err != nil {
-				return err
-			}
-
-			numTx := prevBlock.NumberOfTX()
-			if numTx == 0 {
-				continue
-			}
-
-			_, err = prevBlock.Build()
-			if err != nil {
-				return err
-			}
-
-			proof := prevBlock.CreateProof(tx.UID())
-			prevTx, err := prevBlock.GetTx(tx.UID())
-			if err != nil {
-				continue
-			}
-
-			if tx.Nonce().Uint64() != prevTx.Nonce().Uint64()+1 {
-				continue
-			}
-
-			if !bytes.Equal(tx.Amount().Bytes(), prevTx.Amount().Bytes()) {
-				continue
-			}
-
-			found, err := s.VerifyTxProof(
-				tx.UID(), prevTx.Hash(), tx.PrevBlock().Uint64(), proof)
+			reason, err := s.checkInput(tx.Inputs()[0], sender)
 			if err != nil {
 				return err
 			}
 
-			if !found {
+			if reason != "" {
+				reject(tx, reason)
 				continue
 			}
 			txs[tx.UID().String()] = tx
@@ -269,6 +668,16 @@ func (s *Service) ValidateBlock(ctx context.Context) error {
 
 	// TODO: revert
 
+	var valid []*transaction.Transaction
+	for _, v := range txs {
+		valid = append(valid, v)
+	}
+
+	for _, tx := range s.chargeFees(valid) {
+		reject(tx, ErrFeeNotPaid.Error())
+		delete(txs, tx.UID().String())
+	}
+
 	s.currentBlock = transactions.NewBlock()
 
 	for _, v := range txs {
@@ -278,5 +687,179 @@ func (s *Service) ValidateBlock(ctx context.Context) error {
 		}
 	}
 
+	rejected := before - s.currentBlock.NumberOfTX()
+	blockValidateRejected.Add(float64(rejected))
+	mempoolDepth.Set(float64(s.currentBlock.NumberOfTX()))
+
+	l.Info("Block validated", "txs", s.currentBlock.NumberOfTX(),
+		"rejected", rejected)
 	return nil
 }
+
+// checkInput checks that the coin spent by the input is created
+// by the transaction in the previous block and is owned by the sender.
+// It returns the reason why the input is rejected.
+func (s *Service) checkInput(in transaction.Input,
+	sender common.Address) (string, error) {
+	rawBlock, err := s.RawBlockFromDB(in.PrevBlock.Uint64())
+	if err != nil {
+		return "", err
+	}
+
+	prevBlock, err := s.newBlock(in.PrevBlock.Uint64())
+	if err != nil {
+		return "", err
+	}
+
+	err = prevBlock.Unmarshal(rawBlock)
+	if err != nil {
+		return "", err
+	}
+
+	if prevBlock.NumberOfTX() == 0 {
+		return "previous block is empty", nil
+	}
+
+	_, err = prevBlock.Build()
+	if err != nil {
+		return "", err
+	}
+
+	prevTx, err := prevBlock.GetTx(in.UID)
+	if err != nil {
+		return "previous transaction not found", nil
+	}
+
+	coin, ok := prevTx.Coin(in.UID)
+	if !ok {
+		return "coin is spent", nil
+	}
+
+	if in.Nonce.Uint64() != coin.Nonce.Uint64()+1 {
+		return "wrong nonce", nil
+	}
+
+	if !bytes.Equal(in.Amount.Bytes(), coin.Amount.Bytes()) {
+		return "wrong amount", nil
+	}
+
+	if coin.Owner != sender {
+		return "sender is not the owner", nil
+	}
+
+	proof := prevBlock.CreateProof(in.UID)
+	found, err := s.VerifyTxProof(
+		in.UID, prevTx.Hash(), in.PrevBlock.Uint64(), proof)
+	if err != nil {
+		return "", err
+	}
+
+	if !found {
+		return "previous transaction is not included", nil
+	}
+	return "", nil
+}
+
+// checkCoins checks inputs of a split, a merge or a swap transaction
+// and the deposits the coins belong to. The transaction is rejected
+// as a whole if any of its inputs is invalid.
+func (s *Service) checkCoins(tx *transaction.Transaction) (string, error) {
+	if isSplit(tx) && !s.splits {
+		return "split and merge transactions are disabled", nil
+	}
+
+	signers, err := transaction.Signers(tx, s.Domain())
+	if err != nil {
+		return "invalid signature", nil
+	}
+
+	for i, in := range tx.Inputs() {
+		reason, err := s.checkInput(in, signers[i])
+		if err != nil || reason != "" {
+			return reason, err
+		}
+
+		root, err := s.coinRoot(in.UID, in.PrevBlock.Uint64())
+		if err != nil {
+			return "", err
+		}
+
+		if root == nil {
+			return "coin history is broken", nil
+		}
+
+		if tx.Root() != nil && root.Cmp(tx.Root()) != 0 {
+			return "coin does not belong to the deposit", nil
+		}
+
+		amount, err := s.session.Wallet(common.BigToHash(root))
+		if err != nil {
+			return "", err
+		}
+
+		if amount.Sign() == 0 {
+			return "deposit does not exist", nil
+		}
+	}
+	return "", nil
+}
+
+// subCoinExists returns true if the transfer spends a sub-coin
+// of an existing deposit.
+func (s *Service) subCoinExists(tx *transaction.Transaction,
+	lastBlock uint64) (bool, error) {
+	if lastBlock == 0 || tx.Nonce().Sign() == 0 {
+		return false, nil
+	}
+
+	root, err := s.coinRoot(tx.UID(), tx.PrevBlock().Uint64())
+	if err != nil {
+		return false, err
+	}
+
+	if root == nil || root.Cmp(tx.UID()) == 0 {
+		return false, nil
+	}
+
+	amount, err := s.session.Wallet(common.BigToHash(root))
+	if err != nil {
+		return false, err
+	}
+	return amount.Sign() != 0, nil
+}
+
+// coinRoot returns uid of the deposit the coin belongs to. It follows
+// transfers of the coin back to the split or the merge which created it.
+// The result is nil if the history of the coin is broken.
+func (s *Service) coinRoot(uid *big.Int, number uint64) (*big.Int, error) {
+	for {
+		rawBlock, err := s.RawBlockFromDB(number)
+		if err != nil {
+			return nil, err
+		}
+
+		block := transactions.NewBlock()
+		err = block.Unmarshal(rawBlock)
+		if err != nil {
+			return nil, err
+		}
+
+		tx, err := block.GetTx(uid)
+		if err != nil {
+			return nil, nil
+		}
+
+		if tx.Type() != transaction.TransferType {
+			return tx.Root(), nil
+		}
+
+		if tx.Nonce().Sign() == 0 {
+			return uid, nil
+		}
+
+		if tx.PrevBlock().Uint64() >= number {
+			return nil, nil
+		}
+		number = tx.PrevBlock().Uint64()
+	}
+}
@@ -3,9 +3,13 @@ package service
 import (
 	"bytes"
 	"context"
+	"math/big"
 	"testing"
 
 	"github.com/SmartMeshFoundation/Spectrum/common"
+	"github.com/pkg/errors"
+
+	"github.com/SmartMeshFoundation/SmartPlasma/blockchan/block/transactions"
 )
 
 func TestAcceptTransaction(t *testing.T) {
@@ -153,3 +157,137 @@ func TestRawBlockFromDB(t *testing.T) {
 		t.Fatal("block must be in the database")
 	}
 }
+
+func TestPublishBlock(t *testing.T) {
+	i := newInstance(t)
+
+	number, err := i.service.PublishBlock(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if number != 0 {
+		t.Fatal("empty block is published")
+	}
+
+	tx := testTx(t, zero, one, two, three, owner.From, owner)
+	if err := i.service.AcceptTransaction(tx); err != nil {
+		t.Fatal(err)
+	}
+
+	number, err = i.service.PublishBlock(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hash, err := i.service.BlockHash(number)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root, err := i.rootOwnerSession.ChildChain(one)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if number != 1 || hash != common.Hash(root) {
+		t.Fatal("block is not published")
+	}
+
+	if i.service.CurrentBlock().NumberOfTX() != 0 {
+		t.Fatal("new block is not initialized")
+	}
+
+	// another operator publishes a block which is not stored
+	sendTx, err := i.rootOwnerSession.NewBlock(common.Hash{1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := i.service.mineTx(context.Background(), sendTx); err != nil {
+		t.Fatal(err)
+	}
+
+	tx = testTx(t, one, one, two, three, owner.From, owner)
+	if err := i.service.AcceptTransaction(tx); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = i.service.PublishBlock(context.Background())
+	if errors.Cause(err) != ErrBlocksNotStored {
+		t.Fatalf("expected error %s, got %v", ErrBlocksNotStored, err)
+	}
+
+	last, err := i.service.LastBlockNumber(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if last.Uint64() != 2 {
+		t.Fatal("block number is reused")
+	}
+}
+
+func TestLegacyBlocks(t *testing.T) {
+	i := newInstance(t)
+
+	legacy := transactions.NewLegacyBlock()
+	numeric := transactions.NewBlock()
+	for _, uid := range []int64{10, 11, 100} {
+		tx := testTx(t, zero, big.NewInt(uid), two, three, owner.From, owner)
+		if err := legacy.AddTx(tx); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := numeric.AddTx(tx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	legacyRoot, err := legacy.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	numericRoot, err := numeric.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if legacyRoot == numericRoot {
+		t.Fatal("roots of both orders are equal")
+	}
+
+	blockHash := func(number uint64, expected common.Hash) {
+		hash, err := i.service.BlockHash(number)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if hash != expected {
+			t.Fatalf("block %d is built with a wrong order", number)
+		}
+	}
+
+	// blocks are built with the order they are saved with
+	if err := i.service.SaveBlockToDB(1, legacy); err != nil {
+		t.Fatal(err)
+	}
+	blockHash(1, legacyRoot)
+
+	if err := i.service.SaveBlockToDB(2, numeric); err != nil {
+		t.Fatal(err)
+	}
+	blockHash(2, numericRoot)
+
+	// a block saved by an older version has no order
+	raw, err := numeric.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := i.service.blockBase.Set([]byte("3"), raw); err != nil {
+		t.Fatal(err)
+	}
+	blockHash(3, legacyRoot)
+}
@@ -9,40 +9,71 @@ import (
 	"github.com/pkg/errors"
 
 	"github.com/SmartMeshFoundation/SmartPlasma/blockchan/block/checkpoints"
-	"github.com/SmartMeshFoundation/SmartPlasma/blockchan/block/transactions"
+	"github.com/SmartMeshFoundation/SmartPlasma/blockchan/transaction"
 	"github.com/SmartMeshFoundation/SmartPlasma/contract/rootchain"
+	"github.com/SmartMeshFoundation/SmartPlasma/logger"
 	"github.com/SmartMeshFoundation/SmartPlasma/merkle"
 )
 
 // AcceptUIDState accept uid with transaction number for current checkpoint.
+// The state is accepted without consent of the owner of the coin,
+// owners opt in with AcceptOptIn.
 func (s *Service) AcceptUIDState(
 	uid, number *big.Int, blockNumber uint64) error {
+	if _, err := s.uidCoin(uid, number, blockNumber); err != nil {
+		return err
+	}
+
+	s.chptMtx.Lock()
+	defer s.chptMtx.Unlock()
+
+	if err := s.currentChpt.AddCheckpoint(uid, number); err != nil {
+		return err
+	}
+
+	s.logger.Debug("UID state accepted", "uid", uid,
+		"nonce", number, "block", blockNumber)
+	return nil
+}
+
+// uidCoin returns the coin with the uid and the nonce
+// created in the block.
+func (s *Service) uidCoin(uid, number *big.Int,
+	blockNumber uint64) (transaction.Coin, error) {
 	rawBlock, err := s.RawBlockFromDB(blockNumber)
 	if err != nil {
-		return err
+		return transaction.Coin{}, err
+	}
+
+	block, err := s.newBlock(blockNumber)
+	if err != nil {
+		return transaction.Coin{}, err
 	}
 
-	block := transactions.NewBlock()
 	err = block.Unmarshal(rawBlock)
 	if err != nil {
-		return err
+		return transaction.Coin{}, err
 	}
 
 	_, err = block.Build()
 	if err != nil {
-		return err
+		return transaction.Coin{}, err
 	}
 
 	tx, err := block.GetTx(uid)
 	if err != nil {
-		return err
+		return transaction.Coin{}, err
 	}
 
-	if tx.Nonce().Uint64() != number.Uint64() {
-		return errors.New("wrong the nonce")
+	coin, ok := tx.Coin(uid)
+	if !ok {
+		return transaction.Coin{}, errors.New("the coin is spent")
 	}
 
-	return s.currentChpt.AddCheckpoint(uid, number)
+	if coin.Nonce.Cmp(number) != 0 {
+		return transaction.Coin{}, errors.New("wrong the nonce")
+	}
+	return coin, nil
 }
 
 // CreateUIDStateProof creates merkle proof for particular uid.
@@ -54,27 +85,59 @@ func (s *Service) CreateUIDStateProof(uid *big.Int,
 		return nil, nil, err
 	}
 
-	blk := checkpoints.NewBlock()
-	err = buildBlockFromBytes(blk, raw)
+	blk, err := buildCheckpoint(chptHash, raw)
 	if err != nil {
 		return nil, nil, err
 	}
 	return blk.CreateProof(uid), blk.GetNonce(uid), err
 }
 
+// buildCheckpoint builds the checkpoint read from database.
+// A checkpoint published with the legacy order of Merkle tree levels
+// is built with the legacy order.
+func buildCheckpoint(hash common.Hash,
+	raw []byte) (checkpoints.CheckpointBlock, error) {
+	blk := checkpoints.NewBlock()
+	if err := buildBlockFromBytes(blk, raw); err != nil {
+		return nil, err
+	}
+
+	matched, err := checkpoints.MatchHash(blk, hash)
+	if err != nil || matched == nil {
+		return blk, err
+	}
+	return matched, nil
+}
+
 // CurrentCheckpoint returns current checkpoint.
 func (s *Service) CurrentCheckpoint() checkpoints.CheckpointBlock {
+	s.chptMtx.Lock()
+	defer s.chptMtx.Unlock()
 	return s.currentChpt
 }
 
 // InitCheckpoint initializes a new checkpoint.
 func (s *Service) InitCheckpoint() {
+	s.chptMtx.Lock()
+	defer s.chptMtx.Unlock()
 	s.currentChpt = checkpoints.NewBlock()
+	s.optIns = make(map[string]*transaction.OptIn)
 }
 
 // BuildCheckpoint build current Checkpoint block.
 func (s *Service) BuildCheckpoint() (common.Hash, error) {
-	return s.currentChpt.Build()
+	s.chptMtx.Lock()
+	defer s.chptMtx.Unlock()
+
+	hash, err := s.currentChpt.Build()
+	if err != nil {
+		s.logger.Error("Failed to build checkpoint", "err", err)
+		return hash, err
+	}
+
+	s.logger.Info("Checkpoint built", "hash", hash,
+		"uids", s.currentChpt.NumberOfCheckpoints())
+	return hash, nil
 }
 
 // RawCheckpointFromDB returns raw Checkpoint block from database.
@@ -82,14 +145,43 @@ func (s *Service) RawCheckpointFromDB(hash common.Hash) ([]byte, error) {
 	return s.chptBase.Get(hash.Bytes())
 }
 
-// SaveCheckpointToDB saves Checkpoint Block to database.
+// SaveCheckpointToDB saves Checkpoint Block to database. Opt-ins
+// of owners accepted for the current checkpoint are saved
+// with the block if the block fixes their coins.
 func (s *Service) SaveCheckpointToDB(chpt checkpoints.CheckpointBlock) error {
+	s.chptMtx.Lock()
+	optIns := make(map[string]*transaction.OptIn, len(s.optIns))
+	for uid, optIn := range s.optIns {
+		optIns[uid] = optIn
+	}
+	s.chptMtx.Unlock()
+
+	return s.saveCheckpoint(chpt, optIns)
+}
+
+func (s *Service) saveCheckpoint(chpt checkpoints.CheckpointBlock,
+	optIns map[string]*transaction.OptIn) error {
 	raw, err := chpt.Marshal()
 	if err != nil {
 		return err
 	}
 
-	return s.chptBase.Set(chpt.Hash().Bytes(), raw)
+	if err := s.chptBase.Set(chpt.Hash().Bytes(), raw); err != nil {
+		s.logger.Error("Failed to save checkpoint",
+			"hash", chpt.Hash(), "err", err)
+		return err
+	}
+
+	saved, err := s.saveOptIns(chpt, optIns)
+	if err != nil {
+		s.logger.Error("Failed to save checkpoint opt-ins",
+			"hash", chpt.Hash(), "err", err)
+		return err
+	}
+
+	s.logger.Info("Checkpoint saved", "hash", chpt.Hash(), "bytes", len(raw),
+		"optIns", saved)
+	return nil
 }
 
 // SendChptHash sends a Checkpoint block hash to the blockchain.
@@ -97,7 +189,17 @@ func (s *Service) SendChptHash(
 	ctx context.Context, hash common.Hash) (*types.Transaction, error) {
 	session := rootchain.CopySession(s.session)
 	session.TransactOpts.Context = ctx
-	return session.NewCheckpoint(hash)
+
+	l := logger.FromContext(ctx, s.logger)
+
+	tx, err := session.NewCheckpoint(hash)
+	if err != nil {
+		l.Error("Failed to send checkpoint hash", "hash", hash, "err", err)
+		return nil, err
+	}
+
+	l.Info("Checkpoint hash sent", "hash", hash, "tx", tx.Hash())
+	return tx, nil
 }
 
 // IsValidCheckpoint returns true if the uid is fixed at the checkpoint
@@ -1,18 +1,32 @@
 package service
 
 import (
+	"sync"
+
+	"github.com/SmartMeshFoundation/Spectrum/core/types"
+	"github.com/SmartMeshFoundation/Spectrum/log"
+
 	"github.com/SmartMeshFoundation/SmartPlasma/blockchan/backend"
 	"github.com/SmartMeshFoundation/SmartPlasma/blockchan/block/checkpoints"
 	"github.com/SmartMeshFoundation/SmartPlasma/blockchan/block/transactions"
+	"github.com/SmartMeshFoundation/SmartPlasma/blockchan/transaction"
 	"github.com/SmartMeshFoundation/SmartPlasma/contract/build"
 	"github.com/SmartMeshFoundation/SmartPlasma/contract/rootchain"
 	"github.com/SmartMeshFoundation/SmartPlasma/database"
+	"github.com/SmartMeshFoundation/SmartPlasma/logger"
 )
 
 // Service implements PlasmaCash methods.
 type Service struct {
 	currentBlock             transactions.TxBlock
+	blockMtx                 sync.RWMutex
+	blockTx                  *types.Transaction
 	currentChpt              checkpoints.CheckpointBlock
+	chptMtx                  sync.Mutex
+	chptPolicy               CheckpointPolicy
+	optIns                   map[string]*transaction.OptIn
+	uids                     *uidState
+	covered                  uint64
 	blockBase                database.Database
 	chptBase                 database.Database
 	session                  *rootchain.RootChainSession
@@ -20,6 +34,13 @@ type Service struct {
 	rootChainContractWrapper *build.Contract
 	mediatorContractWrapper  *build.Contract
 	strongMode               bool
+	swaps                    *swapPool
+	fees                     *feeLedger
+	domain                   transaction.Domain
+	domainMtx                sync.RWMutex
+	splits                   bool
+	swapsEnabled             bool
+	logger                   log.Logger
 }
 
 // NewService creates new PlasmaCash service.
@@ -38,9 +59,61 @@ func NewService(session *rootchain.RootChainSession, backend backend.Backend,
 		rootChainContractWrapper: rootChainContractWrapper,
 		mediatorContractWrapper:  mediatorContractWrapper,
 		strongMode:               strongMode,
+		swaps:                    newSwapPool(),
+		fees:                     newFeeLedger(),
+		uids:                     newUIDState(),
+		optIns:                   make(map[string]*transaction.OptIn),
+		logger:                   logger.New("service"),
 	}
 }
 
+// SetDomain sets the domain which signatures of transactions are bound to.
+// The legacy domain is used by default. The deployed RootChain contract
+// verifies signatures of the legacy domain only, so coins signed
+// in another domain could not exit and other domains are refused
+// with ErrDomainNotSupported.
+func (s *Service) SetDomain(domain transaction.Domain) error {
+	if !domain.Legacy() {
+		return ErrDomainNotSupported
+	}
+
+	s.domainMtx.Lock()
+	defer s.domainMtx.Unlock()
+	s.domain = domain
+	return nil
+}
+
+// Domain returns the domain which signatures of transactions
+// are bound to.
+func (s *Service) Domain() transaction.Domain {
+	s.domainMtx.RLock()
+	defer s.domainMtx.RUnlock()
+	return s.domain
+}
+
+// SetSplits enables split and merge transactions, they are rejected
+// by default. Splits and merges are off-chain only: RootChain contract
+// does not decode them, so a sub-coin can not exit and a split can not
+// challenge an exit of the whole deposit by its previous owner.
+// Enable them only if exits of split coins are secured another way.
+func (s *Service) SetSplits(enabled bool) {
+	s.splits = enabled
+}
+
+// SetSwaps enables swap transactions, they are rejected by default.
+// Swaps are off-chain only like splits: RootChain contract does not
+// decode them, so a swapped coin can not exit until it is transferred
+// twice and a swap can not challenge an exit of a previous owner.
+// Enable them only if exits of swapped coins are secured another way.
+func (s *Service) SetSwaps(enabled bool) {
+	s.swapsEnabled = enabled
+}
+
+// SetLogger sets the logger for the service.
+func (s *Service) SetLogger(l log.Logger) {
+	s.logger = logger.Wrap(l)
+}
+
 // Close stops service.
 func (s *Service) Close() error {
 	err := s.blockBase.Close()
@@ -0,0 +1,65 @@
+// Package service implements the operator of Plasma Cash. The operator
+// accepts transactions, builds blocks and checkpoints, saves them
+// to databases and publishes their roots to RootChain contract.
+//
+// The previous owner of a split deposit can still exit the whole deposit,
+// so split and merge transactions are rejected with ErrSplitsDisabled
+// unless SetSplits enables them. StartExit and Withdraw of transport.Client
+// return transport.ErrNotExitable for split, merge and sub-coin
+// transactions.
+//
+// Swaps are rejected with ErrSwapsDisabled unless SetSwaps enables them.
+// A swap signed by one owner is kept, up to MaxPendingSwaps and one swap
+// for a coin, until the current block is published. It is added
+// to the current block when the second signature arrives, a swap which
+// is not signed by both owners before the block is published is dropped.
+// StartExit and Withdraw of the client refuse swaps as well.
+//
+// Fees are disabled until SetFeePolicy sets a fee policy. A fee is paid
+// with a coin given to the receiver of the policy in the same block,
+// a transfer of a whole coin or an output of a split. Coins given
+// to the operator cover MinFee for each other transaction of the payer
+// in the block, other transactions are rejected with ErrFeeNotPaid.
+// Block validation charges fees again and removes transactions which
+// fees are no longer paid. Transactions of the operator are free. Fees
+// are added up when the block is saved and stored with it, so a new
+// receiver does not change fees of earlier blocks. Fees of blocks saved
+// by older versions are added up with the current policy.
+//
+// The service uses transaction.LegacyDomain, the deployed RootChain
+// contract verifies signatures of the legacy domain only, so SetDomain
+// refuses other domains with ErrDomainNotSupported. Clients get
+// the domain of the server with Domain of transport.Client.
+//
+// The operator checkpoints stored blocks periodically with a checkpoint
+// policy:
+//
+//	s.SetCheckpointPolicy(service.CheckpointPolicy{Interval: time.Hour})
+//	go s.RunCheckpointScheduler(ctx)
+//
+// At every tick the scheduler collects the latest nonce of every live UID
+// from blocks stored since the previous checkpoint, builds the checkpoint
+// block, saves it to the database and publishes its hash to RootChain
+// contract. The checkpoint is skipped if no blocks were stored since
+// the previous checkpoint. With OptIn only UIDs of owners who opted in
+// are checkpointed. MakeCheckpoint makes a checkpoint without
+// the scheduler.
+//
+// Owners consent to checkpointing of their coins (Plasma XT) with opt-ins
+// signed by the owner who got the coin with the nonce in the block.
+// AcceptOptIn verifies the signature against the owner of the coin
+// in the stored block. It rejects opt-ins of other accounts with
+// ErrNotCoinOwner and of coins spent in a later stored block with
+// ErrCoinSpent, so a previous owner can not opt in a stale state. Opt-ins are saved with the checkpoint block which fixes
+// their coins, CheckpointOptIn returns the signed opt-in as a proof
+// of consent. AcceptUIDState adds a UID to the checkpoint without
+// consent, it is not available over RPC.
+//
+// Published checkpoints are recorded in the checkpoint registry with
+// the hash of the publishing transaction, the time in RootChain contract,
+// the number of the last covered block and the number of UIDs.
+// ListCheckpoints pages through the registry, LatestCheckpointFor finds
+// the latest checkpoint of a coin. Checkpoints published through the RPC
+// API are recorded by RegisterPublishedCheckpoint when they are saved
+// after publishing or when the next checkpoint is initialized.
+package service
@@ -0,0 +1,256 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/SmartMeshFoundation/Spectrum/common"
+	"github.com/SmartMeshFoundation/Spectrum/rlp"
+	"github.com/pkg/errors"
+
+	"github.com/SmartMeshFoundation/SmartPlasma/blockchan/block/transactions"
+	"github.com/SmartMeshFoundation/SmartPlasma/blockchan/transaction"
+)
+
+// ErrFeeNotPaid is returned when the sender did not pay the fee
+// for a transaction in the current block.
+var ErrFeeNotPaid = errors.New("fee is not paid")
+
+// feesPrefix is the prefix of keys of fees saved
+// with blocks in the blocks database.
+var feesPrefix = []byte("fees-")
+
+// FeePolicy configures fees paid to the operator. A fee is paid with
+// a coin given to the fee receiver in the same block: a transfer
+// to Receiver, an output of a split owned by Receiver and so on.
+// The paid amount covers MinFee for every other transaction
+// of the payer in the block.
+type FeePolicy struct {
+	// Receiver is the address of the operator which receives fees.
+	Receiver common.Address
+	// MinFee is the fee for one transaction. Zero disables fees.
+	MinFee *big.Int
+}
+
+func (p FeePolicy) enabled() bool {
+	return p.MinFee != nil && p.MinFee.Sign() > 0
+}
+
+// payment returns the payer of the transaction and the amount
+// the transaction gives to the fee receiver.
+func (p FeePolicy) payment(tx *transaction.Transaction,
+	domain transaction.Domain) (common.Address, *big.Int, error) {
+	signers, err := transaction.Signers(tx, domain)
+	if err != nil {
+		return common.Address{}, nil, err
+	}
+	payer := signers[0]
+
+	amount := new(big.Int)
+	if payer == p.Receiver {
+		return payer, amount, nil
+	}
+
+	for _, coin := range tx.Coins() {
+		if coin.Owner == p.Receiver {
+			amount.Add(amount, coin.Amount)
+		}
+	}
+	return payer, amount, nil
+}
+
+// feeLedger counts fees paid and spent by senders in the current block.
+type feeLedger struct {
+	mtx    sync.Mutex
+	policy FeePolicy
+	paid   map[common.Address]*big.Int
+	used   map[common.Address]*big.Int
+}
+
+func newFeeLedger() *feeLedger {
+	return &feeLedger{
+		paid: make(map[common.Address]*big.Int),
+		used: make(map[common.Address]*big.Int),
+	}
+}
+
+func (l *feeLedger) setPolicy(policy FeePolicy) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	l.policy = policy
+}
+
+func (l *feeLedger) getPolicy() FeePolicy {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	return l.policy
+}
+
+func (l *feeLedger) reset() {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	l.paid = make(map[common.Address]*big.Int)
+	l.used = make(map[common.Address]*big.Int)
+}
+
+// admit records a fee payment or charges the fee for the transaction.
+// The returned function reverts the record, it is called
+// if the transaction is not added to the block.
+func (l *feeLedger) admit(tx *transaction.Transaction,
+	domain transaction.Domain) (revert func(), err error) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	if !l.policy.enabled() {
+		return func() {}, nil
+	}
+
+	payer, amount, err := l.policy.payment(tx, domain)
+	if err != nil {
+		return nil, err
+	}
+
+	if payer == l.policy.Receiver {
+		return func() {}, nil
+	}
+
+	account := l.paid
+	if amount.Sign() == 0 {
+		used := l.balance(l.used, payer)
+		if new(big.Int).Add(used, l.policy.MinFee).Cmp(
+			l.balance(l.paid, payer)) > 0 {
+			return nil, ErrFeeNotPaid
+		}
+		account, amount = l.used, l.policy.MinFee
+	}
+
+	l.balance(account, payer).Add(account[payer], amount)
+	return func() {
+		l.mtx.Lock()
+		defer l.mtx.Unlock()
+
+		account[payer].Sub(account[payer], amount)
+	}, nil
+}
+
+func (l *feeLedger) balance(account map[common.Address]*big.Int,
+	payer common.Address) *big.Int {
+	if _, ok := account[payer]; !ok {
+		account[payer] = new(big.Int)
+	}
+	return account[payer]
+}
+
+// SetFeePolicy sets the fee policy. Fees are disabled by default.
+func (s *Service) SetFeePolicy(policy FeePolicy) {
+	s.fees.setPolicy(policy)
+}
+
+// FeePolicy returns the fee policy.
+func (s *Service) FeePolicy() FeePolicy {
+	return s.fees.getPolicy()
+}
+
+// chargeFees charges fees for the transactions of the validated block,
+// fee payments are counted first. It returns transactions
+// which fees are not paid.
+func (s *Service) chargeFees(
+	txs []*transaction.Transaction) (unpaid []*transaction.Transaction) {
+	s.fees.reset()
+
+	policy := s.fees.getPolicy()
+	if !policy.enabled() {
+		return nil
+	}
+
+	var payments, others []*transaction.Transaction
+	for _, tx := range txs {
+		_, amount, err := policy.payment(tx, s.Domain())
+		if err == nil && amount.Sign() > 0 {
+			payments = append(payments, tx)
+			continue
+		}
+		others = append(others, tx)
+	}
+
+	sort.Slice(others, func(i, j int) bool {
+		hi, hj := others[i].Hash(), others[j].Hash()
+		return bytes.Compare(hi[:], hj[:]) < 0
+	})
+
+	for _, tx := range append(payments, others...) {
+		if _, err := s.fees.admit(tx, s.Domain()); err != nil {
+			unpaid = append(unpaid, tx)
+		}
+	}
+	return unpaid
+}
+
+// BlockFees returns the amount of fees paid to the fee receiver
+// in the Plasma block. Fees are added up with the fee policy of the time
+// the block is saved, so a later policy does not change them. Fees
+// of blocks saved before fees were stored are added up with the current
+// policy.
+func (s *Service) BlockFees(number uint64) (*big.Int, error) {
+	stored, err := s.blockBase.Get(feesKey(number))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(stored) > 0 {
+		fees := new(big.Int)
+		if err := rlp.DecodeBytes(stored, fees); err != nil {
+			return nil, err
+		}
+		return fees, nil
+	}
+
+	raw, err := s.RawBlockFromDB(number)
+	if err != nil {
+		return nil, err
+	}
+
+	blk := transactions.NewBlock()
+	if err := blk.Unmarshal(raw); err != nil {
+		return nil, err
+	}
+	return s.blockFees(blk)
+}
+
+func (s *Service) blockFees(blk transactions.TxBlock) (*big.Int, error) {
+	policy := s.fees.getPolicy()
+
+	total := new(big.Int)
+	if (policy.Receiver == common.Address{}) {
+		return total, nil
+	}
+
+	for tx := range blk.Transactions(context.Background()) {
+		_, amount, err := policy.payment(tx, s.Domain())
+		if err != nil {
+			continue
+		}
+		total.Add(total, amount)
+	}
+	return total, nil
+}
+
+// saveFees saves fees of the Plasma block.
+func (s *Service) saveFees(number uint64, fees *big.Int) error {
+	raw, err := rlp.EncodeToBytes(fees)
+	if err != nil {
+		return err
+	}
+	return s.blockBase.Set(feesKey(number), raw)
+}
+
+func feesKey(number uint64) []byte {
+	return strconv.AppendUint(append([]byte{}, feesPrefix...), number, 10)
+}
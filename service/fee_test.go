@@ -0,0 +1,85 @@
+package service
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestFees(t *testing.T) {
+	i := newInstance(t)
+	i.service.SetFeePolicy(FeePolicy{Receiver: owner.From, MinFee: two})
+
+	unpaid := testTx(t, one, one, one, one, user1.From, user1)
+	if err := i.service.AcceptTransaction(unpaid); errors.Cause(
+		err) != ErrFeeNotPaid {
+		t.Fatalf("expected error %s, got %v", ErrFeeNotPaid, err)
+	}
+
+	// the fee is paid with two coins given to the operator
+	fee1 := testTx(t, one, two, one, one, owner.From, user1)
+	if err := i.service.AcceptTransaction(fee1); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := i.service.AcceptTransaction(unpaid); errors.Cause(
+		err) != ErrFeeNotPaid {
+		t.Fatalf("expected error %s, got %v", ErrFeeNotPaid, err)
+	}
+
+	fee2 := testTx(t, one, three, one, one, owner.From, user1)
+	if err := i.service.AcceptTransaction(fee2); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := i.service.AcceptTransaction(unpaid); err != nil {
+		t.Fatal(err)
+	}
+
+	// the operator does not pay fees
+	operatorTx := testTx(t, one, big.NewInt(4), one, one, owner.From, owner)
+	if err := i.service.AcceptTransaction(operatorTx); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := i.service.BuildBlock(); err != nil {
+		t.Fatal(err)
+	}
+
+	err := i.service.SaveBlockToDB(one.Uint64(), i.service.CurrentBlock())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fees, err := i.service.BlockFees(one.Uint64())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fees.Cmp(two) != 0 {
+		t.Fatalf("wrong fees %s, expected %s", fees, two)
+	}
+
+	// fees of the saved block do not depend on a new receiver
+	i.service.SetFeePolicy(FeePolicy{Receiver: user1.From, MinFee: two})
+
+	fees, err = i.service.BlockFees(one.Uint64())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fees.Cmp(two) != 0 {
+		t.Fatalf("wrong fees %s, expected %s", fees, two)
+	}
+	i.service.SetFeePolicy(FeePolicy{Receiver: owner.From, MinFee: two})
+
+	// fees paid in the previous block do not cover the new block
+	i.service.InitBlock()
+
+	next := testTx(t, one, big.NewInt(5), one, two, user1.From, user1)
+	if err := i.service.AcceptTransaction(next); errors.Cause(
+		err) != ErrFeeNotPaid {
+		t.Fatalf("expected error %s, got %v", ErrFeeNotPaid, err)
+	}
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"github.com/SmartMeshFoundation/SmartPlasma/metrics"
+)
+
+// Service metrics.
+var (
+	blockBuildDuration = metrics.DefaultRegistry.NewHistogram(
+		"smartplasma_block_build_duration_seconds",
+		"Time spent building the current transactions block.", nil)
+	blockBuildErrors = metrics.DefaultRegistry.NewCounter(
+		"smartplasma_block_build_errors_total",
+		"Number of failed transactions block builds.")
+	blockTransactions = metrics.DefaultRegistry.NewHistogram(
+		"smartplasma_block_transactions",
+		"Number of transactions in built blocks.",
+		metrics.ExponentialBuckets(1, 4, 10))
+
+	blockValidateDuration = metrics.DefaultRegistry.NewHistogram(
+		"smartplasma_block_validate_duration_seconds",
+		"Time spent validating the current transactions block.",
+		metrics.ExponentialBuckets(0.01, 4, 10))
+	blockValidateRejected = metrics.DefaultRegistry.NewCounter(
+		"smartplasma_block_validate_rejected_total",
+		"Number of transactions removed from blocks by validation.")
+	blockValidateErrors = metrics.DefaultRegistry.NewCounter(
+		"smartplasma_block_validate_errors_total",
+		"Number of failed block validations.")
+
+	blockSaveDuration = metrics.DefaultRegistry.NewHistogram(
+		"smartplasma_block_save_duration_seconds",
+		"Time spent saving transactions blocks to the database.", nil)
+	blockSaveBytes = metrics.DefaultRegistry.NewHistogram(
+		"smartplasma_block_save_bytes",
+		"Size of saved transactions blocks in bytes.",
+		metrics.ExponentialBuckets(256, 4, 10))
+
+	blockHashSent = metrics.DefaultRegistry.NewCounter(
+		"smartplasma_block_hash_sent_total",
+		"Number of block hashes sent to the RootChain contract.", "result")
+	blockHashSendDuration = metrics.DefaultRegistry.NewHistogram(
+		"smartplasma_block_hash_send_duration_seconds",
+		"Time spent sending block hashes to the RootChain contract.", nil)
+
+	mempoolDepth = metrics.DefaultRegistry.NewGauge(
+		"smartplasma_mempool_transactions",
+		"Number of transactions in the current block.")
+	acceptedTransactions = metrics.DefaultRegistry.NewCounter(
+		"smartplasma_accepted_transactions_total",
+		"Number of transactions offered to the current block.", "result")
+	feesCollected = metrics.DefaultRegistry.NewCounter(
+		"smartplasma_fees_collected_total",
+		"Amount of fees paid to the operator in saved blocks.")
+
+	checkpointsMade = metrics.DefaultRegistry.NewCounter(
+		"smartplasma_checkpoints_made_total",
+		"Number of checkpoint rounds of the scheduler.", "result")
+	checkpointUIDs = metrics.DefaultRegistry.NewHistogram(
+		"smartplasma_checkpoint_uids",
+		"Number of UIDs in checkpoints made by the scheduler.",
+		metrics.ExponentialBuckets(1, 4, 10))
+)
+
+func resultLabel(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "ok"
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/SmartMeshFoundation/SmartPlasma/metrics"
+)
+
+func TestMetrics(t *testing.T) {
+	i := newInstance(t)
+	tx := testTx(t, zero, one, two, three, owner.From, owner)
+
+	if err := i.service.AcceptTransaction(tx); err != nil {
+		t.Fatal(err)
+	}
+
+	hash, err := i.service.BuildBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sendBlockTx, err := i.service.SendBlockHash(context.Background(), hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := i.service.mineTx(
+		context.Background(), sendBlockTx); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := i.service.SaveBlockToDB(
+		one.Uint64(), i.service.CurrentBlock()); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := bytes.NewBuffer(nil)
+	if _, err := metrics.DefaultRegistry.WriteTo(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, line := range []string{
+		"smartplasma_mempool_transactions 1",
+		"smartplasma_block_build_duration_seconds_count",
+		"smartplasma_block_transactions_count",
+		"smartplasma_block_save_bytes_count",
+		`smartplasma_block_hash_sent_total{result="ok"}`,
+		`smartplasma_backend_mined_transactions_total{status="successful"}`,
+	} {
+		if !strings.Contains(buf.String(), line) {
+			t.Fatalf("metric %s not found", line)
+		}
+	}
+
+	i.service.InitBlock()
+
+	buf.Reset()
+	if _, err := metrics.DefaultRegistry.WriteTo(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(buf.String(), "smartplasma_mempool_transactions 0") {
+		t.Fatal("mempool depth is not reset")
+	}
+}
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"math/big"
+	"sort"
+
+	"github.com/SmartMeshFoundation/Spectrum/common"
+	"github.com/SmartMeshFoundation/Spectrum/rlp"
+	"github.com/pkg/errors"
+
+	"github.com/SmartMeshFoundation/SmartPlasma/blockchan/block/checkpoints"
+	"github.com/SmartMeshFoundation/SmartPlasma/blockchan/transaction"
+)
+
+// Errors.
+var (
+	ErrNotCoinOwner  = errors.New("opt-in is not signed by the coin owner")
+	ErrOptInNotFound = errors.New("opt-in not found")
+	ErrCoinSpent     = errors.New("coin is spent in a later block")
+)
+
+// optInsPrefix is the prefix of keys of opt-ins saved
+// with checkpoints in the checkpoints database.
+var optInsPrefix = []byte("optins-")
+
+// AcceptOptIn accepts the opt-in of the owner of a coin for current
+// checkpoint (Plasma XT). The opt-in must be signed in the domain
+// of the service by the owner who got the coin with the nonce
+// in the block, and the coin must not be spent in stored blocks
+// after the block, so a previous owner can not opt in a stale state.
+func (s *Service) AcceptOptIn(optIn *transaction.OptIn) error {
+	signer, err := optIn.Signer(s.Domain())
+	if err != nil {
+		return err
+	}
+
+	coin, err := s.uidCoin(optIn.UID, optIn.Nonce, optIn.Block)
+	if err != nil {
+		return err
+	}
+
+	if coin.Owner != signer {
+		return ErrNotCoinOwner
+	}
+
+	s.chptMtx.Lock()
+	defer s.chptMtx.Unlock()
+
+	ctx := context.Background()
+	last, err := s.LastBlockNumber(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := s.collectUIDState(ctx, last.Uint64()); err != nil {
+		return err
+	}
+
+	nonce, ok := s.uids.nonces[optIn.UID.String()]
+	if !ok || nonce.Cmp(optIn.Nonce) != 0 {
+		return ErrCoinSpent
+	}
+
+	if err := s.currentChpt.AddCheckpoint(optIn.UID,
+		optIn.Nonce); err != nil {
+		return err
+	}
+	s.optIns[optIn.UID.String()] = optIn
+
+	s.logger.Debug("Opt-in accepted", "uid", optIn.UID,
+		"nonce", optIn.Nonce, "block", optIn.Block, "owner", signer)
+	return nil
+}
+
+// saveOptIns saves opt-ins which coins are fixed by the built checkpoint
+// and returns the number of saved opt-ins.
+func (s *Service) saveOptIns(chpt checkpoints.CheckpointBlock,
+	optIns map[string]*transaction.OptIn) (int, error) {
+	if !chpt.IsBuilt() {
+		return 0, nil
+	}
+
+	var fixed []*transaction.OptIn
+	for _, optIn := range optIns {
+		if chpt.GetNonce(optIn.UID).Cmp(optIn.Nonce) == 0 {
+			fixed = append(fixed, optIn)
+		}
+	}
+
+	if len(fixed) == 0 {
+		return 0, nil
+	}
+
+	sort.Slice(fixed, func(i, j int) bool {
+		return fixed[i].UID.Cmp(fixed[j].UID) < 0
+	})
+
+	raw, err := rlp.EncodeToBytes(fixed)
+	if err != nil {
+		return 0, err
+	}
+	return len(fixed), s.chptBase.Set(optInsKey(chpt.Hash()), raw)
+}
+
+// CheckpointOptIns returns opt-ins of owners saved with the checkpoint.
+func (s *Service) CheckpointOptIns(
+	hash common.Hash) ([]*transaction.OptIn, error) {
+	raw, err := s.chptBase.Get(optInsKey(hash))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(raw) == 0 {
+		return nil, nil
+	}
+
+	var optIns []*transaction.OptIn
+	if err := rlp.DecodeBytes(raw, &optIns); err != nil {
+		return nil, err
+	}
+	return optIns, nil
+}
+
+// CheckpointOptIn returns the opt-in of the owner of the coin
+// saved with the checkpoint. It proves that the owner
+// consented to the checkpoint.
+func (s *Service) CheckpointOptIn(hash common.Hash,
+	uid *big.Int) (*transaction.OptIn, error) {
+	optIns, err := s.CheckpointOptIns(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, optIn := range optIns {
+		if optIn.UID.Cmp(uid) == 0 {
+			return optIn, nil
+		}
+	}
+	return nil, ErrOptInNotFound
+}
+
+func optInsKey(hash common.Hash) []byte {
+	return append(append([]byte{}, optInsPrefix...), hash.Bytes()...)
+}
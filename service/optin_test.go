@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/SmartMeshFoundation/SmartPlasma/blockchan/account"
+	"github.com/SmartMeshFoundation/SmartPlasma/blockchan/transaction"
+)
+
+func testOptIn(t *testing.T, i *instance, signer *account.PlasmaTransactOpts,
+	optIn *transaction.OptIn) *transaction.OptIn {
+	signed, err := signer.OptInSigner(signer.From, optIn, i.service.Domain())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestAcceptOptIn(t *testing.T) {
+	i := newInstance(t)
+	i.service.SetCheckpointPolicy(CheckpointPolicy{OptIn: true})
+
+	publishBlock(t, i, 1,
+		testTx(t, zero, one, one, zero, user1.From, user1),
+		testTx(t, zero, two, one, zero, user1.From, user1))
+	publishBlock(t, i, 2,
+		testTx(t, one, one, one, one, owner.From, user1))
+
+	optIn, err := transaction.NewOptIn(one, one, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = i.service.AcceptOptIn(testOptIn(t, i, user1, optIn))
+	if err != ErrNotCoinOwner {
+		t.Fatalf("expected error %s, got %v", ErrNotCoinOwner, err)
+	}
+
+	if err := i.service.AcceptOptIn(optIn); err == nil {
+		t.Fatal("unsigned opt-in is accepted")
+	}
+
+	if err := i.service.AcceptOptIn(
+		testOptIn(t, i, owner, optIn)); err != nil {
+		t.Fatal(err)
+	}
+
+	stale, err := transaction.NewOptIn(one, zero, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the previous owner opts in the state before the transfer
+	err = i.service.AcceptOptIn(testOptIn(t, i, user1, stale))
+	if err != ErrCoinSpent {
+		t.Fatalf("expected error %s, got %v", ErrCoinSpent, err)
+	}
+
+	// the state of the coin is checkpointed without the opt-in
+	if err := i.service.AcceptUIDState(two, zero, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	hash, err := i.service.MakeCheckpoint(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkUIDState(t, i, one, one, hash)
+	checkUIDState(t, i, two, zero, hash)
+
+	optIns, err := i.service.CheckpointOptIns(hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(optIns) != 1 || optIns[0].UID.Cmp(one) != 0 {
+		t.Fatal("wrong opt-ins of the checkpoint")
+	}
+
+	signer, err := optIns[0].Signer(i.service.Domain())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if signer != owner.From {
+		t.Fatal("wrong signer of the opt-in")
+	}
+
+	if _, err := i.service.CheckpointOptIn(hash, two); err != ErrOptInNotFound {
+		t.Fatalf("expected error %s, got %v", ErrOptInNotFound, err)
+	}
+}
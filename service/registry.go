@@ -0,0 +1,287 @@
+package service
+
+import (
+	"context"
+	"math/big"
+	"strconv"
+
+	"github.com/SmartMeshFoundation/Spectrum/common"
+	"github.com/SmartMeshFoundation/Spectrum/rlp"
+	"github.com/pkg/errors"
+
+	"github.com/SmartMeshFoundation/SmartPlasma/blockchan/block/checkpoints"
+)
+
+// ErrCheckpointNotPublished is returned when a checkpoint which hash
+// is not in RootChain contract is registered.
+var ErrCheckpointNotPublished = errors.New("checkpoint is not published")
+
+// Keys of the checkpoint registry in the checkpoints database.
+var (
+	registryCountKey   = []byte("registry")
+	registryPrefix     = []byte("registry-")
+	registryHashPrefix = []byte("registered-")
+)
+
+// CheckpointInfo is the record of a published checkpoint
+// in the checkpoint registry.
+type CheckpointInfo struct {
+	// Index is the position of the checkpoint in the registry from 1.
+	Index uint64
+	Hash  common.Hash
+	// Tx is the hash of the transaction which published the checkpoint,
+	// it is zero if the checkpoint was published before it was registered.
+	Tx common.Hash
+	// Time is the time of the checkpoint in RootChain contract.
+	Time *big.Int
+	// Block is the number of the last transactions block
+	// covered by the checkpoint.
+	Block uint64
+	// UIDs is the number of UIDs fixed by the checkpoint.
+	UIDs uint64
+}
+
+// RegisterCheckpoint records the checkpoint published by the transaction
+// in the checkpoint registry. The checkpoint must be saved to database
+// and published to RootChain contract. A registered checkpoint
+// is not recorded again, its record is returned.
+func (s *Service) RegisterCheckpoint(ctx context.Context, hash,
+	tx common.Hash, covered uint64) (*CheckpointInfo, error) {
+	s.chptMtx.Lock()
+	defer s.chptMtx.Unlock()
+
+	return s.registerCheckpoint(ctx, hash, tx, covered)
+}
+
+// RegisterPublishedCheckpoint records the checkpoint made by hand
+// in the checkpoint registry like a scheduled one. It covers the last
+// transactions block, the transaction which published it is not known.
+// Nothing is recorded until the checkpoint is both saved to database
+// and published to RootChain contract.
+func (s *Service) RegisterPublishedCheckpoint(ctx context.Context,
+	hash common.Hash) error {
+	s.chptMtx.Lock()
+	defer s.chptMtx.Unlock()
+
+	last, err := s.LastBlockNumber(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.registerCheckpoint(ctx, hash, common.Hash{}, last.Uint64())
+	if err == ErrBlockNotFound || err == ErrCheckpointNotPublished {
+		return nil
+	}
+	return err
+}
+
+func (s *Service) registerCheckpoint(ctx context.Context, hash,
+	tx common.Hash, covered uint64) (*CheckpointInfo, error) {
+	if info, err := s.RegisteredCheckpoint(hash); err == nil {
+		return info, nil
+	} else if err != ErrCheckpointNotFound {
+		return nil, err
+	}
+
+	raw, err := s.RawCheckpointFromDB(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(raw) == 0 {
+		return nil, ErrBlockNotFound
+	}
+
+	chpt := checkpoints.NewBlock()
+	if err := chpt.Unmarshal(raw); err != nil {
+		return nil, err
+	}
+
+	published, err := s.checkpointTime(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	if published.Sign() == 0 {
+		return nil, ErrCheckpointNotPublished
+	}
+
+	count, err := s.CheckpointCount()
+	if err != nil {
+		return nil, err
+	}
+
+	info := &CheckpointInfo{
+		Index: count + 1,
+		Hash:  hash,
+		Tx:    tx,
+		Time:  published,
+		Block: covered,
+		UIDs:  uint64(chpt.NumberOfCheckpoints()),
+	}
+
+	rawInfo, err := rlp.EncodeToBytes(info)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.chptBase.Set(registryKey(info.Index), rawInfo); err != nil {
+		return nil, err
+	}
+
+	err = s.chptBase.Set(registryHashKey(hash),
+		strconv.AppendUint(nil, info.Index, 10))
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.chptBase.Set(registryCountKey,
+		strconv.AppendUint(nil, info.Index, 10))
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Checkpoint registered", "hash", hash,
+		"index", info.Index, "block", covered, "uids", info.UIDs)
+	return info, nil
+}
+
+// CheckpointCount returns the number of checkpoints in the registry.
+func (s *Service) CheckpointCount() (uint64, error) {
+	return s.readIndex(registryCountKey)
+}
+
+// RegisteredCheckpoint returns the record of the checkpoint
+// in the checkpoint registry.
+func (s *Service) RegisteredCheckpoint(
+	hash common.Hash) (*CheckpointInfo, error) {
+	index, err := s.readIndex(registryHashKey(hash))
+	if err != nil {
+		return nil, err
+	}
+
+	if index == 0 {
+		return nil, ErrCheckpointNotFound
+	}
+	return s.checkpointInfo(index)
+}
+
+// ListCheckpoints returns at most limit records of the checkpoint
+// registry starting with the index from, the oldest checkpoint
+// has the index 1.
+func (s *Service) ListCheckpoints(ctx context.Context, from uint64,
+	limit int) ([]*CheckpointInfo, error) {
+	if limit > MaxBatchSize {
+		return nil, ErrBatchTooLarge
+	}
+
+	count, err := s.CheckpointCount()
+	if err != nil {
+		return nil, err
+	}
+
+	if from == 0 {
+		from = 1
+	}
+
+	var result []*CheckpointInfo
+	for index := from; index <= count && len(result) < limit; index++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		info, err := s.checkpointInfo(index)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, info)
+	}
+	return result, nil
+}
+
+// LatestCheckpointFor returns the record of the latest registered
+// checkpoint which fixes the UID. Checkpoint blocks are read from
+// the newest to the oldest until the UID is found.
+func (s *Service) LatestCheckpointFor(ctx context.Context,
+	uid *big.Int) (*CheckpointInfo, error) {
+	count, err := s.CheckpointCount()
+	if err != nil {
+		return nil, err
+	}
+
+	for index := count; index > 0; index-- {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		info, err := s.checkpointInfo(index)
+		if err != nil {
+			return nil, err
+		}
+
+		raw, err := s.RawCheckpointFromDB(info.Hash)
+		if err != nil {
+			return nil, err
+		}
+
+		chpt := checkpoints.NewBlock()
+		if err := chpt.Unmarshal(raw); err != nil {
+			return nil, errors.Wrapf(err,
+				"failed to read checkpoint %s", info.Hash.Hex())
+		}
+
+		if chpt.Contains(uid) {
+			return info, nil
+		}
+	}
+	return nil, ErrCheckpointNotFound
+}
+
+// CheckpointCovers returns the number of the last transactions block
+// covered by the registered checkpoint.
+func (s *Service) CheckpointCovers(hash common.Hash) (uint64, error) {
+	info, err := s.RegisteredCheckpoint(hash)
+	if err != nil {
+		return 0, err
+	}
+	return info.Block, nil
+}
+
+func (s *Service) checkpointInfo(index uint64) (*CheckpointInfo, error) {
+	raw, err := s.chptBase.Get(registryKey(index))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(raw) == 0 {
+		return nil, errors.Wrapf(ErrCheckpointNotFound,
+			"registry index %d", index)
+	}
+
+	info := new(CheckpointInfo)
+	if err := rlp.DecodeBytes(raw, info); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+func (s *Service) readIndex(key []byte) (uint64, error) {
+	raw, err := s.chptBase.Get(key)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(raw) == 0 {
+		return 0, nil
+	}
+	return strconv.ParseUint(string(raw), 10, 64)
+}
+
+func registryKey(index uint64) []byte {
+	return strconv.AppendUint(
+		append([]byte{}, registryPrefix...), index, 10)
+}
+
+func registryHashKey(hash common.Hash) []byte {
+	return append(append([]byte{}, registryHashPrefix...), hash.Bytes()...)
+}
@@ -0,0 +1,181 @@
+package service
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/SmartMeshFoundation/Spectrum/common"
+)
+
+func TestCheckpointRegistry(t *testing.T) {
+	i := newInstance(t)
+	ctx := context.Background()
+	i.service.SetCheckpointPolicy(CheckpointPolicy{OptIn: true})
+
+	publishBlock(t, i, 1,
+		testTx(t, zero, one, one, zero, user1.From, user1),
+		testTx(t, zero, two, one, zero, user1.From, user1))
+
+	if err := i.service.AcceptUIDState(one, zero, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := i.service.AcceptUIDState(two, zero, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	hash1, err := i.service.MakeCheckpoint(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	publishBlock(t, i, 2, testTx(t, one, one, one, one, owner.From, user1))
+
+	if err := i.service.AcceptUIDState(one, one, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	hash2, err := i.service.MakeCheckpoint(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	infos, err := i.service.ListCheckpoints(ctx, 0, MaxBatchSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(infos) != 2 {
+		t.Fatalf("expected 2 checkpoints, got %d", len(infos))
+	}
+
+	for n, want := range []struct {
+		hash  common.Hash
+		block uint64
+		uids  uint64
+	}{{hash1, 1, 2}, {hash2, 2, 1}} {
+		info := infos[n]
+		if info.Index != uint64(n+1) || info.Hash != want.hash ||
+			info.Block != want.block || info.UIDs != want.uids {
+			t.Fatalf("wrong checkpoint %d: %+v", n+1, info)
+		}
+
+		if (info.Tx == common.Hash{}) {
+			t.Fatalf("checkpoint %d: no publishing transaction", n+1)
+		}
+
+		published, err := i.service.checkpointTime(ctx, info.Hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if info.Time.Cmp(published) != 0 {
+			t.Fatalf("checkpoint %d: expected time %s, got %s",
+				n+1, published, info.Time)
+		}
+	}
+
+	infos, err = i.service.ListCheckpoints(ctx, 2, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(infos) != 1 || infos[0].Hash != hash2 {
+		t.Fatal("wrong page of checkpoints")
+	}
+
+	if _, err := i.service.ListCheckpoints(
+		ctx, 1, MaxBatchSize+1); err != ErrBatchTooLarge {
+		t.Fatalf("expected error %s, got %v", ErrBatchTooLarge, err)
+	}
+
+	latest, err := i.service.LatestCheckpointFor(ctx, one)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if latest.Hash != hash2 {
+		t.Fatalf("expected checkpoint %s, got %s", hash2.Hex(),
+			latest.Hash.Hex())
+	}
+
+	latest, err = i.service.LatestCheckpointFor(ctx, two)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if latest.Hash != hash1 {
+		t.Fatalf("expected checkpoint %s, got %s", hash1.Hex(),
+			latest.Hash.Hex())
+	}
+
+	_, err = i.service.LatestCheckpointFor(ctx, big.NewInt(100))
+	if err != ErrCheckpointNotFound {
+		t.Fatalf("expected error %s, got %v", ErrCheckpointNotFound, err)
+	}
+
+	// a registered checkpoint is not recorded again
+	info, err := i.service.RegisterCheckpoint(ctx, hash1, common.Hash{}, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	count, err := i.service.CheckpointCount()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Index != 1 || info.Block != 1 || count != 2 {
+		t.Fatal("registered checkpoint is recorded again")
+	}
+}
+
+func TestRegisterUnpublishedCheckpoint(t *testing.T) {
+	i := newInstance(t)
+	ctx := context.Background()
+
+	publishBlock(t, i, 1, testTx(t, zero, one, one, zero, user1.From, user1))
+
+	if err := i.service.AcceptUIDState(one, zero, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	hash, err := i.service.BuildCheckpoint()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = i.service.RegisterCheckpoint(ctx, hash, common.Hash{}, 1)
+	if err != ErrBlockNotFound {
+		t.Fatalf("expected error %s, got %v", ErrBlockNotFound, err)
+	}
+
+	err = i.service.SaveCheckpointToDB(i.service.CurrentCheckpoint())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = i.service.RegisterCheckpoint(ctx, hash, common.Hash{}, 1)
+	if err != ErrCheckpointNotPublished {
+		t.Fatalf("expected error %s, got %v", ErrCheckpointNotPublished, err)
+	}
+
+	tx, err := i.service.SendChptHash(ctx, hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := i.service.mineTx(ctx, tx); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := i.service.RegisterCheckpoint(ctx, hash, tx.Hash(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Index != 1 || info.Tx != tx.Hash() || info.UIDs != 1 {
+		t.Fatalf("wrong checkpoint: %+v", info)
+	}
+}
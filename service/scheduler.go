@@ -0,0 +1,229 @@
+package service
+
+import (
+	"context"
+	"math/big"
+	"time"
+
+	"github.com/SmartMeshFoundation/Spectrum/common"
+	"github.com/pkg/errors"
+
+	"github.com/SmartMeshFoundation/SmartPlasma/blockchan/block/checkpoints"
+	"github.com/SmartMeshFoundation/SmartPlasma/blockchan/transaction"
+	"github.com/SmartMeshFoundation/SmartPlasma/contract/rootchain"
+	"github.com/SmartMeshFoundation/SmartPlasma/logger"
+)
+
+// Errors.
+var (
+	ErrSchedulerDisabled  = errors.New("checkpoint scheduler is disabled")
+	ErrCheckpointNotFound = errors.New("checkpoint not found")
+)
+
+// CheckpointPolicy defines when and what the operator checkpoints.
+type CheckpointPolicy struct {
+	// Interval is the period between checkpoints,
+	// the scheduler does not run if it is zero.
+	Interval time.Duration
+	// OptIn if it is true then only UIDs of the current checkpoint
+	// are checkpointed: owners opt in with AcceptOptIn and the operator
+	// adds UIDs with AcceptUIDState. Otherwise every live UID
+	// is checkpointed with its latest nonce in stored blocks.
+	OptIn bool
+}
+
+// uidState is the latest nonce of every live UID in stored blocks.
+type uidState struct {
+	block  uint64
+	nonces map[string]*big.Int
+}
+
+func newUIDState() *uidState {
+	return &uidState{nonces: make(map[string]*big.Int)}
+}
+
+// apply removes coins spent by the transaction and adds created coins.
+// It is idempotent, so a block can be applied again.
+func (st *uidState) apply(tx *transaction.Transaction) {
+	for _, in := range tx.Inputs() {
+		delete(st.nonces, in.UID.String())
+	}
+
+	for _, coin := range tx.Coins() {
+		st.nonces[coin.UID.String()] = coin.Nonce
+	}
+}
+
+// SetCheckpointPolicy sets the policy of the checkpoint scheduler.
+func (s *Service) SetCheckpointPolicy(policy CheckpointPolicy) {
+	s.chptMtx.Lock()
+	defer s.chptMtx.Unlock()
+	s.chptPolicy = policy
+}
+
+// CheckpointPolicy returns the policy of the checkpoint scheduler.
+func (s *Service) CheckpointPolicy() CheckpointPolicy {
+	s.chptMtx.Lock()
+	defer s.chptMtx.Unlock()
+	return s.chptPolicy
+}
+
+// RunCheckpointScheduler makes checkpoints with the interval
+// of the checkpoint policy until the context is done. Failed checkpoints
+// are logged and made again at the next tick.
+func (s *Service) RunCheckpointScheduler(ctx context.Context) error {
+	policy := s.CheckpointPolicy()
+	if policy.Interval <= 0 {
+		return ErrSchedulerDisabled
+	}
+
+	s.logger.Info("Checkpoint scheduler started",
+		"interval", policy.Interval, "optIn", policy.OptIn)
+
+	ticker := time.NewTicker(policy.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.logger.Info("Checkpoint scheduler stopped")
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		if _, err := s.MakeCheckpoint(ctx); err != nil {
+			s.logger.Error("Failed to make checkpoint", "err", err)
+		}
+	}
+}
+
+// MakeCheckpoint checkpoints stored blocks according to the checkpoint
+// policy. The checkpoint block is built, saved to database, its hash is
+// sent to RootChain contract and the checkpoint is recorded in the
+// checkpoint registry with the number of the last block covered
+// by it. It returns the zero hash if there is
+// nothing to checkpoint.
+func (s *Service) MakeCheckpoint(ctx context.Context) (common.Hash, error) {
+	s.chptMtx.Lock()
+	defer s.chptMtx.Unlock()
+
+	hash, err := s.makeCheckpoint(ctx)
+	checkpointsMade.Inc(resultLabel(err))
+	return hash, err
+}
+
+func (s *Service) makeCheckpoint(ctx context.Context) (common.Hash, error) {
+	l := logger.FromContext(ctx, s.logger)
+
+	last, err := s.LastBlockNumber(ctx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	chpt := s.currentChpt
+	if !s.chptPolicy.OptIn {
+		if err := s.collectUIDState(ctx, last.Uint64()); err != nil {
+			return common.Hash{}, err
+		}
+
+		if s.covered == last.Uint64() {
+			l.Debug("No blocks to checkpoint", "block", last)
+			return common.Hash{}, nil
+		}
+
+		chpt = checkpoints.NewBlock()
+		for uid, nonce := range s.uids.nonces {
+			id, _ := new(big.Int).SetString(uid, 10)
+			if err := chpt.AddCheckpoint(id, nonce); err != nil {
+				return common.Hash{}, err
+			}
+		}
+	}
+
+	if chpt.NumberOfCheckpoints() == 0 {
+		l.Debug("No UIDs to checkpoint", "block", last)
+		return common.Hash{}, nil
+	}
+
+	hash := chpt.Hash()
+	if !chpt.IsBuilt() {
+		if hash, err = chpt.Build(); err != nil {
+			return common.Hash{}, err
+		}
+	}
+
+	if err := s.saveCheckpoint(chpt, s.optIns); err != nil {
+		return common.Hash{}, err
+	}
+
+	tx, err := s.publishCheckpoint(ctx, hash)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	_, err = s.registerCheckpoint(ctx, hash, tx, last.Uint64())
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	s.covered = last.Uint64()
+	s.currentChpt = checkpoints.NewBlock()
+	s.optIns = make(map[string]*transaction.OptIn)
+	checkpointUIDs.Observe(float64(chpt.NumberOfCheckpoints()))
+
+	l.Info("Checkpoint made", "hash", hash,
+		"uids", chpt.NumberOfCheckpoints(), "block", last)
+	return hash, nil
+}
+
+// collectUIDState applies stored blocks up to the last block
+// to the state of live UIDs.
+func (s *Service) collectUIDState(ctx context.Context, last uint64) error {
+	for number := s.uids.block + 1; number <= last; number++ {
+		blk, err := s.buildBlock(number)
+		if err != nil {
+			return errors.Wrapf(err, "failed to collect block %d", number)
+		}
+
+		for tx := range blk.Transactions(ctx) {
+			s.uids.apply(tx)
+		}
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		s.uids.block = number
+	}
+	return nil
+}
+
+// publishCheckpoint sends the checkpoint hash to RootChain contract
+// and waits until it is mined, it returns the hash of the transaction.
+// A hash which is already published is not sent again, RootChain
+// contract rejects it, then the zero transaction hash is returned.
+func (s *Service) publishCheckpoint(ctx context.Context,
+	hash common.Hash) (common.Hash, error) {
+	published, err := s.checkpointTime(ctx, hash)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	if published.Sign() > 0 {
+		return common.Hash{}, nil
+	}
+
+	tx, err := s.SendChptHash(ctx, hash)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return tx.Hash(), s.mineTx(ctx, tx)
+}
+
+// checkpointTime returns the time when the checkpoint was published
+// to RootChain contract or zero.
+func (s *Service) checkpointTime(ctx context.Context,
+	hash common.Hash) (*big.Int, error) {
+	session := rootchain.CopySession(s.session)
+	session.CallOpts.Context = ctx
+	return session.Checkpoints(hash)
+}
@@ -0,0 +1,203 @@
+package service
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/SmartMeshFoundation/Spectrum/common"
+
+	"github.com/SmartMeshFoundation/SmartPlasma/blockchan/transaction"
+)
+
+func publishBlock(t *testing.T, i *instance, number uint64,
+	txs ...*transaction.Transaction) {
+	for _, tx := range txs {
+		if err := i.service.AcceptTransaction(tx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	hash, err := i.service.BuildBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tx, err := i.service.SendBlockHash(context.Background(), hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := i.service.mineTx(context.Background(), tx); err != nil {
+		t.Fatal(err)
+	}
+
+	err = i.service.SaveBlockToDB(number, i.service.CurrentBlock())
+	if err != nil {
+		t.Fatal(err)
+	}
+	i.service.InitBlock()
+}
+
+func checkUIDState(t *testing.T, i *instance, uid, nonce *big.Int,
+	chpt common.Hash) {
+	proof, stored, err := i.service.CreateUIDStateProof(uid, chpt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stored.Cmp(nonce) != 0 {
+		t.Fatalf("uid %s: expected nonce %s, got %s", uid, nonce, stored)
+	}
+
+	valid, err := i.service.IsValidCheckpoint(
+		context.Background(), uid, nonce, chpt, proof)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !valid {
+		t.Fatalf("uid %s is not checkpointed", uid)
+	}
+}
+
+func TestMakeCheckpoint(t *testing.T) {
+	i := newInstance(t)
+	ctx := context.Background()
+
+	publishBlock(t, i, 1,
+		testTx(t, zero, one, one, zero, user1.From, user1),
+		testTx(t, zero, two, one, zero, user1.From, user1))
+	publishBlock(t, i, 2,
+		testTx(t, one, one, one, one, owner.From, user1))
+
+	hash, err := i.service.MakeCheckpoint(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if (hash == common.Hash{}) {
+		t.Fatal("checkpoint is not made")
+	}
+
+	checkUIDState(t, i, one, one, hash)
+	checkUIDState(t, i, two, zero, hash)
+
+	covers, err := i.service.CheckpointCovers(hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if covers != 2 {
+		t.Fatalf("expected covered block 2, got %d", covers)
+	}
+
+	// there are no new blocks
+	hash2, err := i.service.MakeCheckpoint(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if (hash2 != common.Hash{}) {
+		t.Fatal("checkpoint without new blocks is made")
+	}
+
+	publishBlock(t, i, 3,
+		testTx(t, two, one, one, two, user1.From, owner))
+
+	hash3, err := i.service.MakeCheckpoint(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkUIDState(t, i, one, two, hash3)
+
+	if _, err := i.service.CheckpointCovers(
+		common.Hash{}); err != ErrCheckpointNotFound {
+		t.Fatalf("expected error %s, got %v", ErrCheckpointNotFound, err)
+	}
+}
+
+func TestMakeCheckpointOptIn(t *testing.T) {
+	i := newInstance(t)
+	ctx := context.Background()
+	i.service.SetCheckpointPolicy(CheckpointPolicy{OptIn: true})
+
+	publishBlock(t, i, 1,
+		testTx(t, zero, one, one, zero, user1.From, user1),
+		testTx(t, zero, two, one, zero, user1.From, user1))
+
+	// nothing is requested
+	hash, err := i.service.MakeCheckpoint(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if (hash != common.Hash{}) {
+		t.Fatal("empty checkpoint is made")
+	}
+
+	if err := i.service.AcceptUIDState(two, zero, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	hash, err = i.service.MakeCheckpoint(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkUIDState(t, i, two, zero, hash)
+
+	_, nonce, err := i.service.CreateUIDStateProof(one, hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if nonce.Sign() != 0 {
+		t.Fatal("uid is checkpointed without request")
+	}
+
+	if i.service.CurrentCheckpoint().NumberOfCheckpoints() != 0 {
+		t.Fatal("the current checkpoint was not initialized")
+	}
+}
+
+func TestRunCheckpointScheduler(t *testing.T) {
+	i := newInstance(t)
+
+	err := i.service.RunCheckpointScheduler(context.Background())
+	if err != ErrSchedulerDisabled {
+		t.Fatalf("expected error %s, got %v", ErrSchedulerDisabled, err)
+	}
+
+	publishBlock(t, i, 1, testTx(t, zero, one, one, zero, user1.From, user1))
+
+	i.service.SetCheckpointPolicy(CheckpointPolicy{
+		Interval: 10 * time.Millisecond})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	done := make(chan error)
+	go func() {
+		done <- i.service.RunCheckpointScheduler(ctx)
+	}()
+
+	for covered(i) != 1 {
+		select {
+		case err := <-done:
+			t.Fatalf("checkpoint is not made: %v", err)
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	cancel()
+
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("expected error %s, got %v", context.Canceled, err)
+	}
+}
+
+func covered(i *instance) uint64 {
+	i.service.chptMtx.Lock()
+	defer i.service.chptMtx.Unlock()
+	return i.service.covered
+}
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"sync"
+
+	"github.com/SmartMeshFoundation/Spectrum/common"
+	"github.com/pkg/errors"
+
+	"github.com/SmartMeshFoundation/SmartPlasma/blockchan/transaction"
+)
+
+// MaxPendingSwaps is the maximum number of swaps
+// waiting for the signature of the second owner.
+const MaxPendingSwaps = 10000
+
+// Swap errors.
+var (
+	ErrTooManySwaps = errors.New("too many pending swaps")
+	ErrSwapNotFound = errors.New("swap not found")
+	ErrInvalidSwap  = errors.New("swap input is not owned by its owner")
+)
+
+// swapPool keeps swaps signed by one owner until the other owner signs.
+// A coin has one pending swap, a new swap signed by the owner
+// of the coin replaces the previous one.
+type swapPool struct {
+	mtx   sync.Mutex
+	swaps map[common.Hash]*transaction.Transaction
+	coins map[string]common.Hash
+}
+
+func newSwapPool() *swapPool {
+	return &swapPool{
+		swaps: make(map[common.Hash]*transaction.Transaction),
+		coins: make(map[string]common.Hash),
+	}
+}
+
+// add adds signatures of the swap to the pending swap with the same hash.
+// It returns the swap when it is signed by both owners.
+func (p *swapPool) add(tx *transaction.Transaction,
+	domain transaction.Domain) (*transaction.Transaction, error) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	hash := tx.Hash()
+
+	pending, ok := p.swaps[hash]
+	if !ok {
+		if len(p.swaps) >= MaxPendingSwaps {
+			return nil, ErrTooManySwaps
+		}
+		pending = tx
+	}
+
+	for _, sig := range tx.Signatures() {
+		if len(sig) == 0 {
+			continue
+		}
+
+		var err error
+		pending, err = pending.WithSignature(sig, domain)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if !pending.Complete() {
+		for i, sig := range pending.Signatures() {
+			if len(sig) == 0 {
+				continue
+			}
+
+			uid := pending.Inputs()[i].UID.String()
+			if prev, ok := p.coins[uid]; ok && prev != hash {
+				p.remove(prev)
+			}
+			p.coins[uid] = hash
+		}
+		p.swaps[hash] = pending
+		return nil, nil
+	}
+
+	p.remove(hash)
+	return pending, nil
+}
+
+// remove removes the pending swap with the hash.
+func (p *swapPool) remove(hash common.Hash) {
+	pending, ok := p.swaps[hash]
+	if !ok {
+		return
+	}
+
+	for _, in := range pending.Inputs() {
+		if p.coins[in.UID.String()] == hash {
+			delete(p.coins, in.UID.String())
+		}
+	}
+	delete(p.swaps, hash)
+}
+
+// reset removes all pending swaps.
+func (p *swapPool) reset() {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	p.swaps = make(map[common.Hash]*transaction.Transaction)
+	p.coins = make(map[string]common.Hash)
+}
+
+func (p *swapPool) get(hash common.Hash) (*transaction.Transaction, bool) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	tx, ok := p.swaps[hash]
+	return tx, ok
+}
+
+// acceptSwap adds signatures of the swap to the pending swap if owners
+// of the swap own its input coins. It returns the swap when it is signed
+// by both owners.
+func (s *Service) acceptSwap(
+	tx *transaction.Transaction) (*transaction.Transaction, error) {
+	owners := tx.Owners()
+	for i, in := range tx.Inputs() {
+		reason, err := s.checkInput(in, owners[i])
+		if err != nil {
+			return nil, err
+		}
+
+		if reason != "" {
+			return nil, errors.Wrapf(ErrInvalidSwap, "uid %s: %s",
+				in.UID, reason)
+		}
+	}
+	return s.swaps.add(tx, s.Domain())
+}
+
+// PendingSwap returns the swap with the hash which waits
+// for the signature of the second owner.
+func (s *Service) PendingSwap(
+	hash common.Hash) (*transaction.Transaction, bool) {
+	return s.swaps.get(hash)
+}
@@ -0,0 +1,113 @@
+package transport
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Errors.
+var (
+	ErrUnauthorized = errors.New("rpc: unauthorized")
+	ErrReadOnly     = errors.New("rpc: read-only server")
+)
+
+const (
+	authorizationHeader = "Authorization"
+	bearerPrefix        = "Bearer "
+)
+
+// adminMethods are methods which change the state of the operator,
+// they are available only to authenticated clients.
+var adminMethods = map[string]bool{
+	BuildBlockMethod:                 true,
+	SendBlockHashMethod:              true,
+	SaveBlockToDBMethod:              true,
+	InitBlockMethod:                  true,
+	SaveCurrentBlockMethod:           true,
+	BuildCheckpointMethod:            true,
+	SendCheckpointHashMethod:         true,
+	SaveCheckpointToDBMethod:         true,
+	InitCheckpointMethod:             true,
+	SaveCurrentCheckpointBlockMethod: true,
+}
+
+// IsAdminMethod returns true if the RPC method is available
+// only to authenticated clients.
+func IsAdminMethod(method string) bool {
+	return adminMethods[method]
+}
+
+// readMethods are methods which neither change the state of the operator
+// nor send transactions, a read-only server serves only them.
+var readMethods = map[string]bool{
+	CreateProofMethod:                true,
+	CreateUIDStateProofMethod:        true,
+	VerifyTxProofMethod:              true,
+	VerifyCheckpointProofMethod:      true,
+	CreateProofsMethod:               true,
+	CreateUIDStateProofsMethod:       true,
+	GetTransactionsBlocksMethod:      true,
+	PendingCodeAtMethod:              true,
+	PendingNonceAtMethod:             true,
+	SuggestGasPriceMethod:            true,
+	EstimateGasMethod:                true,
+	WaitMinedMethod:                  true,
+	ChallengeExistsMethod:            true,
+	CheckpointIsChallengeMethod:      true,
+	ChallengesLengthMethod:           true,
+	CheckpointChallengesLengthMethod: true,
+	GetChallengeMethod:               true,
+	GetCheckpointChallengeMethod:     true,
+	LastBlockNumberMethod:            true,
+	GetTransactionsBlockMethod:       true,
+	GetCheckpointsBlockMethod:        true,
+	GetCheckpointOptInMethod:         true,
+	ListCheckpointsMethod:            true,
+	LatestCheckpointForMethod:        true,
+	DepositCountMethod:               true,
+	ChallengePeriodMethod:            true,
+	OperatorMethod:                   true,
+	ChildChainMethod:                 true,
+	ExitsMethod:                      true,
+	WalletMethod:                     true,
+	Wallet2Method:                    true,
+	FeePolicyMethod:                  true,
+	BlockFeesMethod:                  true,
+	DomainMethod:                     true,
+}
+
+// IsReadMethod returns true if the RPC method is served
+// by a read-only server.
+func IsReadMethod(method string) bool {
+	return readMethods[method]
+}
+
+// authenticator checks bearer tokens of RPC connections.
+type authenticator struct {
+	tokens [][]byte
+}
+
+// authorized returns true if the HTTP CONNECT request
+// has a valid admin token.
+func (a *authenticator) authorized(req *http.Request) bool {
+	header := req.Header.Get(authorizationHeader)
+	if !strings.HasPrefix(header, bearerPrefix) {
+		return false
+	}
+	token := []byte(strings.TrimPrefix(header, bearerPrefix))
+
+	result := false
+	for _, t := range a.tokens {
+		if subtle.ConstantTimeCompare(t, token) == 1 {
+			result = true
+		}
+	}
+	return result
+}
+
+func bearer(token string) string {
+	return bearerPrefix + token
+}
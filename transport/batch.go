@@ -0,0 +1,185 @@
+package transport
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/SmartMeshFoundation/Spectrum/common"
+	"github.com/pkg/errors"
+
+	"github.com/SmartMeshFoundation/SmartPlasma/blockchan/block/transactions"
+	"github.com/SmartMeshFoundation/SmartPlasma/transport/handlers"
+)
+
+// DefaultBatchSize is the default number of items
+// in one batch request of the client.
+const DefaultBatchSize = 100
+
+// ErrWrongBatch is returned when a reply to a batch request
+// does not match the request.
+var ErrWrongBatch = errors.New("wrong number of items in the reply")
+
+// SetBatchSize sets the number of items in one batch request.
+// Larger requests are split into several calls.
+func (c *Client) SetBatchSize(size int) {
+	if size < 1 {
+		size = 1
+	}
+	c.batchSize = size
+}
+
+// chunk returns the end of the batch which starts at start.
+func (c *Client) chunk(start, length int) int {
+	end := start + c.batchSize
+	if end > length {
+		end = length
+	}
+	return end
+}
+
+// CreateProofs returns merkle proofs for pairs of UID and block number.
+// An element of errs is not nil if the proof of the pair was not created.
+func (c *Client) CreateProofs(
+	items []handlers.ProofItem) (proofs [][]byte, errs []error, err error) {
+	ctx, cancel := c.newContext()
+	defer cancel()
+
+	return c.CreateProofsContext(ctx, items)
+}
+
+// CreateProofsContext is like CreateProofs but takes a context.
+func (c *Client) CreateProofsContext(ctx context.Context,
+	items []handlers.ProofItem) (proofs [][]byte, errs []error, err error) {
+	proofs = make([][]byte, 0, len(items))
+	errs = make([]error, 0, len(items))
+
+	for start := 0; start < len(items); start = c.chunk(start, len(items)) {
+		end := c.chunk(start, len(items))
+
+		req := &handlers.CreateProofsReq{Items: items[start:end]}
+		var resp *handlers.CreateProofsResp
+		if err := c.call(ctx, CreateProofsMethod, req, &resp); err != nil {
+			return nil, nil, err
+		}
+
+		if resp.Error != "" {
+			return nil, nil, respError(resp.Error)
+		}
+
+		if len(resp.Proofs) != end-start || len(resp.Errors) != end-start {
+			return nil, nil, ErrWrongBatch
+		}
+
+		for i := range resp.Proofs {
+			proofs = append(proofs, resp.Proofs[i])
+			if resp.Errors[i] != "" {
+				errs = append(errs, respError(resp.Errors[i]))
+				continue
+			}
+			errs = append(errs, nil)
+		}
+	}
+	return proofs, errs, nil
+}
+
+// CreateUIDStateProofs returns merkle proofs and nonces
+// for UIDs in the checkpoint block.
+func (c *Client) CreateUIDStateProofs(uids []*big.Int,
+	checkpointHash common.Hash) (proofs [][]byte, nonces []*big.Int,
+	err error) {
+	ctx, cancel := c.newContext()
+	defer cancel()
+
+	return c.CreateUIDStateProofsContext(ctx, uids, checkpointHash)
+}
+
+// CreateUIDStateProofsContext is like CreateUIDStateProofs
+// but takes a context.
+func (c *Client) CreateUIDStateProofsContext(ctx context.Context,
+	uids []*big.Int, checkpointHash common.Hash) (proofs [][]byte,
+	nonces []*big.Int, err error) {
+	proofs = make([][]byte, 0, len(uids))
+	nonces = make([]*big.Int, 0, len(uids))
+
+	for start := 0; start < len(uids); start = c.chunk(start, len(uids)) {
+		end := c.chunk(start, len(uids))
+
+		req := &handlers.CreateUIDStateProofsReq{
+			UIDs:           uids[start:end],
+			CheckpointHash: checkpointHash,
+		}
+		var resp *handlers.CreateUIDStateProofsResp
+		if err := c.call(ctx, CreateUIDStateProofsMethod,
+			req, &resp); err != nil {
+			return nil, nil, err
+		}
+
+		if resp.Error != "" {
+			return nil, nil, respError(resp.Error)
+		}
+
+		if len(resp.Proofs) != end-start || len(resp.Nonces) != end-start {
+			return nil, nil, ErrWrongBatch
+		}
+
+		proofs = append(proofs, resp.Proofs...)
+		nonces = append(nonces, resp.Nonces...)
+	}
+	return proofs, nonces, nil
+}
+
+// GetTransactionsBlocks gets and builds transactions blocks
+// with numbers from `from` to `to` inclusive.
+func (c *Client) GetTransactionsBlocks(
+	from, to uint64) ([]transactions.TxBlock, error) {
+	ctx, cancel := c.newContext()
+	defer cancel()
+
+	return c.GetTransactionsBlocksContext(ctx, from, to)
+}
+
+// GetTransactionsBlocksContext is like GetTransactionsBlocks
+// but takes a context.
+func (c *Client) GetTransactionsBlocksContext(ctx context.Context,
+	from, to uint64) ([]transactions.TxBlock, error) {
+	if from > to {
+		return nil, nil
+	}
+
+	var blocks []transactions.TxBlock
+	for start := from; start <= to; {
+		end := start + uint64(c.batchSize) - 1
+		if end > to || end < start {
+			end = to
+		}
+
+		req := &handlers.GetTransactionsBlocksReq{From: start, To: end}
+		var resp *handlers.GetTransactionsBlocksResp
+		if err := c.call(ctx, GetTransactionsBlocksMethod,
+			req, &resp); err != nil {
+			return nil, err
+		}
+
+		if resp.Error != "" {
+			return nil, respError(resp.Error)
+		}
+
+		if uint64(len(resp.Blocks)) != end-start+1 {
+			return nil, ErrWrongBatch
+		}
+
+		for _, raw := range resp.Blocks {
+			bl, err := unmarshalTxBlock(raw)
+			if err != nil {
+				return nil, err
+			}
+			blocks = append(blocks, bl)
+		}
+
+		if end == to {
+			break
+		}
+		start = end + 1
+	}
+	return blocks, nil
+}
@@ -1,11 +1,11 @@
 package transport
 
 import (
+	"context"
 	"math/big"
 
 	"github.com/SmartMeshFoundation/Spectrum/common"
 	"github.com/SmartMeshFoundation/Spectrum/core/types"
-	"github.com/pkg/errors"
 
 	"github.com/SmartMeshFoundation/SmartPlasma/contract/rootchain"
 	"github.com/SmartMeshFoundation/SmartPlasma/transport/handlers"
@@ -17,6 +17,13 @@ func (c *Client) ChallengeExit(uid *big.Int, challengeTx,
 	ctx, cancel := c.newContext()
 	defer cancel()
 
+	return c.ChallengeExitContext(ctx, uid, challengeTx, proof, challengeBlockNum)
+}
+
+// ChallengeExitContext is like ChallengeExit but takes a context.
+func (c *Client) ChallengeExitContext(ctx context.Context, uid *big.Int,
+	challengeTx, proof []byte, challengeBlockNum *big.Int) (*types.Transaction,
+	error) {
 	if c.sessionRootChain != nil {
 		session := rootchain.CopySession(c.sessionRootChain)
 		session.TransactOpts.Context = ctx
@@ -42,18 +49,12 @@ func (c *Client) ChallengeExit(uid *big.Int, challengeTx,
 	}
 
 	var resp handlers.RawResp
-	call := c.connect.Go(ChallengeExitMethod, req, &resp, nil)
-	select {
-	case replay := <-call.Done:
-		if replay.Error != nil {
-			return nil, replay.Error
-		}
-	case <-ctx.Done():
-		return nil, errors.New("timeout")
+	if err := c.call(ctx, ChallengeExitMethod, req, &resp); err != nil {
+		return nil, err
 	}
 
 	if resp.Error != "" {
-		return nil, errors.New(resp.Error)
+		return nil, respError(resp.Error)
 	}
 
 	return tx, err
@@ -67,6 +68,14 @@ func (c *Client) ChallengeCheckpoint(uid *big.Int, checkpointRoot [32]byte,
 	ctx, cancel := c.newContext()
 	defer cancel()
 
+	return c.ChallengeCheckpointContext(ctx, uid, checkpointRoot, checkpointProof,
+		wrongNonce, lastTx, lastTxProof, lastTxBlockNum)
+}
+
+// ChallengeCheckpointContext is like ChallengeCheckpoint but takes a context.
+func (c *Client) ChallengeCheckpointContext(ctx context.Context, uid *big.Int,
+	checkpointRoot [32]byte, checkpointProof []byte, wrongNonce *big.Int, lastTx,
+	lastTxProof []byte, lastTxBlockNum *big.Int) (*types.Transaction, error) {
 	if c.sessionRootChain != nil {
 		session := rootchain.CopySession(c.sessionRootChain)
 		session.TransactOpts.Context = ctx
@@ -92,19 +101,12 @@ func (c *Client) ChallengeCheckpoint(uid *big.Int, checkpointRoot [32]byte,
 		RawTx: raw,
 	}
 	var resp handlers.RawResp
-	call := c.connect.Go(ChallengeCheckpointMethod, req, &resp, nil)
-
-	select {
-	case replay := <-call.Done:
-		if replay.Error != nil {
-			return nil, replay.Error
-		}
-	case <-ctx.Done():
-		return nil, errors.New("timeout")
+	if err := c.call(ctx, ChallengeCheckpointMethod, req, &resp); err != nil {
+		return nil, err
 	}
 
 	if resp.Error != "" {
-		return nil, errors.New(resp.Error)
+		return nil, respError(resp.Error)
 	}
 
 	return tx, err
@@ -117,6 +119,14 @@ func (c *Client) RespondChallengeExit(uid *big.Int, challengeTx, respondTx,
 	ctx, cancel := c.newContext()
 	defer cancel()
 
+	return c.RespondChallengeExitContext(ctx, uid, challengeTx, respondTx, proof,
+		blockNum)
+}
+
+// RespondChallengeExitContext is like RespondChallengeExit but takes a context.
+func (c *Client) RespondChallengeExitContext(ctx context.Context, uid *big.Int,
+	challengeTx, respondTx, proof []byte, blockNum *big.Int) (*types.Transaction,
+	error) {
 	if c.sessionRootChain != nil {
 		session := rootchain.CopySession(c.sessionRootChain)
 		session.TransactOpts.Context = ctx
@@ -142,18 +152,12 @@ func (c *Client) RespondChallengeExit(uid *big.Int, challengeTx, respondTx,
 		RawTx: raw,
 	}
 	var resp handlers.RawResp
-	call := c.connect.Go(RespondChallengeExitMethod, req, &resp, nil)
-	select {
-	case replay := <-call.Done:
-		if replay.Error != nil {
-			return nil, replay.Error
-		}
-	case <-ctx.Done():
-		return nil, errors.New("timeout")
+	if err := c.call(ctx, RespondChallengeExitMethod, req, &resp); err != nil {
+		return nil, err
 	}
 
 	if resp.Error != "" {
-		return nil, errors.New(resp.Error)
+		return nil, respError(resp.Error)
 	}
 
 	return tx, err
@@ -168,6 +172,16 @@ func (c *Client) RespondChallengeExitWithCheckpoint(uid *big.Int,
 	ctx, cancel := c.newContext()
 	defer cancel()
 
+	return c.RespondChallengeExitWithCheckpointContext(ctx, uid, challengeTx,
+		checkpointRoot, checkpointProof, checkpointNonce)
+}
+
+// RespondChallengeExitWithCheckpointContext is like
+// RespondChallengeExitWithCheckpoint but takes a context.
+func (c *Client) RespondChallengeExitWithCheckpointContext(ctx context.Context,
+	uid *big.Int, challengeTx []byte, checkpointRoot [32]byte,
+	checkpointProof []byte, checkpointNonce [32]byte) (*types.Transaction,
+	error) {
 	if c.sessionRootChain != nil {
 		session := rootchain.CopySession(c.sessionRootChain)
 		session.TransactOpts.Context = ctx
@@ -194,19 +208,13 @@ func (c *Client) RespondChallengeExitWithCheckpoint(uid *big.Int,
 	}
 
 	var resp handlers.RawResp
-	call := c.connect.Go(RespondChallengeExitWithCheckpointMethod,
-		req, &resp, nil)
-	select {
-	case replay := <-call.Done:
-		if replay.Error != nil {
-			return nil, replay.Error
-		}
-	case <-ctx.Done():
-		return nil, errors.New("timeout")
+	if err := c.call(ctx, RespondChallengeExitWithCheckpointMethod,
+		req, &resp); err != nil {
+		return nil, err
 	}
 
 	if resp.Error != "" {
-		return nil, errors.New(resp.Error)
+		return nil, respError(resp.Error)
 	}
 
 	return tx, err
@@ -220,6 +228,15 @@ func (c *Client) RespondCheckpointChallenge(uid *big.Int,
 	ctx, cancel := c.newContext()
 	defer cancel()
 
+	return c.RespondCheckpointChallengeContext(ctx, uid, checkpointRoot,
+		challengeTx, respondTx, proof, blockNum)
+}
+
+// RespondCheckpointChallengeContext is like RespondCheckpointChallenge
+// but takes a context.
+func (c *Client) RespondCheckpointChallengeContext(ctx context.Context,
+	uid *big.Int, checkpointRoot [32]byte, challengeTx []byte, respondTx []byte,
+	proof []byte, blockNum *big.Int) (*types.Transaction, error) {
 	if c.sessionRootChain != nil {
 		session := rootchain.CopySession(c.sessionRootChain)
 		session.TransactOpts.Context = ctx
@@ -246,19 +263,13 @@ func (c *Client) RespondCheckpointChallenge(uid *big.Int,
 	}
 
 	var resp handlers.RawResp
-	call := c.connect.Go(RespondCheckpointChallengeMethod, req, &resp, nil)
-
-	select {
-	case replay := <-call.Done:
-		if replay.Error != nil {
-			return nil, replay.Error
-		}
-	case <-ctx.Done():
-		return nil, errors.New("timeout")
+	if err := c.call(ctx, RespondCheckpointChallengeMethod,
+		req, &resp); err != nil {
+		return nil, err
 	}
 
 	if resp.Error != "" {
-		return nil, errors.New(resp.Error)
+		return nil, respError(resp.Error)
 	}
 
 	return tx, err
@@ -273,6 +284,17 @@ func (c *Client) RespondWithHistoricalCheckpoint(uid *big.Int,
 	ctx, cancel := c.newContext()
 	defer cancel()
 
+	return c.RespondWithHistoricalCheckpointContext(ctx, uid, checkpointRoot,
+		checkpointProof, historicalCheckpointRoot, historicalCheckpointProof,
+		challengeTx, moreNonce)
+}
+
+// RespondWithHistoricalCheckpointContext is like
+// RespondWithHistoricalCheckpoint but takes a context.
+func (c *Client) RespondWithHistoricalCheckpointContext(ctx context.Context,
+	uid *big.Int, checkpointRoot [32]byte, checkpointProof []byte,
+	historicalCheckpointRoot [32]byte, historicalCheckpointProof []byte,
+	challengeTx []byte, moreNonce *big.Int) (*types.Transaction, error) {
 	if c.sessionRootChain != nil {
 		session := rootchain.CopySession(c.sessionRootChain)
 		session.TransactOpts.Context = ctx
@@ -301,20 +323,13 @@ func (c *Client) RespondWithHistoricalCheckpoint(uid *big.Int,
 	}
 
 	var resp handlers.RawResp
-	call := c.connect.Go(
-		RespondWithHistoricalCheckpointMethod, req, &resp, nil)
-
-	select {
-	case replay := <-call.Done:
-		if replay.Error != nil {
-			return nil, replay.Error
-		}
-	case <-ctx.Done():
-		return nil, errors.New("timeout")
+	if err := c.call(ctx, RespondWithHistoricalCheckpointMethod,
+		req, &resp); err != nil {
+		return nil, err
 	}
 
 	if resp.Error != "" {
-		return nil, errors.New(resp.Error)
+		return nil, respError(resp.Error)
 	}
 
 	return tx, err
@@ -327,6 +342,12 @@ func (c *Client) ChallengeExists(
 	ctx, cancel := c.newContext()
 	defer cancel()
 
+	return c.ChallengeExistsContext(ctx, uid, challengeTx)
+}
+
+// ChallengeExistsContext is like ChallengeExists but takes a context.
+func (c *Client) ChallengeExistsContext(ctx context.Context, uid *big.Int,
+	challengeTx []byte) (exists bool, err error) {
 	if c.sessionRootChain != nil {
 		session := rootchain.CopySession(c.sessionRootChain)
 		session.TransactOpts.Context = ctx
@@ -337,22 +358,12 @@ func (c *Client) ChallengeExists(
 		ChallengeTx: challengeTx,
 	}
 	var resp *handlers.ChallengeExistsResp
-	call := c.connect.Go(ChallengeExistsMethod, req, &resp, nil)
-	if err != nil {
+	if err := c.call(ctx, ChallengeExistsMethod, req, &resp); err != nil {
 		return false, err
 	}
 
-	select {
-	case replay := <-call.Done:
-		if replay.Error != nil {
-			return false, replay.Error
-		}
-	case <-ctx.Done():
-		return false, errors.New("timeout")
-	}
-
 	if resp.Error != "" {
-		return false, errors.New(resp.Error)
+		return false, respError(resp.Error)
 	}
 
 	return resp.Exists, err
@@ -366,6 +377,13 @@ func (c *Client) CheckpointIsChallenge(
 	ctx, cancel := c.newContext()
 	defer cancel()
 
+	return c.CheckpointIsChallengeContext(ctx, uid, checkpoint, challengeTx)
+}
+
+// CheckpointIsChallengeContext is like CheckpointIsChallenge
+// but takes a context.
+func (c *Client) CheckpointIsChallengeContext(ctx context.Context, uid *big.Int,
+	checkpoint common.Hash, challengeTx []byte) (exists bool, err error) {
 	if c.sessionRootChain != nil {
 		session := rootchain.CopySession(c.sessionRootChain)
 		session.TransactOpts.Context = ctx
@@ -378,19 +396,12 @@ func (c *Client) CheckpointIsChallenge(
 		ChallengeTx: challengeTx,
 	}
 	var resp *handlers.CheckpointIsChallengeResp
-	call := c.connect.Go(CheckpointIsChallengeMethod, req, &resp, nil)
-
-	select {
-	case replay := <-call.Done:
-		if replay.Error != nil {
-			return false, replay.Error
-		}
-	case <-ctx.Done():
-		return false, errors.New("timeout")
+	if err := c.call(ctx, CheckpointIsChallengeMethod, req, &resp); err != nil {
+		return false, err
 	}
 
 	if resp.Error != "" {
-		return false, errors.New(resp.Error)
+		return false, respError(resp.Error)
 	}
 
 	return resp.Exists, err
@@ -401,6 +412,12 @@ func (c *Client) ChallengesLength(uid *big.Int) (length *big.Int, err error) {
 	ctx, cancel := c.newContext()
 	defer cancel()
 
+	return c.ChallengesLengthContext(ctx, uid)
+}
+
+// ChallengesLengthContext is like ChallengesLength but takes a context.
+func (c *Client) ChallengesLengthContext(ctx context.Context,
+	uid *big.Int) (length *big.Int, err error) {
 	if c.sessionRootChain != nil {
 		session := rootchain.CopySession(c.sessionRootChain)
 		session.TransactOpts.Context = ctx
@@ -410,19 +427,12 @@ func (c *Client) ChallengesLength(uid *big.Int) (length *big.Int, err error) {
 		UID: uid,
 	}
 	var resp *handlers.ChallengesLengthResp
-	call := c.connect.Go(ChallengesLengthMethod, req, &resp, nil)
-
-	select {
-	case replay := <-call.Done:
-		if replay.Error != nil {
-			return nil, replay.Error
-		}
-	case <-ctx.Done():
-		return nil, errors.New("timeout")
+	if err := c.call(ctx, ChallengesLengthMethod, req, &resp); err != nil {
+		return nil, err
 	}
 
 	if resp.Error != "" {
-		return nil, errors.New(resp.Error)
+		return nil, respError(resp.Error)
 	}
 
 	return resp.Length, err
@@ -435,6 +445,13 @@ func (c *Client) CheckpointChallengesLength(
 	ctx, cancel := c.newContext()
 	defer cancel()
 
+	return c.CheckpointChallengesLengthContext(ctx, uid, checkpoint)
+}
+
+// CheckpointChallengesLengthContext is like CheckpointChallengesLength
+// but takes a context.
+func (c *Client) CheckpointChallengesLengthContext(ctx context.Context,
+	uid *big.Int, checkpoint common.Hash) (length *big.Int, err error) {
 	if c.sessionRootChain != nil {
 		session := rootchain.CopySession(c.sessionRootChain)
 		session.TransactOpts.Context = ctx
@@ -445,22 +462,13 @@ func (c *Client) CheckpointChallengesLength(
 		Checkpoint: checkpoint,
 	}
 	var resp *handlers.CheckpointChallengesLengthResp
-	call := c.connect.Go(CheckpointChallengesLengthMethod, req, &resp, nil)
-	if err != nil {
+	if err := c.call(ctx, CheckpointChallengesLengthMethod,
+		req, &resp); err != nil {
 		return nil, err
 	}
 
-	select {
-	case replay := <-call.Done:
-		if replay.Error != nil {
-			return nil, replay.Error
-		}
-	case <-ctx.Done():
-		return nil, errors.New("timeout")
-	}
-
 	if resp.Error != "" {
-		return nil, errors.New(resp.Error)
+		return nil, respError(resp.Error)
 	}
 
 	return resp.Length, err
@@ -471,6 +479,13 @@ func (c *Client) GetChallenge(
 	uid, index *big.Int) (resp *handlers.GetChallengeResp, err error) {
 	ctx, cancel := c.newContext()
 	defer cancel()
+
+	return c.GetChallengeContext(ctx, uid, index)
+}
+
+// GetChallengeContext is like GetChallenge but takes a context.
+func (c *Client) GetChallengeContext(ctx context.Context, uid,
+	index *big.Int) (resp *handlers.GetChallengeResp, err error) {
 	if c.sessionRootChain != nil {
 		session := rootchain.CopySession(c.sessionRootChain)
 		session.TransactOpts.Context = ctx
@@ -488,19 +503,12 @@ func (c *Client) GetChallenge(
 		UID:   uid,
 		Index: index,
 	}
-	call := c.connect.Go(GetChallengeMethod, req, &resp, nil)
-
-	select {
-	case replay := <-call.Done:
-		if replay.Error != nil {
-			return nil, replay.Error
-		}
-	case <-ctx.Done():
-		return nil, errors.New("timeout")
+	if err := c.call(ctx, GetChallengeMethod, req, &resp); err != nil {
+		return nil, err
 	}
 
 	if resp.Error != "" {
-		return nil, errors.New(resp.Error)
+		return nil, respError(resp.Error)
 	}
 
 	return resp, err
@@ -513,6 +521,14 @@ func (c *Client) GetCheckpointChallenge(uid *big.Int, checkpoint common.Hash,
 	ctx, cancel := c.newContext()
 	defer cancel()
 
+	return c.GetCheckpointChallengeContext(ctx, uid, checkpoint, index)
+}
+
+// GetCheckpointChallengeContext is like GetCheckpointChallenge
+// but takes a context.
+func (c *Client) GetCheckpointChallengeContext(ctx context.Context,
+	uid *big.Int, checkpoint common.Hash,
+	index *big.Int) (resp *handlers.GetCheckpointChallengeResp, err error) {
 	if c.sessionRootChain != nil {
 		session := rootchain.CopySession(c.sessionRootChain)
 		session.TransactOpts.Context = ctx
@@ -532,19 +548,12 @@ func (c *Client) GetCheckpointChallenge(uid *big.Int, checkpoint common.Hash,
 		Checkpoint: checkpoint,
 		Index:      index,
 	}
-	call := c.connect.Go(GetCheckpointChallengeMethod, req, &resp, nil)
-
-	select {
-	case replay := <-call.Done:
-		if replay.Error != nil {
-			return nil, replay.Error
-		}
-	case <-ctx.Done():
-		return nil, errors.New("timeout")
+	if err := c.call(ctx, GetCheckpointChallengeMethod, req, &resp); err != nil {
+		return nil, err
 	}
 
 	if resp.Error != "" {
-		return nil, errors.New(resp.Error)
+		return nil, respError(resp.Error)
 	}
 
 	return resp, err
@@ -1,12 +1,17 @@
 package transport
 
 import (
+	"context"
+	"math/big"
+
 	"github.com/SmartMeshFoundation/Spectrum/common"
 	"github.com/SmartMeshFoundation/Spectrum/core/types"
-	"github.com/pkg/errors"
+	"github.com/SmartMeshFoundation/Spectrum/rlp"
 
 	"github.com/SmartMeshFoundation/SmartPlasma/blockchan/block/checkpoints"
+	"github.com/SmartMeshFoundation/SmartPlasma/blockchan/transaction"
 	"github.com/SmartMeshFoundation/SmartPlasma/contract/rootchain"
+	"github.com/SmartMeshFoundation/SmartPlasma/service"
 	"github.com/SmartMeshFoundation/SmartPlasma/transport/handlers"
 )
 
@@ -15,21 +20,20 @@ func (c *Client) BuildCheckpoint() (hash common.Hash, err error) {
 	ctx, cancel := c.newContext()
 	defer cancel()
 
+	return c.BuildCheckpointContext(ctx)
+}
+
+// BuildCheckpointContext is like BuildCheckpoint but takes a context.
+func (c *Client) BuildCheckpointContext(ctx context.Context) (hash common.Hash,
+	err error) {
 	req := &handlers.BuildCheckpointReq{}
 	var resp *handlers.BuildCheckpointResp
-	call := c.connect.Go(BuildCheckpointMethod, req, &resp, nil)
-
-	select {
-	case replay := <-call.Done:
-		if replay.Error != nil {
-			return common.Hash{}, replay.Error
-		}
-	case <-ctx.Done():
-		return common.Hash{}, errors.New("timeout")
+	if err := c.call(ctx, BuildCheckpointMethod, req, &resp); err != nil {
+		return common.Hash{}, err
 	}
 
 	if resp.Error != "" {
-		return common.Hash{}, errors.New(resp.Error)
+		return common.Hash{}, respError(resp.Error)
 	}
 	return resp.Hash, err
 }
@@ -40,6 +44,12 @@ func (c *Client) SendCheckpointHash(hash common.Hash) (tx *types.Transaction,
 	ctx, cancel := c.newContext()
 	defer cancel()
 
+	return c.SendCheckpointHashContext(ctx, hash)
+}
+
+// SendCheckpointHashContext is like SendCheckpointHash but takes a context.
+func (c *Client) SendCheckpointHashContext(ctx context.Context,
+	hash common.Hash) (tx *types.Transaction, err error) {
 	if c.sessionRootChain != nil {
 		session := rootchain.CopySession(c.sessionRootChain)
 		session.TransactOpts.Context = ctx
@@ -48,19 +58,12 @@ func (c *Client) SendCheckpointHash(hash common.Hash) (tx *types.Transaction,
 
 	req := &handlers.SendCheckpointHashReq{Hash: hash}
 	var resp *handlers.SendCheckpointHashResp
-	call := c.connect.Go(SendCheckpointHashMethod, req, &resp, nil)
-
-	select {
-	case replay := <-call.Done:
-		if replay.Error != nil {
-			return nil, replay.Error
-		}
-	case <-ctx.Done():
-		return nil, errors.New("timeout")
+	if err := c.call(ctx, SendCheckpointHashMethod, req, &resp); err != nil {
+		return nil, err
 	}
 
 	if resp.Error != "" {
-		return nil, errors.New(resp.Error)
+		return nil, respError(resp.Error)
 	}
 
 	tx = &types.Transaction{}
@@ -77,21 +80,20 @@ func (c *Client) CurrentCheckpoint() (checkpoint []byte, err error) {
 	ctx, cancel := c.newContext()
 	defer cancel()
 
+	return c.CurrentCheckpointContext(ctx)
+}
+
+// CurrentCheckpointContext is like CurrentCheckpoint but takes a context.
+func (c *Client) CurrentCheckpointContext(
+	ctx context.Context) (checkpoint []byte, err error) {
 	req := &handlers.CurrentCheckpointReq{}
 	var resp *handlers.CurrentCheckpointResp
-	call := c.connect.Go(CurrentCheckpointMethod, req, &resp, nil)
-
-	select {
-	case replay := <-call.Done:
-		if replay.Error != nil {
-			return nil, replay.Error
-		}
-	case <-ctx.Done():
-		return nil, errors.New("timeout")
+	if err := c.call(ctx, CurrentCheckpointMethod, req, &resp); err != nil {
+		return nil, err
 	}
 
 	if resp.Error != "" {
-		return nil, errors.New(resp.Error)
+		return nil, respError(resp.Error)
 	}
 
 	return resp.Checkpoint, err
@@ -102,23 +104,22 @@ func (c *Client) SaveCheckpointToDB(raw []byte) error {
 	ctx, cancel := c.newContext()
 	defer cancel()
 
+	return c.SaveCheckpointToDBContext(ctx, raw)
+}
+
+// SaveCheckpointToDBContext is like SaveCheckpointToDB but takes a context.
+func (c *Client) SaveCheckpointToDBContext(ctx context.Context,
+	raw []byte) error {
 	req := &handlers.SaveCheckpointToDBReq{
 		Block: raw,
 	}
 	var resp *handlers.SaveCheckpointToDBResp
-	call := c.connect.Go(SaveCheckpointToDBMethod, req, &resp, nil)
-
-	select {
-	case replay := <-call.Done:
-		if replay.Error != nil {
-			return replay.Error
-		}
-	case <-ctx.Done():
-		return errors.New("timeout")
+	if err := c.call(ctx, SaveCheckpointToDBMethod, req, &resp); err != nil {
+		return err
 	}
 
 	if resp.Error != "" {
-		return errors.New(resp.Error)
+		return respError(resp.Error)
 	}
 	return nil
 }
@@ -128,21 +129,19 @@ func (c *Client) InitCheckpoint() error {
 	ctx, cancel := c.newContext()
 	defer cancel()
 
+	return c.InitCheckpointContext(ctx)
+}
+
+// InitCheckpointContext is like InitCheckpoint but takes a context.
+func (c *Client) InitCheckpointContext(ctx context.Context) error {
 	req := &handlers.InitCheckpointReq{}
 	var resp *handlers.InitCheckpointResp
-	call := c.connect.Go(InitCheckpointMethod, req, &resp, nil)
-
-	select {
-	case replay := <-call.Done:
-		if replay.Error != nil {
-			return replay.Error
-		}
-	case <-ctx.Done():
-		return errors.New("timeout")
+	if err := c.call(ctx, InitCheckpointMethod, req, &resp); err != nil {
+		return err
 	}
 
 	if resp.Error != "" {
-		return errors.New(resp.Error)
+		return respError(resp.Error)
 	}
 
 	return nil
@@ -154,22 +153,22 @@ func (c *Client) SaveCurrentCheckpointBlock() error {
 	ctx, cancel := c.newContext()
 	defer cancel()
 
+	return c.SaveCurrentCheckpointBlockContext(ctx)
+}
+
+// SaveCurrentCheckpointBlockContext is like SaveCurrentCheckpointBlock
+// but takes a context.
+func (c *Client) SaveCurrentCheckpointBlockContext(ctx context.Context) error {
 	req := &handlers.SaveCurrentCheckpointBlockReq{}
 
 	var resp *handlers.SaveCurrentCheckpointBlockResp
-	call := c.connect.Go(SaveCurrentCheckpointBlockMethod, req, &resp, nil)
-
-	select {
-	case replay := <-call.Done:
-		if replay.Error != nil {
-			return replay.Error
-		}
-	case <-ctx.Done():
-		return errors.New("timeout")
+	if err := c.call(ctx, SaveCurrentCheckpointBlockMethod,
+		req, &resp); err != nil {
+		return err
 	}
 
 	if resp.Error != "" {
-		return errors.New(resp.Error)
+		return respError(resp.Error)
 	}
 	return nil
 }
@@ -180,24 +179,23 @@ func (c *Client) GetCheckpointsBlock(
 	ctx, cancel := c.newContext()
 	defer cancel()
 
+	return c.GetCheckpointsBlockContext(ctx, hash)
+}
+
+// GetCheckpointsBlockContext is like GetCheckpointsBlock but takes a context.
+func (c *Client) GetCheckpointsBlockContext(ctx context.Context,
+	hash common.Hash) (checkpoints.CheckpointBlock, error) {
 	req := &handlers.GetCheckpointsBlockReq{
 		Hash: hash,
 	}
 
 	var resp *handlers.GetCheckpointsBlockResp
-	call := c.connect.Go(GetCheckpointsBlockMethod, req, &resp, nil)
-
-	select {
-	case replay := <-call.Done:
-		if replay.Error != nil {
-			return nil, replay.Error
-		}
-	case <-ctx.Done():
-		return nil, errors.New("timeout")
+	if err := c.call(ctx, GetCheckpointsBlockMethod, req, &resp); err != nil {
+		return nil, err
 	}
 
 	if resp.Error != "" {
-		return nil, errors.New(resp.Error)
+		return nil, respError(resp.Error)
 	}
 
 	bl := checkpoints.NewBlock()
@@ -212,3 +210,84 @@ func (c *Client) GetCheckpointsBlock(
 
 	return bl, nil
 }
+
+// CheckpointOptIn returns the opt-in of the owner of UID saved
+// with checkpoints block. It proves that the owner consented
+// to the checkpoint.
+func (c *Client) CheckpointOptIn(hash common.Hash,
+	uid *big.Int) (*transaction.OptIn, error) {
+	ctx, cancel := c.newContext()
+	defer cancel()
+
+	return c.CheckpointOptInContext(ctx, hash, uid)
+}
+
+// CheckpointOptInContext is like CheckpointOptIn but takes a context.
+func (c *Client) CheckpointOptInContext(ctx context.Context, hash common.Hash,
+	uid *big.Int) (*transaction.OptIn, error) {
+	req := &handlers.GetCheckpointOptInReq{Hash: hash, UID: uid}
+	var resp *handlers.GetCheckpointOptInResp
+	if err := c.call(ctx, GetCheckpointOptInMethod, req, &resp); err != nil {
+		return nil, err
+	}
+
+	if resp.Error != "" {
+		return nil, respError(resp.Error)
+	}
+
+	optIn := &transaction.OptIn{}
+	if err := rlp.DecodeBytes(resp.OptIn, optIn); err != nil {
+		return nil, err
+	}
+	return optIn, nil
+}
+
+// ListCheckpoints returns at most limit records of the checkpoint registry
+// starting with the index from, the oldest checkpoint has the index 1.
+func (c *Client) ListCheckpoints(from uint64,
+	limit int) ([]*service.CheckpointInfo, error) {
+	ctx, cancel := c.newContext()
+	defer cancel()
+
+	return c.ListCheckpointsContext(ctx, from, limit)
+}
+
+// ListCheckpointsContext is like ListCheckpoints but takes a context.
+func (c *Client) ListCheckpointsContext(ctx context.Context, from uint64,
+	limit int) ([]*service.CheckpointInfo, error) {
+	req := &handlers.ListCheckpointsReq{From: from, Limit: limit}
+	var resp *handlers.ListCheckpointsResp
+	if err := c.call(ctx, ListCheckpointsMethod, req, &resp); err != nil {
+		return nil, err
+	}
+
+	if resp.Error != "" {
+		return nil, respError(resp.Error)
+	}
+	return resp.Checkpoints, nil
+}
+
+// LatestCheckpointFor returns the record of the latest registered
+// checkpoint which fixes UID.
+func (c *Client) LatestCheckpointFor(
+	uid *big.Int) (*service.CheckpointInfo, error) {
+	ctx, cancel := c.newContext()
+	defer cancel()
+
+	return c.LatestCheckpointForContext(ctx, uid)
+}
+
+// LatestCheckpointForContext is like LatestCheckpointFor but takes a context.
+func (c *Client) LatestCheckpointForContext(ctx context.Context,
+	uid *big.Int) (*service.CheckpointInfo, error) {
+	req := &handlers.LatestCheckpointForReq{UID: uid}
+	var resp *handlers.LatestCheckpointForResp
+	if err := c.call(ctx, LatestCheckpointForMethod, req, &resp); err != nil {
+		return nil, err
+	}
+
+	if resp.Error != "" {
+		return nil, respError(resp.Error)
+	}
+	return resp.Checkpoint, nil
+}
@@ -1,9 +1,15 @@
 package transport
 
 import (
+	"bufio"
 	"context"
+	"crypto/tls"
 	"fmt"
+	"io"
+	"net"
+	"net/http"
 	"net/rpc"
+	"sync"
 	"time"
 
 	"github.com/SmartMeshFoundation/Spectrum/accounts/abi/bind"
@@ -15,20 +21,53 @@ import (
 	"github.com/SmartMeshFoundation/SmartPlasma/contract/build"
 	"github.com/SmartMeshFoundation/SmartPlasma/contract/mediator"
 	"github.com/SmartMeshFoundation/SmartPlasma/contract/rootchain"
+	"github.com/SmartMeshFoundation/SmartPlasma/service"
+	"github.com/SmartMeshFoundation/SmartPlasma/transport/handlers"
 )
 
 const (
 	tcpProtocol = "tcp"
+
+	// minBackoff and maxBackoff are the limits of the delay
+	// between attempts to reconnect.
+	minBackoff = 100 * time.Millisecond
+	maxBackoff = 10 * time.Second
 )
 
 // Errors.
 var (
-	ErrTransactor = errors.New("transactor is missing")
+	ErrTransactor   = errors.New("transactor is missing")
+	ErrNotConnected = errors.New("client is not connected")
+	ErrInterrupted  = errors.New(
+		"connection dropped after the request was sent")
 )
 
+// serverErrors are errors which are restored from their messages
+// in replies of the server.
+var serverErrors = []error{
+	ErrUnauthorized,
+	ErrReadOnly,
+	ErrRateLimited,
+	ErrRequestTooLarge,
+	ErrBusy,
+	context.DeadlineExceeded,
+	context.Canceled,
+	service.ErrNotCoinOwner,
+	service.ErrOptInNotFound,
+	service.ErrCoinSpent,
+	service.ErrCheckpointNotFound,
+	service.ErrSplitsDisabled,
+	service.ErrSwapsDisabled,
+}
+
 // Client is RPC client for PlasmaCash.
 type Client struct {
+	mtx              sync.Mutex
+	sending          sync.Mutex
 	connect          *rpc.Client
+	codec            *clientCodec
+	address          string
+	closed           bool
 	backend          backend.Backend
 	sessionMediator  *mediator.MediatorSession
 	sessionRootChain *rootchain.RootChainSession
@@ -36,14 +75,18 @@ type Client struct {
 	timeout          uint64 // in seconds
 	med              *build.Contract
 	root             *build.Contract
+	token            string
+	tls              *tls.Config
+	batchSize        int
 }
 
 // NewClient creates new PlasmaCash client.
 // The Client must initialize RemoteEthereumClient or DirectEthereumClient.
 func NewClient(timeout uint64, opts *account.PlasmaTransactOpts) *Client {
 	return &Client{
-		timeout: timeout,
-		opts:    opts,
+		timeout:   timeout,
+		opts:      opts,
+		batchSize: DefaultBatchSize,
 	}
 }
 
@@ -76,32 +119,218 @@ func (c *Client) DirectEthereumClient(opts bind.TransactOpts,
 	c.backend = backend
 }
 
+// SetAuthToken sets the bearer token which allows to call admin methods.
+// It must be called before Connect.
+func (c *Client) SetAuthToken(token string) {
+	c.token = token
+}
+
+// SetTLSConfig enables TLS for connections to the RPC server.
+// It must be called before Connect.
+func (c *Client) SetTLSConfig(config *tls.Config) {
+	c.tls = config
+}
+
 // Connect tries to connect to a PlasmaCash RPC server.
 func (c *Client) Connect(address string, port uint16) error {
-	client, err := rpc.DialHTTP(tcpProtocol,
-		fmt.Sprintf("%s:%d", address, port))
+	return c.ConnectString(fmt.Sprintf("%s:%d", address, port))
+}
+
+// ConnectString tries to connect to a PlasmaCash RPC server.
+// If the connection drops, it is restored by the next call.
+func (c *Client) ConnectString(str string) error {
+	ctx, cancel := c.newContext()
+	defer cancel()
+
+	client, codec, err := c.dialHTTP(ctx, str)
 	if err != nil {
 		return err
 	}
 
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.address = str
+	c.closed = false
 	c.connect = client
+	c.codec = codec
 	return nil
 }
 
-// ConnectString tries to connect to a PlasmaCash RPC server.
-func (c *Client) ConnectString(str string) error {
-	client, err := rpc.DialHTTP(tcpProtocol, str)
+// dialHTTP connects to an HTTP RPC server the same way as rpc.DialHTTP
+// does, but uses TLS and sends the bearer token if they are set.
+func (c *Client) dialHTTP(ctx context.Context,
+	address string) (*rpc.Client, *clientCodec, error) {
+	var conn net.Conn
+	var err error
+	if c.tls != nil {
+		// tls.DialWithDialer does not take the context, the dialer
+		// stops on its deadline or when the context is done
+		dialer := &net.Dialer{Cancel: ctx.Done()}
+		if deadline, ok := ctx.Deadline(); ok {
+			dialer.Deadline = deadline
+		}
+		conn, err = tls.DialWithDialer(dialer, tcpProtocol, address, c.tls)
+	} else {
+		dialer := &net.Dialer{}
+		conn, err = dialer.DialContext(ctx, tcpProtocol, address)
+	}
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 
-	c.connect = client
-	return nil
+	req := "CONNECT " + rpc.DefaultRPCPath + " HTTP/1.0\n"
+	if c.token != "" {
+		req += authorizationHeader + ": " + bearer(c.token) + "\n"
+	}
+	io.WriteString(conn, req+"\n")
+
+	resp, err := http.ReadResponse(bufio.NewReader(conn),
+		&http.Request{Method: http.MethodConnect})
+	if err == nil && resp.Status == connected {
+		codec := newClientCodec(conn)
+		return rpc.NewClientWithCodec(codec), codec, nil
+	}
+	if err == nil {
+		err = errors.New("unexpected HTTP response: " + resp.Status)
+	}
+	conn.Close()
+
+	return nil, nil, &net.OpError{
+		Op:   "dial-http",
+		Net:  tcpProtocol + " " + address,
+		Addr: nil,
+		Err:  err,
+	}
+}
+
+// connection returns the connection to the server. If the connection
+// dropped, it tries to reconnect with exponential backoff
+// until the context is done.
+func (c *Client) connection(
+	ctx context.Context) (*rpc.Client, *clientCodec, error) {
+	backoff := minBackoff
+	for {
+		c.mtx.Lock()
+		client, codec := c.connect, c.codec
+		closed, address := c.closed, c.address
+		c.mtx.Unlock()
+
+		switch {
+		case closed:
+			return nil, nil, rpc.ErrShutdown
+		case client != nil:
+			return client, codec, nil
+		case address == "":
+			return nil, nil, ErrNotConnected
+		}
+
+		client, codec, err := c.dialHTTP(ctx, address)
+		if err == nil {
+			c.mtx.Lock()
+			if c.connect == nil && !c.closed {
+				c.connect, c.codec = client, codec
+				c.mtx.Unlock()
+				return client, codec, nil
+			}
+			c.mtx.Unlock()
+
+			client.Close()
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// drop forgets the broken connection, the next call reconnects.
+func (c *Client) drop(client *rpc.Client) {
+	c.mtx.Lock()
+	if c.connect == client {
+		c.connect = nil
+		c.codec = nil
+	}
+	c.mtx.Unlock()
+
+	client.Close()
+}
+
+// call invokes the RPC method and waits for the reply. If the context
+// is done first, the server is asked to cancel the call and the error
+// of the context is returned. A request which was not sent because
+// the connection dropped is sent again after reconnection. A request
+// which was sent may have been executed by the server, so it is not sent
+// again and ErrInterrupted is returned.
+func (c *Client) call(ctx context.Context, method string,
+	req, resp interface{}) error {
+	for {
+		client, codec, err := c.connection(ctx)
+		if err != nil {
+			return err
+		}
+
+		// the request is written by Go, so its sequence number
+		// is known when Go returns.
+		c.sending.Lock()
+		codec.reset()
+		call := client.Go(method, req, resp, make(chan *rpc.Call, 1))
+		seq, sent := codec.lastSeq()
+		c.sending.Unlock()
+
+		select {
+		case <-call.Done:
+		case <-ctx.Done():
+			if sent {
+				c.cancel(client, seq)
+			}
+			return ctx.Err()
+		}
+
+		switch call.Error {
+		case nil:
+			return nil
+		case rpc.ErrShutdown, io.EOF, io.ErrUnexpectedEOF:
+			c.drop(client)
+			if !sent {
+				continue
+			}
+			return ErrInterrupted
+		}
+		return serverError(call.Error)
+	}
+}
+
+// cancel asks the server to cancel the call with the sequence number.
+func (c *Client) cancel(client *rpc.Client, seq uint64) {
+	c.sending.Lock()
+	defer c.sending.Unlock()
+
+	client.Go(CancelMethod, &handlers.CancelReq{Seq: seq},
+		&handlers.CancelResp{}, make(chan *rpc.Call, 1))
 }
 
 // Close closes connection to PlasmaCash RPC server.
 func (c *Client) Close() error {
-	return c.connect.Close()
+	c.mtx.Lock()
+	client := c.connect
+	c.closed = true
+	c.connect = nil
+	c.codec = nil
+	c.mtx.Unlock()
+
+	if client == nil {
+		return nil
+	}
+	return client.Close()
 }
 
 func (c *Client) newContext() (context.Context, context.CancelFunc) {
@@ -113,3 +342,22 @@ func (c *Client) newContext() (context.Context, context.CancelFunc) {
 func (c *Client) Opts() *account.PlasmaTransactOpts {
 	return c.opts
 }
+
+// serverError restores a known error from the error of a call.
+func serverError(err error) error {
+	if _, ok := err.(rpc.ServerError); !ok {
+		return err
+	}
+	return respError(err.Error())
+}
+
+// respError creates an error from the error message of a reply.
+// Known errors are restored, so they can be compared.
+func respError(msg string) error {
+	for _, err := range serverErrors {
+		if msg == err.Error() {
+			return err
+		}
+	}
+	return errors.New(msg)
+}
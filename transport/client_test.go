@@ -299,12 +299,13 @@ func testTx(t *testing.T, prevBlock, uid,
 		t.Fatal(err)
 	}
 
-	tx, err := signer.PlasmaSigner(signer.From, unsignedTx)
+	tx, err := signer.PlasmaSigner(signer.From, unsignedTx,
+		transaction.LegacyDomain)
 	if err != nil {
 		t.Fatalf("failed to sign transaction %s", err)
 	}
 
-	addr, err := transaction.Sender(tx)
+	addr, err := transaction.Sender(tx, transaction.LegacyDomain)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -544,27 +545,65 @@ func TestCreateProof(t *testing.T) {
 	testCreateProof(t, false)
 }
 
-func testAddCheckpoint(t *testing.T, direct bool) {
-	s := newTestService(t, 1)
+func testOptInCheckpoint(t *testing.T, direct bool) {
+	s := newTestService(t, 2)
 	defer s.Close()
 
 	cli := testClient(t, s, direct, s.accounts[0])
 	defer cli.Close()
 
+	cli1 := testClient(t, s, direct, s.accounts[1])
+	defer cli1.Close()
+
 	tx1 := testTx(t, zero, one, one, four, s.accounts[0].From, s.accounts[0])
 	objects1, _ := addTx(t,
 		one, []*transaction.Transaction{tx1}, nil, cli, false)
 	tx1Obj := objects1[tx1.UID().String()]
 
-	err := cli.AddCheckpoint(one, four, tx1Obj.block)
+	err := cli1.OptInCheckpoint(one, four, tx1Obj.block)
+	if err != service.ErrNotCoinOwner {
+		t.Fatalf("expected error %s, got %v", service.ErrNotCoinOwner, err)
+	}
+
+	err = cli.OptInCheckpoint(one, four, tx1Obj.block)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hash, err := s.service.BuildCheckpoint()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.service.SaveCheckpointToDB(s.service.CurrentCheckpoint())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	optIn, err := cli.CheckpointOptIn(hash, one)
 	if err != nil {
 		t.Fatal(err)
 	}
+
+	signer, err := optIn.Signer(transaction.LegacyDomain)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if signer != s.accounts[0].From || optIn.Nonce.Cmp(four) != 0 ||
+		optIn.Block != tx1Obj.block {
+		t.Fatal("wrong opt-in")
+	}
+
+	_, err = cli.CheckpointOptIn(hash, two)
+	if err != service.ErrOptInNotFound {
+		t.Fatalf("expected error %s, got %v", service.ErrOptInNotFound, err)
+	}
 }
 
-func TestAddCheckpoint(t *testing.T) {
-	testAddCheckpoint(t, true)
-	testAddCheckpoint(t, false)
+func TestOptInCheckpoint(t *testing.T) {
+	testOptInCheckpoint(t, true)
+	testOptInCheckpoint(t, false)
 }
 
 func testCreateUIDStateProof(t *testing.T, direct bool) {
@@ -1089,7 +1128,9 @@ func TestRespondChallengeExitWithCheckpoint(t *testing.T) {
 	tx4Obj := objects4[tx4.UID().String()]
 	tx5Obj := objects5[tx5.UID().String()]
 
-	err := cli.AddCheckpoint(uid, tx3.Nonce(), tx3Obj.block)
+	// the operator checkpoints the historical state of the coin,
+	// the owner can not opt in a state spent by later blocks
+	err := s.service.AcceptUIDState(uid, tx3.Nonce(), tx3Obj.block)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -1217,7 +1258,8 @@ func TestCheckpointChallenge(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	err = cli.AddCheckpoint(uid, badNonce, badBlockNum.Uint64())
+	// the operator checkpoints the state without consent of the owner
+	err = s.service.AcceptUIDState(uid, badNonce, badBlockNum.Uint64())
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -1342,6 +1384,9 @@ func TestRespondWithHistoricalCheckpoint(t *testing.T) {
 	cli := testClient(t, s, true, owner)
 	defer cli.Close()
 
+	cli2 := testClient(t, s, true, u2)
+	defer cli2.Close()
+
 	uid := deposit(t, s, cli, one)
 
 	tx1 := testTx(t, zero, uid, one, one, u1.From, owner)
@@ -1359,7 +1404,9 @@ func TestRespondWithHistoricalCheckpoint(t *testing.T) {
 	tx2Obj := objects2[tx2.UID().String()]
 	tx3Obj := objects3[tx3.UID().String()]
 
-	err := cli.AddCheckpoint(uid, tx2.Nonce(), tx2Obj.block)
+	// the operator checkpoints the historical state of the coin,
+	// the owner can not opt in a state spent by later blocks
+	err := s.service.AcceptUIDState(uid, tx2.Nonce(), tx2Obj.block)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -1432,7 +1479,7 @@ func TestRespondWithHistoricalCheckpoint(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	err = cli.AddCheckpoint(uid, tx3.Nonce(), tx3Obj.block)
+	err = cli2.OptInCheckpoint(uid, tx3.Nonce(), tx3Obj.block)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -1553,3 +1600,626 @@ func TestValidateBlock(t *testing.T) {
 	addTx(t, uid, []*transaction.Transaction{validTx2}, nil, cli0, true)
 	addTx(t, uid, []*transaction.Transaction{validTx3}, nil, cli1, true)
 }
+
+func signTx(t *testing.T, tx *transaction.Transaction,
+	signer *account.PlasmaTransactOpts) *transaction.Transaction {
+	signed, err := signer.PlasmaSigner(signer.From, tx,
+		transaction.LegacyDomain)
+	if err != nil {
+		t.Fatalf("failed to sign transaction %s", err)
+	}
+	return signed
+}
+
+func TestSplitMerge(t *testing.T) {
+	s := newTestService(t, 3)
+	defer s.Close()
+
+	cli := testClient(t, s, true, s.accounts[0])
+	defer cli.Close()
+
+	ten := big.NewInt(10)
+	four := big.NewInt(4)
+	six := big.NewInt(6)
+
+	uid := deposit(t, s, cli, ten)
+
+	validTx1 := testTx(t, zero, uid, ten, zero, s.accounts[0].From, s.accounts[0])
+	addTx(t, uid, []*transaction.Transaction{validTx1}, nil, cli, true)
+
+	outputs := []transaction.Output{
+		{Amount: four, Owner: s.accounts[1].From},
+		{Amount: six, Owner: s.accounts[2].From},
+	}
+
+	_, err := transaction.NewSplit(one, uid, one, one, uid, outputs)
+	if err != transaction.ErrInvalidAmount {
+		t.Fatalf("expected %s, got %v", transaction.ErrInvalidAmount, err)
+	}
+
+	split, err := transaction.NewSplit(one, uid, ten, one, uid, outputs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	validSplit := signTx(t, split, s.accounts[0])
+
+	// splits are disabled by default
+	buf := bytes.NewBuffer([]byte{})
+	if err := validSplit.EncodeRLP(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	err = cli.AcceptTransaction(buf.Bytes())
+	if err != service.ErrSplitsDisabled {
+		t.Fatalf("expected error %s, got %v", service.ErrSplitsDisabled,
+			err)
+	}
+	s.service.SetSplits(true)
+
+	// the sender is not the owner
+	badSplit := signTx(t, split, s.accounts[1])
+	addTx(t, uid, nil, []*transaction.Transaction{badSplit}, cli, true)
+
+	addTx(t, uid, []*transaction.Transaction{validSplit}, nil, cli, true)
+
+	child0 := transaction.ChildUID(uid, 0)
+	child1 := transaction.ChildUID(uid, 1)
+
+	// the coin is spent by the split
+	badTx1 := testTx(t, two, uid, ten, two, s.accounts[1].From, s.accounts[0])
+	addTx(t, uid, nil, []*transaction.Transaction{badTx1}, cli, true)
+
+	// wrong amount of the sub-coin
+	badTx2 := testTx(t, two, child0, ten, one, s.accounts[2].From, s.accounts[1])
+	addTx(t, child0, nil, []*transaction.Transaction{badTx2}, cli, true)
+
+	validTx2 := testTx(t, two, child0, four, one, s.accounts[2].From, s.accounts[1])
+	addTx(t, child0, []*transaction.Transaction{validTx2}, nil, cli, true)
+
+	inputs := []transaction.Input{
+		{PrevBlock: three, UID: child0, Amount: four, Nonce: two},
+		{PrevBlock: two, UID: child1, Amount: six, Nonce: one},
+	}
+
+	// the coins do not belong to the deposit
+	wrongRoot, err := transaction.NewMerge(
+		big.NewInt(1000), inputs, s.accounts[0].From)
+	if err != nil {
+		t.Fatal(err)
+	}
+	badMerge := signTx(t, wrongRoot, s.accounts[2])
+	addTx(t, uid, nil, []*transaction.Transaction{badMerge}, cli, true)
+
+	merge, err := transaction.NewMerge(uid, inputs, s.accounts[0].From)
+	if err != nil {
+		t.Fatal(err)
+	}
+	validMerge := signTx(t, merge, s.accounts[2])
+	txs, _ := addTx(t, uid, []*transaction.Transaction{validMerge},
+		nil, cli, true)
+
+	merged := transaction.MergeUID(uid, []*big.Int{child0, child1})
+	if validMerge.UID().Cmp(merged) != 0 {
+		t.Fatal("wrong uid of the merged coin")
+	}
+
+	if validMerge.Amount().Cmp(ten) != 0 {
+		t.Fatal("wrong amount of the merged coin")
+	}
+
+	mergeBlock := txs[merged.String()].block
+
+	validTx3 := testTx(t, new(big.Int).SetUint64(mergeBlock), merged, ten,
+		one, s.accounts[1].From, s.accounts[0])
+	txs2, _ := addTx(t, merged, []*transaction.Transaction{validTx3},
+		nil, cli, true)
+
+	_, err = cli.StartExit(txs[merged.String()].rawTx,
+		txs[merged.String()].proof,
+		new(big.Int).SetUint64(mergeBlock),
+		txs2[merged.String()].rawTx, txs2[merged.String()].proof,
+		new(big.Int).SetUint64(txs2[merged.String()].block))
+	if err != ErrNotExitable {
+		t.Fatalf("expected %s, got %v", ErrNotExitable, err)
+	}
+
+	_, err = cli.StartExit(txs2[merged.String()].rawTx,
+		txs2[merged.String()].proof,
+		new(big.Int).SetUint64(txs2[merged.String()].block),
+		txs2[merged.String()].rawTx, txs2[merged.String()].proof,
+		new(big.Int).SetUint64(txs2[merged.String()].block))
+	if err != ErrNotExitable {
+		t.Fatalf("expected %s, got %v", ErrNotExitable, err)
+	}
+}
+
+func TestSwap(t *testing.T) {
+	s := newTestService(t, 2)
+	defer s.Close()
+
+	cli0 := testClient(t, s, true, s.accounts[0])
+	defer cli0.Close()
+
+	cli1 := testClient(t, s, true, s.accounts[1])
+	defer cli1.Close()
+
+	uidA := deposit(t, s, cli0, one)
+	uidB := deposit(t, s, cli0, two)
+
+	validTxA1 := testTx(t, zero, uidA, one, zero, s.accounts[0].From, s.accounts[0])
+	validTxB1 := testTx(t, zero, uidB, two, zero, s.accounts[0].From, s.accounts[0])
+	addTx(t, uidA, []*transaction.Transaction{validTxA1, validTxB1},
+		nil, cli0, true)
+
+	validTxB2 := testTx(t, one, uidB, two, one, s.accounts[1].From, s.accounts[0])
+	addTx(t, uidB, []*transaction.Transaction{validTxB2}, nil, cli0, true)
+
+	a := transaction.Input{PrevBlock: one, UID: uidA, Amount: one, Nonce: one}
+	b := transaction.Input{PrevBlock: two, UID: uidB, Amount: two, Nonce: two}
+
+	// the second coin has wrong nonce, the swap is rejected as a whole
+	badB := b
+	badB.Nonce = three
+
+	bad, err := transaction.NewSwap(a, badB,
+		s.accounts[0].From, s.accounts[1].From)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// swaps are disabled by default
+	if err := cli0.Swap(bad); err != service.ErrSwapsDisabled {
+		t.Fatalf("expected error %s, got %v", service.ErrSwapsDisabled, err)
+	}
+	s.service.SetSwaps(true)
+
+	if err := cli0.Swap(bad); err == nil {
+		t.Fatal("the swap of a spent coin is accepted")
+	}
+
+	// the first owner does not own the second coin
+	stolen, err := transaction.NewSwap(b, a,
+		s.accounts[0].From, s.accounts[1].From)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := cli0.Swap(stolen); err == nil {
+		t.Fatal("the swap of a coin of another owner is accepted")
+	}
+
+	swap, err := transaction.NewSwap(a, b,
+		s.accounts[0].From, s.accounts[1].From)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// pending swaps are dropped with the block
+	if err := cli0.Swap(swap); err != nil {
+		t.Fatal(err)
+	}
+	s.service.InitBlock()
+
+	if _, err := cli1.PendingSwap(swap.Hash()); err == nil {
+		t.Fatal("the swap is pending after the block")
+	}
+
+	if err := cli0.Swap(swap); err != nil {
+		t.Fatal(err)
+	}
+
+	pending, err := cli1.PendingSwap(swap.Hash())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if pending.Complete() || pending.Hash() != swap.Hash() {
+		t.Fatal("wrong pending swap")
+	}
+
+	curBlock, err := cli0.CurrentBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testBlock := transactions.NewBlock()
+	if err := testBlock.Unmarshal(curBlock); err != nil {
+		t.Fatal(err)
+	}
+
+	if testBlock.NumberOfTX() != 0 {
+		t.Fatal("the swap signed by one owner is in the block")
+	}
+
+	if err := cli1.Swap(pending); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := cli1.PendingSwap(swap.Hash()); err == nil {
+		t.Fatal("the swap is still pending")
+	}
+
+	addTx(t, uidA, nil, nil, cli0, true)
+
+	lastBlock, err := cli0.LastBlockNumber()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, uid := range []*big.Int{uidA, uidB} {
+		proof, err := cli0.CreateProof(uid, lastBlock.Uint64())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		included, err := cli0.VerifyTxProof(uid, swap.Hash(),
+			lastBlock.Uint64(), proof)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !included {
+			t.Fatal("the swap is not included")
+		}
+	}
+
+	// the coin A belongs to the second owner after the swap
+	validTxA2 := testTx(t, lastBlock, uidA, one, two, s.accounts[0].From, s.accounts[1])
+	addTx(t, uidA, []*transaction.Transaction{validTxA2}, nil, cli0, true)
+}
+
+func TestBatch(t *testing.T) {
+	s := newTestService(t, 1)
+	defer s.Close()
+
+	cli := testClient(t, s, false, s.accounts[0])
+	defer cli.Close()
+
+	cli.SetBatchSize(2)
+
+	newOwner := account.Account(account.GenKey())
+
+	tx, err := transaction.NewTransaction(zero, one, two, three, newOwner.From)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.service.AcceptTransaction(tx); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = s.service.BuildBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	curBlock := s.service.CurrentBlock()
+
+	for i := uint64(1); i <= 3; i++ {
+		if err := s.service.SaveBlockToDB(i, curBlock); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	blocks, err := cli.GetTransactionsBlocks(1, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(blocks) != 3 {
+		t.Fatal("wrong number of blocks")
+	}
+
+	for _, bl := range blocks {
+		if bl.Hash() != curBlock.Hash() {
+			t.Fatal("wrong block")
+		}
+	}
+
+	if _, err := cli.GetTransactionsBlocks(3, 4); err == nil {
+		t.Fatal("error is expected")
+	}
+
+	proofs, errs, err := cli.CreateProofs([]handlers.ProofItem{
+		{UID: one, Block: 1},
+		{UID: one, Block: 2},
+		{UID: one, Block: 3},
+		{UID: one, Block: 4},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(proofs) != 4 || len(errs) != 4 {
+		t.Fatal("wrong number of proofs")
+	}
+
+	for i := 0; i < 3; i++ {
+		if errs[i] != nil {
+			t.Fatal(errs[i])
+		}
+
+		if len(proofs[i]) == 0 {
+			t.Fatal("empty proof")
+		}
+	}
+
+	if errs[3] == nil {
+		t.Fatal("error is expected")
+	}
+
+	err = s.service.AcceptUIDState(one, three, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = s.service.BuildCheckpoint()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chpt := s.service.CurrentCheckpoint()
+
+	if err := s.service.SaveCheckpointToDB(chpt); err != nil {
+		t.Fatal(err)
+	}
+
+	chptProofs, nonces, err := cli.CreateUIDStateProofs(
+		[]*big.Int{one, two, three}, chpt.Hash())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(chptProofs) != 3 || len(nonces) != 3 {
+		t.Fatal("wrong number of proofs")
+	}
+
+	if nonces[0].Cmp(three) != 0 {
+		t.Fatal("wrong nonce")
+	}
+}
+
+func TestFees(t *testing.T) {
+	s := newTestService(t, 3)
+	defer s.Close()
+
+	cli0 := testClient(t, s, true, s.accounts[0])
+	defer cli0.Close()
+
+	uidA := deposit(t, s, cli0, two)
+	uidB := deposit(t, s, cli0, one)
+
+	validTxA1 := testTx(t, zero, uidA, two, zero, s.accounts[0].From, s.accounts[0])
+	validTxB1 := testTx(t, zero, uidB, one, zero, s.accounts[0].From, s.accounts[0])
+	addTx(t, uidA, []*transaction.Transaction{validTxA1, validTxB1},
+		nil, cli0, true)
+
+	validTxA2 := testTx(t, one, uidA, two, one, s.accounts[1].From, s.accounts[0])
+	validTxB2 := testTx(t, one, uidB, one, one, s.accounts[1].From, s.accounts[0])
+	addTx(t, uidA, []*transaction.Transaction{validTxA2, validTxB2},
+		nil, cli0, true)
+
+	s.service.SetFeePolicy(service.FeePolicy{
+		Receiver: s.accounts[0].From,
+		MinFee:   one,
+	})
+
+	receiver, minFee, err := cli0.FeePolicy()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if receiver != s.accounts[0].From || minFee.Cmp(one) != 0 {
+		t.Fatal("wrong fee policy")
+	}
+
+	validTxA3 := testTx(t, two, uidA, two, two, s.accounts[2].From, s.accounts[1])
+
+	buf := bytes.NewBuffer(nil)
+	if err := validTxA3.EncodeRLP(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	err = cli0.AcceptTransaction(buf.Bytes())
+	if err == nil || err.Error() != service.ErrFeeNotPaid.Error() {
+		t.Fatalf("expected error %s, got %v", service.ErrFeeNotPaid, err)
+	}
+
+	// the coin B pays the fee for the transfer of the coin A
+	feeTxB3 := testTx(t, two, uidB, one, two, s.accounts[0].From, s.accounts[1])
+	addTx(t, uidA, []*transaction.Transaction{feeTxB3, validTxA3},
+		nil, cli0, true)
+
+	lastBlock, err := cli0.LastBlockNumber()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fees, err := cli0.BlockFees(lastBlock.Uint64())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fees.Cmp(one) != 0 {
+		t.Fatalf("wrong fees %s, expected %s", fees, one)
+	}
+
+	fees, err = cli0.BlockFees(lastBlock.Uint64() - 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fees.Sign() != 0 {
+		t.Fatal("transfers from the operator are not fees")
+	}
+}
+
+func TestDomain(t *testing.T) {
+	s := newTestService(t, 1)
+	defer s.Close()
+
+	cli := testClient(t, s, true, s.accounts[0])
+	defer cli.Close()
+
+	// the contract verifies signatures of the legacy domain only
+	domain := transaction.NewDomain(big.NewInt(1337), s.rootChainAddress)
+	if err := s.service.SetDomain(
+		domain); err != service.ErrDomainNotSupported {
+		t.Fatalf("expected error %s, got %v",
+			service.ErrDomainNotSupported, err)
+	}
+
+	resp, err := cli.Domain()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.Separator() != transaction.LegacyDomain.Separator() {
+		t.Fatal("wrong domain")
+	}
+
+	uid := deposit(t, s, cli, one)
+
+	unsignedTx, err := transaction.NewTransaction(
+		zero, uid, one, zero, s.accounts[0].From)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the transaction signed for another domain is rejected
+	replayed, err := s.accounts[0].PlasmaSigner(s.accounts[0].From,
+		unsignedTx, domain)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	valid, err := s.accounts[0].PlasmaSigner(s.accounts[0].From,
+		unsignedTx, transaction.LegacyDomain)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if replayed.Hash() != valid.Hash() {
+		t.Fatal("hashes not equal")
+	}
+
+	addTx(t, uid, nil, []*transaction.Transaction{replayed}, cli, true)
+	addTx(t, uid, []*transaction.Transaction{valid}, nil, cli, true)
+}
+
+func TestListCheckpoints(t *testing.T) {
+	s := newTestService(t, 1)
+	defer s.Close()
+
+	cli := testClient(t, s, true, s.accounts[0])
+	defer cli.Close()
+
+	uid := deposit(t, s, cli, one)
+	tx := testTx(t, zero, uid, one, zero, s.accounts[0].From, s.accounts[0])
+	addTx(t, uid, []*transaction.Transaction{tx}, nil, cli, true)
+
+	hash, err := s.service.MakeCheckpoint(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	infos, err := cli.ListCheckpoints(1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(infos) != 1 || infos[0].Hash != hash || infos[0].UIDs != 1 ||
+		infos[0].Time.Sign() == 0 {
+		t.Fatal("wrong list of checkpoints")
+	}
+
+	info, err := cli.LatestCheckpointFor(uid)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Hash != hash || info.Index != 1 {
+		t.Fatal("wrong latest checkpoint")
+	}
+
+	_, err = cli.LatestCheckpointFor(new(big.Int).Add(uid, one))
+	if err != service.ErrCheckpointNotFound {
+		t.Fatalf("expected error %s, got %v",
+			service.ErrCheckpointNotFound, err)
+	}
+}
+
+func TestListManualCheckpoints(t *testing.T) {
+	s := newTestService(t, 2)
+	defer s.Close()
+
+	owner := s.accounts[0]
+
+	cli := testClient(t, s, true, owner)
+	defer cli.Close()
+
+	uid := deposit(t, s, cli, one)
+
+	checkpoint := func(tx *transaction.Transaction,
+		saveFirst bool) (common.Hash, uint64) {
+		objects, _ := addTx(t,
+			uid, []*transaction.Transaction{tx}, nil, cli, true)
+		block := objects[uid.String()].block
+
+		err := s.service.AcceptUIDState(uid, tx.Nonce(), block)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		hash, err := cli.BuildCheckpoint()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if saveFirst {
+			if err := cli.SaveCurrentCheckpointBlock(); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		sendTx, err := cli.SendCheckpointHash(hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := cli.WaitMined(context.Background(), sendTx); err != nil {
+			t.Fatal(err)
+		}
+
+		if !saveFirst {
+			if err := cli.SaveCurrentCheckpointBlock(); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if err := cli.InitCheckpoint(); err != nil {
+			t.Fatal(err)
+		}
+		return hash, block
+	}
+
+	// the checkpoint is registered when it is saved after it is published
+	// or when the next checkpoint is initialized
+	tx1 := testTx(t, zero, uid, one, zero, owner.From, owner)
+	hash1, block := checkpoint(tx1, false)
+
+	tx2 := testTx(t, new(big.Int).SetUint64(block), uid, one, one,
+		s.accounts[1].From, owner)
+	hash2, _ := checkpoint(tx2, true)
+
+	infos, err := cli.ListCheckpoints(1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(infos) != 2 || infos[0].Hash != hash1 || infos[1].Hash != hash2 ||
+		infos[0].UIDs != 1 || infos[1].Time.Sign() == 0 {
+		t.Fatal("wrong list of checkpoints")
+	}
+}
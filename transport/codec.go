@@ -0,0 +1,532 @@
+package transport
+
+import (
+	"bufio"
+	"context"
+	"encoding/gob"
+	"io"
+	"net"
+	"net/http"
+	"net/rpc"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SmartMeshFoundation/Spectrum/log"
+
+	"github.com/SmartMeshFoundation/SmartPlasma/logger"
+	"github.com/SmartMeshFoundation/SmartPlasma/transport/handlers"
+)
+
+const (
+	// connected is the response to a successful HTTP CONNECT,
+	// it must match the one expected by rpc.DialHTTP.
+	connected = "200 Connected to Go RPC"
+)
+
+// contextBinder binds request objects to the contexts of their calls.
+type contextBinder interface {
+	BindContext(ctx context.Context, req interface{})
+	UnbindContext(req interface{})
+}
+
+// rpcHandler serves RPC requests over HTTP CONNECT the same way
+// as rpc.Server.ServeHTTP does, but wraps every connection
+// in the server codec that observes calls.
+type rpcHandler struct {
+	server   *rpc.Server
+	binder   contextBinder
+	auth     *authenticator
+	limiter  *limiter
+	readOnly bool
+	logger   log.Logger
+}
+
+func newRPCHandler(server *rpc.Server, binder contextBinder,
+	logger log.Logger) *rpcHandler {
+	return &rpcHandler{
+		server:  server,
+		binder:  binder,
+		auth:    &authenticator{},
+		limiter: newLimiter(Limits{}),
+		logger:  logger,
+	}
+}
+
+// ServeHTTP implements an http.Handler that answers RPC requests.
+func (h *rpcHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodConnect {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		io.WriteString(w, "405 must CONNECT\n")
+		return
+	}
+
+	conn, _, err := w.(http.Hijacker).Hijack()
+	if err != nil {
+		h.logger.Warn("Failed to hijack connection",
+			"remote", req.RemoteAddr, "err", err)
+		return
+	}
+	io.WriteString(conn, "HTTP/1.0 "+connected+"\n\n")
+
+	admin := h.auth.authorized(req)
+
+	l := h.logger.New("remote", req.RemoteAddr)
+	l.Debug("RPC connection opened", "admin", admin)
+
+	codec := newServerCodec(conn, h.binder, l)
+	codec.admin = admin
+	codec.readOnly = h.readOnly
+	if !admin {
+		codec.limit(h.limiter, remoteIP(req.RemoteAddr))
+	}
+
+	h.server.ServeCodec(codec)
+
+	l.Debug("RPC connection closed")
+}
+
+// gobServerCodec is a copy of the unexported gob codec from net/rpc.
+type gobServerCodec struct {
+	rwc    io.ReadWriteCloser
+	dec    *gob.Decoder
+	enc    *gob.Encoder
+	encBuf *bufio.Writer
+	closed bool
+}
+
+func newGobServerCodec(conn io.ReadWriteCloser,
+	r io.Reader) *gobServerCodec {
+	buf := bufio.NewWriter(conn)
+	return &gobServerCodec{
+		rwc:    conn,
+		dec:    gob.NewDecoder(r),
+		enc:    gob.NewEncoder(buf),
+		encBuf: buf,
+	}
+}
+
+func (c *gobServerCodec) ReadRequestHeader(r *rpc.Request) error {
+	return c.dec.Decode(r)
+}
+
+func (c *gobServerCodec) ReadRequestBody(body interface{}) error {
+	return c.dec.Decode(body)
+}
+
+func (c *gobServerCodec) WriteResponse(r *rpc.Response,
+	body interface{}) (err error) {
+	if err = c.enc.Encode(r); err != nil {
+		if c.encBuf.Flush() == nil {
+			c.Close()
+		}
+		return
+	}
+	if err = c.enc.Encode(body); err != nil {
+		if c.encBuf.Flush() == nil {
+			c.Close()
+		}
+		return
+	}
+	return c.encBuf.Flush()
+}
+
+func (c *gobServerCodec) Close() error {
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	return c.rwc.Close()
+}
+
+// gobClientCodec is a copy of the unexported gob codec from net/rpc.
+type gobClientCodec struct {
+	rwc    io.ReadWriteCloser
+	dec    *gob.Decoder
+	enc    *gob.Encoder
+	encBuf *bufio.Writer
+}
+
+func (c *gobClientCodec) WriteRequest(r *rpc.Request,
+	body interface{}) (err error) {
+	if err = c.enc.Encode(r); err != nil {
+		return
+	}
+	if err = c.enc.Encode(body); err != nil {
+		return
+	}
+	return c.encBuf.Flush()
+}
+
+func (c *gobClientCodec) ReadResponseHeader(r *rpc.Response) error {
+	return c.dec.Decode(r)
+}
+
+func (c *gobClientCodec) ReadResponseBody(body interface{}) error {
+	return c.dec.Decode(body)
+}
+
+func (c *gobClientCodec) Close() error {
+	return c.rwc.Close()
+}
+
+// clientCodec remembers the sequence number of the last written request,
+// so the client can ask the server to cancel the call.
+type clientCodec struct {
+	rpc.ClientCodec
+
+	mtx     sync.Mutex
+	last    uint64
+	written bool
+}
+
+func newClientCodec(conn io.ReadWriteCloser) *clientCodec {
+	buf := bufio.NewWriter(conn)
+	return &clientCodec{
+		ClientCodec: &gobClientCodec{
+			rwc:    conn,
+			dec:    gob.NewDecoder(conn),
+			enc:    gob.NewEncoder(buf),
+			encBuf: buf,
+		},
+	}
+}
+
+// WriteRequest writes the request and remembers its sequence number.
+func (c *clientCodec) WriteRequest(r *rpc.Request, body interface{}) error {
+	c.mtx.Lock()
+	c.last = r.Seq
+	c.written = true
+	c.mtx.Unlock()
+
+	return c.ClientCodec.WriteRequest(r, body)
+}
+
+// reset forgets the last written request.
+func (c *clientCodec) reset() {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.written = false
+}
+
+// lastSeq returns the sequence number of the request
+// written after the last reset.
+func (c *clientCodec) lastSeq() (uint64, bool) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	return c.last, c.written
+}
+
+// call is an RPC call in progress on a connection.
+type call struct {
+	id      string
+	method  string
+	start   time.Time
+	req     interface{}
+	release func()
+	cancel  context.CancelFunc
+}
+
+// serverCodec tracks calls on one connection, assigns them
+// request identifiers, records metrics and logs every answered call.
+// Calls refused by the codec are answered without being passed
+// to the RPC server.
+type serverCodec struct {
+	rpc.ServerCodec
+
+	binder   contextBinder
+	logger   log.Logger
+	admin    bool
+	readOnly bool
+	limiter  *limiter
+	ip       string
+	reader   *sizeReader
+
+	mtx     sync.Mutex
+	calls   map[uint64]*call
+	reading *call
+
+	sending sync.Mutex
+}
+
+func newServerCodec(conn io.ReadWriteCloser, binder contextBinder,
+	logger log.Logger) *serverCodec {
+	reader := newSizeReader(conn)
+	return &serverCodec{
+		ServerCodec: newGobServerCodec(conn, reader),
+		binder:      binder,
+		logger:      logger,
+		reader:      reader,
+		calls:       make(map[uint64]*call),
+	}
+}
+
+// limit applies the limits to the calls from the IP address.
+func (c *serverCodec) limit(limiter *limiter, ip string) {
+	c.limiter = limiter
+	c.ip = ip
+	c.reader.max = limiter.limits.MaxRequestSize
+}
+
+// ReadRequestHeader reads the request header and starts tracking the call.
+// Refused calls are answered here and the next header is read.
+func (c *serverCodec) ReadRequestHeader(r *rpc.Request) error {
+	for {
+		*r = rpc.Request{}
+		c.reader.reset()
+		if err := c.ServerCodec.ReadRequestHeader(r); err != nil {
+			c.cancelAll()
+			if c.reader.exceeded {
+				return ErrRequestTooLarge
+			}
+			return err
+		}
+
+		if r.ServiceMethod == CancelMethod {
+			if err := c.cancel(r); err != nil {
+				return err
+			}
+			continue
+		}
+
+		cl := &call{
+			id:     logger.NewRequestID(),
+			method: r.ServiceMethod,
+			start:  time.Now(),
+		}
+
+		if reason := c.check(r, cl); reason != nil {
+			if err := c.refuse(r, cl, reason); err != nil {
+				return err
+			}
+			continue
+		}
+
+		c.mtx.Lock()
+		c.calls[r.Seq] = cl
+		c.reading = cl
+		c.mtx.Unlock()
+		return nil
+	}
+}
+
+// check returns the reason to refuse the call or nil.
+func (c *serverCodec) check(r *rpc.Request, cl *call) error {
+	if c.readOnly && !IsReadMethod(r.ServiceMethod) {
+		return ErrReadOnly
+	}
+
+	if IsAdminMethod(r.ServiceMethod) && !c.admin {
+		return ErrUnauthorized
+	}
+
+	if c.limiter == nil {
+		return nil
+	}
+
+	if !c.limiter.allowIP(c.ip) {
+		return ErrRateLimited
+	}
+
+	release, ok := c.limiter.acquire(r.ServiceMethod)
+	if !ok {
+		return ErrBusy
+	}
+	cl.release = release
+	return nil
+}
+
+// refuse discards the request body and answers with the error.
+func (c *serverCodec) refuse(r *rpc.Request, cl *call, reason error) error {
+	if err := c.ServerCodec.ReadRequestBody(nil); err != nil {
+		if c.reader.exceeded {
+			return ErrRequestTooLarge
+		}
+		return err
+	}
+
+	if cl.release != nil {
+		cl.release()
+	}
+	c.finish(cl, reason.Error(), "")
+
+	c.sending.Lock()
+	defer c.sending.Unlock()
+
+	return c.ServerCodec.WriteResponse(&rpc.Response{
+		ServiceMethod: r.ServiceMethod,
+		Seq:           r.Seq,
+		Error:         reason.Error(),
+	}, struct{}{})
+}
+
+// cancel cancels the context of a call in progress.
+func (c *serverCodec) cancel(r *rpc.Request) error {
+	req := &handlers.CancelReq{}
+	if err := c.ServerCodec.ReadRequestBody(req); err != nil {
+		if c.reader.exceeded {
+			return ErrRequestTooLarge
+		}
+		return err
+	}
+
+	c.mtx.Lock()
+	cl, ok := c.calls[req.Seq]
+	c.mtx.Unlock()
+
+	if ok && cl.cancel != nil {
+		cl.cancel()
+		c.logger.Debug("RPC call cancelled",
+			logger.RequestIDKey, cl.id, "method", cl.method)
+	}
+
+	c.sending.Lock()
+	defer c.sending.Unlock()
+
+	return c.ServerCodec.WriteResponse(&rpc.Response{
+		ServiceMethod: r.ServiceMethod,
+		Seq:           r.Seq,
+	}, &handlers.CancelResp{})
+}
+
+// cancelAll cancels the contexts of all calls in progress.
+func (c *serverCodec) cancelAll() {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	for _, cl := range c.calls {
+		if cl.cancel != nil {
+			cl.cancel()
+		}
+	}
+}
+
+// ReadRequestBody reads the request body and binds it
+// to the context of the call. The call is refused if the request
+// is too large or the sender of the request exceeds the rate limit.
+func (c *serverCodec) ReadRequestBody(body interface{}) error {
+	err := c.ServerCodec.ReadRequestBody(body)
+
+	c.mtx.Lock()
+	cl := c.reading
+	c.reading = nil
+	c.mtx.Unlock()
+
+	if err != nil {
+		if c.reader.exceeded {
+			return ErrRequestTooLarge
+		}
+		return err
+	}
+
+	if body == nil || cl == nil {
+		return nil
+	}
+
+	if c.limiter != nil {
+		address, ok := requestAddress(body, c.limiter.domain())
+		if ok && !c.limiter.allowAddress(address) {
+			return ErrRateLimited
+		}
+	}
+
+	if c.binder == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(
+		logger.WithRequestID(context.Background(), cl.id))
+
+	c.mtx.Lock()
+	cl.req = body
+	cl.cancel = cancel
+	c.mtx.Unlock()
+
+	c.binder.BindContext(ctx, body)
+	return nil
+}
+
+// WriteResponse writes the response and finishes tracking the call.
+func (c *serverCodec) WriteResponse(r *rpc.Response,
+	body interface{}) error {
+	c.mtx.Lock()
+	cl, ok := c.calls[r.Seq]
+	delete(c.calls, r.Seq)
+	c.mtx.Unlock()
+
+	if ok {
+		if cl.req != nil {
+			c.binder.UnbindContext(cl.req)
+		}
+		if cl.release != nil {
+			cl.release()
+		}
+		if cl.cancel != nil {
+			cl.cancel()
+		}
+		c.finish(cl, r.Error, responseError(body))
+	}
+
+	c.sending.Lock()
+	defer c.sending.Unlock()
+
+	return c.ServerCodec.WriteResponse(r, body)
+}
+
+func (c *serverCodec) finish(cl *call, rpcErr, respErr string) {
+	observeCall(cl, rpcErr, respErr)
+
+	l := c.logger.New(logger.RequestIDKey, cl.id,
+		"method", cl.method, "elapsed", time.Since(cl.start))
+
+	switch {
+	case rpcErr != "":
+		l.Warn("RPC call failed", "err", rpcErr)
+	case respErr != "":
+		l.Debug("RPC call returned error", "err", respErr)
+	default:
+		l.Debug("RPC call served")
+	}
+}
+
+// remoteIP returns the IP address of the remote address of a request.
+func remoteIP(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
+
+// responseError returns the value of the `Error` field
+// of a response object.
+func responseError(body interface{}) string {
+	v := reflect.ValueOf(body)
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+
+	if v.Kind() != reflect.Struct {
+		return ""
+	}
+
+	field := v.FieldByName("Error")
+	if !field.IsValid() || field.Kind() != reflect.String {
+		return ""
+	}
+	return field.String()
+}
+
+// isUnknownMethod returns true if net/rpc rejected the call
+// because of the wrong method name.
+func isUnknownMethod(rpcErr string) bool {
+	return strings.HasPrefix(rpcErr, "rpc: can't find") ||
+		strings.HasPrefix(rpcErr, "rpc: service/method request ill-formed")
+}
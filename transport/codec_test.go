@@ -0,0 +1,228 @@
+package transport
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/rpc"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SmartMeshFoundation/SmartPlasma/logger"
+)
+
+const (
+	waitMethod = "Test.Wait"
+	echoMethod = "Test.Echo"
+)
+
+// contextService is RPC service which uses contexts of calls.
+type contextService struct {
+	mtx       sync.Mutex
+	contexts  map[interface{}]context.Context
+	cancelled chan struct{}
+}
+
+func newContextService() *contextService {
+	return &contextService{
+		contexts:  make(map[interface{}]context.Context),
+		cancelled: make(chan struct{}, 1),
+	}
+}
+
+func (s *contextService) BindContext(ctx context.Context, req interface{}) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.contexts[req] = ctx
+}
+
+func (s *contextService) UnbindContext(req interface{}) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	delete(s.contexts, req)
+}
+
+// Wait waits until the call is cancelled.
+func (s *contextService) Wait(req *int, resp *int) error {
+	s.mtx.Lock()
+	ctx := s.contexts[req]
+	s.mtx.Unlock()
+
+	select {
+	case <-ctx.Done():
+		s.cancelled <- struct{}{}
+	case <-time.After(10 * time.Second):
+	}
+	return nil
+}
+
+// Echo returns the value of the request.
+func (s *contextService) Echo(req *int, resp *int) error {
+	*resp = *req
+	return nil
+}
+
+func serveTest(t *testing.T, addr string, s *contextService) net.Listener {
+	rpcServer := rpc.NewServer()
+	if err := rpcServer.RegisterName("Test", s); err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := net.Listen(tcp, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go http.Serve(l,
+		newRPCHandler(rpcServer, s, logger.New("transport")))
+	return l
+}
+
+func TestClientCancel(t *testing.T) {
+	s := newContextService()
+	l := serveTest(t, "localhost:0", s)
+	defer l.Close()
+
+	cli := NewClient(100, nil)
+	if err := cli.ConnectString(l.Addr().String()); err != nil {
+		t.Fatal(err)
+	}
+	defer cli.Close()
+
+	ctx, cancel := context.WithTimeout(
+		context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	var resp int
+	err := cli.call(ctx, waitMethod, new(int), &resp)
+	if err != context.DeadlineExceeded {
+		t.Fatalf("expected %s, got %v", context.DeadlineExceeded, err)
+	}
+
+	select {
+	case <-s.cancelled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("call is not cancelled on the server")
+	}
+
+	req := 1
+	if err := cli.call(context.Background(), echoMethod,
+		&req, &resp); err != nil {
+		t.Fatal(err)
+	}
+
+	if resp != 1 {
+		t.Fatal("wrong response")
+	}
+}
+
+func TestClientReconnect(t *testing.T) {
+	s := newContextService()
+	l := serveTest(t, "localhost:0", s)
+	addr := l.Addr().String()
+
+	cli := NewClient(100, nil)
+	if err := cli.ConnectString(addr); err != nil {
+		t.Fatal(err)
+	}
+	defer cli.Close()
+
+	echo := func(ctx context.Context) error {
+		var resp int
+		return cli.call(ctx, echoMethod, new(int), &resp)
+	}
+
+	// the connection drops.
+	cli.connect.Close()
+
+	if err := echo(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// the server stops.
+	l.Close()
+	cli.connect.Close()
+
+	ctx, cancel := context.WithTimeout(
+		context.Background(), 300*time.Millisecond)
+	defer cancel()
+
+	if err := echo(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("expected %s, got %v", context.DeadlineExceeded, err)
+	}
+
+	l = serveTest(t, addr, s)
+	defer l.Close()
+
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := echo(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	cli.Close()
+	if err := echo(context.Background()); err != rpc.ErrShutdown {
+		t.Fatalf("expected %s, got %v", rpc.ErrShutdown, err)
+	}
+}
+
+// serveDrop serves RPC connections which are closed when the first
+// request is received. It counts received requests.
+func serveDrop(t *testing.T, requests chan<- struct{}) net.Listener {
+	l, err := net.Listen(tcp, "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			r := bufio.NewReader(conn)
+			if _, err := http.ReadRequest(r); err != nil {
+				conn.Close()
+				continue
+			}
+			io.WriteString(conn, "HTTP/1.0 "+connected+"\n\n")
+
+			if _, err := r.ReadByte(); err == nil {
+				requests <- struct{}{}
+			}
+			conn.Close()
+		}
+	}()
+	return l
+}
+
+func TestClientInterrupted(t *testing.T) {
+	requests := make(chan struct{}, 10)
+	l := serveDrop(t, requests)
+	defer l.Close()
+
+	cli := NewClient(100, nil)
+	if err := cli.ConnectString(l.Addr().String()); err != nil {
+		t.Fatal(err)
+	}
+	defer cli.Close()
+
+	var resp int
+	err := cli.call(context.Background(), echoMethod, new(int), &resp)
+	if err != ErrInterrupted {
+		t.Fatalf("expected %s, got %v", ErrInterrupted, err)
+	}
+
+	// the sent request is not sent again
+	time.Sleep(100 * time.Millisecond)
+	if len(requests) != 1 {
+		t.Fatalf("request is sent %d times", len(requests))
+	}
+}
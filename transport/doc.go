@@ -0,0 +1,50 @@
+// Package transport implements the RPC server of the operator
+// and the client of its methods.
+//
+// The server exposes metrics in the Prometheus text format on the
+// /metrics path of its HTTP port. Metrics cover RPC calls (count, latency
+// and result code per method), building, validation and saving of blocks,
+// sending of block hashes, the number of transactions in the current
+// block, collected fees and gas used by mined Spectrum transactions.
+//
+// Methods which manage blocks and checkpoints (BuildBlock, SendBlockHash,
+// SaveBlockToDB, InitBlock, SaveCurrentBlock and their checkpoint
+// counterparts) are admin methods, IsAdminMethod reports them. They are
+// refused with ErrUnauthorized unless the client connects with one
+// of the admin tokens of the server, without tokens admin methods
+// are refused to everyone:
+//
+//	srv := transport.NewServer(timeout, port, s)
+//	srv.SetAdminTokens("secret")
+//	go srv.ListenAndServeTLS("server.crt", "server.key")
+//
+//	cli := transport.NewClient(timeout, opts)
+//	cli.SetAuthToken("secret")
+//	cli.SetTLSConfig(&tls.Config{RootCAs: pool})
+//	err := cli.Connect("localhost", port)
+//
+// Calls of public clients, without an admin token, are limited with
+// SetLimits: the rate of calls from one IP address, the rate of
+// transactions from one Plasma address, the size of a request and
+// the number of concurrent expensive calls (proofs, block batches,
+// opt-ins, the checkpoint registry and ValidateBlock). Zero values
+// disable a limit. Refused calls return ErrRateLimited,
+// ErrRequestTooLarge or ErrBusy, the connection is closed after
+// a request which is too large.
+//
+// Every method of Client has a variant with the Context suffix. When
+// the context is done, the call is cancelled on the server and the error
+// of the context is returned. Known errors of the server are returned
+// as the same values. A dropped connection is restored with exponential
+// backoff on the next call. A request which was not sent before
+// the connection dropped is sent again, a request which was sent may
+// have been executed, so ErrInterrupted is returned for it.
+//
+// Proofs and blocks are requested in batches with CreateProofs,
+// CreateUIDStateProofs and GetTransactionsBlocks. The client splits
+// a large batch into requests of SetBatchSize items, the server refuses
+// requests of more than service.MaxBatchSize items.
+//
+// A read-only server, SetReadOnly, refuses methods which change the state
+// of the operator or send transactions with ErrReadOnly.
+package transport
@@ -0,0 +1,63 @@
+package transport
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/SmartMeshFoundation/Spectrum/common"
+
+	"github.com/SmartMeshFoundation/SmartPlasma/transport/handlers"
+)
+
+// FeePolicy returns the address which receives fees and the fee
+// for one transaction. Fees are disabled if the fee is zero.
+func (c *Client) FeePolicy() (receiver common.Address,
+	minFee *big.Int, err error) {
+	ctx, cancel := c.newContext()
+	defer cancel()
+
+	return c.FeePolicyContext(ctx)
+}
+
+// FeePolicyContext is like FeePolicy but takes a context.
+func (c *Client) FeePolicyContext(ctx context.Context) (
+	receiver common.Address, minFee *big.Int, err error) {
+	req := &handlers.FeePolicyReq{}
+	var resp *handlers.FeePolicyResp
+	if err := c.call(ctx, FeePolicyMethod, req, &resp); err != nil {
+		return common.Address{}, nil, err
+	}
+
+	if resp.Error != "" {
+		return common.Address{}, nil, respError(resp.Error)
+	}
+
+	if resp.MinFee == nil {
+		resp.MinFee = new(big.Int)
+	}
+	return resp.Receiver, resp.MinFee, nil
+}
+
+// BlockFees returns the amount of fees paid to the operator
+// in the Plasma block.
+func (c *Client) BlockFees(block uint64) (*big.Int, error) {
+	ctx, cancel := c.newContext()
+	defer cancel()
+
+	return c.BlockFeesContext(ctx, block)
+}
+
+// BlockFeesContext is like BlockFees but takes a context.
+func (c *Client) BlockFeesContext(ctx context.Context,
+	block uint64) (*big.Int, error) {
+	req := &handlers.BlockFeesReq{Block: block}
+	var resp *handlers.BlockFeesResp
+	if err := c.call(ctx, BlockFeesMethod, req, &resp); err != nil {
+		return nil, err
+	}
+
+	if resp.Error != "" {
+		return nil, respError(resp.Error)
+	}
+	return resp.Fees, nil
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"math/big"
+
+	"github.com/SmartMeshFoundation/SmartPlasma/service"
+)
+
+// CreateProofs creates merkle proofs for pairs of UID and block number.
+func (api *SmartPlasma) CreateProofs(req *CreateProofsReq,
+	resp *CreateProofsResp) error {
+	ctx, cancel := api.newContext(req)
+	defer cancel()
+
+	reqs := make([]service.ProofRequest, len(req.Items))
+	for i, item := range req.Items {
+		reqs[i] = service.ProofRequest{UID: item.UID, Block: item.Block}
+	}
+
+	results, err := api.service.CreateProofs(ctx, reqs)
+	if err != nil {
+		resp.Error = err.Error()
+		return nil
+	}
+
+	resp.Proofs = make([][]byte, len(results))
+	resp.Errors = make([]string, len(results))
+	for i, result := range results {
+		resp.Proofs[i] = result.Proof
+		if result.Err != nil {
+			resp.Errors[i] = result.Err.Error()
+		}
+	}
+	return nil
+}
+
+// CreateUIDStateProofs creates merkle proofs for UIDs
+// in a checkpoint block.
+func (api *SmartPlasma) CreateUIDStateProofs(req *CreateUIDStateProofsReq,
+	resp *CreateUIDStateProofsResp) error {
+	ctx, cancel := api.newContext(req)
+	defer cancel()
+
+	proofs, err := api.service.CreateUIDStateProofs(
+		ctx, req.UIDs, req.CheckpointHash)
+	if err != nil {
+		resp.Error = err.Error()
+		return nil
+	}
+
+	resp.Proofs = make([][]byte, len(proofs))
+	resp.Nonces = make([]*big.Int, len(proofs))
+	for i, proof := range proofs {
+		resp.Proofs[i] = proof.Proof
+		resp.Nonces[i] = proof.Nonce
+		if proof.Nonce == nil {
+			resp.Nonces[i] = new(big.Int)
+		}
+	}
+	return nil
+}
+
+// GetTransactionsBlocks returns transactions blocks by range of numbers.
+func (api *SmartPlasma) GetTransactionsBlocks(req *GetTransactionsBlocksReq,
+	resp *GetTransactionsBlocksResp) error {
+	ctx, cancel := api.newContext(req)
+	defer cancel()
+
+	blocks, err := api.service.RawBlocksFromDB(ctx, req.From, req.To)
+	if err != nil {
+		resp.Error = err.Error()
+		return nil
+	}
+	resp.Blocks = blocks
+	return nil
+}
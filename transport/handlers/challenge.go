@@ -4,7 +4,7 @@ package handlers
 // on RootChain contract from a specific account.
 // Function received raw signed Ethereum transaction.
 func (api *SmartPlasma) ChallengeExit(req *RawReq, resp *RawResp) error {
-	ctx, cancel := api.newContext()
+	ctx, cancel := api.newContext(req)
 	defer cancel()
 
 	if err := api.service.RootChainTransaction(
@@ -19,7 +19,7 @@ func (api *SmartPlasma) ChallengeExit(req *RawReq, resp *RawResp) error {
 // Function received raw signed Ethereum transaction.
 func (api *SmartPlasma) ChallengeCheckpoint(req *RawReq,
 	resp *RawResp) error {
-	ctx, cancel := api.newContext()
+	ctx, cancel := api.newContext(req)
 	defer cancel()
 
 	if err := api.service.RootChainTransaction(
@@ -34,7 +34,7 @@ func (api *SmartPlasma) ChallengeCheckpoint(req *RawReq,
 // Function received raw signed Ethereum transaction.
 func (api *SmartPlasma) RespondChallengeExit(req *RawReq,
 	resp *RawResp) error {
-	ctx, cancel := api.newContext()
+	ctx, cancel := api.newContext(req)
 	defer cancel()
 
 	if err := api.service.RootChainTransaction(
@@ -50,7 +50,7 @@ func (api *SmartPlasma) RespondChallengeExit(req *RawReq,
 // Function received raw signed Ethereum transaction.
 func (api *SmartPlasma) RespondChallengeExitWithCheckpoint(req *RawReq,
 	resp *RawResp) error {
-	ctx, cancel := api.newContext()
+	ctx, cancel := api.newContext(req)
 	defer cancel()
 
 	if err := api.service.RootChainTransaction(
@@ -65,7 +65,7 @@ func (api *SmartPlasma) RespondChallengeExitWithCheckpoint(req *RawReq,
 // Function received raw signed Ethereum transaction.
 func (api *SmartPlasma) RespondCheckpointChallenge(req *RawReq,
 	resp *RawResp) error {
-	ctx, cancel := api.newContext()
+	ctx, cancel := api.newContext(req)
 	defer cancel()
 
 	if err := api.service.RootChainTransaction(
@@ -80,7 +80,7 @@ func (api *SmartPlasma) RespondCheckpointChallenge(req *RawReq,
 // Function received raw signed Ethereum transaction.
 func (api *SmartPlasma) RespondWithHistoricalCheckpoint(req *RawReq,
 	resp *RawResp) error {
-	ctx, cancel := api.newContext()
+	ctx, cancel := api.newContext(req)
 	defer cancel()
 
 	if err := api.service.RootChainTransaction(
@@ -94,7 +94,7 @@ func (api *SmartPlasma) RespondWithHistoricalCheckpoint(req *RawReq,
 // that a exit is blocked by a transaction of challenge.
 func (api *SmartPlasma) ChallengeExists(
 	req *ChallengeExistsReq, resp *ChallengeExistsResp) error {
-	ctx, cancel := api.newContext()
+	ctx, cancel := api.newContext(req)
 	defer cancel()
 
 	exists, err := api.service.ChallengeExists(
@@ -110,7 +110,7 @@ func (api *SmartPlasma) ChallengeExists(
 // that a checkpoint is blocked by a transaction of challenge.
 func (api *SmartPlasma) CheckpointIsChallenge(
 	req *CheckpointIsChallengeReq, resp *CheckpointIsChallengeResp) error {
-	ctx, cancel := api.newContext()
+	ctx, cancel := api.newContext(req)
 	defer cancel()
 
 	exists, err := api.service.CheckpointIsChallenge(ctx,
@@ -125,7 +125,7 @@ func (api *SmartPlasma) CheckpointIsChallenge(
 // ChallengesLength returns number of disputes on withdrawal of uid.
 func (api *SmartPlasma) ChallengesLength(
 	req *ChallengesLengthReq, resp *ChallengesLengthResp) error {
-	ctx, cancel := api.newContext()
+	ctx, cancel := api.newContext(req)
 	defer cancel()
 
 	length, err := api.service.ChallengesLength(ctx, req.UID)
@@ -141,7 +141,7 @@ func (api *SmartPlasma) ChallengesLength(
 func (api *SmartPlasma) CheckpointChallengesLength(
 	req *CheckpointChallengesLengthReq,
 	resp *CheckpointChallengesLengthResp) error {
-	ctx, cancel := api.newContext()
+	ctx, cancel := api.newContext(req)
 	defer cancel()
 
 	length, err := api.service.CheckpointChallengesLength(
@@ -156,7 +156,7 @@ func (api *SmartPlasma) CheckpointChallengesLength(
 // GetChallenge returns exit challenge transaction by uid and index.
 func (api *SmartPlasma) GetChallenge(
 	req *GetChallengeReq, resp *GetChallengeResp) error {
-	ctx, cancel := api.newContext()
+	ctx, cancel := api.newContext(req)
 	defer cancel()
 
 	result, err := api.service.GetChallenge(
@@ -173,7 +173,7 @@ func (api *SmartPlasma) GetChallenge(
 // by checkpoint merkle root, uid and index.
 func (api *SmartPlasma) GetCheckpointChallenge(
 	req *GetCheckpointChallengeReq, resp *GetCheckpointChallengeResp) error {
-	ctx, cancel := api.newContext()
+	ctx, cancel := api.newContext(req)
 	defer cancel()
 
 	result, err := api.service.GetCheckpointChallenge(
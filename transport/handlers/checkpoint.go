@@ -1,6 +1,10 @@
 package handlers
 
-import "github.com/SmartMeshFoundation/SmartPlasma/blockchan/block/checkpoints"
+import (
+	"github.com/SmartMeshFoundation/Spectrum/rlp"
+
+	"github.com/SmartMeshFoundation/SmartPlasma/blockchan/block/checkpoints"
+)
 
 // BuildCheckpoint builds current checkpoints block.
 func (api *SmartPlasma) BuildCheckpoint(req *BuildCheckpointReq,
@@ -16,7 +20,7 @@ func (api *SmartPlasma) BuildCheckpoint(req *BuildCheckpointReq,
 // SendCheckpointHash sends hash of checkpoints block to RootChain contract.
 func (api *SmartPlasma) SendCheckpointHash(req *SendCheckpointHashReq,
 	resp *SendCheckpointHashResp) error {
-	ctx, cancel := api.newContext()
+	ctx, cancel := api.newContext(req)
 	defer cancel()
 
 	tx, err := api.service.SendChptHash(ctx, req.Hash)
@@ -61,6 +65,15 @@ func (api *SmartPlasma) SaveCheckpointToDB(req *SaveCheckpointToDBReq,
 	}
 
 	err = api.service.SaveCheckpointToDB(blk)
+	if err != nil {
+		resp.Error = err.Error()
+		return nil
+	}
+
+	ctx, cancel := api.newContext(req)
+	defer cancel()
+
+	err = api.service.RegisterPublishedCheckpoint(ctx, blk.Hash())
 	if err != nil {
 		resp.Error = err.Error()
 	}
@@ -70,6 +83,16 @@ func (api *SmartPlasma) SaveCheckpointToDB(req *SaveCheckpointToDBReq,
 // InitCheckpoint initializes the new current checkpoints block.
 func (api *SmartPlasma) InitCheckpoint(req *InitCheckpointReq,
 	resp *InitCheckpointResp) error {
+	ctx, cancel := api.newContext(req)
+	defer cancel()
+
+	err := api.service.RegisterPublishedCheckpoint(ctx,
+		api.service.CurrentCheckpoint().Hash())
+	if err != nil {
+		resp.Error = err.Error()
+		return nil
+	}
+
 	api.service.InitCheckpoint()
 	return nil
 }
@@ -78,7 +101,17 @@ func (api *SmartPlasma) InitCheckpoint(req *InitCheckpointReq,
 func (api *SmartPlasma) SaveCurrentCheckpointBlock(
 	req *SaveCurrentCheckpointBlockReq,
 	resp *SaveCurrentCheckpointBlockResp) error {
-	err := api.service.SaveCheckpointToDB(api.service.CurrentCheckpoint())
+	chpt := api.service.CurrentCheckpoint()
+	err := api.service.SaveCheckpointToDB(chpt)
+	if err != nil {
+		resp.Error = err.Error()
+		return nil
+	}
+
+	ctx, cancel := api.newContext(req)
+	defer cancel()
+
+	err = api.service.RegisterPublishedCheckpoint(ctx, chpt.Hash())
 	if err != nil {
 		resp.Error = err.Error()
 	}
@@ -95,3 +128,52 @@ func (api *SmartPlasma) GetCheckpointsBlock(req *GetCheckpointsBlockReq,
 	resp.Block = raw
 	return nil
 }
+
+// GetCheckpointOptIn returns RLP encoded opt-in of the owner of UID
+// saved with checkpoints block.
+func (api *SmartPlasma) GetCheckpointOptIn(req *GetCheckpointOptInReq,
+	resp *GetCheckpointOptInResp) error {
+	optIn, err := api.service.CheckpointOptIn(req.Hash, req.UID)
+	if err != nil {
+		resp.Error = err.Error()
+		return nil
+	}
+
+	raw, err := rlp.EncodeToBytes(optIn)
+	if err != nil {
+		resp.Error = err.Error()
+	}
+	resp.OptIn = raw
+	return nil
+}
+
+// ListCheckpoints returns records of the checkpoint registry.
+func (api *SmartPlasma) ListCheckpoints(req *ListCheckpointsReq,
+	resp *ListCheckpointsResp) error {
+	ctx, cancel := api.newContext(req)
+	defer cancel()
+
+	infos, err := api.service.ListCheckpoints(ctx, req.From, req.Limit)
+	if err != nil {
+		resp.Error = err.Error()
+		return nil
+	}
+	resp.Checkpoints = infos
+	return nil
+}
+
+// LatestCheckpointFor returns the record of the latest registered
+// checkpoint which fixes UID.
+func (api *SmartPlasma) LatestCheckpointFor(req *LatestCheckpointForReq,
+	resp *LatestCheckpointForResp) error {
+	ctx, cancel := api.newContext(req)
+	defer cancel()
+
+	info, err := api.service.LatestCheckpointFor(ctx, req.UID)
+	if err != nil {
+		resp.Error = err.Error()
+		return nil
+	}
+	resp.Checkpoint = info
+	return nil
+}
@@ -3,7 +3,7 @@ package handlers
 // DepositCount returns a deposit counter.
 func (api *SmartPlasma) DepositCount(
 	req *DepositCountReq, resp *DepositCountResp) error {
-	ctx, cancel := api.newContext()
+	ctx, cancel := api.newContext(req)
 	defer cancel()
 
 	count, err := api.service.DepositCount(ctx)
@@ -17,7 +17,7 @@ func (api *SmartPlasma) DepositCount(
 // ChallengePeriod returns a period for challenging in seconds.
 func (api *SmartPlasma) ChallengePeriod(
 	req *ChallengePeriodReq, resp *ChallengePeriodResp) error {
-	ctx, cancel := api.newContext()
+	ctx, cancel := api.newContext(req)
 	defer cancel()
 
 	secs, err := api.service.ChallengePeriod(ctx)
@@ -30,7 +30,7 @@ func (api *SmartPlasma) ChallengePeriod(
 
 // Operator returns a Plasma Cash operator address.
 func (api *SmartPlasma) Operator(req *OperatorReq, resp *OperatorResp) error {
-	ctx, cancel := api.newContext()
+	ctx, cancel := api.newContext(req)
 	defer cancel()
 
 	operator, err := api.service.Operator(ctx)
@@ -44,7 +44,7 @@ func (api *SmartPlasma) Operator(req *OperatorReq, resp *OperatorResp) error {
 // ChildChain returns a block hash by a block number.
 func (api *SmartPlasma) ChildChain(
 	req *ChildChainReq, resp *ChildChainResp) error {
-	ctx, cancel := api.newContext()
+	ctx, cancel := api.newContext(req)
 	defer cancel()
 
 	hash, err := api.service.ChildChain(ctx, req.BlockNumber)
@@ -58,7 +58,7 @@ func (api *SmartPlasma) ChildChain(
 // Wallet returns a deposit amount.
 func (api *SmartPlasma) Wallet(
 	req *WalletReq, resp *WalletResp) error {
-	ctx, cancel := api.newContext()
+	ctx, cancel := api.newContext(req)
 	defer cancel()
 
 	amount, err := api.service.Wallet(ctx, req.UID)
@@ -72,7 +72,7 @@ func (api *SmartPlasma) Wallet(
 // Wallet2 returns a deposit SmartPlasma block.
 func (api *SmartPlasma) Wallet2(
 	req *Wallet2Req, resp *Wallet2Resp) error {
-	ctx, cancel := api.newContext()
+	ctx, cancel := api.newContext(req)
 	defer cancel()
 
 	block, err := api.service.Wallet2(ctx, req.UID)
@@ -85,7 +85,7 @@ func (api *SmartPlasma) Wallet2(
 
 // Exits returns a incomplete exit by UID.
 func (api *SmartPlasma) Exits(req *ExitsReq, resp *ExitsResp) error {
-	ctx, cancel := api.newContext()
+	ctx, cancel := api.newContext(req)
 	defer cancel()
 
 	result, err := api.service.Exits(ctx, req.UID)
@@ -100,3 +100,32 @@ func (api *SmartPlasma) Exits(req *ExitsReq, resp *ExitsResp) error {
 	resp.TxBeforeExitTx = result.TxBeforeExitTx
 	return nil
 }
+
+// FeePolicy returns the fee policy of the operator.
+func (api *SmartPlasma) FeePolicy(req *FeePolicyReq,
+	resp *FeePolicyResp) error {
+	policy := api.service.FeePolicy()
+	resp.Receiver = policy.Receiver
+	resp.MinFee = policy.MinFee
+	return nil
+}
+
+// BlockFees returns the amount of fees paid in a Plasma block.
+func (api *SmartPlasma) BlockFees(req *BlockFeesReq,
+	resp *BlockFeesResp) error {
+	fees, err := api.service.BlockFees(req.Block)
+	if err != nil {
+		resp.Error = err.Error()
+		return nil
+	}
+	resp.Fees = fees
+	return nil
+}
+
+// Domain returns the domain which signatures of transactions are bound to.
+func (api *SmartPlasma) Domain(req *DomainReq, resp *DomainResp) error {
+	domain := api.service.Domain()
+	resp.ChainID = domain.ChainID
+	resp.RootChain = domain.RootChain
+	return nil
+}
@@ -5,6 +5,8 @@ import (
 
 	"github.com/SmartMeshFoundation/Spectrum"
 	"github.com/SmartMeshFoundation/Spectrum/common"
+
+	"github.com/SmartMeshFoundation/SmartPlasma/service"
 )
 
 // AcceptTransactionReq is request for send Plasma transaction to PRC server.
@@ -29,18 +31,51 @@ type CreateProofResp struct {
 	Error string
 }
 
-// AddCheckpointReq is request for AddCheckpoint method.
-type AddCheckpointReq struct {
-	UID         *big.Int
-	Nonce       *big.Int
-	BlockNumber uint64
+// OptInCheckpointReq is request for OptInCheckpoint method.
+type OptInCheckpointReq struct {
+	OptIn []byte
 }
 
-// AddCheckpointResp is response for AddCheckpoint method.
-type AddCheckpointResp struct {
+// OptInCheckpointResp is response for OptInCheckpoint method.
+type OptInCheckpointResp struct {
 	Error string
 }
 
+// GetCheckpointOptInReq is request for GetCheckpointOptIn method.
+type GetCheckpointOptInReq struct {
+	Hash common.Hash
+	UID  *big.Int
+}
+
+// GetCheckpointOptInResp is response for GetCheckpointOptIn method.
+type GetCheckpointOptInResp struct {
+	OptIn []byte
+	Error string
+}
+
+// ListCheckpointsReq is request for ListCheckpoints method.
+type ListCheckpointsReq struct {
+	From  uint64
+	Limit int
+}
+
+// ListCheckpointsResp is response for ListCheckpoints method.
+type ListCheckpointsResp struct {
+	Checkpoints []*service.CheckpointInfo
+	Error       string
+}
+
+// LatestCheckpointForReq is request for LatestCheckpointFor method.
+type LatestCheckpointForReq struct {
+	UID *big.Int
+}
+
+// LatestCheckpointForResp is response for LatestCheckpointFor method.
+type LatestCheckpointForResp struct {
+	Checkpoint *service.CheckpointInfo
+	Error      string
+}
+
 // CreateUIDStateProofReq is request for CreateUIDStateProof method.
 type CreateUIDStateProofReq struct {
 	UID            *big.Int
@@ -454,3 +489,99 @@ type ValidateBlockReq struct {
 type ValidateBlockResp struct {
 	Error string
 }
+
+// CancelReq is request to cancel a call in progress.
+type CancelReq struct {
+	Seq uint64
+}
+
+// CancelResp is response for Cancel method.
+type CancelResp struct {
+	Error string
+}
+
+// ProofItem is a pair of UID and block number for a proof.
+type ProofItem struct {
+	UID   *big.Int
+	Block uint64
+}
+
+// CreateProofsReq is request for CreateProofs method.
+type CreateProofsReq struct {
+	Items []ProofItem
+}
+
+// CreateProofsResp is response for CreateProofs method.
+// Errors contains the error of every item or an empty string.
+type CreateProofsResp struct {
+	Proofs [][]byte
+	Errors []string
+	Error  string
+}
+
+// CreateUIDStateProofsReq is request for CreateUIDStateProofs method.
+type CreateUIDStateProofsReq struct {
+	UIDs           []*big.Int
+	CheckpointHash common.Hash
+}
+
+// CreateUIDStateProofsResp is response for CreateUIDStateProofs method.
+type CreateUIDStateProofsResp struct {
+	Proofs [][]byte
+	Nonces []*big.Int
+	Error  string
+}
+
+// GetTransactionsBlocksReq is request for GetTransactionsBlocks method.
+type GetTransactionsBlocksReq struct {
+	From uint64
+	To   uint64
+}
+
+// GetTransactionsBlocksResp is response for GetTransactionsBlocks method.
+type GetTransactionsBlocksResp struct {
+	Blocks [][]byte
+	Error  string
+}
+
+// GetPendingSwapReq is request for a swap waiting for a signature.
+type GetPendingSwapReq struct {
+	Hash common.Hash
+}
+
+// GetPendingSwapResp is response for a swap waiting for a signature.
+type GetPendingSwapResp struct {
+	Tx    []byte
+	Error string
+}
+
+// FeePolicyReq is request for FeePolicy method.
+type FeePolicyReq struct{}
+
+// FeePolicyResp is response for FeePolicy method.
+type FeePolicyResp struct {
+	Receiver common.Address
+	MinFee   *big.Int
+	Error    string
+}
+
+// BlockFeesReq is request for BlockFees method.
+type BlockFeesReq struct {
+	Block uint64
+}
+
+// BlockFeesResp is response for BlockFees method.
+type BlockFeesResp struct {
+	Fees  *big.Int
+	Error string
+}
+
+// DomainReq is request for Domain method.
+type DomainReq struct{}
+
+// DomainResp is response for Domain method.
+type DomainResp struct {
+	ChainID   *big.Int
+	RootChain common.Address
+	Error     string
+}
@@ -21,7 +21,7 @@ func (api *SmartPlasma) BuildBlock(req *BuildBlockReq,
 // SendBlockHash sends hash of transactions block to RootChain contract.
 func (api *SmartPlasma) SendBlockHash(req *SendBlockHashReq,
 	resp *SendBlockHashResp) error {
-	ctx, cancel := api.newContext()
+	ctx, cancel := api.newContext(req)
 	defer cancel()
 
 	tx, err := api.service.SendBlockHash(ctx, req.Hash)
@@ -40,7 +40,7 @@ func (api *SmartPlasma) SendBlockHash(req *SendBlockHashReq,
 // LastBlockNumber gets number by transactions block from RootChain contract.
 func (api *SmartPlasma) LastBlockNumber(req *LastBlockNumberReq,
 	resp *LastBlockNumberResp) error {
-	ctx, cancel := api.newContext()
+	ctx, cancel := api.newContext(req)
 	defer cancel()
 
 	number, err := api.service.LastBlockNumber(ctx)
@@ -111,7 +111,7 @@ func (api *SmartPlasma) GetTransactionsBlock(req *GetTransactionsBlockReq,
 func (api *SmartPlasma) ValidateBlock(req *ValidateBlockReq,
 	resp *ValidateBlockResp) error {
 	ctx, cancel := context.WithTimeout(
-		context.Background(), time.Second*600) // TODO: timeout hardcoded
+		api.callContext(req), time.Second*600) // TODO: timeout hardcoded
 	defer cancel()
 
 	if err := api.service.ValidateBlock(ctx); err != nil {
@@ -43,7 +43,7 @@ func (api *SmartPlasma) CreateUIDStateProof(req *CreateUIDStateProofReq,
 // VerifyCheckpointProof checks whether the UID is included in the block.
 func (api *SmartPlasma) VerifyCheckpointProof(req *VerifyCheckpointProofReq,
 	resp *VerifyCheckpointProofResp) error {
-	ctx, cancel := api.newContext()
+	ctx, cancel := api.newContext(req)
 	defer cancel()
 
 	exists, err := api.service.IsValidCheckpoint(
@@ -2,8 +2,12 @@ package handlers
 
 import (
 	"context"
+	"sync"
 	"time"
 
+	"github.com/SmartMeshFoundation/Spectrum/log"
+
+	"github.com/SmartMeshFoundation/SmartPlasma/logger"
 	"github.com/SmartMeshFoundation/SmartPlasma/service"
 )
 
@@ -11,17 +15,64 @@ import (
 type SmartPlasma struct {
 	timeout int
 	service *service.Service
+	logger  log.Logger
+
+	mtx      sync.Mutex
+	contexts map[interface{}]context.Context
 }
 
 // NewSmartPlasma creates new SmartPlasma handler service.
 func NewSmartPlasma(timeout int, service *service.Service) *SmartPlasma {
 	return &SmartPlasma{
-		timeout: timeout,
-		service: service,
+		timeout:  timeout,
+		service:  service,
+		logger:   logger.New("handlers"),
+		contexts: make(map[interface{}]context.Context),
+	}
+}
+
+// SetLogger sets the logger for the handler service.
+func (api *SmartPlasma) SetLogger(l log.Logger) {
+	api.logger = logger.Wrap(l)
+}
+
+// BindContext binds a request object to the context of its RPC call.
+// Contexts used by the handler of the request are derived
+// from the bound context.
+func (api *SmartPlasma) BindContext(ctx context.Context, req interface{}) {
+	api.mtx.Lock()
+	defer api.mtx.Unlock()
+
+	api.contexts[req] = ctx
+}
+
+// UnbindContext removes the binding made by BindContext.
+func (api *SmartPlasma) UnbindContext(req interface{}) {
+	api.mtx.Lock()
+	defer api.mtx.Unlock()
+
+	delete(api.contexts, req)
+}
+
+// callContext returns the context bound to the request object
+// or a new context with a new request identifier.
+func (api *SmartPlasma) callContext(req interface{}) context.Context {
+	api.mtx.Lock()
+	ctx, ok := api.contexts[req]
+	api.mtx.Unlock()
+
+	if ok {
+		return ctx
 	}
+	return logger.WithRequestID(context.Background(), logger.NewRequestID())
+}
+
+func (api *SmartPlasma) newContext(
+	req interface{}) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(api.callContext(req),
+		time.Duration(api.timeout)*time.Second)
 }
 
-func (api *SmartPlasma) newContext() (context.Context, context.CancelFunc) {
-	return context.WithTimeout(
-		context.Background(), time.Duration(api.timeout)*time.Second)
+func (api *SmartPlasma) log(req interface{}) log.Logger {
+	return logger.FromContext(api.callContext(req), api.logger)
 }
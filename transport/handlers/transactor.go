@@ -5,7 +5,7 @@ import "github.com/SmartMeshFoundation/Spectrum/core/types"
 // PendingCodeAt returns the code of the given Account in the pending state.
 func (api *SmartPlasma) PendingCodeAt(req *PendingCodeAtReq,
 	resp *PendingCodeAtResp) error {
-	ctx, cancel := api.newContext()
+	ctx, cancel := api.newContext(req)
 	defer cancel()
 
 	code, err := api.service.PendingCodeAt(ctx, req.Account)
@@ -21,7 +21,7 @@ func (api *SmartPlasma) PendingCodeAt(req *PendingCodeAtReq,
 // associated with an Account.
 func (api *SmartPlasma) PendingNonceAt(req *PendingNonceAtReq,
 	resp *PendingNonceAtResp) error {
-	ctx, cancel := api.newContext()
+	ctx, cancel := api.newContext(req)
 	defer cancel()
 
 	nonce, err := api.service.PendingNonceAt(ctx, req.Account)
@@ -37,7 +37,7 @@ func (api *SmartPlasma) PendingNonceAt(req *PendingNonceAtReq,
 // to allow a timely execution of a transaction.
 func (api *SmartPlasma) SuggestGasPrice(req *SuggestGasPriceReq,
 	resp *SuggestGasPriceResp) error {
-	ctx, cancel := api.newContext()
+	ctx, cancel := api.newContext(req)
 	defer cancel()
 
 	price, err := api.service.SuggestGasPrice(ctx)
@@ -56,7 +56,7 @@ func (api *SmartPlasma) SuggestGasPrice(req *SuggestGasPriceReq,
 // for setting a reasonable default.
 func (api *SmartPlasma) EstimateGas(req *EstimateGasReq,
 	resp *EstimateGasResp) error {
-	ctx, cancel := api.newContext()
+	ctx, cancel := api.newContext(req)
 	defer cancel()
 
 	gas, err := api.service.EstimateGas(ctx, req.Call)
@@ -79,7 +79,7 @@ func (api *SmartPlasma) WaitMined(req *WaitMinedReq,
 		return nil
 	}
 
-	ctx, cancel := api.newContext()
+	ctx, cancel := api.newContext(req)
 	defer cancel()
 
 	tr, err := api.service.Mine(ctx, tx)
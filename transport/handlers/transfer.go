@@ -3,13 +3,16 @@ package handlers
 import (
 	"bytes"
 
+	"github.com/SmartMeshFoundation/Spectrum/rlp"
+
 	"github.com/SmartMeshFoundation/SmartPlasma/blockchan/transaction"
+	"github.com/SmartMeshFoundation/SmartPlasma/service"
 )
 
 // Deposit invokes deposit method on Mediator contract from a specific account.
 // Function received raw signed Ethereum transaction.
 func (api *SmartPlasma) Deposit(req *RawReq, resp *RawResp) error {
-	ctx, cancel := api.newContext()
+	ctx, cancel := api.newContext(req)
 	defer cancel()
 
 	if err := api.service.MediatorTransaction(
@@ -23,7 +26,7 @@ func (api *SmartPlasma) Deposit(req *RawReq, resp *RawResp) error {
 // on Mediator contract from a specific account.
 // Function received raw signed Ethereum transaction.
 func (api *SmartPlasma) Withdraw(req *RawReq, resp *RawResp) error {
-	ctx, cancel := api.newContext()
+	ctx, cancel := api.newContext(req)
 	defer cancel()
 
 	if err := api.service.MediatorTransaction(
@@ -37,7 +40,7 @@ func (api *SmartPlasma) Withdraw(req *RawReq, resp *RawResp) error {
 // on RootChain contract from a specific account.
 // Function received raw signed Ethereum transaction.
 func (api *SmartPlasma) StartExit(req *RawReq, resp *RawResp) error {
-	ctx, cancel := api.newContext()
+	ctx, cancel := api.newContext(req)
 	defer cancel()
 
 	if err := api.service.RootChainTransaction(
@@ -53,6 +56,8 @@ func (api *SmartPlasma) AcceptTransaction(req *AcceptTransactionReq,
 	tx := &transaction.Transaction{}
 
 	if err := transaction.DecodeRLP(bytes.NewBuffer(req.Tx), tx); err != nil {
+		api.log(req).Debug("Failed to decode transaction",
+			"size", len(req.Tx), "err", err)
 		resp.Error = err.Error()
 		return nil
 	}
@@ -62,12 +67,37 @@ func (api *SmartPlasma) AcceptTransaction(req *AcceptTransactionReq,
 	return nil
 }
 
-// AddCheckpoint accepts UID with transaction number for current checkpoint.
-func (api *SmartPlasma) AddCheckpoint(req *AddCheckpointReq,
-	resp *AddCheckpointResp) error {
-	if err := api.service.AcceptUIDState(
-		req.UID, req.Nonce, req.BlockNumber); err != nil {
+// OptInCheckpoint accepts RLP encoded opt-in of a coin owner
+// for current checkpoint.
+func (api *SmartPlasma) OptInCheckpoint(req *OptInCheckpointReq,
+	resp *OptInCheckpointResp) error {
+	optIn := &transaction.OptIn{}
+	if err := rlp.DecodeBytes(req.OptIn, optIn); err != nil {
+		resp.Error = err.Error()
+		return nil
+	}
+
+	if err := api.service.AcceptOptIn(optIn); err != nil {
+		resp.Error = err.Error()
+	}
+	return nil
+}
+
+// GetPendingSwap returns a swap which waits for the signature
+// of the second owner.
+func (api *SmartPlasma) GetPendingSwap(req *GetPendingSwapReq,
+	resp *GetPendingSwapResp) error {
+	tx, ok := api.service.PendingSwap(req.Hash)
+	if !ok {
+		resp.Error = service.ErrSwapNotFound.Error()
+		return nil
+	}
+
+	buf := bytes.NewBuffer(nil)
+	if err := tx.EncodeRLP(buf); err != nil {
 		resp.Error = err.Error()
+		return nil
 	}
+	resp.Tx = buf.Bytes()
 	return nil
 }
@@ -1,11 +1,12 @@
 package transport
 
 import (
+	"context"
 	"math/big"
 
 	"github.com/SmartMeshFoundation/Spectrum/common"
-	"github.com/pkg/errors"
 
+	"github.com/SmartMeshFoundation/SmartPlasma/blockchan/transaction"
 	"github.com/SmartMeshFoundation/SmartPlasma/contract/rootchain"
 	"github.com/SmartMeshFoundation/SmartPlasma/transport/handlers"
 )
@@ -15,6 +16,12 @@ func (c *Client) DepositCount() (count *big.Int, err error) {
 	ctx, cancel := c.newContext()
 	defer cancel()
 
+	return c.DepositCountContext(ctx)
+}
+
+// DepositCountContext is like DepositCount but takes a context.
+func (c *Client) DepositCountContext(ctx context.Context) (count *big.Int,
+	err error) {
 	if c.sessionRootChain != nil {
 		session := rootchain.CopySession(c.sessionRootChain)
 		session.TransactOpts.Context = ctx
@@ -22,19 +29,12 @@ func (c *Client) DepositCount() (count *big.Int, err error) {
 	}
 	req := &handlers.DepositCountReq{}
 	var resp *handlers.DepositCountResp
-	call := c.connect.Go(DepositCountMethod, req, &resp, nil)
-
-	select {
-	case replay := <-call.Done:
-		if replay.Error != nil {
-			return nil, replay.Error
-		}
-	case <-ctx.Done():
-		return nil, errors.New("timeout")
+	if err := c.call(ctx, DepositCountMethod, req, &resp); err != nil {
+		return nil, err
 	}
 
 	if resp.Error != "" {
-		return nil, errors.New(resp.Error)
+		return nil, respError(resp.Error)
 	}
 
 	return resp.Count, err
@@ -45,6 +45,12 @@ func (c *Client) ChallengePeriod() (count *big.Int, err error) {
 	ctx, cancel := c.newContext()
 	defer cancel()
 
+	return c.ChallengePeriodContext(ctx)
+}
+
+// ChallengePeriodContext is like ChallengePeriod but takes a context.
+func (c *Client) ChallengePeriodContext(ctx context.Context) (count *big.Int,
+	err error) {
 	if c.sessionRootChain != nil {
 		session := rootchain.CopySession(c.sessionRootChain)
 		session.TransactOpts.Context = ctx
@@ -53,19 +59,12 @@ func (c *Client) ChallengePeriod() (count *big.Int, err error) {
 
 	req := &handlers.ChallengePeriodReq{}
 	var resp *handlers.ChallengePeriodResp
-	call := c.connect.Go(ChallengePeriodMethod, req, &resp, nil)
-
-	select {
-	case replay := <-call.Done:
-		if replay.Error != nil {
-			return nil, replay.Error
-		}
-	case <-ctx.Done():
-		return nil, errors.New("timeout")
+	if err := c.call(ctx, ChallengePeriodMethod, req, &resp); err != nil {
+		return nil, err
 	}
 
 	if resp.Error != "" {
-		return nil, errors.New(resp.Error)
+		return nil, respError(resp.Error)
 	}
 
 	return resp.ChallengePeriod, err
@@ -76,6 +75,12 @@ func (c *Client) Operator() (address common.Address, err error) {
 	ctx, cancel := c.newContext()
 	defer cancel()
 
+	return c.OperatorContext(ctx)
+}
+
+// OperatorContext is like Operator but takes a context.
+func (c *Client) OperatorContext(ctx context.Context) (address common.Address,
+	err error) {
 	if c.sessionRootChain != nil {
 		session := rootchain.CopySession(c.sessionRootChain)
 		session.TransactOpts.Context = ctx
@@ -83,19 +88,12 @@ func (c *Client) Operator() (address common.Address, err error) {
 	}
 	req := &handlers.OperatorReq{}
 	var resp *handlers.OperatorResp
-	call := c.connect.Go(OperatorMethod, req, &resp, nil)
-
-	select {
-	case replay := <-call.Done:
-		if replay.Error != nil {
-			return common.Address{}, replay.Error
-		}
-	case <-ctx.Done():
-		return common.Address{}, errors.New("timeout")
+	if err := c.call(ctx, OperatorMethod, req, &resp); err != nil {
+		return common.Address{}, err
 	}
 
 	if resp.Error != "" {
-		return common.Address{}, errors.New(resp.Error)
+		return common.Address{}, respError(resp.Error)
 	}
 
 	return resp.Operator, err
@@ -107,6 +105,12 @@ func (c *Client) ChildChain(
 	ctx, cancel := c.newContext()
 	defer cancel()
 
+	return c.ChildChainContext(ctx, blockNumber)
+}
+
+// ChildChainContext is like ChildChain but takes a context.
+func (c *Client) ChildChainContext(ctx context.Context,
+	blockNumber *big.Int) (hash common.Hash, err error) {
 	if c.sessionRootChain != nil {
 		session := rootchain.CopySession(c.sessionRootChain)
 		session.TransactOpts.Context = ctx
@@ -116,19 +120,12 @@ func (c *Client) ChildChain(
 		BlockNumber: blockNumber,
 	}
 	var resp *handlers.ChildChainResp
-	call := c.connect.Go(ChildChainMethod, req, &resp, nil)
-
-	select {
-	case replay := <-call.Done:
-		if replay.Error != nil {
-			return common.Hash{}, replay.Error
-		}
-	case <-ctx.Done():
-		return common.Hash{}, errors.New("timeout")
+	if err := c.call(ctx, ChildChainMethod, req, &resp); err != nil {
+		return common.Hash{}, err
 	}
 
 	if resp.Error != "" {
-		return common.Hash{}, errors.New(resp.Error)
+		return common.Hash{}, respError(resp.Error)
 	}
 
 	return resp.BlockHash, err
@@ -139,6 +136,12 @@ func (c *Client) Exits(uid *big.Int) (resp *handlers.ExitsResp, err error) {
 	ctx, cancel := c.newContext()
 	defer cancel()
 
+	return c.ExitsContext(ctx, uid)
+}
+
+// ExitsContext is like Exits but takes a context.
+func (c *Client) ExitsContext(ctx context.Context,
+	uid *big.Int) (resp *handlers.ExitsResp, err error) {
 	if c.sessionRootChain != nil {
 		session := rootchain.CopySession(c.sessionRootChain)
 		session.TransactOpts.Context = ctx
@@ -160,19 +163,12 @@ func (c *Client) Exits(uid *big.Int) (resp *handlers.ExitsResp, err error) {
 		UID: uid,
 	}
 
-	call := c.connect.Go(ExitsMethod, req, &resp, nil)
-
-	select {
-	case replay := <-call.Done:
-		if replay.Error != nil {
-			return nil, replay.Error
-		}
-	case <-ctx.Done():
-		return nil, errors.New("timeout")
+	if err := c.call(ctx, ExitsMethod, req, &resp); err != nil {
+		return nil, err
 	}
 
 	if resp.Error != "" {
-		return nil, errors.New(resp.Error)
+		return nil, respError(resp.Error)
 	}
 
 	return resp, err
@@ -183,6 +179,12 @@ func (c *Client) Wallet(uid *big.Int) (amount *big.Int, err error) {
 	ctx, cancel := c.newContext()
 	defer cancel()
 
+	return c.WalletContext(ctx, uid)
+}
+
+// WalletContext is like Wallet but takes a context.
+func (c *Client) WalletContext(ctx context.Context,
+	uid *big.Int) (amount *big.Int, err error) {
 	if c.sessionRootChain != nil {
 		session := rootchain.CopySession(c.sessionRootChain)
 		session.TransactOpts.Context = ctx
@@ -192,19 +194,12 @@ func (c *Client) Wallet(uid *big.Int) (amount *big.Int, err error) {
 		UID: uid,
 	}
 	var resp *handlers.WalletResp
-	call := c.connect.Go(WalletMethod, req, &resp, nil)
-
-	select {
-	case replay := <-call.Done:
-		if replay.Error != nil {
-			return nil, replay.Error
-		}
-	case <-ctx.Done():
-		return nil, errors.New("timeout")
+	if err := c.call(ctx, WalletMethod, req, &resp); err != nil {
+		return nil, err
 	}
 
 	if resp.Error != "" {
-		return nil, errors.New(resp.Error)
+		return nil, respError(resp.Error)
 	}
 
 	return resp.Amount, err
@@ -215,6 +210,12 @@ func (c *Client) Wallet2(uid *big.Int) (block *big.Int, err error) {
 	ctx, cancel := c.newContext()
 	defer cancel()
 
+	return c.Wallet2Context(ctx, uid)
+}
+
+// Wallet2Context is like Wallet2 but takes a context.
+func (c *Client) Wallet2Context(ctx context.Context,
+	uid *big.Int) (block *big.Int, err error) {
 	if c.sessionRootChain != nil {
 		session := rootchain.CopySession(c.sessionRootChain)
 		session.TransactOpts.Context = ctx
@@ -224,20 +225,37 @@ func (c *Client) Wallet2(uid *big.Int) (block *big.Int, err error) {
 		UID: uid,
 	}
 	var resp *handlers.Wallet2Resp
-	call := c.connect.Go(Wallet2Method, req, &resp, nil)
-
-	select {
-	case replay := <-call.Done:
-		if replay.Error != nil {
-			return nil, replay.Error
-		}
-	case <-ctx.Done():
-		return nil, errors.New("timeout")
+	if err := c.call(ctx, Wallet2Method, req, &resp); err != nil {
+		return nil, err
 	}
 
 	if resp.Error != "" {
-		return nil, errors.New(resp.Error)
+		return nil, respError(resp.Error)
 	}
 
 	return resp.BlockNumber, err
 }
+
+// Domain returns the domain which signatures of transactions
+// are bound to by PlasmaCash RPC server.
+func (c *Client) Domain() (transaction.Domain, error) {
+	ctx, cancel := c.newContext()
+	defer cancel()
+
+	return c.DomainContext(ctx)
+}
+
+// DomainContext is like Domain but takes a context.
+func (c *Client) DomainContext(
+	ctx context.Context) (transaction.Domain, error) {
+	req := &handlers.DomainReq{}
+	var resp *handlers.DomainResp
+	if err := c.call(ctx, DomainMethod, req, &resp); err != nil {
+		return transaction.Domain{}, err
+	}
+
+	if resp.Error != "" {
+		return transaction.Domain{}, respError(resp.Error)
+	}
+	return transaction.NewDomain(resp.ChainID, resp.RootChain), nil
+}
@@ -0,0 +1,334 @@
+package transport
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/SmartMeshFoundation/Spectrum/common"
+	"github.com/pkg/errors"
+
+	"github.com/SmartMeshFoundation/SmartPlasma/blockchan/transaction"
+	"github.com/SmartMeshFoundation/SmartPlasma/transport/handlers"
+)
+
+// Errors returned to clients which hit limits of the server.
+var (
+	ErrRateLimited     = errors.New("rpc: rate limit exceeded")
+	ErrRequestTooLarge = errors.New("rpc: request too large")
+	ErrBusy            = errors.New("rpc: too many concurrent requests")
+
+	errBadMessageSize = errors.New("rpc: invalid message size")
+)
+
+// maxBuckets is the number of rate limit buckets after which
+// the buckets of idle clients are removed.
+const maxBuckets = 10000
+
+// expensiveMethods are methods which rebuild or scan Plasma blocks
+// or checkpoints.
+var expensiveMethods = map[string]bool{
+	CreateProofMethod:           true,
+	CreateUIDStateProofMethod:   true,
+	ValidateBlockMethod:         true,
+	CreateProofsMethod:          true,
+	CreateUIDStateProofsMethod:  true,
+	GetTransactionsBlocksMethod: true,
+	OptInCheckpointMethod:       true,
+	GetCheckpointOptInMethod:    true,
+	ListCheckpointsMethod:       true,
+	LatestCheckpointForMethod:   true,
+	BlockFeesMethod:             true,
+}
+
+// IsExpensiveMethod returns true if the number of concurrent calls
+// of the RPC method is limited by Limits.MaxExpensiveCalls.
+func IsExpensiveMethod(method string) bool {
+	return expensiveMethods[method]
+}
+
+// Limits are limits of the RPC server for public clients.
+// Clients with an admin token are not limited. Zero values disable limits.
+type Limits struct {
+	// IPRate is the number of calls per second from one IP address.
+	IPRate float64
+	// IPBurst is the number of calls from one IP address
+	// which can be made at once.
+	IPBurst int
+	// AddressRate is the number of transactions per second
+	// from one Plasma address.
+	AddressRate float64
+	// AddressBurst is the number of transactions from one Plasma address
+	// which can be sent at once.
+	AddressBurst int
+	// MaxRequestSize is the maximum size of a request in bytes.
+	MaxRequestSize int64
+	// MaxExpensiveCalls is the maximum number of concurrent calls
+	// of expensive methods (proof generation, block validation,
+	// opt-ins and checkpoint registry scans).
+	MaxExpensiveCalls int
+}
+
+// limiter applies limits to the calls of public clients.
+type limiter struct {
+	limits    Limits
+	ips       *rateLimiter
+	addresses *rateLimiter
+	expensive chan struct{}
+	// domain returns the domain senders of transactions
+	// are recovered in, it is read for each request
+	domain func() transaction.Domain
+}
+
+func newLimiter(limits Limits) *limiter {
+	l := &limiter{limits: limits, domain: func() transaction.Domain {
+		return transaction.LegacyDomain
+	}}
+
+	if limits.IPRate > 0 {
+		l.ips = newRateLimiter(limits.IPRate, limits.IPBurst)
+	}
+
+	if limits.AddressRate > 0 {
+		l.addresses = newRateLimiter(limits.AddressRate, limits.AddressBurst)
+	}
+
+	if limits.MaxExpensiveCalls > 0 {
+		l.expensive = make(chan struct{}, limits.MaxExpensiveCalls)
+	}
+	return l
+}
+
+// allowIP returns true if a call from the IP address is allowed.
+func (l *limiter) allowIP(ip string) bool {
+	if l.ips == nil {
+		return true
+	}
+	return l.ips.allow(ip)
+}
+
+// allowAddress returns true if a call from the Plasma address is allowed.
+func (l *limiter) allowAddress(address common.Address) bool {
+	if l.addresses == nil {
+		return true
+	}
+	return l.addresses.allow(address.Hex())
+}
+
+// acquire reserves a slot for an expensive call. The returned function
+// releases the slot, it is nil if the method is not expensive.
+func (l *limiter) acquire(method string) (release func(), ok bool) {
+	if l.expensive == nil || !IsExpensiveMethod(method) {
+		return nil, true
+	}
+
+	select {
+	case l.expensive <- struct{}{}:
+		var once sync.Once
+		return func() {
+			once.Do(func() { <-l.expensive })
+		}, true
+	default:
+		return nil, false
+	}
+}
+
+// requestAddress returns the Plasma address which sends the request
+// in the domain.
+func requestAddress(body interface{},
+	domain transaction.Domain) (common.Address, bool) {
+	req, ok := body.(*handlers.AcceptTransactionReq)
+	if !ok {
+		return common.Address{}, false
+	}
+
+	tx := &transaction.Transaction{}
+	if err := transaction.DecodeRLP(bytes.NewReader(req.Tx), tx); err != nil {
+		return common.Address{}, false
+	}
+
+	sender, err := transaction.Sender(tx, domain)
+	if err != nil {
+		return common.Address{}, false
+	}
+	return sender, true
+}
+
+// bucket is a token bucket of one client.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// rateLimiter limits the rate of calls per key with token buckets.
+type rateLimiter struct {
+	mtx     sync.Mutex
+	rate    float64
+	burst   float64
+	buckets map[string]*bucket
+	now     func() time.Time
+}
+
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &rateLimiter{
+		rate:    rate,
+		burst:   float64(burst),
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// allow takes a token from the bucket of the key.
+func (l *rateLimiter) allow(key string) bool {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	now := l.now()
+
+	b, ok := l.buckets[key]
+	if !ok {
+		if len(l.buckets) >= maxBuckets {
+			l.purge(now)
+		}
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = math.Min(l.burst,
+		b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// purge removes full buckets.
+func (l *rateLimiter) purge(now time.Time) {
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// sizeReader counts the bytes of gob messages of a request and refuses
+// a message which exceeds the request size limit before it is read.
+type sizeReader struct {
+	r        *bufio.Reader
+	max      int64
+	read     int64
+	left     int64
+	exceeded bool
+}
+
+func newSizeReader(r io.Reader) *sizeReader {
+	return &sizeReader{r: bufio.NewReader(r)}
+}
+
+// reset starts counting of a new request.
+func (r *sizeReader) reset() {
+	r.read = 0
+}
+
+// next checks the size of the next message if the current one is read.
+func (r *sizeReader) next() error {
+	if r.exceeded {
+		return ErrRequestTooLarge
+	}
+
+	if r.max <= 0 || r.left > 0 {
+		return nil
+	}
+
+	size, err := r.peekSize()
+	if err != nil {
+		return err
+	}
+
+	if size < 0 || r.read+size > r.max {
+		r.exceeded = true
+		return ErrRequestTooLarge
+	}
+
+	r.left = size
+	return nil
+}
+
+// peekSize returns the size of the next gob message including its count.
+// The count is an unsigned integer in the gob encoding.
+func (r *sizeReader) peekSize() (int64, error) {
+	b, err := r.r.Peek(1)
+	if err != nil {
+		return 0, err
+	}
+
+	if b[0] < 0x80 {
+		return 1 + int64(b[0]), nil
+	}
+
+	n := -int(int8(b[0]))
+	if n < 1 || n > 8 {
+		return 0, errBadMessageSize
+	}
+
+	b, err = r.r.Peek(1 + n)
+	if err != nil {
+		return 0, err
+	}
+
+	buf := make([]byte, 8)
+	copy(buf[8-n:], b[1:])
+
+	count := binary.BigEndian.Uint64(buf)
+	if count > math.MaxInt64-uint64(1+n) {
+		return -1, nil
+	}
+	return int64(1+n) + int64(count), nil
+}
+
+func (r *sizeReader) consumed(n int) {
+	r.read += int64(n)
+	if r.max > 0 {
+		r.left -= int64(n)
+	}
+}
+
+// Read implements io.Reader.
+func (r *sizeReader) Read(p []byte) (int, error) {
+	if err := r.next(); err != nil {
+		return 0, err
+	}
+
+	if r.max > 0 && int64(len(p)) > r.left {
+		p = p[:r.left]
+	}
+
+	n, err := r.r.Read(p)
+	r.consumed(n)
+	return n, err
+}
+
+// ReadByte implements io.ByteReader,
+// so the gob decoder reads messages without buffering.
+func (r *sizeReader) ReadByte() (byte, error) {
+	if err := r.next(); err != nil {
+		return 0, err
+	}
+
+	b, err := r.r.ReadByte()
+	if err == nil {
+		r.consumed(1)
+	}
+	return b, err
+}
@@ -0,0 +1,116 @@
+package transport
+
+import (
+	"bytes"
+	"encoding/gob"
+	"testing"
+	"time"
+
+	"github.com/SmartMeshFoundation/Spectrum/common"
+)
+
+func TestRateLimiter(t *testing.T) {
+	now := time.Now()
+
+	l := newRateLimiter(2, 3)
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if !l.allow("a") {
+			t.Fatal("call in burst is refused")
+		}
+	}
+
+	if l.allow("a") {
+		t.Fatal("call over burst is allowed")
+	}
+
+	if !l.allow("b") {
+		t.Fatal("keys are not independent")
+	}
+
+	now = now.Add(time.Second)
+
+	for i := 0; i < 2; i++ {
+		if !l.allow("a") {
+			t.Fatal("tokens are not refilled")
+		}
+	}
+
+	if l.allow("a") {
+		t.Fatal("too many tokens are refilled")
+	}
+
+	now = now.Add(time.Hour)
+	l.purge(now)
+
+	if len(l.buckets) != 0 {
+		t.Fatal("idle buckets are not removed")
+	}
+}
+
+func TestLimiterAcquire(t *testing.T) {
+	l := newLimiter(Limits{MaxExpensiveCalls: 1})
+
+	release, ok := l.acquire(CreateProofMethod)
+	if !ok || release == nil {
+		t.Fatal("expensive call is refused")
+	}
+
+	for _, method := range []string{ValidateBlockMethod,
+		OptInCheckpointMethod, ListCheckpointsMethod} {
+		if _, ok := l.acquire(method); ok {
+			t.Fatalf("too many expensive calls of %s", method)
+		}
+	}
+
+	if release, ok := l.acquire(CurrentBlockMethod); !ok || release != nil {
+		t.Fatal("cheap call is limited")
+	}
+
+	release()
+	release()
+
+	release, ok = l.acquire(ValidateBlockMethod)
+	if !ok {
+		t.Fatal("slot is not released")
+	}
+	release()
+
+	if !newLimiter(Limits{}).allowAddress(common.Address{}) {
+		t.Fatal("disabled limit refuses calls")
+	}
+}
+
+func TestSizeReader(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	enc := gob.NewEncoder(buf)
+
+	for _, msg := range [][]byte{make([]byte, 10), make([]byte, 1000)} {
+		if err := enc.Encode(msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := newSizeReader(buf)
+	r.max = 500
+	dec := gob.NewDecoder(r)
+
+	var msg []byte
+	if err := dec.Decode(&msg); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(msg) != 10 || r.read == 0 {
+		t.Fatal("wrong message")
+	}
+
+	r.reset()
+	if err := dec.Decode(&msg); err != ErrRequestTooLarge {
+		t.Fatalf("expected %s, got %v", ErrRequestTooLarge, err)
+	}
+
+	if !r.exceeded {
+		t.Fatal("exceeded flag is not set")
+	}
+}
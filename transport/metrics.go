@@ -0,0 +1,58 @@
+package transport
+
+import (
+	"github.com/SmartMeshFoundation/SmartPlasma/metrics"
+)
+
+// Result codes of RPC calls.
+const (
+	CodeOK           = "ok"
+	CodeError        = "error"
+	CodeRPCError     = "rpc_error"
+	CodeUnauthorized = "unauthorized"
+	CodeReadOnly     = "read_only"
+	CodeRateLimited  = "rate_limited"
+	CodeTooLarge     = "too_large"
+	CodeBusy         = "busy"
+)
+
+// refusalCodes maps errors of refused calls to result codes.
+var refusalCodes = map[string]string{
+	ErrUnauthorized.Error():    CodeUnauthorized,
+	ErrReadOnly.Error():        CodeReadOnly,
+	ErrRateLimited.Error():     CodeRateLimited,
+	ErrRequestTooLarge.Error(): CodeTooLarge,
+	ErrBusy.Error():            CodeBusy,
+}
+
+const unknownMethod = "unknown"
+
+// RPC server metrics.
+var (
+	rpcRequests = metrics.DefaultRegistry.NewCounter(
+		"smartplasma_rpc_requests_total",
+		"Number of handled RPC requests.", "method", "code")
+	rpcDuration = metrics.DefaultRegistry.NewHistogram(
+		"smartplasma_rpc_request_duration_seconds",
+		"Time spent handling RPC requests.", nil, "method")
+)
+
+func observeCall(cl *call, rpcErr, respErr string) {
+	method := cl.method
+	if isUnknownMethod(rpcErr) {
+		method = unknownMethod
+	}
+
+	code := CodeOK
+	switch {
+	case refusalCodes[rpcErr] != "":
+		code = refusalCodes[rpcErr]
+	case rpcErr != "":
+		code = CodeRPCError
+	case respErr != "":
+		code = CodeError
+	}
+
+	rpcRequests.Inc(method, code)
+	rpcDuration.Since(cl.start, method)
+}
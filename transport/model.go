@@ -7,7 +7,8 @@ const (
 	WithdrawMethod          = "SmartPlasma.Withdraw"
 	StartExitMethod         = "SmartPlasma.StartExit"
 	AcceptTransactionMethod = "SmartPlasma.AcceptTransaction"
-	AddCheckpointMethod     = "SmartPlasma.AddCheckpoint"
+	OptInCheckpointMethod   = "SmartPlasma.OptInCheckpoint"
+	GetPendingSwapMethod    = "SmartPlasma.GetPendingSwap"
 
 	// proof methods
 	CreateProofMethod           = "SmartPlasma.CreateProof"
@@ -15,6 +16,11 @@ const (
 	VerifyTxProofMethod         = "SmartPlasma.VerifyTxProof"
 	VerifyCheckpointProofMethod = "SmartPlasma.VerifyCheckpointProof"
 
+	// batch methods
+	CreateProofsMethod          = "SmartPlasma.CreateProofs"
+	CreateUIDStateProofsMethod  = "SmartPlasma.CreateUIDStateProofs"
+	GetTransactionsBlocksMethod = "SmartPlasma.GetTransactionsBlocks"
+
 	// transactor methods
 	PendingCodeAtMethod   = "SmartPlasma.PendingCodeAt"
 	PendingNonceAtMethod  = "SmartPlasma.PendingNonceAt"
@@ -55,6 +61,9 @@ const (
 	InitCheckpointMethod             = "SmartPlasma.InitCheckpoint"
 	SaveCurrentCheckpointBlockMethod = "SmartPlasma.SaveCurrentCheckpointBlock"
 	GetCheckpointsBlockMethod        = "SmartPlasma.GetCheckpointsBlock"
+	GetCheckpointOptInMethod         = "SmartPlasma.GetCheckpointOptIn"
+	ListCheckpointsMethod            = "SmartPlasma.ListCheckpoints"
+	LatestCheckpointForMethod        = "SmartPlasma.LatestCheckpointFor"
 
 	// info methods
 	DepositCountMethod    = "SmartPlasma.DepositCount"
@@ -64,4 +73,11 @@ const (
 	ExitsMethod           = "SmartPlasma.Exits"
 	WalletMethod          = "SmartPlasma.Wallet"
 	Wallet2Method         = "SmartPlasma.Wallet2"
+	FeePolicyMethod       = "SmartPlasma.FeePolicy"
+	BlockFeesMethod       = "SmartPlasma.BlockFees"
+	DomainMethod          = "SmartPlasma.Domain"
+
+	// CancelMethod cancels a call in progress on the same connection,
+	// it is handled by the transport layer.
+	CancelMethod = "SmartPlasma.Cancel"
 )
@@ -1,11 +1,11 @@
 package transport
 
 import (
+	"context"
 	"math/big"
 
 	"github.com/SmartMeshFoundation/Spectrum/common"
 	"github.com/SmartMeshFoundation/Spectrum/core/types"
-	"github.com/pkg/errors"
 
 	"github.com/SmartMeshFoundation/SmartPlasma/blockchan/block/transactions"
 	"github.com/SmartMeshFoundation/SmartPlasma/contract/rootchain"
@@ -17,21 +17,20 @@ func (c *Client) BuildBlock() (hash common.Hash, err error) {
 	ctx, cancel := c.newContext()
 	defer cancel()
 
+	return c.BuildBlockContext(ctx)
+}
+
+// BuildBlockContext is like BuildBlock but takes a context.
+func (c *Client) BuildBlockContext(ctx context.Context) (hash common.Hash,
+	err error) {
 	req := &handlers.BuildBlockReq{}
 	var resp *handlers.BuildBlockResp
-	call := c.connect.Go(BuildBlockMethod, req, &resp, nil)
-
-	select {
-	case replay := <-call.Done:
-		if replay.Error != nil {
-			return common.Hash{}, replay.Error
-		}
-	case <-ctx.Done():
-		return common.Hash{}, errors.New("timeout")
+	if err := c.call(ctx, BuildBlockMethod, req, &resp); err != nil {
+		return common.Hash{}, err
 	}
 
 	if resp.Error != "" {
-		return common.Hash{}, errors.New(resp.Error)
+		return common.Hash{}, respError(resp.Error)
 	}
 
 	return resp.Hash, err
@@ -42,6 +41,12 @@ func (c *Client) SendBlockHash(hash common.Hash) (*types.Transaction, error) {
 	ctx, cancel := c.newContext()
 	defer cancel()
 
+	return c.SendBlockHashContext(ctx, hash)
+}
+
+// SendBlockHashContext is like SendBlockHash but takes a context.
+func (c *Client) SendBlockHashContext(ctx context.Context,
+	hash common.Hash) (*types.Transaction, error) {
 	if c.sessionRootChain != nil {
 		session := rootchain.CopySession(c.sessionRootChain)
 		session.TransactOpts.Context = ctx
@@ -50,19 +55,12 @@ func (c *Client) SendBlockHash(hash common.Hash) (*types.Transaction, error) {
 
 	req := &handlers.SendBlockHashReq{Hash: hash}
 	var resp *handlers.SendBlockHashResp
-	call := c.connect.Go(SendBlockHashMethod, req, &resp, nil)
-
-	select {
-	case replay := <-call.Done:
-		if replay.Error != nil {
-			return nil, replay.Error
-		}
-	case <-ctx.Done():
-		return nil, errors.New("timeout")
+	if err := c.call(ctx, SendBlockHashMethod, req, &resp); err != nil {
+		return nil, err
 	}
 
 	if resp.Error != "" {
-		return nil, errors.New(resp.Error)
+		return nil, respError(resp.Error)
 	}
 
 	tx := &types.Transaction{}
@@ -80,6 +78,12 @@ func (c *Client) LastBlockNumber() (number *big.Int, err error) {
 	ctx, cancel := c.newContext()
 	defer cancel()
 
+	return c.LastBlockNumberContext(ctx)
+}
+
+// LastBlockNumberContext is like LastBlockNumber but takes a context.
+func (c *Client) LastBlockNumberContext(ctx context.Context) (number *big.Int,
+	err error) {
 	if c.sessionRootChain != nil {
 		session := rootchain.CopySession(c.sessionRootChain)
 		session.TransactOpts.Context = ctx
@@ -87,19 +91,12 @@ func (c *Client) LastBlockNumber() (number *big.Int, err error) {
 	}
 	req := &handlers.LastBlockNumberReq{}
 	var resp handlers.LastBlockNumberResp
-	call := c.connect.Go(LastBlockNumberMethod, req, &resp, nil)
-
-	select {
-	case replay := <-call.Done:
-		if replay.Error != nil {
-			return nil, replay.Error
-		}
-	case <-ctx.Done():
-		return nil, errors.New("timeout")
+	if err := c.call(ctx, LastBlockNumberMethod, req, &resp); err != nil {
+		return nil, err
 	}
 
 	if resp.Error != "" {
-		return nil, errors.New(resp.Error)
+		return nil, respError(resp.Error)
 	}
 
 	return resp.Number, err
@@ -110,21 +107,20 @@ func (c *Client) CurrentBlock() (block []byte, err error) {
 	ctx, cancel := c.newContext()
 	defer cancel()
 
+	return c.CurrentBlockContext(ctx)
+}
+
+// CurrentBlockContext is like CurrentBlock but takes a context.
+func (c *Client) CurrentBlockContext(ctx context.Context) (block []byte,
+	err error) {
 	req := &handlers.CurrentBlockReq{}
 	var resp *handlers.CurrentBlockResp
-	call := c.connect.Go(CurrentBlockMethod, req, &resp, nil)
-
-	select {
-	case replay := <-call.Done:
-		if replay.Error != nil {
-			return nil, replay.Error
-		}
-	case <-ctx.Done():
-		return nil, errors.New("timeout")
+	if err := c.call(ctx, CurrentBlockMethod, req, &resp); err != nil {
+		return nil, err
 	}
 
 	if resp.Error != "" {
-		return nil, errors.New(resp.Error)
+		return nil, respError(resp.Error)
 	}
 
 	return resp.Block, err
@@ -135,24 +131,23 @@ func (c *Client) SaveBlockToDB(number uint64, raw []byte) error {
 	ctx, cancel := c.newContext()
 	defer cancel()
 
+	return c.SaveBlockToDBContext(ctx, number, raw)
+}
+
+// SaveBlockToDBContext is like SaveBlockToDB but takes a context.
+func (c *Client) SaveBlockToDBContext(ctx context.Context, number uint64,
+	raw []byte) error {
 	req := &handlers.SaveBlockToDBReq{
 		Number: number,
 		Block:  raw,
 	}
 	var resp *handlers.SaveBlockToDBResp
-	call := c.connect.Go(SaveBlockToDBMethod, req, &resp, nil)
-
-	select {
-	case replay := <-call.Done:
-		if replay.Error != nil {
-			return replay.Error
-		}
-	case <-ctx.Done():
-		return errors.New("timeout")
+	if err := c.call(ctx, SaveBlockToDBMethod, req, &resp); err != nil {
+		return err
 	}
 
 	if resp.Error != "" {
-		return errors.New(resp.Error)
+		return respError(resp.Error)
 	}
 	return nil
 }
@@ -162,21 +157,19 @@ func (c *Client) InitBlock() error {
 	ctx, cancel := c.newContext()
 	defer cancel()
 
+	return c.InitBlockContext(ctx)
+}
+
+// InitBlockContext is like InitBlock but takes a context.
+func (c *Client) InitBlockContext(ctx context.Context) error {
 	req := &handlers.InitBlockReq{}
 	var resp *handlers.InitBlockResp
-	call := c.connect.Go(InitBlockMethod, req, &resp, nil)
-
-	select {
-	case replay := <-call.Done:
-		if replay.Error != nil {
-			return replay.Error
-		}
-	case <-ctx.Done():
-		return errors.New("timeout")
+	if err := c.call(ctx, InitBlockMethod, req, &resp); err != nil {
+		return err
 	}
 
 	if resp.Error != "" {
-		return errors.New(resp.Error)
+		return respError(resp.Error)
 	}
 
 	return nil
@@ -187,24 +180,23 @@ func (c *Client) SaveCurrentBlock(number uint64) error {
 	ctx, cancel := c.newContext()
 	defer cancel()
 
+	return c.SaveCurrentBlockContext(ctx, number)
+}
+
+// SaveCurrentBlockContext is like SaveCurrentBlock but takes a context.
+func (c *Client) SaveCurrentBlockContext(ctx context.Context,
+	number uint64) error {
 	req := &handlers.SaveCurrentBlockReq{
 		Number: number,
 	}
 
 	var resp *handlers.SaveCurrentBlockResp
-	call := c.connect.Go(SaveCurrentBlockMethod, req, &resp, nil)
-
-	select {
-	case replay := <-call.Done:
-		if replay.Error != nil {
-			return replay.Error
-		}
-	case <-ctx.Done():
-		return errors.New("timeout")
+	if err := c.call(ctx, SaveCurrentBlockMethod, req, &resp); err != nil {
+		return err
 	}
 
 	if resp.Error != "" {
-		return errors.New(resp.Error)
+		return respError(resp.Error)
 	}
 	return nil
 }
@@ -215,28 +207,32 @@ func (c *Client) GetTransactionsBlock(
 	ctx, cancel := c.newContext()
 	defer cancel()
 
+	return c.GetTransactionsBlockContext(ctx, number)
+}
+
+// GetTransactionsBlockContext is like GetTransactionsBlock but takes a context.
+func (c *Client) GetTransactionsBlockContext(ctx context.Context,
+	number uint64) (transactions.TxBlock, error) {
 	req := &handlers.GetTransactionsBlockReq{
 		Number: number,
 	}
 
 	var resp *handlers.GetTransactionsBlockResp
-	call := c.connect.Go(GetTransactionsBlockMethod, req, &resp, nil)
-
-	select {
-	case replay := <-call.Done:
-		if replay.Error != nil {
-			return nil, replay.Error
-		}
-	case <-ctx.Done():
-		return nil, errors.New("timeout")
+	if err := c.call(ctx, GetTransactionsBlockMethod, req, &resp); err != nil {
+		return nil, err
 	}
 
 	if resp.Error != "" {
-		return nil, errors.New(resp.Error)
+		return nil, respError(resp.Error)
 	}
 
+	return unmarshalTxBlock(resp.Block)
+}
+
+// unmarshalTxBlock unmarshals and builds raw transactions block.
+func unmarshalTxBlock(raw []byte) (transactions.TxBlock, error) {
 	bl := transactions.NewBlock()
-	err := bl.Unmarshal(resp.Block)
+	err := bl.Unmarshal(raw)
 	if err != nil {
 		return nil, err
 	}
@@ -253,18 +249,16 @@ func (c *Client) ValidateBlock() error {
 	ctx, cancel := c.newContext()
 	defer cancel()
 
+	return c.ValidateBlockContext(ctx)
+}
+
+// ValidateBlockContext is like ValidateBlock but takes a context.
+func (c *Client) ValidateBlockContext(ctx context.Context) error {
 	req := &handlers.ValidateBlockReq{}
 
 	var resp *handlers.ValidateBlockResp
-	call := c.connect.Go(ValidateBlockMethod, req, &resp, nil)
-
-	select {
-	case replay := <-call.Done:
-		if replay.Error != nil {
-			return replay.Error
-		}
-	case <-ctx.Done():
-		return errors.New("timeout")
+	if err := c.call(ctx, ValidateBlockMethod, req, &resp); err != nil {
+		return err
 	}
 	return nil
 }
@@ -1,10 +1,10 @@
 package transport
 
 import (
+	"context"
 	"math/big"
 
 	"github.com/SmartMeshFoundation/Spectrum/common"
-	"github.com/pkg/errors"
 
 	"github.com/SmartMeshFoundation/SmartPlasma/transport/handlers"
 )
@@ -15,21 +15,20 @@ func (c *Client) CreateProof(uid *big.Int, block uint64) ([]byte, error) {
 	ctx, cancel := c.newContext()
 	defer cancel()
 
+	return c.CreateProofContext(ctx, uid, block)
+}
+
+// CreateProofContext is like CreateProof but takes a context.
+func (c *Client) CreateProofContext(ctx context.Context, uid *big.Int,
+	block uint64) ([]byte, error) {
 	req := &handlers.CreateProofReq{UID: uid, Block: block}
 	var resp *handlers.CreateProofResp
-	call := c.connect.Go(CreateProofMethod, req, &resp, nil)
-
-	select {
-	case replay := <-call.Done:
-		if replay.Error != nil {
-			return nil, replay.Error
-		}
-	case <-ctx.Done():
-		return nil, errors.New("timeout")
+	if err := c.call(ctx, CreateProofMethod, req, &resp); err != nil {
+		return nil, err
 	}
 
 	if resp.Error != "" {
-		return nil, errors.New(resp.Error)
+		return nil, respError(resp.Error)
 	}
 
 	return resp.Proof, nil
@@ -42,6 +41,12 @@ func (c *Client) VerifyTxProof(uid *big.Int, hash common.Hash,
 	ctx, cancel := c.newContext()
 	defer cancel()
 
+	return c.VerifyTxProofContext(ctx, uid, hash, block, proof)
+}
+
+// VerifyTxProofContext is like VerifyTxProof but takes a context.
+func (c *Client) VerifyTxProofContext(ctx context.Context, uid *big.Int,
+	hash common.Hash, block uint64, proof []byte) (exists bool, err error) {
 	req := &handlers.VerifyTxProofReq{
 		UID:   uid,
 		Hash:  hash,
@@ -49,19 +54,12 @@ func (c *Client) VerifyTxProof(uid *big.Int, hash common.Hash,
 		Proof: proof,
 	}
 	var resp *handlers.VerifyTxProofResp
-	call := c.connect.Go(VerifyTxProofMethod, req, &resp, nil)
-
-	select {
-	case replay := <-call.Done:
-		if replay.Error != nil {
-			return false, replay.Error
-		}
-	case <-ctx.Done():
-		return false, errors.New("timeout")
+	if err := c.call(ctx, VerifyTxProofMethod, req, &resp); err != nil {
+		return false, err
 	}
 
 	if resp.Error != "" {
-		return false, errors.New(resp.Error)
+		return false, respError(resp.Error)
 	}
 
 	return resp.Exists, err
@@ -74,24 +72,23 @@ func (c *Client) CreateUIDStateProof(
 	ctx, cancel := c.newContext()
 	defer cancel()
 
+	return c.CreateUIDStateProofContext(ctx, uid, checkpointHash)
+}
+
+// CreateUIDStateProofContext is like CreateUIDStateProof but takes a context.
+func (c *Client) CreateUIDStateProofContext(ctx context.Context, uid *big.Int,
+	checkpointHash common.Hash) ([]byte, *big.Int, error) {
 	req := &handlers.CreateUIDStateProofReq{
 		UID:            uid,
 		CheckpointHash: checkpointHash,
 	}
 	var resp *handlers.CreateUIDStateProofResp
-	call := c.connect.Go(CreateUIDStateProofMethod, req, &resp, nil)
-
-	select {
-	case replay := <-call.Done:
-		if replay.Error != nil {
-			return nil, nil, replay.Error
-		}
-	case <-ctx.Done():
-		return nil, nil, errors.New("timeout")
+	if err := c.call(ctx, CreateUIDStateProofMethod, req, &resp); err != nil {
+		return nil, nil, err
 	}
 
 	if resp.Error != "" {
-		return nil, nil, errors.New(resp.Error)
+		return nil, nil, respError(resp.Error)
 	}
 
 	return resp.Proof, resp.Nonce, nil
@@ -104,6 +101,14 @@ func (c *Client) VerifyCheckpointProof(uid *big.Int, number *big.Int,
 	ctx, cancel := c.newContext()
 	defer cancel()
 
+	return c.VerifyCheckpointProofContext(ctx, uid, number, checkpoint, proof)
+}
+
+// VerifyCheckpointProofContext is like VerifyCheckpointProof
+// but takes a context.
+func (c *Client) VerifyCheckpointProofContext(ctx context.Context, uid *big.Int,
+	number *big.Int, checkpoint common.Hash, proof []byte) (exists bool,
+	err error) {
 	req := &handlers.VerifyCheckpointProofReq{
 		UID:        uid,
 		Number:     number,
@@ -111,19 +116,12 @@ func (c *Client) VerifyCheckpointProof(uid *big.Int, number *big.Int,
 		Proof:      proof,
 	}
 	var resp *handlers.VerifyCheckpointProofResp
-	call := c.connect.Go(VerifyCheckpointProofMethod, req, &resp, nil)
-
-	select {
-	case replay := <-call.Done:
-		if replay.Error != nil {
-			return false, replay.Error
-		}
-	case <-ctx.Done():
-		return false, errors.New("timeout")
+	if err := c.call(ctx, VerifyCheckpointProofMethod, req, &resp); err != nil {
+		return false, err
 	}
 
 	if resp.Error != "" {
-		return false, errors.New(resp.Error)
+		return false, respError(resp.Error)
 	}
 
 	return resp.Exists, err
@@ -1,52 +1,142 @@
 package transport
 
 import (
+	"crypto/tls"
 	"net"
 	"net/http"
 	"net/rpc"
 	"strconv"
 
+	"github.com/SmartMeshFoundation/Spectrum/log"
+
+	"github.com/SmartMeshFoundation/SmartPlasma/logger"
+	"github.com/SmartMeshFoundation/SmartPlasma/metrics"
 	"github.com/SmartMeshFoundation/SmartPlasma/service"
 	"github.com/SmartMeshFoundation/SmartPlasma/transport/handlers"
 )
 
+// MetricsPath is HTTP path where the server exposes metrics
+// in the Prometheus text format.
+const MetricsPath = "/metrics"
+
 // Server is RPC server to Plasma Cash service.
 type Server struct {
 	port    uint16
 	server  *http.Server
 	service *service.Service
+	handler *handlers.SmartPlasma
+	rpc     *rpcHandler
+	tls     *tls.Config
+	logger  log.Logger
 }
 
 // NewServer creates new RPC server to Plasma Cash service.
 func NewServer(timeout int, port uint16, service *service.Service) *Server {
+	l := logger.New("transport")
 	rpcServer := rpc.NewServer()
+	handler := handlers.NewSmartPlasma(timeout, service)
+
+	rpcServer.RegisterName("SmartPlasma", handler)
 
-	rpcServer.RegisterName(
-		"SmartPlasma", handlers.NewSmartPlasma(timeout, service))
+	rpcHandler := newRPCHandler(rpcServer, handler, l)
+
+	mux := http.NewServeMux()
+	mux.Handle(rpc.DefaultRPCPath, rpcHandler)
+	mux.Handle(MetricsPath, metrics.Handler(metrics.DefaultRegistry))
 
 	httpServer := &http.Server{
-		Handler: rpcServer,
+		Handler: mux,
 	}
 
 	return &Server{
 		port:    port,
 		server:  httpServer,
 		service: service,
+		handler: handler,
+		rpc:     rpcHandler,
+		logger:  l,
+	}
+}
+
+// SetLogger sets the logger for the server and its handlers.
+// It must be called before ListenAndServe.
+func (srv *Server) SetLogger(l log.Logger) {
+	srv.logger = logger.Wrap(l)
+	srv.rpc.logger = srv.logger
+	srv.handler.SetLogger(l)
+}
+
+// SetAdminTokens sets bearer tokens which allow clients to call
+// admin methods. If no tokens are set, admin methods are refused
+// to all clients. It must be called before ListenAndServe.
+func (srv *Server) SetAdminTokens(tokens ...string) {
+	srv.rpc.auth.tokens = nil
+	for _, token := range tokens {
+		if token == "" {
+			continue
+		}
+		srv.rpc.auth.tokens = append(srv.rpc.auth.tokens, []byte(token))
+	}
+}
+
+// SetReadOnly makes the server serve only methods which neither change
+// the state of the operator nor send transactions, other methods
+// are refused to all clients. Replicas of the operator are read-only.
+// It must be called before ListenAndServe.
+func (srv *Server) SetReadOnly(readOnly bool) {
+	srv.rpc.readOnly = readOnly
+}
+
+// SetLimits sets limits for public clients. Senders of transactions
+// are recovered in the current domain of the service.
+// It must be called before ListenAndServe.
+func (srv *Server) SetLimits(limits Limits) {
+	srv.rpc.limiter = newLimiter(limits)
+	if srv.service != nil {
+		srv.rpc.limiter.domain = srv.service.Domain
 	}
 }
 
+// SetTLSConfig enables TLS on the listener.
+// It must be called before ListenAndServe.
+func (srv *Server) SetTLSConfig(config *tls.Config) {
+	srv.tls = config
+}
+
 // ListenAndServe starts RPC server to Plasma Cash service.
 func (srv *Server) ListenAndServe() error {
 	l, err := net.Listen("tcp", ":"+strconv.Itoa(int(srv.port)))
 	if err != nil {
+		srv.logger.Error("Failed to listen", "port", srv.port, "err", err)
 		return err
 	}
 
+	if srv.tls != nil {
+		l = tls.NewListener(l, srv.tls)
+	}
+
+	srv.logger.Info("RPC server started", "addr", l.Addr(),
+		"tls", srv.tls != nil)
 	return srv.server.Serve(l)
 }
 
+// ListenAndServeTLS starts RPC server to Plasma Cash service
+// with TLS certificate and key from files.
+func (srv *Server) ListenAndServeTLS(certFile, keyFile string) error {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		srv.logger.Error("Failed to load TLS certificate",
+			"cert", certFile, "err", err)
+		return err
+	}
+
+	srv.SetTLSConfig(&tls.Config{Certificates: []tls.Certificate{cert}})
+	return srv.ListenAndServe()
+}
+
 // Close stops RPC server to Plasma Cash service.
 func (srv *Server) Close() error {
+	srv.logger.Info("RPC server stopped")
 	srv.service.Close()
 	return srv.server.Close()
 }
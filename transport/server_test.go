@@ -2,16 +2,27 @@ package transport
 
 import (
 	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
 	"io/ioutil"
+	"math/big"
 	"net"
+	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/SmartMeshFoundation/Spectrum/accounts/abi"
 	"github.com/SmartMeshFoundation/Spectrum/common"
+	"github.com/SmartMeshFoundation/Spectrum/log"
 	"github.com/pborman/uuid"
 
 	"github.com/SmartMeshFoundation/SmartPlasma/blockchan/account"
@@ -21,6 +32,7 @@ import (
 	"github.com/SmartMeshFoundation/SmartPlasma/contract/mediator"
 	"github.com/SmartMeshFoundation/SmartPlasma/contract/rootchain"
 	"github.com/SmartMeshFoundation/SmartPlasma/database/bolt"
+	"github.com/SmartMeshFoundation/SmartPlasma/logger"
 	"github.com/SmartMeshFoundation/SmartPlasma/service"
 )
 
@@ -38,6 +50,7 @@ var (
 type Env struct {
 	dir             string
 	server          *Server
+	service         *service.Service
 	accounts        []*account.PlasmaTransactOpts
 	mediatorAddress common.Address
 	rootChainAddr   common.Address
@@ -61,8 +74,8 @@ func getPort() (port int, err error) {
 	return l.Addr().(*net.TCPAddr).Port, nil
 }
 
-func newEnv() *Env {
-	testAccounts := account.GenAccounts(numUsers)
+func newEnv(numberAcc int, setup ...func(srv *Server)) *Env {
+	testAccounts := account.GenAccounts(numberAcc)
 	owner := testAccounts[0]
 
 	server := backend.NewSimulatedBackend(account.Addresses(testAccounts))
@@ -130,6 +143,9 @@ func newEnv() *Env {
 		session, server, blockDB, checkpointDB, rchc, mc, false)
 
 	srv := NewServer(100, rpcPort, s)
+	for _, fn := range setup {
+		fn(srv)
+	}
 
 	fatal := make(chan error)
 
@@ -145,9 +161,12 @@ func newEnv() *Env {
 	case <-time.After(time.Microsecond * 100):
 	}
 
+	waitPort(rpcPort)
+
 	return &Env{
 		dir:             dir,
 		server:          srv,
+		service:         s,
 		accounts:        testAccounts,
 		mediatorAddress: mediatorAddr,
 		rootChainAddr:   rootChainAddr,
@@ -157,6 +176,18 @@ func newEnv() *Env {
 	}
 }
 
+// waitPort waits until the port accepts connections.
+func waitPort(port uint16) {
+	for i := 0; i < 100; i++ {
+		conn, err := net.Dial(tcp, fmt.Sprintf("localhost:%d", port))
+		if err == nil {
+			conn.Close()
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
 func (e *Env) Close() error {
 	defer os.RemoveAll(e.dir)
 	return e.server.Close()
@@ -189,7 +220,7 @@ func initEnv() {
 
 	rpcPort = uint16(port)
 
-	env = newEnv()
+	env = newEnv(numUsers)
 	defer env.Close()
 
 	for _, acc := range env.accounts {
@@ -234,7 +265,7 @@ func BenchmarkAcceptTransaction(b *testing.B) {
 		}
 
 		signedTx, err := clients[0].Opts().PlasmaSigner(
-			clients[0].Opts().From, tx)
+			clients[0].Opts().From, tx, transaction.LegacyDomain)
 		if err != nil {
 			b.Fatal(err)
 		}
@@ -249,3 +280,437 @@ func BenchmarkAcceptTransaction(b *testing.B) {
 		clients[0].AcceptTransaction(buf.Bytes())
 	}
 }
+
+func TestMetrics(t *testing.T) {
+	port, err := getPort()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rpcPort = uint16(port)
+
+	e := newEnv(2)
+	defer e.Close()
+
+	cli := newClient(e.accounts[0], e.rootChainAddr,
+		e.mediatorAddress, e.rootChainABI, e.mediatorABI)
+	if err := cli.Connect("localhost", rpcPort); err != nil {
+		t.Fatal(err)
+	}
+	defer cli.Close()
+
+	if _, err := cli.CurrentBlock(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := cli.AcceptTransaction([]byte{1}); err == nil {
+		t.Fatal("expected error")
+	}
+
+	var resp *struct{}
+	if err := cli.connect.Call("SmartPlasma.Missing",
+		&struct{}{}, &resp); err == nil {
+		t.Fatal("expected error")
+	}
+
+	httpResp, err := http.Get(
+		fmt.Sprintf("http://localhost:%d%s", rpcPort, MetricsPath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer httpResp.Body.Close()
+
+	body, err := ioutil.ReadAll(httpResp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, line := range []string{
+		`smartplasma_rpc_requests_total{method="` +
+			CurrentBlockMethod + `",code="ok"}`,
+		`smartplasma_rpc_requests_total{method="` +
+			AcceptTransactionMethod + `",code="error"}`,
+		`smartplasma_rpc_requests_total{method="unknown",code="rpc_error"}`,
+		`smartplasma_rpc_request_duration_seconds_count{method="` +
+			CurrentBlockMethod + `"}`,
+		`smartplasma_backend_gas_used_count`,
+	} {
+		if !strings.Contains(string(body), line) {
+			t.Fatalf("metric %s not found", line)
+		}
+	}
+}
+
+type testLogHandler struct {
+	mtx     sync.Mutex
+	records []*log.Record
+}
+
+func (h *testLogHandler) Log(r *log.Record) error {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	h.records = append(h.records, r)
+	return nil
+}
+
+// find returns the context of the first record with the message.
+func (h *testLogHandler) find(msg string) map[string]interface{} {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	for _, r := range h.records {
+		if r.Msg != msg {
+			continue
+		}
+
+		ctx := make(map[string]interface{})
+		for i := 0; i+1 < len(r.Ctx); i += 2 {
+			ctx[r.Ctx[i].(string)] = r.Ctx[i+1]
+		}
+		return ctx
+	}
+	return nil
+}
+
+func TestRequestTracing(t *testing.T) {
+	port, err := getPort()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rpcPort = uint16(port)
+
+	e := newEnv(1)
+	defer e.Close()
+
+	h := &testLogHandler{}
+	l := log.New()
+	l.SetHandler(h)
+
+	e.server.SetLogger(l)
+	e.service.SetLogger(l)
+
+	cli := newClient(e.accounts[0], e.rootChainAddr,
+		e.mediatorAddress, e.rootChainABI, e.mediatorABI)
+	if err := cli.Connect("localhost", rpcPort); err != nil {
+		t.Fatal(err)
+	}
+	defer cli.Close()
+
+	if err := cli.ValidateBlock(); err != nil {
+		t.Fatal(err)
+	}
+
+	served := h.find("RPC call served")
+	if served == nil {
+		t.Fatal("call is not logged")
+	}
+
+	if served["method"] != ValidateBlockMethod {
+		t.Fatal("wrong method")
+	}
+
+	id, ok := served[logger.RequestIDKey].(string)
+	if !ok || id == "" {
+		t.Fatal("request id is not logged")
+	}
+
+	validated := h.find("Block validated")
+	if validated == nil {
+		t.Fatal("validation is not logged")
+	}
+
+	if validated[logger.RequestIDKey] != id {
+		t.Fatal("request id is not propagated to the service")
+	}
+}
+
+func TestAdminMethods(t *testing.T) {
+	port, err := getPort()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rpcPort = uint16(port)
+
+	token := "secret"
+
+	e := newEnv(1, func(srv *Server) {
+		srv.SetAdminTokens(token)
+	})
+	defer e.Close()
+
+	connect := func(token string) *Client {
+		cli := newClient(e.accounts[0], e.rootChainAddr,
+			e.mediatorAddress, e.rootChainABI, e.mediatorABI)
+		cli.SetAuthToken(token)
+		if err := cli.Connect("localhost", rpcPort); err != nil {
+			t.Fatal(err)
+		}
+		return cli
+	}
+
+	for _, tok := range []string{"", "wrong"} {
+		cli := connect(tok)
+		defer cli.Close()
+
+		if _, err := cli.BuildBlock(); err != ErrUnauthorized {
+			t.Fatalf("expected %s, got %v", ErrUnauthorized, err)
+		}
+
+		if err := cli.InitBlock(); err != ErrUnauthorized {
+			t.Fatalf("expected %s, got %v", ErrUnauthorized, err)
+		}
+
+		if _, err := cli.CurrentBlock(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	admin := connect(token)
+	defer admin.Close()
+
+	if _, err := admin.BuildBlock(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := admin.InitBlock(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIsAdminMethod(t *testing.T) {
+	for method, expected := range map[string]bool{
+		BuildBlockMethod:         true,
+		SendBlockHashMethod:      true,
+		InitCheckpointMethod:     true,
+		SendCheckpointHashMethod: true,
+		CurrentBlockMethod:       false,
+		AcceptTransactionMethod:  false,
+		CreateProofMethod:        false,
+	} {
+		if IsAdminMethod(method) != expected {
+			t.Fatalf("wrong result for method %s", method)
+		}
+	}
+}
+
+func TestReadOnly(t *testing.T) {
+	port, err := getPort()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rpcPort = uint16(port)
+
+	token := "secret"
+
+	e := newEnv(1, func(srv *Server) {
+		srv.SetAdminTokens(token)
+		srv.SetReadOnly(true)
+	})
+	defer e.Close()
+
+	cli := newClient(e.accounts[0], e.rootChainAddr,
+		e.mediatorAddress, e.rootChainABI, e.mediatorABI)
+	cli.SetAuthToken(token)
+	if err := cli.Connect("localhost", rpcPort); err != nil {
+		t.Fatal(err)
+	}
+	defer cli.Close()
+
+	// admin methods are refused even to authenticated clients
+	if _, err := cli.BuildBlock(); err != ErrReadOnly {
+		t.Fatalf("expected %s, got %v", ErrReadOnly, err)
+	}
+
+	if _, err := cli.CurrentBlock(); err != ErrReadOnly {
+		t.Fatalf("expected %s, got %v", ErrReadOnly, err)
+	}
+
+	if _, err := cli.LastBlockNumber(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := cli.GetTransactionsBlock(1); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIsReadMethod(t *testing.T) {
+	for method, expected := range map[string]bool{
+		GetTransactionsBlockMethod: true,
+		CreateProofMethod:          true,
+		ListCheckpointsMethod:      true,
+		BuildBlockMethod:           false,
+		CurrentBlockMethod:         false,
+		AcceptTransactionMethod:    false,
+		DepositMethod:              false,
+	} {
+		if IsReadMethod(method) != expected {
+			t.Fatalf("wrong result for method %s", method)
+		}
+	}
+}
+
+// selfSignedCert generates a TLS certificate for localhost.
+func selfSignedCert() (tls.Certificate, *x509.CertPool, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, nil, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "localhost"},
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template,
+		&key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, nil, err
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return tls.Certificate{}, nil, err
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+
+	return tls.Certificate{
+		Certificate: [][]byte{der},
+		PrivateKey:  key,
+	}, pool, nil
+}
+
+func TestTLS(t *testing.T) {
+	port, err := getPort()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rpcPort = uint16(port)
+
+	cert, pool, err := selfSignedCert()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := newEnv(1, func(srv *Server) {
+		srv.SetTLSConfig(&tls.Config{Certificates: []tls.Certificate{cert}})
+	})
+	defer e.Close()
+
+	cli := newClient(e.accounts[0], e.rootChainAddr,
+		e.mediatorAddress, e.rootChainABI, e.mediatorABI)
+	cli.SetTLSConfig(&tls.Config{RootCAs: pool, ServerName: "localhost"})
+	if err := cli.Connect("localhost", rpcPort); err != nil {
+		t.Fatal(err)
+	}
+	defer cli.Close()
+
+	if _, err := cli.CurrentBlock(); err != nil {
+		t.Fatal(err)
+	}
+
+	plain := newClient(e.accounts[0], e.rootChainAddr,
+		e.mediatorAddress, e.rootChainABI, e.mediatorABI)
+	if err := plain.Connect("localhost", rpcPort); err == nil {
+		plain.Close()
+		t.Fatal("expected error")
+	}
+}
+
+func signedTx(t *testing.T, from, to *account.PlasmaTransactOpts) []byte {
+	tx, err := transaction.NewTransaction(zero, one, two, three, to.From)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signed, err := from.PlasmaSigner(from.From, tx,
+		transaction.LegacyDomain)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf := bytes.NewBuffer([]byte{})
+	if err := signed.EncodeRLP(buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestLimits(t *testing.T) {
+	port, err := getPort()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rpcPort = uint16(port)
+
+	token := "secret"
+
+	e := newEnv(2, func(srv *Server) {
+		srv.SetAdminTokens(token)
+		srv.SetLimits(Limits{
+			IPRate:         0.001,
+			IPBurst:        6,
+			AddressRate:    0.001,
+			AddressBurst:   1,
+			MaxRequestSize: 4096,
+		})
+	})
+	defer e.Close()
+
+	connect := func(token string) *Client {
+		cli := newClient(e.accounts[0], e.rootChainAddr,
+			e.mediatorAddress, e.rootChainABI, e.mediatorABI)
+		cli.SetAuthToken(token)
+		if err := cli.Connect("localhost", rpcPort); err != nil {
+			t.Fatal(err)
+		}
+		return cli
+	}
+
+	expect := func(err, expected error) {
+		if err != expected {
+			t.Fatalf("expected %s, got %v", expected, err)
+		}
+	}
+
+	large := connect("")
+	defer large.Close()
+
+	expect(large.AcceptTransaction(make([]byte, 8192)), ErrRequestTooLarge)
+
+	cli := connect("")
+	defer cli.Close()
+
+	tx := signedTx(t, e.accounts[0], e.accounts[1])
+	if err := cli.AcceptTransaction(tx); err != nil {
+		t.Fatal(err)
+	}
+	expect(cli.AcceptTransaction(tx), ErrRateLimited)
+
+	for i := 0; i < 3; i++ {
+		if _, err := cli.CurrentBlock(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err = cli.CurrentBlock()
+	expect(err, ErrRateLimited)
+
+	admin := connect(token)
+	defer admin.Close()
+
+	for i := 0; i < 10; i++ {
+		if _, err := admin.CurrentBlock(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/SmartMeshFoundation/Spectrum/common"
+
+	"github.com/SmartMeshFoundation/SmartPlasma/blockchan/transaction"
+	"github.com/SmartMeshFoundation/SmartPlasma/transport/handlers"
+)
+
+// Swap signs the swap transaction by the account of the client for
+// the domain of the server and sends it to PlasmaCash RPC server.
+// The server keeps the swap until the other owner signs it, the swap
+// is included in a block when it is signed by both owners.
+func (c *Client) Swap(tx *transaction.Transaction) error {
+	ctx, cancel := c.newContext()
+	defer cancel()
+
+	return c.SwapContext(ctx, tx)
+}
+
+// SwapContext is like Swap but takes a context.
+func (c *Client) SwapContext(ctx context.Context,
+	tx *transaction.Transaction) error {
+	if c.opts == nil {
+		return ErrTransactor
+	}
+
+	domain, err := c.DomainContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	signed, err := c.opts.PlasmaSigner(c.opts.From, tx, domain)
+	if err != nil {
+		return err
+	}
+
+	buf := bytes.NewBuffer(nil)
+	if err := signed.EncodeRLP(buf); err != nil {
+		return err
+	}
+	return c.AcceptTransactionContext(ctx, buf.Bytes())
+}
+
+// PendingSwap returns the swap transaction with the hash
+// which waits for the signature of the second owner.
+func (c *Client) PendingSwap(
+	hash common.Hash) (*transaction.Transaction, error) {
+	ctx, cancel := c.newContext()
+	defer cancel()
+
+	return c.PendingSwapContext(ctx, hash)
+}
+
+// PendingSwapContext is like PendingSwap but takes a context.
+func (c *Client) PendingSwapContext(ctx context.Context,
+	hash common.Hash) (*transaction.Transaction, error) {
+	req := &handlers.GetPendingSwapReq{Hash: hash}
+	var resp *handlers.GetPendingSwapResp
+	if err := c.call(ctx, GetPendingSwapMethod, req, &resp); err != nil {
+		return nil, err
+	}
+
+	if resp.Error != "" {
+		return nil, respError(resp.Error)
+	}
+
+	tx := &transaction.Transaction{}
+	err := transaction.DecodeRLP(bytes.NewReader(resp.Tx), tx)
+	if err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
@@ -7,7 +7,6 @@ import (
 	"github.com/SmartMeshFoundation/Spectrum"
 	"github.com/SmartMeshFoundation/Spectrum/common"
 	"github.com/SmartMeshFoundation/Spectrum/core/types"
-	"github.com/pkg/errors"
 
 	"github.com/SmartMeshFoundation/SmartPlasma/transport/handlers"
 )
@@ -20,19 +19,12 @@ func (c *Client) PendingCodeAt(
 		Account: account,
 	}
 	var resp handlers.PendingCodeAtResp
-	call := c.connect.Go(PendingCodeAtMethod, req, &resp, nil)
-
-	select {
-	case replay := <-call.Done:
-		if replay.Error != nil {
-			return nil, replay.Error
-		}
-	case <-ctx.Done():
-		return nil, errors.New("timeout")
+	if err := c.call(ctx, PendingCodeAtMethod, req, &resp); err != nil {
+		return nil, err
 	}
 
 	if resp.Error != "" {
-		return nil, errors.New(resp.Error)
+		return nil, respError(resp.Error)
 	}
 
 	return resp.Code, nil
@@ -46,19 +38,12 @@ func (c *Client) PendingNonceAt(
 		Account: account,
 	}
 	var resp handlers.PendingNonceAtResp
-	call := c.connect.Go(PendingNonceAtMethod, req, &resp, nil)
-
-	select {
-	case replay := <-call.Done:
-		if replay.Error != nil {
-			return 0, replay.Error
-		}
-	case <-ctx.Done():
-		return 0, errors.New("timeout")
+	if err := c.call(ctx, PendingNonceAtMethod, req, &resp); err != nil {
+		return 0, err
 	}
 
 	if resp.Error != "" {
-		return 0, errors.New(resp.Error)
+		return 0, respError(resp.Error)
 	}
 
 	return resp.Nonce, nil
@@ -70,19 +55,12 @@ func (c *Client) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
 	req := &handlers.SuggestGasPriceReq{}
 
 	var resp handlers.SuggestGasPriceResp
-	call := c.connect.Go(SuggestGasPriceMethod, req, &resp, nil)
-
-	select {
-	case replay := <-call.Done:
-		if replay.Error != nil {
-			return nil, replay.Error
-		}
-	case <-ctx.Done():
-		return nil, errors.New("timeout")
+	if err := c.call(ctx, SuggestGasPriceMethod, req, &resp); err != nil {
+		return nil, err
 	}
 
 	if resp.Error != "" {
-		return nil, errors.New(resp.Error)
+		return nil, respError(resp.Error)
 	}
 
 	return resp.Price, nil
@@ -100,19 +78,12 @@ func (c *Client) EstimateGas(
 	}
 
 	var resp handlers.EstimateGasResp
-	call2 := c.connect.Go(EstimateGasMethod, req, &resp, nil)
-
-	select {
-	case replay := <-call2.Done:
-		if replay.Error != nil {
-			return nil, replay.Error
-		}
-	case <-ctx.Done():
-		return nil, errors.New("timeout")
+	if err := c.call(ctx, EstimateGasMethod, req, &resp); err != nil {
+		return nil, err
 	}
 
 	if resp.Error != "" {
-		return nil, errors.New(resp.Error)
+		return nil, respError(resp.Error)
 	}
 
 	return resp.Gas, nil
@@ -135,19 +106,12 @@ func (c *Client) WaitMined(
 	}
 
 	var resp handlers.WaitMinedResp
-	call := c.connect.Go(WaitMinedMethod, req, &resp, nil)
-
-	select {
-	case replay := <-call.Done:
-		if replay.Error != nil {
-			return nil, err
-		}
-	case <-ctx.Done():
-		return nil, errors.New("timeout")
+	if err := c.call(ctx, WaitMinedMethod, req, &resp); err != nil {
+		return nil, err
 	}
 
 	if resp.Error != "" {
-		return nil, errors.New(resp.Error)
+		return nil, respError(resp.Error)
 	}
 
 	tr := &types.Receipt{}
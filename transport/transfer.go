@@ -1,23 +1,38 @@
 package transport
 
 import (
+	"bytes"
+	"context"
 	"math/big"
 
 	"github.com/SmartMeshFoundation/Spectrum/common"
 	"github.com/SmartMeshFoundation/Spectrum/core/types"
+	"github.com/SmartMeshFoundation/Spectrum/rlp"
 	"github.com/pkg/errors"
 
+	"github.com/SmartMeshFoundation/SmartPlasma/blockchan/transaction"
 	"github.com/SmartMeshFoundation/SmartPlasma/contract/mediator"
 	"github.com/SmartMeshFoundation/SmartPlasma/contract/rootchain"
 	"github.com/SmartMeshFoundation/SmartPlasma/transport/handlers"
 )
 
+// ErrNotExitable is returned when an exit uses split, merge or swap
+// transactions or a sub-coin. These transactions are off-chain only,
+// RootChain contract accepts exits of transfers of whole deposits.
+var ErrNotExitable = errors.New("the coin can not exit with RootChain")
+
 // Deposit transacts deposit function from Mediator contract.
 func (c *Client) Deposit(currency common.Address,
 	amount *big.Int) (tx *types.Transaction, err error) {
 	ctx, cancel := c.newContext()
 	defer cancel()
 
+	return c.DepositContext(ctx, currency, amount)
+}
+
+// DepositContext is like Deposit but takes a context.
+func (c *Client) DepositContext(ctx context.Context, currency common.Address,
+	amount *big.Int) (tx *types.Transaction, err error) {
 	if c.sessionMediator != nil {
 		session := mediator.CopySession(c.sessionMediator)
 		session.TransactOpts.Context = ctx
@@ -42,19 +57,12 @@ func (c *Client) Deposit(currency common.Address,
 		RawTx: raw,
 	}
 	var resp handlers.RawResp
-	call := c.connect.Go(DepositMethod, req, &resp, nil)
-
-	select {
-	case replay := <-call.Done:
-		if replay.Error != nil {
-			return nil, replay.Error
-		}
-	case <-ctx.Done():
-		return nil, errors.New("timeout")
+	if err := c.call(ctx, DepositMethod, req, &resp); err != nil {
+		return nil, err
 	}
 
 	if resp.Error != "" {
-		return nil, errors.New(resp.Error)
+		return nil, respError(resp.Error)
 	}
 
 	return tx, err
@@ -66,6 +74,18 @@ func (c *Client) Withdraw(prevTx, prevTxProof []byte, prevTxBlkNum *big.Int,
 	ctx, cancel := c.newContext()
 	defer cancel()
 
+	return c.WithdrawContext(ctx, prevTx, prevTxProof, prevTxBlkNum,
+		txRaw, txProof, txBlkNum)
+}
+
+// WithdrawContext is like Withdraw but takes a context.
+func (c *Client) WithdrawContext(ctx context.Context, prevTx,
+	prevTxProof []byte, prevTxBlkNum *big.Int, txRaw, txProof []byte,
+	txBlkNum *big.Int) (*types.Transaction, error) {
+	if err := c.checkExit(ctx, prevTx, txRaw); err != nil {
+		return nil, err
+	}
+
 	if c.sessionMediator != nil {
 		session := mediator.CopySession(c.sessionMediator)
 		session.TransactOpts.Context = ctx
@@ -92,19 +112,12 @@ func (c *Client) Withdraw(prevTx, prevTxProof []byte, prevTxBlkNum *big.Int,
 		RawTx: raw,
 	}
 	var resp handlers.RawResp
-	call := c.connect.Go(WithdrawMethod, req, &resp, nil)
-
-	select {
-	case replay := <-call.Done:
-		if replay.Error != nil {
-			return nil, replay.Error
-		}
-	case <-ctx.Done():
-		return nil, errors.New("timeout")
+	if err := c.call(ctx, WithdrawMethod, req, &resp); err != nil {
+		return nil, err
 	}
 
 	if resp.Error != "" {
-		return nil, errors.New(resp.Error)
+		return nil, respError(resp.Error)
 	}
 
 	return tx, err
@@ -117,6 +130,18 @@ func (c *Client) StartExit(previousTx, previousTxProof []byte,
 	ctx, cancel := c.newContext()
 	defer cancel()
 
+	return c.StartExitContext(ctx, previousTx, previousTxProof,
+		previousTxBlockNum, lastTx, lastTxProof, lastTxBlockNum)
+}
+
+// StartExitContext is like StartExit but takes a context.
+func (c *Client) StartExitContext(ctx context.Context, previousTx,
+	previousTxProof []byte, previousTxBlockNum *big.Int, lastTx,
+	lastTxProof []byte, lastTxBlockNum *big.Int) (*types.Transaction, error) {
+	if err := c.checkExit(ctx, previousTx, lastTx); err != nil {
+		return nil, err
+	}
+
 	if c.sessionRootChain != nil {
 		session := rootchain.CopySession(c.sessionRootChain)
 		session.TransactOpts.Context = ctx
@@ -144,19 +169,12 @@ func (c *Client) StartExit(previousTx, previousTxProof []byte,
 		RawTx: raw,
 	}
 	var resp handlers.RawResp
-	call := c.connect.Go(StartExitMethod, req, &resp, nil)
-
-	select {
-	case replay := <-call.Done:
-		if replay.Error != nil {
-			return nil, replay.Error
-		}
-	case <-ctx.Done():
-		return nil, errors.New("timeout")
+	if err := c.call(ctx, StartExitMethod, req, &resp); err != nil {
+		return nil, err
 	}
 
 	if resp.Error != "" {
-		return nil, errors.New(resp.Error)
+		return nil, respError(resp.Error)
 	}
 
 	return tx, err
@@ -167,52 +185,100 @@ func (c *Client) AcceptTransaction(rawTx []byte) (err error) {
 	ctx, cancel := c.newContext()
 	defer cancel()
 
+	return c.AcceptTransactionContext(ctx, rawTx)
+}
+
+// AcceptTransactionContext is like AcceptTransaction but takes a context.
+func (c *Client) AcceptTransactionContext(ctx context.Context,
+	rawTx []byte) (err error) {
 	req := &handlers.AcceptTransactionReq{Tx: rawTx}
 	var resp *handlers.AcceptTransactionResp
-	call := c.connect.Go(AcceptTransactionMethod, req, &resp, nil)
-
-	select {
-	case replay := <-call.Done:
-		if replay.Error != nil {
-			return replay.Error
-		}
-	case <-ctx.Done():
-		return errors.New("timeout")
+	if err := c.call(ctx, AcceptTransactionMethod, req, &resp); err != nil {
+		return err
 	}
 
 	if resp.Error != "" {
-		return errors.New(resp.Error)
+		return respError(resp.Error)
 	}
 
 	return nil
 }
 
-// AddCheckpoint sends UID and current transaction nonce
-// for inclusion in a checkpoint.
-func (c *Client) AddCheckpoint(uid, nonce *big.Int, blockNumber uint64) error {
+// OptInCheckpoint signs the opt-in of the account of the client
+// for inclusion of UID in a checkpoint and sends it to the server.
+// The account must own the coin which got the nonce in the block.
+func (c *Client) OptInCheckpoint(uid, nonce *big.Int,
+	blockNumber uint64) error {
 	ctx, cancel := c.newContext()
 	defer cancel()
 
-	req := &handlers.AddCheckpointReq{
-		UID:         uid,
-		Nonce:       nonce,
-		BlockNumber: blockNumber,
+	return c.OptInCheckpointContext(ctx, uid, nonce, blockNumber)
+}
+
+// OptInCheckpointContext is like OptInCheckpoint but takes a context.
+func (c *Client) OptInCheckpointContext(ctx context.Context, uid,
+	nonce *big.Int, blockNumber uint64) error {
+	if c.opts == nil || c.opts.OptInSigner == nil {
+		return ErrTransactor
 	}
-	var resp *handlers.AddCheckpointResp
-	call := c.connect.Go(AddCheckpointMethod, req, &resp, nil)
 
-	select {
-	case replay := <-call.Done:
-		if replay.Error != nil {
-			return replay.Error
-		}
-	case <-ctx.Done():
-		return errors.New("timeout")
+	optIn, err := transaction.NewOptIn(uid, nonce, blockNumber)
+	if err != nil {
+		return err
+	}
+
+	domain, err := c.DomainContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	signed, err := c.opts.OptInSigner(c.opts.From, optIn, domain)
+	if err != nil {
+		return err
+	}
+
+	raw, err := rlp.EncodeToBytes(signed)
+	if err != nil {
+		return err
+	}
+
+	req := &handlers.OptInCheckpointReq{OptIn: raw}
+	var resp *handlers.OptInCheckpointResp
+	if err := c.call(ctx, OptInCheckpointMethod, req, &resp); err != nil {
+		return err
 	}
 
 	if resp.Error != "" {
-		return errors.New(resp.Error)
+		return respError(resp.Error)
 	}
 
 	return nil
 }
+
+// checkExit checks that the transactions of an exit are transfers
+// of a deposit.
+func (c *Client) checkExit(ctx context.Context, prevTx, lastTx []byte) error {
+	var uid *big.Int
+	for _, raw := range [][]byte{prevTx, lastTx} {
+		tx := &transaction.Transaction{}
+		err := transaction.DecodeRLP(bytes.NewReader(raw), tx)
+		if err != nil {
+			return err
+		}
+
+		if tx.Type() != transaction.TransferType {
+			return ErrNotExitable
+		}
+		uid = tx.UID()
+	}
+
+	amount, err := c.WalletContext(ctx, uid)
+	if err != nil {
+		return err
+	}
+
+	if amount.Sign() == 0 {
+		return ErrNotExitable
+	}
+	return nil
+}
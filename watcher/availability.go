@@ -0,0 +1,327 @@
+package watcher
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/SmartMeshFoundation/Spectrum/common"
+	"github.com/SmartMeshFoundation/Spectrum/log"
+	"github.com/pkg/errors"
+
+	"github.com/SmartMeshFoundation/SmartPlasma/blockchan/account"
+	"github.com/SmartMeshFoundation/SmartPlasma/blockchan/backend"
+	"github.com/SmartMeshFoundation/SmartPlasma/blockchan/block/transactions"
+	"github.com/SmartMeshFoundation/SmartPlasma/contract/rootchain"
+	"github.com/SmartMeshFoundation/SmartPlasma/logger"
+	"github.com/SmartMeshFoundation/SmartPlasma/transport"
+)
+
+// DefaultGracePeriod is the default time a published block may be
+// unavailable before the availability monitor raises an alarm.
+const DefaultGracePeriod = time.Minute
+
+// Availability errors.
+var (
+	ErrBlockNotPublished = errors.New("block is not published")
+	ErrBlockUnavailable  = errors.New("operator does not serve the block")
+	ErrBlockMismatch     = errors.New("block does not match its root")
+)
+
+// Alarm is raised by the availability monitor when the operator
+// does not serve a published block or serves a block which does not
+// match the root in RootChain contract.
+type Alarm struct {
+	// Number is the number of the Plasma block.
+	Number uint64
+	// Root is the root of the block in RootChain contract.
+	Root common.Hash
+	// Hash is the root of the block served by the operator,
+	// it is zero if the block is unavailable.
+	Hash common.Hash
+	// Reason is ErrBlockUnavailable or ErrBlockMismatch
+	// with the details.
+	Reason error
+}
+
+// AvailabilityMonitor checks that the operator serves every block
+// published in RootChain contract. For every NewBlock event
+// the monitor requests the block from the operator, rebuilds its tree
+// and compares the root with the root in the contract. A block which
+// does not match its root or which is unavailable longer than the grace
+// period raises an alarm. Alarms are logged, passed to alarm handlers
+// and, if the exit manager is set, exit all coins of its history.
+type AvailabilityMonitor struct {
+	session  *rootchain.RootChainSession
+	backend  backend.Backend
+	contract common.Address
+	operator *transport.Client
+	interval time.Duration
+	grace    time.Duration
+	logger   log.Logger
+
+	mtx      sync.Mutex
+	next     uint64
+	checked  uint64
+	pending  []pendingBlock
+	alarms   []Alarm
+	handlers []func(ctx context.Context, alarm Alarm)
+	exits    *ExitManager
+}
+
+// pendingBlock is a block which is not verified yet.
+type pendingBlock struct {
+	number  uint64
+	seen    time.Time
+	alarmed bool
+}
+
+// NewAvailabilityMonitor creates a monitor of blocks published
+// in RootChain contract, blocks are requested from the operator.
+func NewAvailabilityMonitor(opts *account.PlasmaTransactOpts,
+	server backend.Backend, rootChain common.Address,
+	operator *transport.Client) (*AvailabilityMonitor, error) {
+	session, err := rootchain.NewRootChainSession(
+		*opts.TransactOpts, rootChain, server)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AvailabilityMonitor{
+		session:  session,
+		backend:  server,
+		contract: rootChain,
+		operator: operator,
+		interval: DefaultInterval,
+		grace:    DefaultGracePeriod,
+		logger:   logger.New("availability"),
+	}, nil
+}
+
+// SetInterval sets the period between polls of RootChain events.
+func (a *AvailabilityMonitor) SetInterval(interval time.Duration) {
+	a.interval = interval
+}
+
+// SetGracePeriod sets the time a block may be unavailable
+// before the monitor raises an alarm.
+func (a *AvailabilityMonitor) SetGracePeriod(grace time.Duration) {
+	a.grace = grace
+}
+
+// SetStartBlock sets the number of the Spectrum block the monitor
+// starts to read events from and the number of the last Plasma block
+// which is already verified.
+func (a *AvailabilityMonitor) SetStartBlock(number, checked uint64) {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	a.next, a.checked = number, checked
+}
+
+// SetLogger sets the logger of the monitor.
+func (a *AvailabilityMonitor) SetLogger(l log.Logger) {
+	a.logger = logger.Wrap(l)
+}
+
+// SetExitManager sets the exit manager which exits all coins
+// of its history when an alarm is raised.
+func (a *AvailabilityMonitor) SetExitManager(m *ExitManager) {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	a.exits = m
+}
+
+// OnAlarm adds the handler of alarms.
+func (a *AvailabilityMonitor) OnAlarm(
+	handler func(ctx context.Context, alarm Alarm)) {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	a.handlers = append(a.handlers, handler)
+}
+
+// Alarms returns alarms raised by the monitor.
+func (a *AvailabilityMonitor) Alarms() []Alarm {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	return append([]Alarm{}, a.alarms...)
+}
+
+// Run polls RootChain events with the interval until the context
+// is done. Failed polls are logged and repeated at the next tick.
+func (a *AvailabilityMonitor) Run(ctx context.Context) error {
+	a.logger.Info("Availability monitor started", "interval", a.interval)
+
+	ticker := time.NewTicker(a.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := a.Poll(ctx); err != nil && ctx.Err() == nil {
+			a.logger.Error("Failed to poll RootChain events", "err", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			a.logger.Info("Availability monitor stopped")
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll verifies blocks published since the previous poll. NewBlock
+// events do not contain numbers of blocks, so after new events
+// the monitor reads the number of the last block from RootChain
+// contract and verifies all blocks after the last verified one.
+// Unavailable blocks are requested again by next polls until they
+// are served. It returns the first error.
+func (a *AvailabilityMonitor) Poll(ctx context.Context) error {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	logs, err := rootchain.FilterEvents(ctx, a.backend, a.contract,
+		a.next, rootchain.NewBlockEvent)
+	if err != nil {
+		return err
+	}
+
+	session := rootchain.CopySession(a.session)
+	session.CallOpts.Context = ctx
+
+	if len(logs) > 0 {
+		last, err := session.BlockNumber()
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		for n := a.checked + 1; n <= last.Uint64(); n++ {
+			a.pending = append(a.pending,
+				pendingBlock{number: n, seen: now})
+		}
+
+		if last.Uint64() > a.checked {
+			a.checked = last.Uint64()
+		}
+		a.next = logs[len(logs)-1].BlockNumber + 1
+	}
+
+	var first error
+	pending := a.pending[:0]
+	for _, blk := range a.pending {
+		alarm, err := a.CheckBlock(ctx, blk.number)
+		if err == nil {
+			if blk.alarmed {
+				a.logger.Info("Block became available",
+					"number", blk.number)
+			}
+			continue
+		}
+
+		if alarm != nil && !blk.alarmed &&
+			(errors.Cause(err) == ErrBlockMismatch ||
+				time.Since(blk.seen) > a.grace) {
+			a.raise(ctx, *alarm)
+			blk.alarmed = true
+		}
+
+		a.logger.Warn("Failed to verify block", "number", blk.number,
+			"err", err)
+		if first == nil {
+			first = errors.Wrapf(err, "block %d", blk.number)
+		}
+
+		// the root of an inconsistent block never changes,
+		// the block is not checked again
+		if errors.Cause(err) != ErrBlockMismatch {
+			pending = append(pending, blk)
+		}
+	}
+	a.pending = pending
+	return first
+}
+
+// CheckBlock requests the block from the operator and compares
+// the root of the block with the root in RootChain contract.
+// If the operator does not serve the block or serves a wrong block,
+// it returns the alarm about the block with the error.
+func (a *AvailabilityMonitor) CheckBlock(ctx context.Context,
+	number uint64) (alarm *Alarm, err error) {
+	defer func() {
+		blocksVerified.Inc(verifyLabel(err))
+	}()
+
+	session := rootchain.CopySession(a.session)
+	session.CallOpts.Context = ctx
+
+	raw, err := session.ChildChain(new(big.Int).SetUint64(number))
+	if err != nil {
+		return nil, err
+	}
+	root := common.Hash(raw)
+
+	if root == (common.Hash{}) {
+		return nil, ErrBlockNotPublished
+	}
+
+	blk, err := a.operator.GetTransactionsBlockContext(ctx, number)
+	if err == nil && blk.NumberOfTX() == 0 {
+		// the operator returns an empty block for unknown numbers
+		err = errors.New("empty block")
+	}
+
+	if err != nil {
+		err = errors.WithMessage(ErrBlockUnavailable, err.Error())
+		return &Alarm{Number: number, Root: root, Reason: err}, err
+	}
+
+	// the client rebuilds the tree of the received block, a block
+	// published with the legacy order of Merkle tree levels matches
+	// its root with the legacy order
+	matched, err := transactions.MatchRoot(blk, root)
+	if err != nil {
+		return nil, err
+	}
+
+	if matched == nil {
+		err = ErrBlockMismatch
+		return &Alarm{Number: number, Root: root, Hash: blk.Hash(),
+			Reason: err}, err
+	}
+	return nil, nil
+}
+
+// raise logs the alarm, passes it to handlers and exits coins
+// of the exit manager.
+func (a *AvailabilityMonitor) raise(ctx context.Context, alarm Alarm) {
+	availabilityAlarms.Inc(verifyLabel(alarm.Reason))
+	a.logger.Error("Operator withholds block", "number", alarm.Number,
+		"root", alarm.Root, "hash", alarm.Hash, "reason", alarm.Reason)
+
+	a.alarms = append(a.alarms, alarm)
+	for _, handler := range a.handlers {
+		handler(ctx, alarm)
+	}
+
+	if a.exits == nil {
+		return
+	}
+
+	exits, err := a.exits.ExitAll(ctx)
+	if err != nil {
+		a.logger.Error("Failed to exit coins", "err", err)
+	}
+	a.logger.Warn("Coins are exiting", "exits", exits)
+}
+
+func verifyLabel(err error) string {
+	switch errors.Cause(err) {
+	case nil:
+		return "ok"
+	case ErrBlockUnavailable:
+		return "unavailable"
+	case ErrBlockMismatch:
+		return "mismatch"
+	}
+	return "error"
+}
@@ -0,0 +1,197 @@
+package watcher
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/SmartMeshFoundation/Spectrum/common"
+	"github.com/pkg/errors"
+
+	"github.com/SmartMeshFoundation/SmartPlasma/blockchan/block/transactions"
+)
+
+func (i *instance) availabilityMonitor(t *testing.T) *AvailabilityMonitor {
+	a, err := NewAvailabilityMonitor(i.accounts[1], i.backend, i.rootChain,
+		i.client(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+// withholdBlock publishes the root of a block
+// without saving the block.
+func (i *instance) withholdBlock(t *testing.T) uint64 {
+	tx, err := i.session.NewBlock(common.Hash{1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	i.mustSucceed(t, tx, "failed to publish block")
+
+	i.blocks++
+	return i.blocks
+}
+
+func TestAvailabilityMonitor(t *testing.T) {
+	i := newInstance(t, 3)
+	defer i.Close()
+
+	user1, user2 := i.accounts[1], i.accounts[2]
+	uid := big.NewInt(7)
+
+	i.publishTx(t, testTx(t, zero, uid, zero, user2.From, user1))
+	i.publishTx(t, testTx(t, one, uid, one, user1.From, user2))
+
+	a := i.availabilityMonitor(t)
+	var handled []Alarm
+	a.OnAlarm(func(ctx context.Context, alarm Alarm) {
+		handled = append(handled, alarm)
+	})
+
+	if err := a.Poll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	withheld := i.withholdBlock(t)
+
+	// the block is not alarmed during the grace period
+	a.SetGracePeriod(time.Hour)
+	if err := a.Poll(context.Background()); errors.Cause(
+		err) != ErrBlockUnavailable {
+		t.Fatalf("expected error %s, got %v", ErrBlockUnavailable, err)
+	}
+
+	if len(a.Alarms()) != 0 {
+		t.Fatal("alarm is raised during the grace period")
+	}
+
+	a.SetGracePeriod(0)
+	for n := 0; n < 2; n++ {
+		if err := a.Poll(context.Background()); errors.Cause(
+			err) != ErrBlockUnavailable {
+			t.Fatalf("expected error %s, got %v", ErrBlockUnavailable, err)
+		}
+	}
+
+	alarms := a.Alarms()
+	if len(alarms) != 1 || len(handled) != 1 {
+		t.Fatal("alarm is not raised once")
+	}
+
+	if alarms[0].Number != withheld || alarms[0].Root != (common.Hash{1}) ||
+		errors.Cause(alarms[0].Reason) != ErrBlockUnavailable {
+		t.Fatal("wrong alarm")
+	}
+
+	// the operator serves a block which does not match the root
+	blk := transactions.NewBlock()
+	err := blk.AddTx(testTx(t, two, uid, two, user2.From, user1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := blk.Build(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := i.service.SaveBlockToDB(withheld, blk); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := a.Poll(context.Background()); errors.Cause(
+		err) != ErrBlockMismatch {
+		t.Fatalf("expected error %s, got %v", ErrBlockMismatch, err)
+	}
+
+	// the inconsistent block is not alarmed again
+	if len(a.Alarms()) != 1 {
+		t.Fatal("alarm is raised again")
+	}
+
+	if err := a.Poll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAvailabilityMonitorMismatch(t *testing.T) {
+	i := newInstance(t, 3)
+	defer i.Close()
+
+	user1, user2 := i.accounts[1], i.accounts[2]
+	uid := big.NewInt(7)
+
+	i.publishTx(t, testTx(t, zero, uid, zero, user2.From, user1))
+
+	// the operator serves another block than the published one
+	blk := transactions.NewBlock()
+	err := blk.AddTx(testTx(t, one, uid, one, user1.From, user2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := blk.Build(); err != nil {
+		t.Fatal(err)
+	}
+
+	number := i.withholdBlock(t)
+	if err := i.service.SaveBlockToDB(number, blk); err != nil {
+		t.Fatal(err)
+	}
+
+	a := i.availabilityMonitor(t)
+	if err := a.Poll(context.Background()); errors.Cause(
+		err) != ErrBlockMismatch {
+		t.Fatalf("expected error %s, got %v", ErrBlockMismatch, err)
+	}
+
+	alarms := a.Alarms()
+	if len(alarms) != 1 || alarms[0].Number != number ||
+		alarms[0].Hash != blk.Hash() {
+		t.Fatal("inconsistent block is not alarmed")
+	}
+}
+
+func TestAvailabilityMonitorExit(t *testing.T) {
+	i := newInstance(t, 3)
+	defer i.Close()
+
+	user1 := i.accounts[1]
+	uid := i.deposit(t, user1)
+	history := NewHistory(i.newDB(t, "history"))
+
+	records := i.publishHistory(t, uid)
+	for _, n := range []int{3, 4} {
+		if err := history.Add(uid, records[n]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m := i.exitManager(t, user1, history, i.newDB(t, "exits"))
+	a := i.availabilityMonitor(t)
+	a.SetExitManager(m)
+	a.SetGracePeriod(0)
+
+	if err := a.Poll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := m.Status(uid); err != ErrExitNotFound {
+		t.Fatal("coin is exited without alarms")
+	}
+
+	i.withholdBlock(t)
+	if err := a.Poll(context.Background()); err == nil {
+		t.Fatal("withheld block is verified")
+	}
+
+	status, err := m.Status(uid)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if status.Stage != ExitStarted || i.exitState(t, uid) != ExitStateActive {
+		t.Fatal("exit is not started after the alarm")
+	}
+}
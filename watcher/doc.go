@@ -0,0 +1,45 @@
+// Package watcher protects coins of a wallet from a fraudulent operator.
+//
+// The wallet records every transaction of its coins with the proof
+// of inclusion in a History:
+//
+//	history := watcher.NewHistory(db)
+//	err := history.Add(uid, watcher.CoinRecord{Tx: rawTx, Proof: proof,
+//		Block: number})
+//
+//	w, err := watcher.NewWatcher(opts, server, rootChainAddr, client,
+//		history)
+//	go w.Run(ctx)
+//
+// The Watcher polls NewCheckpoint events of RootChain contract, requests
+// the checkpoint block from the operator and compares the nonce of every
+// coin of the history with the nonce of its last transaction.
+// A checkpoint which fixes a greater nonce is challenged directly from
+// the account of the wallet. Checkpoints whose blocks the operator does
+// not return are checked again at next polls until the challenge period
+// is over.
+//
+// The watcher adds honest checkpoints of the coins to the history.
+// The Responder uses the history to answer challenges of exits
+// and checkpoints of the wallet with the transaction which follows
+// the challenge transaction or with a checkpoint which fixes a greater
+// nonce. Of the valid responses the one with the lowest gas estimate
+// is sent. Exits of other accounts are not answered, disputes which
+// could not be answered are retried at next polls.
+//
+// The ExitManager exits coins of the history and tracks exits until
+// the coins are withdrawn. Exit starts the exit with the last two
+// transactions of the coin, the last one must transfer the coin
+// to the wallet. Polls read the state of exits from RootChain contract
+// and withdraw coins once the exit time has passed and the exit has
+// no challenges. Exits are saved to the database of the manager,
+// so they are continued after restarts.
+//
+// The AvailabilityMonitor requests every block published by NewBlock
+// events from the operator, rebuilds its tree and compares the root
+// with the root in RootChain contract. A block which does not match its
+// root raises an alarm at once, a block which the operator does not serve
+// raises an alarm when it is still unavailable after the grace period.
+// Alarms are logged, passed to handlers set with OnAlarm and, if
+// SetExitManager sets the exit manager, exit all coins of its history.
+package watcher
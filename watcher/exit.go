@@ -0,0 +1,512 @@
+package watcher
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/SmartMeshFoundation/Spectrum/common"
+	"github.com/SmartMeshFoundation/Spectrum/core/types"
+	"github.com/SmartMeshFoundation/Spectrum/log"
+	"github.com/SmartMeshFoundation/Spectrum/rlp"
+	"github.com/pkg/errors"
+
+	"github.com/SmartMeshFoundation/SmartPlasma/blockchan/account"
+	"github.com/SmartMeshFoundation/SmartPlasma/blockchan/backend"
+	"github.com/SmartMeshFoundation/SmartPlasma/contract/mediator"
+	"github.com/SmartMeshFoundation/SmartPlasma/contract/rootchain"
+	"github.com/SmartMeshFoundation/SmartPlasma/database"
+	"github.com/SmartMeshFoundation/SmartPlasma/logger"
+)
+
+// Exit manager errors.
+var (
+	ErrExitNotFound  = errors.New("exit not found")
+	ErrExitExists    = errors.New("exit of the coin is in progress")
+	ErrNoExitHistory = errors.New("history has no transactions to exit with")
+	ErrNotCoinOwner  = errors.New("wallet does not own the coin")
+)
+
+// ExitStage is the stage of an exit tracked by the exit manager.
+type ExitStage uint8
+
+// Stages of exits.
+const (
+	// ExitPending is an exit which StartExit transaction
+	// is not mined yet.
+	ExitPending ExitStage = iota
+	// ExitStarted is an exit which waits for the end
+	// of the challenge period.
+	ExitStarted
+	// ExitChallenged is an exit with unanswered challenges.
+	ExitChallenged
+	// ExitWithdrawn is an exit which coin is withdrawn from Mediator.
+	ExitWithdrawn
+	// ExitCanceled is an exit deleted by a successful challenge.
+	ExitCanceled
+)
+
+var exitStageNames = []string{"pending", "started", "challenged",
+	"withdrawn", "canceled"}
+
+// String returns the name of the stage.
+func (s ExitStage) String() string {
+	if int(s) < len(exitStageNames) {
+		return exitStageNames[s]
+	}
+	return "unknown"
+}
+
+// Final returns true if the exit is over.
+func (s ExitStage) Final() bool {
+	return s == ExitWithdrawn || s == ExitCanceled
+}
+
+// Exit states of RootChain contract.
+const (
+	ExitStateNone       = 0
+	ExitStateChallenged = 1
+	ExitStateActive     = 2
+	ExitStateFinished   = 3
+)
+
+// Keys of exits in the database.
+var (
+	exitsKey   = []byte("exits")
+	exitPrefix = []byte("exit-")
+)
+
+// ExitStatus is the status of the exit of a coin.
+type ExitStatus struct {
+	UID   *big.Int
+	Stage ExitStage
+	// ExitTime is the unix time after which the coin can be withdrawn,
+	// it is zero until the exit is started.
+	ExitTime uint64
+	// Challenges is the number of unanswered challenges.
+	Challenges uint64
+	StartTx    common.Hash
+	WithdrawTx common.Hash
+	// Error is the last error of the exit.
+	Error string
+}
+
+// exitIntent is the exit of a coin saved by the exit manager.
+type exitIntent struct {
+	ExitStatus
+	Prev CoinRecord
+	Last CoinRecord
+}
+
+// ExitManager exits coins of the history. Exits are saved
+// to the database and tracked through their challenge periods until
+// coins are withdrawn from Mediator contract, so the manager
+// continues them after restarts. Challenges of exits are answered
+// by the responder.
+type ExitManager struct {
+	session  *rootchain.RootChainSession
+	mediator *mediator.MediatorSession
+	backend  backend.Backend
+	history  *History
+	db       database.Database
+	interval time.Duration
+	logger   log.Logger
+
+	// now returns the current time, exits are withdrawn
+	// after their exit time.
+	now func() time.Time
+
+	mtx sync.Mutex
+}
+
+// NewExitManager creates an exit manager of the wallet which saves
+// exits to the database. Exits are started in RootChain contract,
+// coins are withdrawn from Mediator contract.
+func NewExitManager(opts *account.PlasmaTransactOpts, server backend.Backend,
+	rootChain, mediatorAddr common.Address, history *History,
+	db database.Database) (*ExitManager, error) {
+	session, err := rootchain.NewRootChainSession(
+		*opts.TransactOpts, rootChain, server)
+	if err != nil {
+		return nil, err
+	}
+
+	mediatorSession, err := mediator.NewMediatorSession(
+		*opts.TransactOpts, mediatorAddr, server)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ExitManager{
+		session:  session,
+		mediator: mediatorSession,
+		backend:  server,
+		history:  history,
+		db:       db,
+		interval: DefaultInterval,
+		logger:   logger.New("exits"),
+		now:      time.Now,
+	}, nil
+}
+
+// SetInterval sets the period between polls of exits.
+func (m *ExitManager) SetInterval(interval time.Duration) {
+	m.interval = interval
+}
+
+// SetLogger sets the logger of the exit manager.
+func (m *ExitManager) SetLogger(l log.Logger) {
+	m.logger = logger.Wrap(l)
+}
+
+// Run polls exits with the interval until the context is done.
+// Failed polls are logged and repeated at the next tick.
+func (m *ExitManager) Run(ctx context.Context) error {
+	m.logger.Info("Exit manager started", "interval", m.interval)
+
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := m.Poll(ctx); err != nil && ctx.Err() == nil {
+			m.logger.Error("Failed to poll exits", "err", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			m.logger.Info("Exit manager stopped")
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Exit saves the exit of the coin and starts it with the last
+// two transactions of the coin in the history. An exit which
+// failed to start is started again by next polls.
+func (m *ExitManager) Exit(ctx context.Context, uid *big.Int) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	_, err := m.exit(ctx, uid)
+	return err
+}
+
+// ExitAll exits all coins of the history owned by the wallet,
+// coins with exits in progress and coins which the wallet does not
+// own are skipped. It returns the number of saved exits and the first
+// error, exits which failed to start are started by next polls.
+func (m *ExitManager) ExitAll(ctx context.Context) (int, error) {
+	uids, err := m.history.UIDs()
+	if err != nil {
+		return 0, err
+	}
+
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	var first error
+	exits := 0
+	for _, uid := range uids {
+		saved, err := m.exit(ctx, uid)
+		if saved {
+			exits++
+		}
+
+		switch errors.Cause(err) {
+		case nil, ErrExitExists, ErrNotCoinOwner, ErrNoExitHistory:
+			continue
+		}
+
+		if first == nil {
+			first = errors.Wrapf(err, "uid %s", uid)
+		}
+	}
+	return exits, first
+}
+
+// exit saves the exit of the coin and starts it if the coin has no exit
+// in RootChain contract. It returns true if the exit is saved.
+func (m *ExitManager) exit(ctx context.Context, uid *big.Int) (bool, error) {
+	intent, err := m.intent(uid)
+	switch {
+	case err == nil && !intent.Stage.Final():
+		return false, ErrExitExists
+	case err != nil && err != ErrExitNotFound:
+		return false, err
+	}
+
+	prev, last, err := m.history.ExitRecords(uid, m.session.CallOpts.From)
+	if err != nil {
+		return false, err
+	}
+
+	intent = &exitIntent{
+		ExitStatus: ExitStatus{UID: uid, Stage: ExitPending},
+		Prev:       *prev,
+		Last:       *last,
+	}
+
+	if err := m.save(intent); err != nil {
+		return false, err
+	}
+
+	err = m.startPending(ctx, intent)
+	if saveErr := m.save(intent); err == nil {
+		err = saveErr
+	}
+	return true, err
+}
+
+// Poll updates exits which are not over with states of RootChain
+// contract, starts pending exits and withdraws coins of exits
+// whose exit time has passed. It returns the first error.
+func (m *ExitManager) Poll(ctx context.Context) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	uids, err := m.uids()
+	if err != nil {
+		return err
+	}
+
+	var first error
+	for _, uid := range uids {
+		intent, err := m.intent(uid)
+		if err != nil {
+			return err
+		}
+
+		if intent.Stage.Final() {
+			continue
+		}
+
+		err = m.update(ctx, intent)
+		if saveErr := m.save(intent); err == nil {
+			err = saveErr
+		}
+
+		if err != nil {
+			m.logger.Warn("Failed to update exit", "uid", uid, "err", err)
+			if first == nil {
+				first = errors.Wrapf(err, "uid %s", uid)
+			}
+		}
+	}
+	return first
+}
+
+// Status returns the status of the exit of the coin.
+func (m *ExitManager) Status(uid *big.Int) (*ExitStatus, error) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	intent, err := m.intent(uid)
+	if err != nil {
+		return nil, err
+	}
+	return &intent.ExitStatus, nil
+}
+
+// Statuses returns statuses of all exits in the order they were made.
+func (m *ExitManager) Statuses() ([]*ExitStatus, error) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	uids, err := m.uids()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]*ExitStatus, len(uids))
+	for i, uid := range uids {
+		intent, err := m.intent(uid)
+		if err != nil {
+			return nil, err
+		}
+		statuses[i] = &intent.ExitStatus
+	}
+	return statuses, nil
+}
+
+// start sends StartExit transaction of the exit.
+func (m *ExitManager) start(ctx context.Context, intent *exitIntent) error {
+	session := rootchain.CopySession(m.session)
+	session.TransactOpts.Context = ctx
+
+	tx, err := session.StartExit(intent.Prev.Tx, intent.Prev.Proof,
+		new(big.Int).SetUint64(intent.Prev.Block), intent.Last.Tx,
+		intent.Last.Proof, new(big.Int).SetUint64(intent.Last.Block))
+	if err == nil {
+		err = m.mine(ctx, tx)
+	}
+	exitTransactions.Inc("start", resultLabel(err))
+
+	if err != nil {
+		intent.Error = err.Error()
+		return errors.Wrap(err, "failed to start exit")
+	}
+
+	logger.FromContext(ctx, m.logger).Info("Exit started",
+		"uid", intent.UID, "tx", tx.Hash())
+	intent.Stage, intent.StartTx, intent.Error = ExitStarted, tx.Hash(), ""
+	return nil
+}
+
+// startPending starts the pending exit if the coin has no exit
+// in RootChain contract. An exit started by an earlier call which failed
+// to record it, for example because its transaction was mined after
+// the timeout, is not started again.
+func (m *ExitManager) startPending(ctx context.Context,
+	intent *exitIntent) error {
+	session := rootchain.CopySession(m.session)
+	session.CallOpts.Context = ctx
+
+	exit, err := session.Exits(intent.UID)
+	if err != nil {
+		return err
+	}
+
+	if exit.State.Int64() != ExitStateNone {
+		return nil
+	}
+	return m.start(ctx, intent)
+}
+
+// update updates the exit with its state in RootChain contract.
+func (m *ExitManager) update(ctx context.Context, intent *exitIntent) error {
+	if intent.Stage == ExitPending {
+		if err := m.startPending(ctx, intent); err != nil {
+			return err
+		}
+	}
+
+	session := rootchain.CopySession(m.session)
+	session.CallOpts.Context = ctx
+
+	exit, err := session.Exits(intent.UID)
+	if err != nil {
+		return err
+	}
+
+	length, err := session.ChallengesLength(intent.UID)
+	if err != nil {
+		return err
+	}
+	intent.Challenges = length.Uint64()
+
+	switch exit.State.Int64() {
+	case ExitStateNone:
+		intent.Stage = ExitCanceled
+		logger.FromContext(ctx, m.logger).Warn("Exit canceled by challenge",
+			"uid", intent.UID)
+	case ExitStateChallenged:
+		intent.Stage = ExitChallenged
+	case ExitStateActive:
+		intent.Stage = ExitStarted
+		intent.ExitTime = exit.ExitTime.Uint64()
+		if m.now().Unix() > exit.ExitTime.Int64() && intent.Challenges == 0 {
+			return m.withdraw(ctx, intent)
+		}
+	case ExitStateFinished:
+		intent.Stage = ExitWithdrawn
+	}
+	return nil
+}
+
+// withdraw withdraws the coin of the exit from Mediator contract.
+func (m *ExitManager) withdraw(ctx context.Context,
+	intent *exitIntent) error {
+	session := mediator.CopySession(m.mediator)
+	session.TransactOpts.Context = ctx
+
+	tx, err := session.Withdraw(intent.Prev.Tx, intent.Prev.Proof,
+		new(big.Int).SetUint64(intent.Prev.Block), intent.Last.Tx,
+		intent.Last.Proof, new(big.Int).SetUint64(intent.Last.Block))
+	if err == nil {
+		err = m.mine(ctx, tx)
+	}
+	exitTransactions.Inc("withdraw", resultLabel(err))
+
+	if err != nil {
+		intent.Error = err.Error()
+		return errors.Wrap(err, "failed to withdraw")
+	}
+
+	logger.FromContext(ctx, m.logger).Info("Coin withdrawn",
+		"uid", intent.UID, "tx", tx.Hash())
+	intent.Stage, intent.WithdrawTx, intent.Error = ExitWithdrawn, tx.Hash(), ""
+	return nil
+}
+
+// mine waits until the transaction is mined and checks its status.
+func (m *ExitManager) mine(ctx context.Context, tx *types.Transaction) error {
+	tr, err := m.backend.Mine(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	if tr.Status != types.ReceiptStatusSuccessful {
+		return ErrTransactionFailed
+	}
+	return nil
+}
+
+func (m *ExitManager) intent(uid *big.Int) (*exitIntent, error) {
+	raw, err := m.db.Get(exitKey(uid))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(raw) == 0 {
+		return nil, ErrExitNotFound
+	}
+
+	intent := &exitIntent{}
+	if err := rlp.DecodeBytes(raw, intent); err != nil {
+		return nil, err
+	}
+	return intent, nil
+}
+
+func (m *ExitManager) save(intent *exitIntent) error {
+	uids, err := m.uids()
+	if err != nil {
+		return err
+	}
+
+	if !containsUID(uids, intent.UID) {
+		raw, err := rlp.EncodeToBytes(append(uids, intent.UID))
+		if err != nil {
+			return err
+		}
+
+		if err := m.db.Set(exitsKey, raw); err != nil {
+			return err
+		}
+	}
+
+	raw, err := rlp.EncodeToBytes(intent)
+	if err != nil {
+		return err
+	}
+	return m.db.Set(exitKey(intent.UID), raw)
+}
+
+func (m *ExitManager) uids() ([]*big.Int, error) {
+	raw, err := m.db.Get(exitsKey)
+	if err != nil || len(raw) == 0 {
+		return nil, err
+	}
+
+	var uids []*big.Int
+	if err := rlp.DecodeBytes(raw, &uids); err != nil {
+		return nil, err
+	}
+	return uids, nil
+}
+
+func exitKey(uid *big.Int) []byte {
+	return append(append([]byte{}, exitPrefix...), uid.String()...)
+}
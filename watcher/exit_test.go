@@ -0,0 +1,287 @@
+package watcher
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/SmartMeshFoundation/SmartPlasma/blockchan/account"
+	"github.com/SmartMeshFoundation/SmartPlasma/contract/erc20token"
+	"github.com/SmartMeshFoundation/SmartPlasma/database"
+)
+
+func (i *instance) exitManager(t *testing.T, user *account.PlasmaTransactOpts,
+	history *History, db database.Database) *ExitManager {
+	m, err := NewExitManager(user, i.backend, i.rootChain, i.mediator,
+		history, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func (i *instance) balance(t *testing.T,
+	user *account.PlasmaTransactOpts) int64 {
+	token, err := erc20token.NewExampleTokenSession(
+		*user.TransactOpts, i.token, i.backend)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	balance, err := token.BalanceOf(user.From)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return balance.Int64()
+}
+
+func exitStatus(t *testing.T, m *ExitManager, uid *big.Int) *ExitStatus {
+	status, err := m.Status(uid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return status
+}
+
+func TestExitManager(t *testing.T) {
+	i := newInstance(t, 3)
+	defer i.Close()
+
+	user1, user2 := i.accounts[1], i.accounts[2]
+	uid := i.deposit(t, user1)
+	history := NewHistory(i.newDB(t, "history"))
+	db := i.newDB(t, "exits")
+	ctx := context.Background()
+
+	records := []CoinRecord{
+		i.publishTx(t, testTx(t, zero, uid, zero, user2.From, user1)),
+		i.publishTx(t, testTx(t, one, uid, one, user1.From, user2)),
+	}
+
+	// the simulated clock starts at zero
+	m := i.exitManager(t, user1, history, db)
+	m.now = func() time.Time { return time.Unix(0, 0) }
+
+	if err := m.Exit(ctx, uid); err != ErrCoinNotFound {
+		t.Fatalf("expected error %s, got %v", ErrCoinNotFound, err)
+	}
+
+	for _, r := range records {
+		if err := history.Add(uid, r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := m.Exit(ctx, uid); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Exit(ctx, uid); err != ErrExitExists {
+		t.Fatalf("expected error %s, got %v", ErrExitExists, err)
+	}
+
+	if err := m.Poll(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	status := exitStatus(t, m, uid)
+	if status.Stage != ExitStarted || status.ExitTime == 0 ||
+		status.StartTx.Big().Sign() == 0 {
+		t.Fatalf("wrong status of started exit %+v", status)
+	}
+
+	// the exit continues after a restart
+	i.timeMachine(t)
+	m = i.exitManager(t, user1, history, db)
+	m.now = func() time.Time {
+		return time.Unix(int64(status.ExitTime)+1, 0)
+	}
+
+	if err := m.Poll(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	status = exitStatus(t, m, uid)
+	if status.Stage != ExitWithdrawn || status.WithdrawTx.Big().Sign() == 0 {
+		t.Fatalf("wrong status of withdrawn exit %+v", status)
+	}
+
+	if i.exitState(t, uid) != ExitStateFinished {
+		t.Fatal("exit is not finished")
+	}
+
+	if i.balance(t, user1) != 1 {
+		t.Fatal("coin is not withdrawn")
+	}
+
+	statuses, err := m.Statuses()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(statuses) != 1 || statuses[0].UID.Cmp(uid) != 0 {
+		t.Fatal("wrong statuses")
+	}
+}
+
+func TestExitManagerChallenge(t *testing.T) {
+	i := newInstance(t, 3)
+	defer i.Close()
+
+	user1 := i.accounts[1]
+	uid := i.deposit(t, user1)
+	history := NewHistory(i.newDB(t, "history"))
+	ctx := context.Background()
+
+	records := i.publishHistory(t, uid)
+	for _, n := range []int{3, 4} {
+		if err := history.Add(uid, records[n]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m := i.exitManager(t, user1, history, i.newDB(t, "exits"))
+	m.now = func() time.Time { return time.Unix(0, 0) }
+
+	if err := m.Exit(ctx, uid); err != nil {
+		t.Fatal(err)
+	}
+
+	i.challengeExit(t, uid, records[1])
+
+	if err := m.Poll(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	status := exitStatus(t, m, uid)
+	if status.Stage != ExitChallenged || status.Challenges != 1 {
+		t.Fatalf("wrong status of challenged exit %+v", status)
+	}
+
+	// the exit is not withdrawn until the challenge is answered
+	i.timeMachine(t)
+	m.now = time.Now
+
+	if err := m.Poll(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if exitStatus(t, m, uid).Stage != ExitChallenged {
+		t.Fatal("challenged exit is withdrawn")
+	}
+
+	if err := history.Add(uid, records[2]); err != nil {
+		t.Fatal(err)
+	}
+
+	r := i.responder(t, user1, history)
+	if err := r.Poll(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Poll(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if exitStatus(t, m, uid).Stage != ExitWithdrawn {
+		t.Fatal("answered exit is not withdrawn")
+	}
+}
+
+func TestExitManagerStartedExit(t *testing.T) {
+	i := newInstance(t, 3)
+	defer i.Close()
+
+	user1, user2 := i.accounts[1], i.accounts[2]
+	uid := i.deposit(t, user1)
+	history := NewHistory(i.newDB(t, "history"))
+	ctx := context.Background()
+
+	records := []CoinRecord{
+		i.publishTx(t, testTx(t, zero, uid, zero, user2.From, user1)),
+		i.publishTx(t, testTx(t, one, uid, one, user1.From, user2)),
+	}
+	for _, r := range records {
+		if err := history.Add(uid, r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the exit is started, but it is not recorded by the manager
+	tx, err := i.userSession(t, user1).StartExit(records[0].Tx,
+		records[0].Proof, new(big.Int).SetUint64(records[0].Block),
+		records[1].Tx, records[1].Proof,
+		new(big.Int).SetUint64(records[1].Block))
+	if err != nil {
+		t.Fatal(err)
+	}
+	i.mustSucceed(t, tx, "failed to start exit")
+
+	// the simulated clock starts at zero
+	m := i.exitManager(t, user1, history, i.newDB(t, "exits"))
+	m.now = func() time.Time { return time.Unix(0, 0) }
+
+	if err := m.Exit(ctx, uid); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Poll(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	status := exitStatus(t, m, uid)
+	if status.Stage != ExitStarted || status.ExitTime == 0 ||
+		status.StartTx.Big().Sign() != 0 || status.Error != "" {
+		t.Fatalf("wrong status of started exit %+v", status)
+	}
+}
+
+func TestExitManagerCanceled(t *testing.T) {
+	i := newInstance(t, 3)
+	defer i.Close()
+
+	user1, user2 := i.accounts[1], i.accounts[2]
+	uid := i.deposit(t, user1)
+	history := NewHistory(i.newDB(t, "history"))
+	ctx := context.Background()
+
+	records := []CoinRecord{
+		i.publishTx(t, testTx(t, zero, uid, zero, user2.From, user1)),
+		i.publishTx(t, testTx(t, one, uid, one, user1.From, user2)),
+	}
+	for _, r := range records {
+		if err := history.Add(uid, r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// user1 spends the coin and tries to exit it
+	spent := i.publishTx(t, testTx(t, two, uid, two, user2.From, user1))
+
+	m := i.exitManager(t, user1, history, i.newDB(t, "exits"))
+	if err := m.Exit(ctx, uid); err != nil {
+		t.Fatal(err)
+	}
+
+	i.challengeExit(t, uid, spent)
+
+	if err := m.Poll(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if exitStatus(t, m, uid).Stage != ExitCanceled {
+		t.Fatal("exit is not canceled")
+	}
+
+	// the coin of other owner is not exited
+	if err := history.Add(uid, spent); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Exit(ctx, uid); errors.Cause(err) != ErrNotCoinOwner {
+		t.Fatalf("expected error %s, got %v", ErrNotCoinOwner, err)
+	}
+}
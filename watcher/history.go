@@ -0,0 +1,332 @@
+package watcher
+
+import (
+	"bytes"
+	"math/big"
+	"sort"
+	"sync"
+
+	"github.com/SmartMeshFoundation/Spectrum/common"
+	"github.com/SmartMeshFoundation/Spectrum/rlp"
+	"github.com/pkg/errors"
+
+	"github.com/SmartMeshFoundation/SmartPlasma/blockchan/transaction"
+	"github.com/SmartMeshFoundation/SmartPlasma/database"
+)
+
+// ErrCoinNotFound is returned when the coin is not in the history.
+var ErrCoinNotFound = errors.New("coin not found in the history")
+
+// Keys of the history in the database.
+var (
+	uidsKey          = []byte("uids")
+	coinPrefix       = []byte("coin-")
+	checkpointPrefix = []byte("checkpoints-")
+)
+
+// CoinRecord is a transaction of a coin with the proof
+// of its inclusion in a Plasma block.
+type CoinRecord struct {
+	Tx    []byte
+	Proof []byte
+	Block uint64
+}
+
+// CheckpointRecord is a checkpoint which fixes the nonce of a coin
+// with the proof of the nonce in the checkpoint block.
+type CheckpointRecord struct {
+	Hash  common.Hash
+	Nonce *big.Int
+	Proof []byte
+}
+
+// Transaction decodes the transaction of the record.
+func (r *CoinRecord) Transaction() (*transaction.Transaction, error) {
+	tx := &transaction.Transaction{}
+	if err := transaction.DecodeRLP(bytes.NewReader(r.Tx), tx); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// History is the history of coins owned by a wallet. The wallet records
+// every transaction of its coins and checkpoints which fix them,
+// the history is the evidence the wallet uses against a fraudulent
+// operator and against challenges of its exits and checkpoints.
+type History struct {
+	mtx sync.Mutex
+	db  database.Database
+}
+
+// NewHistory creates the history stored in the database.
+func NewHistory(db database.Database) *History {
+	return &History{db: db}
+}
+
+// Add adds the transaction of the coin to the history. Records
+// are ordered by nonces of the coin, a record with the nonce
+// of an existing record replaces it.
+func (h *History) Add(uid *big.Int, record CoinRecord) error {
+	tx, err := record.Transaction()
+	if err != nil {
+		return err
+	}
+
+	coin, ok := tx.Coin(uid)
+	if !ok {
+		return errors.Errorf("transaction does not create coin %s", uid)
+	}
+
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	records, err := h.records(uid)
+	if err != nil {
+		return err
+	}
+
+	if len(records) == 0 {
+		uids, err := h.uids()
+		if err != nil {
+			return err
+		}
+
+		if err := h.setUIDs(append(uids, uid)); err != nil {
+			return err
+		}
+	}
+
+	nonces := make([]*big.Int, len(records))
+	for i := range records {
+		nonces[i], err = recordNonce(uid, &records[i])
+		if err != nil {
+			return err
+		}
+	}
+
+	i := sort.Search(len(records), func(i int) bool {
+		return nonces[i].Cmp(coin.Nonce) >= 0
+	})
+
+	if i < len(records) && nonces[i].Cmp(coin.Nonce) == 0 {
+		records[i] = record
+	} else {
+		records = append(records, CoinRecord{})
+		copy(records[i+1:], records[i:])
+		records[i] = record
+	}
+	return h.setRecords(uid, records)
+}
+
+// Remove removes the coin from the history, when the wallet
+// no longer owns it.
+func (h *History) Remove(uid *big.Int) error {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	uids, err := h.uids()
+	if err != nil {
+		return err
+	}
+
+	for i, id := range uids {
+		if id.Cmp(uid) == 0 {
+			uids = append(uids[:i], uids[i+1:]...)
+			break
+		}
+	}
+
+	if err := h.setUIDs(uids); err != nil {
+		return err
+	}
+
+	if err := h.db.Set(checkpointKey(uid), nil); err != nil {
+		return err
+	}
+	return h.db.Set(coinKey(uid), nil)
+}
+
+// AddCheckpoint adds the checkpoint of the coin to the history.
+// A checkpoint which is already in the history is not added again.
+func (h *History) AddCheckpoint(uid *big.Int, record CheckpointRecord) error {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	records, err := h.checkpoints(uid)
+	if err != nil {
+		return err
+	}
+
+	for _, r := range records {
+		if r.Hash == record.Hash {
+			return nil
+		}
+	}
+
+	raw, err := rlp.EncodeToBytes(append(records, record))
+	if err != nil {
+		return err
+	}
+	return h.db.Set(checkpointKey(uid), raw)
+}
+
+// Checkpoints returns checkpoints of the coin in the order
+// they were added.
+func (h *History) Checkpoints(uid *big.Int) ([]CheckpointRecord, error) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	return h.checkpoints(uid)
+}
+
+// UIDs returns UIDs of coins in the history.
+func (h *History) UIDs() ([]*big.Int, error) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	return h.uids()
+}
+
+// Records returns records of the coin ordered by nonces.
+func (h *History) Records(uid *big.Int) ([]CoinRecord, error) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	records, err := h.records(uid)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(records) == 0 {
+		return nil, ErrCoinNotFound
+	}
+	return records, nil
+}
+
+// Last returns the record of the coin with the greatest nonce
+// and the nonce.
+func (h *History) Last(uid *big.Int) (*CoinRecord, *big.Int, error) {
+	records, err := h.Records(uid)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	last := &records[len(records)-1]
+	nonce, err := recordNonce(uid, last)
+	if err != nil {
+		return nil, nil, err
+	}
+	return last, nonce, nil
+}
+
+// ExitRecords returns the records an exit of the coin is started with:
+// the last record, which must give the coin to the owner,
+// and the record of the previous transaction of the coin.
+func (h *History) ExitRecords(uid *big.Int,
+	owner common.Address) (prev, last *CoinRecord, err error) {
+	records, err := h.Records(uid)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(records) < 2 {
+		return nil, nil, ErrNoExitHistory
+	}
+
+	last, prev = &records[len(records)-1], &records[len(records)-2]
+
+	tx, err := last.Transaction()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	coin, ok := tx.Coin(uid)
+	if !ok || coin.Owner != owner {
+		return nil, nil, ErrNotCoinOwner
+	}
+
+	prevNonce, err := recordNonce(uid, prev)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if new(big.Int).Add(prevNonce, big.NewInt(1)).Cmp(coin.Nonce) != 0 {
+		return nil, nil, ErrNoExitHistory
+	}
+	return prev, last, nil
+}
+
+func (h *History) uids() ([]*big.Int, error) {
+	raw, err := h.db.Get(uidsKey)
+	if err != nil || len(raw) == 0 {
+		return nil, err
+	}
+
+	var uids []*big.Int
+	if err := rlp.DecodeBytes(raw, &uids); err != nil {
+		return nil, err
+	}
+	return uids, nil
+}
+
+func (h *History) setUIDs(uids []*big.Int) error {
+	raw, err := rlp.EncodeToBytes(uids)
+	if err != nil {
+		return err
+	}
+	return h.db.Set(uidsKey, raw)
+}
+
+func (h *History) records(uid *big.Int) ([]CoinRecord, error) {
+	raw, err := h.db.Get(coinKey(uid))
+	if err != nil || len(raw) == 0 {
+		return nil, err
+	}
+
+	var records []CoinRecord
+	if err := rlp.DecodeBytes(raw, &records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+func (h *History) setRecords(uid *big.Int, records []CoinRecord) error {
+	raw, err := rlp.EncodeToBytes(records)
+	if err != nil {
+		return err
+	}
+	return h.db.Set(coinKey(uid), raw)
+}
+
+func (h *History) checkpoints(uid *big.Int) ([]CheckpointRecord, error) {
+	raw, err := h.db.Get(checkpointKey(uid))
+	if err != nil || len(raw) == 0 {
+		return nil, err
+	}
+
+	var records []CheckpointRecord
+	if err := rlp.DecodeBytes(raw, &records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// recordNonce returns the nonce of the coin created by the record.
+func recordNonce(uid *big.Int, record *CoinRecord) (*big.Int, error) {
+	tx, err := record.Transaction()
+	if err != nil {
+		return nil, err
+	}
+
+	coin, ok := tx.Coin(uid)
+	if !ok {
+		return nil, errors.Errorf("transaction does not create coin %s", uid)
+	}
+	return coin.Nonce, nil
+}
+
+func coinKey(uid *big.Int) []byte {
+	return append(append([]byte{}, coinPrefix...), uid.String()...)
+}
+
+func checkpointKey(uid *big.Int) []byte {
+	return append(append([]byte{}, checkpointPrefix...), uid.String()...)
+}
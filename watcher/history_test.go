@@ -0,0 +1,117 @@
+package watcher
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/SmartMeshFoundation/Spectrum/common"
+)
+
+func TestHistory(t *testing.T) {
+	i := newInstance(t, 2)
+	defer i.Close()
+
+	owner := i.accounts[0]
+	user1 := i.accounts[1]
+	uid := big.NewInt(7)
+	history := NewHistory(i.newDB(t, "history"))
+
+	record := func(nonce *big.Int, block uint64) CoinRecord {
+		tx := testTx(t, zero, uid, nonce, user1.From, owner)
+		return CoinRecord{Tx: rawTx(t, tx), Block: block}
+	}
+
+	if _, _, err := history.Last(uid); err != ErrCoinNotFound {
+		t.Fatalf("expected error %s, got %v", ErrCoinNotFound, err)
+	}
+
+	for _, r := range []CoinRecord{record(two, 3), record(zero, 1),
+		record(one, 2), record(one, 4)} {
+		if err := history.Add(uid, r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	records, err := history.Records(uid)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the record with nonce one is replaced
+	expected := []uint64{1, 4, 3}
+	if len(records) != len(expected) {
+		t.Fatalf("expected %d records, got %d", len(expected), len(records))
+	}
+
+	for n, r := range records {
+		if r.Block != expected[n] {
+			t.Fatalf("record %d: expected block %d, got %d",
+				n, expected[n], r.Block)
+		}
+	}
+
+	last, nonce, err := history.Last(uid)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if nonce.Cmp(two) != 0 || !bytes.Equal(last.Tx, records[2].Tx) {
+		t.Fatal("wrong last record")
+	}
+
+	uids, err := history.UIDs()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(uids) != 1 || uids[0].Cmp(uid) != 0 {
+		t.Fatal("wrong UIDs")
+	}
+
+	for _, hash := range []common.Hash{{1}, {2}, {1}} {
+		err := history.AddCheckpoint(uid, CheckpointRecord{
+			Hash:  hash,
+			Nonce: one,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	checkpoints, err := history.Checkpoints(uid)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the checkpoint is not added twice
+	if len(checkpoints) != 2 || checkpoints[1].Hash != (common.Hash{2}) {
+		t.Fatal("wrong checkpoints")
+	}
+
+	if err := history.Remove(uid); err != nil {
+		t.Fatal(err)
+	}
+
+	uids, err = history.UIDs()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(uids) != 0 {
+		t.Fatal("coin is not removed")
+	}
+
+	if _, err := history.Records(uid); err != ErrCoinNotFound {
+		t.Fatalf("expected error %s, got %v", ErrCoinNotFound, err)
+	}
+
+	checkpoints, err = history.Checkpoints(uid)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(checkpoints) != 0 {
+		t.Fatal("checkpoints are not removed")
+	}
+}
@@ -0,0 +1,38 @@
+package watcher
+
+import (
+	"github.com/SmartMeshFoundation/SmartPlasma/metrics"
+)
+
+// Watcher metrics.
+var (
+	checkpointsChecked = metrics.DefaultRegistry.NewCounter(
+		"smartplasma_watcher_checkpoints_checked_total",
+		"Number of checkpoints checked against the coin history.", "result")
+	checkpointChallenges = metrics.DefaultRegistry.NewCounter(
+		"smartplasma_watcher_checkpoint_challenges_total",
+		"Number of challenges of checkpoints sent by the watcher.", "result")
+	challengeResponses = metrics.DefaultRegistry.NewCounter(
+		"smartplasma_watcher_challenge_responses_total",
+		"Number of responses to challenges sent by the responder.",
+		"method", "result")
+	exitTransactions = metrics.DefaultRegistry.NewCounter(
+		"smartplasma_watcher_exit_transactions_total",
+		"Number of exit and withdrawal transactions sent by the exit manager.",
+		"kind", "result")
+	blocksVerified = metrics.DefaultRegistry.NewCounter(
+		"smartplasma_watcher_blocks_verified_total",
+		"Number of published blocks verified by the availability monitor.",
+		"result")
+	availabilityAlarms = metrics.DefaultRegistry.NewCounter(
+		"smartplasma_watcher_availability_alarms_total",
+		"Number of alarms about blocks the operator does not serve.",
+		"reason")
+)
+
+func resultLabel(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "ok"
+}
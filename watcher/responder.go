@@ -0,0 +1,519 @@
+package watcher
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/SmartMeshFoundation/Spectrum/common"
+	"github.com/SmartMeshFoundation/Spectrum/core/types"
+	"github.com/SmartMeshFoundation/Spectrum/log"
+	"github.com/pkg/errors"
+
+	"github.com/SmartMeshFoundation/SmartPlasma/blockchan/account"
+	"github.com/SmartMeshFoundation/SmartPlasma/blockchan/backend"
+	"github.com/SmartMeshFoundation/SmartPlasma/contract/rootchain"
+	"github.com/SmartMeshFoundation/SmartPlasma/logger"
+)
+
+// ErrNoResponse is returned when neither the history nor checkpoints
+// of the coin answer a challenge.
+var ErrNoResponse = errors.New("no valid response to the challenge")
+
+// Methods of RootChain contract which answer challenges.
+const (
+	respondChallengeExit               = "respondChallengeExit"
+	respondChallengeExitWithCheckpoint = "respondChallengeExitWithCheckpoint"
+	respondCheckpointChallenge         = "respondCheckpointChallenge"
+	respondWithHistoricalCheckpoint    = "respondWithHistoricalCheckpoint"
+)
+
+// Responder answers challenges of exits and checkpoints of coins
+// of the wallet. A challenge is answered with the next transaction
+// of the coin or with a checkpoint which fixes a greater nonce,
+// the response which uses less gas is chosen.
+type Responder struct {
+	session  *rootchain.RootChainSession
+	backend  backend.Backend
+	contract common.Address
+	history  *History
+	interval time.Duration
+	logger   log.Logger
+
+	mtx      sync.Mutex
+	next     uint64
+	disputes []dispute
+}
+
+// dispute is an exit or a checkpoint of a coin
+// which has unanswered challenges.
+type dispute struct {
+	uid *big.Int
+	// checkpoint is zero for exits.
+	checkpoint common.Hash
+}
+
+// response is a call of RootChain contract which answers a challenge.
+type response struct {
+	method string
+	args   []interface{}
+	send   func(session *rootchain.RootChainSession) (*types.Transaction,
+		error)
+}
+
+// NewResponder creates a responder of the wallet. Responses are sent
+// directly to RootChain contract from the account.
+func NewResponder(opts *account.PlasmaTransactOpts, server backend.Backend,
+	rootChain common.Address, history *History) (*Responder, error) {
+	session, err := rootchain.NewRootChainSession(
+		*opts.TransactOpts, rootChain, server)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Responder{
+		session:  session,
+		backend:  server,
+		contract: rootChain,
+		history:  history,
+		interval: DefaultInterval,
+		logger:   logger.New("responder"),
+	}, nil
+}
+
+// SetInterval sets the period between polls of RootChain events.
+func (r *Responder) SetInterval(interval time.Duration) {
+	r.interval = interval
+}
+
+// SetStartBlock sets the number of the Spectrum block
+// the responder starts to read events from.
+func (r *Responder) SetStartBlock(number uint64) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.next = number
+}
+
+// SetLogger sets the logger of the responder.
+func (r *Responder) SetLogger(l log.Logger) {
+	r.logger = logger.Wrap(l)
+}
+
+// Run polls RootChain events with the interval until the context
+// is done. Failed polls are logged and repeated at the next tick.
+func (r *Responder) Run(ctx context.Context) error {
+	r.logger.Info("Responder started", "interval", r.interval)
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.Poll(ctx); err != nil && ctx.Err() == nil {
+			r.logger.Error("Failed to poll RootChain events", "err", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			r.logger.Info("Responder stopped")
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll answers challenges of coins of the history raised since
+// the previous poll. Disputes which failed to be answered are
+// answered again by next polls until they have no challenges.
+// It returns the first error.
+func (r *Responder) Poll(ctx context.Context) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	logs, err := rootchain.FilterEvents(ctx, r.backend, r.contract, r.next,
+		rootchain.ChallengeExitEvent, rootchain.ChallengeCheckpointEvent)
+	if err != nil {
+		return err
+	}
+
+	exitTopic, err := rootchain.EventTopic(rootchain.ChallengeExitEvent)
+	if err != nil {
+		return err
+	}
+
+	for _, l := range logs {
+		var d dispute
+		if l.Topics[0] == exitTopic {
+			event, err := rootchain.ParseChallengeExit(l)
+			if err != nil {
+				return err
+			}
+			d.uid = event.Uid
+		} else {
+			event, err := rootchain.ParseChallengeCheckpoint(l)
+			if err != nil {
+				return err
+			}
+			d.uid, d.checkpoint = event.Uid, event.Checkpoint
+		}
+
+		r.addDispute(d)
+		if l.BlockNumber >= r.next {
+			r.next = l.BlockNumber + 1
+		}
+	}
+
+	if len(r.disputes) == 0 {
+		return nil
+	}
+
+	uids, err := r.history.UIDs()
+	if err != nil {
+		return err
+	}
+
+	var first error
+	disputes := r.disputes[:0]
+	for _, d := range r.disputes {
+		if !containsUID(uids, d.uid) {
+			continue
+		}
+
+		if d.checkpoint == (common.Hash{}) {
+			_, err = r.RespondExit(ctx, d.uid)
+		} else {
+			_, err = r.RespondCheckpoint(ctx, d.uid, d.checkpoint)
+		}
+
+		if err == nil {
+			continue
+		}
+
+		r.logger.Warn("Failed to respond to challenges", "uid", d.uid,
+			"checkpoint", d.checkpoint, "err", err)
+		if first == nil {
+			first = errors.Wrapf(err, "uid %s", d.uid)
+		}
+		disputes = append(disputes, d)
+	}
+	r.disputes = disputes
+	return first
+}
+
+// RespondExit answers challenges of the exit of the coin.
+// Exits of other accounts are not answered. It returns
+// the number of answered challenges.
+func (r *Responder) RespondExit(ctx context.Context,
+	uid *big.Int) (responded int, err error) {
+	l := logger.FromContext(ctx, r.logger)
+
+	session := r.copySession(ctx)
+
+	exit, err := session.Exits(uid)
+	if err != nil {
+		return 0, err
+	}
+
+	if exit.State.Int64() != ExitStateChallenged {
+		return 0, nil
+	}
+
+	exitTx := &CoinRecord{Tx: exit.ExitTx}
+	tx, err := exitTx.Transaction()
+	if err != nil {
+		return 0, err
+	}
+
+	if coin, ok := tx.Coin(uid); !ok || coin.Owner != session.CallOpts.From {
+		return 0, nil
+	}
+
+	length, err := session.ChallengesLength(uid)
+	if err != nil {
+		return 0, err
+	}
+
+	challenges := make([][]byte, length.Int64())
+	for i := range challenges {
+		challenge, err := session.GetChallenge(uid, big.NewInt(int64(i)))
+		if err != nil {
+			return 0, err
+		}
+		challenges[i] = challenge.ChallengeTx
+	}
+
+	for _, challengeTx := range challenges {
+		candidates, err := r.exitResponses(uid, challengeTx)
+		if err != nil {
+			return responded, err
+		}
+
+		method, err := r.respond(ctx, session, candidates)
+		if err != nil {
+			return responded, errors.Wrap(err,
+				"failed to respond to exit challenge")
+		}
+
+		l.Info("Exit challenge answered", "uid", uid, "method", method)
+		responded++
+	}
+	return responded, nil
+}
+
+// RespondCheckpoint answers challenges of the checkpoint of the coin.
+// It returns the number of answered challenges.
+func (r *Responder) RespondCheckpoint(ctx context.Context, uid *big.Int,
+	hash common.Hash) (responded int, err error) {
+	l := logger.FromContext(ctx, r.logger)
+
+	session := r.copySession(ctx)
+
+	length, err := session.CheckpointChallengesLength(uid, hash)
+	if err != nil {
+		return 0, err
+	}
+
+	challenges := make([][]byte, length.Int64())
+	for i := range challenges {
+		challenge, err := session.GetCheckpointChallenge(uid, hash,
+			big.NewInt(int64(i)))
+		if err != nil {
+			return 0, err
+		}
+		challenges[i] = challenge.ChallengeTx
+	}
+
+	for _, challengeTx := range challenges {
+		candidates, err := r.checkpointResponses(uid, hash, challengeTx)
+		if err != nil {
+			return responded, err
+		}
+
+		method, err := r.respond(ctx, session, candidates)
+		if err != nil {
+			return responded, errors.Wrap(err,
+				"failed to respond to checkpoint challenge")
+		}
+
+		l.Info("Checkpoint challenge answered", "uid", uid,
+			"hash", hash, "method", method)
+		responded++
+	}
+	return responded, nil
+}
+
+// exitResponses returns responses to the challenge of the exit
+// from the history of the coin.
+func (r *Responder) exitResponses(uid *big.Int,
+	challengeTx []byte) ([]response, error) {
+	records, checkpoints, err := r.evidence(uid, challengeTx)
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []response
+	for _, rec := range records {
+		rec := rec
+		candidates = append(candidates, response{
+			method: respondChallengeExit,
+			args: []interface{}{uid, challengeTx, rec.Tx, rec.Proof,
+				new(big.Int).SetUint64(rec.Block)},
+			send: func(s *rootchain.RootChainSession) (*types.Transaction,
+				error) {
+				return s.RespondChallengeExit(uid, challengeTx, rec.Tx,
+					rec.Proof, new(big.Int).SetUint64(rec.Block))
+			},
+		})
+	}
+
+	for _, chpt := range checkpoints {
+		chpt := chpt
+		nonce := common.BigToHash(chpt.Nonce)
+		candidates = append(candidates, response{
+			method: respondChallengeExitWithCheckpoint,
+			args: []interface{}{uid, challengeTx, [32]byte(chpt.Hash),
+				chpt.Proof, [32]byte(nonce)},
+			send: func(s *rootchain.RootChainSession) (*types.Transaction,
+				error) {
+				return s.RespondChallengeExitWithCheckpoint(uid,
+					challengeTx, chpt.Hash, chpt.Proof, nonce)
+			},
+		})
+	}
+	return candidates, nil
+}
+
+// checkpointResponses returns responses to the challenge
+// of the checkpoint from the history of the coin.
+func (r *Responder) checkpointResponses(uid *big.Int, hash common.Hash,
+	challengeTx []byte) ([]response, error) {
+	records, checkpoints, err := r.evidence(uid, challengeTx)
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []response
+	for _, rec := range records {
+		rec := rec
+		candidates = append(candidates, response{
+			method: respondCheckpointChallenge,
+			args: []interface{}{uid, [32]byte(hash), challengeTx, rec.Tx,
+				rec.Proof, new(big.Int).SetUint64(rec.Block)},
+			send: func(s *rootchain.RootChainSession) (*types.Transaction,
+				error) {
+				return s.RespondCheckpointChallenge(uid, hash, challengeTx,
+					rec.Tx, rec.Proof, new(big.Int).SetUint64(rec.Block))
+			},
+		})
+	}
+
+	// the proof of the challenged checkpoint is not verified
+	// by RootChain contract, it is sent if the history has it
+	var proof []byte
+	for _, chpt := range checkpoints {
+		if chpt.Hash == hash {
+			proof = chpt.Proof
+		}
+	}
+
+	for _, chpt := range checkpoints {
+		if chpt.Hash == hash {
+			continue
+		}
+
+		chpt := chpt
+		candidates = append(candidates, response{
+			method: respondWithHistoricalCheckpoint,
+			args: []interface{}{uid, [32]byte(hash), proof,
+				[32]byte(chpt.Hash), chpt.Proof, challengeTx, chpt.Nonce},
+			send: func(s *rootchain.RootChainSession) (*types.Transaction,
+				error) {
+				return s.RespondWithHistoricalCheckpoint(uid, hash, proof,
+					chpt.Hash, chpt.Proof, challengeTx, chpt.Nonce)
+			},
+		})
+	}
+	return candidates, nil
+}
+
+// evidence returns records of the coin with the nonce following
+// the nonce of the challenge transaction and checkpoints
+// of the coin with nonces greater than it.
+func (r *Responder) evidence(uid *big.Int,
+	challengeTx []byte) ([]CoinRecord, []CheckpointRecord, error) {
+	challenge := &CoinRecord{Tx: challengeTx}
+	tx, err := challenge.Transaction()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	coin, ok := tx.Coin(uid)
+	if !ok {
+		return nil, nil, errors.Errorf(
+			"challenge does not create coin %s", uid)
+	}
+	next := new(big.Int).Add(coin.Nonce, big.NewInt(1))
+
+	records, err := r.history.Records(uid)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var found []CoinRecord
+	for i := range records {
+		nonce, err := recordNonce(uid, &records[i])
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if nonce.Cmp(next) == 0 {
+			found = append(found, records[i])
+		}
+	}
+
+	checkpoints, err := r.history.Checkpoints(uid)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var greater []CheckpointRecord
+	for _, chpt := range checkpoints {
+		if chpt.Nonce.Cmp(coin.Nonce) > 0 {
+			greater = append(greater, chpt)
+		}
+	}
+	return found, greater, nil
+}
+
+// respond sends the valid response which uses the least gas.
+// Responses which fail are not valid. It returns the method
+// of the sent response.
+func (r *Responder) respond(ctx context.Context,
+	session *rootchain.RootChainSession,
+	candidates []response) (method string, err error) {
+	var best *response
+	var bestGas *big.Int
+	for i := range candidates {
+		gas, err := rootchain.EstimateGas(ctx, r.backend,
+			session.CallOpts.From, r.contract, candidates[i].method,
+			candidates[i].args...)
+		if err != nil {
+			continue
+		}
+
+		if best == nil || gas.Cmp(bestGas) < 0 {
+			best, bestGas = &candidates[i], gas
+		}
+	}
+
+	if best == nil {
+		challengeResponses.Inc("none", resultLabel(ErrNoResponse))
+		return "", ErrNoResponse
+	}
+
+	tx, err := best.send(session)
+	if err == nil {
+		err = r.mine(ctx, tx)
+	}
+	challengeResponses.Inc(best.method, resultLabel(err))
+	return best.method, err
+}
+
+func (r *Responder) addDispute(d dispute) {
+	for _, pending := range r.disputes {
+		if pending.uid.Cmp(d.uid) == 0 && pending.checkpoint == d.checkpoint {
+			return
+		}
+	}
+	r.disputes = append(r.disputes, d)
+}
+
+func (r *Responder) copySession(
+	ctx context.Context) *rootchain.RootChainSession {
+	session := rootchain.CopySession(r.session)
+	session.CallOpts.Context = ctx
+	session.TransactOpts.Context = ctx
+	return session
+}
+
+// mine waits until the transaction is mined and checks its status.
+func (r *Responder) mine(ctx context.Context, tx *types.Transaction) error {
+	tr, err := r.backend.Mine(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	if tr.Status != types.ReceiptStatusSuccessful {
+		return ErrTransactionFailed
+	}
+	return nil
+}
+
+func containsUID(uids []*big.Int, uid *big.Int) bool {
+	for _, id := range uids {
+		if id.Cmp(uid) == 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,304 @@
+package watcher
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/pkg/errors"
+
+	"github.com/SmartMeshFoundation/SmartPlasma/blockchan/account"
+)
+
+func (i *instance) responder(t *testing.T,
+	user *account.PlasmaTransactOpts, history *History) *Responder {
+	r, err := NewResponder(user, i.backend, i.rootChain, history)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+// publishHistory publishes five transactions of the coin, user1
+// owns the coin after the third and the fifth transactions.
+func (i *instance) publishHistory(t *testing.T, uid *big.Int) []CoinRecord {
+	owner, user1, user2 := i.accounts[0], i.accounts[1], i.accounts[2]
+
+	return []CoinRecord{
+		i.publishTx(t, testTx(t, zero, uid, zero, user2.From, user1)),
+		i.publishTx(t, testTx(t, one, uid, one, owner.From, user2)),
+		i.publishTx(t, testTx(t, two, uid, two, user1.From, owner)),
+		i.publishTx(t, testTx(t, three, uid, three, user2.From, user1)),
+		i.publishTx(t, testTx(t, four, uid, four, user1.From, user2)),
+	}
+}
+
+// challengeExit challenges the exit of the coin with the record.
+func (i *instance) challengeExit(t *testing.T, uid *big.Int,
+	record CoinRecord) {
+	tx, err := i.session.ChallengeExit(uid, record.Tx, record.Proof,
+		new(big.Int).SetUint64(record.Block))
+	if err != nil {
+		t.Fatal(err)
+	}
+	i.mustSucceed(t, tx, "failed to challenge exit")
+}
+
+// challengedExit publishes the history of the coin, starts the exit
+// of user1 with the last two transactions and challenges it with
+// the transaction of the second block. It returns the records
+// of the transactions.
+func (i *instance) challengedExit(t *testing.T,
+	uid *big.Int) []CoinRecord {
+	records := i.publishHistory(t, uid)
+
+	exit, err := i.userSession(t, i.accounts[1]).StartExit(records[3].Tx,
+		records[3].Proof, big.NewInt(4), records[4].Tx, records[4].Proof,
+		big.NewInt(5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	i.mustSucceed(t, exit, "failed to start exit")
+
+	i.challengeExit(t, uid, records[1])
+
+	if i.exitState(t, uid) != ExitStateChallenged {
+		t.Fatal("exit is not challenged")
+	}
+	return records
+}
+
+func (i *instance) exitState(t *testing.T, uid *big.Int) int64 {
+	exit, err := i.session.Exits(uid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return exit.State.Int64()
+}
+
+func TestRespondExitChallenge(t *testing.T) {
+	i := newInstance(t, 3)
+	defer i.Close()
+
+	user1 := i.accounts[1]
+	uid := i.deposit(t, user1)
+	history := NewHistory(i.newDB(t, "history"))
+
+	records := i.challengedExit(t, uid)
+	for _, n := range []int{2, 4} {
+		if err := history.Add(uid, records[n]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := i.responder(t, user1, history)
+	if err := r.Poll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if i.exitState(t, uid) != ExitStateActive {
+		t.Fatal("exit challenge is not answered")
+	}
+
+	length, err := i.session.ChallengesLength(uid)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if length.Sign() != 0 {
+		t.Fatal("challenge is not removed")
+	}
+
+	// the answered exit is not answered again
+	responded, err := r.RespondExit(context.Background(), uid)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if responded != 0 {
+		t.Fatal("exit is answered again")
+	}
+}
+
+func TestRespondExitChallengeWithCheckpoint(t *testing.T) {
+	i := newInstance(t, 3)
+	defer i.Close()
+
+	user1 := i.accounts[1]
+	uid := i.deposit(t, user1)
+	history := NewHistory(i.newDB(t, "history"))
+
+	records := i.challengedExit(t, uid)
+	if err := history.Add(uid, records[4]); err != nil {
+		t.Fatal(err)
+	}
+
+	hash := i.publishCheckpoint(t, uid, three, 4)
+	w := i.watcher(t, user1, history)
+	if err := w.Poll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	checkpoints, err := history.Checkpoints(uid)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(checkpoints) != 1 || checkpoints[0].Hash != hash {
+		t.Fatal("checkpoint is not added to the history")
+	}
+
+	r := i.responder(t, user1, history)
+
+	// the checkpoint is not final until the challenge period passes
+	if err := r.Poll(context.Background()); err == nil {
+		t.Fatal("challenge is answered with a fresh checkpoint")
+	}
+
+	i.timeMachine(t)
+
+	if err := r.Poll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if i.exitState(t, uid) != ExitStateActive {
+		t.Fatal("exit challenge is not answered")
+	}
+}
+
+func TestRespondCheckpointChallenge(t *testing.T) {
+	i := newInstance(t, 3)
+	defer i.Close()
+
+	user1, user2 := i.accounts[1], i.accounts[2]
+	uid := big.NewInt(7)
+	history := NewHistory(i.newDB(t, "history"))
+
+	record0 := i.publishTx(t, testTx(t, zero, uid, zero, user2.From, user1))
+	record1 := i.publishTx(t, testTx(t, one, uid, one, user1.From, user2))
+	if err := history.Add(uid, record1); err != nil {
+		t.Fatal(err)
+	}
+
+	hash := i.publishCheckpoint(t, uid, one, 2)
+	chpt, err := i.client(t).GetCheckpointsBlock(hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// user2 challenges the honest checkpoint with the spent coin
+	tx, err := i.userSession(t, user2).ChallengeCheckpoint(uid, hash,
+		chpt.CreateProof(uid), one, record0.Tx, record0.Proof, one)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !i.backend.GoodTransaction(tx) {
+		t.Fatal("failed to challenge checkpoint")
+	}
+
+	if i.challenges(t, uid, hash) != 1 {
+		t.Fatal("checkpoint is not challenged")
+	}
+
+	r := i.responder(t, user1, history)
+	if err := r.Poll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if i.challenges(t, uid, hash) != 0 {
+		t.Fatal("checkpoint challenge is not answered")
+	}
+}
+
+func TestRespondWithHistoricalCheckpoint(t *testing.T) {
+	i := newInstance(t, 3)
+	defer i.Close()
+
+	user1, user2 := i.accounts[1], i.accounts[2]
+	uid := big.NewInt(7)
+	other := big.NewInt(8)
+	history := NewHistory(i.newDB(t, "history"))
+
+	record0 := i.publishTx(t, testTx(t, zero, uid, zero, user2.From, user1))
+	i.publishTx(t, testTx(t, one, uid, one, user1.From, user2))
+	record2 := i.publishTx(t, testTx(t, two, uid, two, user1.From, user1))
+	if err := history.Add(uid, record2); err != nil {
+		t.Fatal(err)
+	}
+
+	historical := i.publishCheckpoint(t, uid, two, 3)
+
+	i.timeMachine(t)
+
+	// the next checkpoint also fixes other coin to get a new hash,
+	// zero nonces do not change the hash
+	i.publishTx(t, testTx(t, zero, other, one, user2.From, user2))
+	if err := i.service.AcceptUIDState(uid, two, 3); err != nil {
+		t.Fatal(err)
+	}
+	hash := i.publishCheckpoint(t, other, one, 4)
+
+	w := i.watcher(t, user1, history)
+	if err := w.Poll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	checkpoints, err := history.Checkpoints(uid)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(checkpoints) != 2 || checkpoints[0].Hash != historical {
+		t.Fatal("checkpoints are not added to the history")
+	}
+
+	chpt, err := i.client(t).GetCheckpointsBlock(hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tx, err := i.userSession(t, user2).ChallengeCheckpoint(uid, hash,
+		chpt.CreateProof(uid), two, record0.Tx, record0.Proof, one)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !i.backend.GoodTransaction(tx) {
+		t.Fatal("failed to challenge checkpoint")
+	}
+
+	r := i.responder(t, user1, history)
+	responded, err := r.RespondCheckpoint(context.Background(), uid, hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if responded != 1 || i.challenges(t, uid, hash) != 0 {
+		t.Fatal("checkpoint challenge is not answered")
+	}
+}
+
+func TestRespondWithoutEvidence(t *testing.T) {
+	i := newInstance(t, 3)
+	defer i.Close()
+
+	user1 := i.accounts[1]
+	uid := i.deposit(t, user1)
+	history := NewHistory(i.newDB(t, "history"))
+
+	records := i.challengedExit(t, uid)
+	if err := history.Add(uid, records[4]); err != nil {
+		t.Fatal(err)
+	}
+
+	r := i.responder(t, user1, history)
+	if _, err := r.RespondExit(context.Background(),
+		uid); errors.Cause(err) != ErrNoResponse {
+		t.Fatalf("expected error %s, got %v", ErrNoResponse, err)
+	}
+
+	if i.exitState(t, uid) != ExitStateChallenged {
+		t.Fatal("exit challenge is answered")
+	}
+}
@@ -0,0 +1,316 @@
+package watcher
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/SmartMeshFoundation/Spectrum/common"
+	"github.com/SmartMeshFoundation/Spectrum/core/types"
+	"github.com/SmartMeshFoundation/Spectrum/log"
+	"github.com/pkg/errors"
+
+	"github.com/SmartMeshFoundation/SmartPlasma/blockchan/account"
+	"github.com/SmartMeshFoundation/SmartPlasma/blockchan/backend"
+	"github.com/SmartMeshFoundation/SmartPlasma/blockchan/block/checkpoints"
+	"github.com/SmartMeshFoundation/SmartPlasma/contract/rootchain"
+	"github.com/SmartMeshFoundation/SmartPlasma/logger"
+	"github.com/SmartMeshFoundation/SmartPlasma/transport"
+)
+
+// DefaultInterval is the default period between polls of RootChain events.
+const DefaultInterval = 15 * time.Second
+
+// Errors.
+var (
+	ErrWrongCheckpoint = errors.New(
+		"checkpoint block does not match its hash")
+	ErrCheckpointNotPublished = errors.New("checkpoint is not published")
+	ErrTransactionFailed      = errors.New("transaction failed")
+)
+
+// Watcher watches checkpoints published by the operator in RootChain
+// contract. A checkpoint which fixes a coin of the wallet with a nonce
+// greater than the nonce of the last transaction in the history
+// of the wallet is challenged with that transaction within
+// the challenge period.
+type Watcher struct {
+	session  *rootchain.RootChainSession
+	backend  backend.Backend
+	contract common.Address
+	operator *transport.Client
+	history  *History
+	interval time.Duration
+	logger   log.Logger
+	// now returns the current time, pending checkpoints are dropped
+	// after their challenge period.
+	now func() time.Time
+
+	mtx     sync.Mutex
+	next    uint64
+	pending []pendingCheckpoint
+}
+
+// pendingCheckpoint is a checkpoint which is not checked yet.
+type pendingCheckpoint struct {
+	hash common.Hash
+	// deadline is the end of the challenge period of the checkpoint,
+	// it is zero until the publishing time is read from RootChain contract
+	deadline time.Time
+}
+
+// NewWatcher creates a watcher of the wallet. Challenges are sent
+// directly to RootChain contract from the account, checkpoint
+// blocks are requested from the operator.
+func NewWatcher(opts *account.PlasmaTransactOpts, server backend.Backend,
+	rootChain common.Address, operator *transport.Client,
+	history *History) (*Watcher, error) {
+	session, err := rootchain.NewRootChainSession(
+		*opts.TransactOpts, rootChain, server)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Watcher{
+		session:  session,
+		backend:  server,
+		contract: rootChain,
+		operator: operator,
+		history:  history,
+		interval: DefaultInterval,
+		logger:   logger.New("watcher"),
+		now:      time.Now,
+	}, nil
+}
+
+// SetInterval sets the period between polls of RootChain events.
+func (w *Watcher) SetInterval(interval time.Duration) {
+	w.interval = interval
+}
+
+// SetStartBlock sets the number of the Spectrum block
+// the watcher starts to read events from.
+func (w *Watcher) SetStartBlock(number uint64) {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	w.next = number
+}
+
+// SetLogger sets the logger of the watcher.
+func (w *Watcher) SetLogger(l log.Logger) {
+	w.logger = logger.Wrap(l)
+}
+
+// Run polls RootChain events with the interval until the context
+// is done. Failed polls are logged and repeated at the next tick.
+func (w *Watcher) Run(ctx context.Context) error {
+	w.logger.Info("Watcher started", "interval", w.interval)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := w.Poll(ctx); err != nil && ctx.Err() == nil {
+			w.logger.Error("Failed to poll RootChain events", "err", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			w.logger.Info("Watcher stopped")
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll checks checkpoints published since the previous poll. Checkpoints
+// which failed to be checked, for example because the operator does not
+// return the checkpoint block, are checked again by next polls until
+// the challenge period of the checkpoint in RootChain contract is over.
+// It returns the first error.
+func (w *Watcher) Poll(ctx context.Context) error {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	logs, err := rootchain.FilterEvents(ctx, w.backend, w.contract,
+		w.next, rootchain.NewCheckpointEvent)
+	if err != nil {
+		return err
+	}
+
+	for _, l := range logs {
+		event, err := rootchain.ParseNewCheckpoint(l)
+		if err != nil {
+			return err
+		}
+
+		w.pending = append(w.pending,
+			pendingCheckpoint{hash: event.Hash})
+		if l.BlockNumber >= w.next {
+			w.next = l.BlockNumber + 1
+		}
+	}
+
+	if len(w.pending) == 0 {
+		return nil
+	}
+
+	session := rootchain.CopySession(w.session)
+	session.CallOpts.Context = ctx
+
+	period, err := session.ChallengePeriod()
+	if err != nil {
+		return err
+	}
+
+	var first error
+	pending := w.pending[:0]
+	for _, chpt := range w.pending {
+		_, err := w.CheckCheckpoint(ctx, chpt.hash)
+		if err == nil {
+			continue
+		}
+
+		// the deadline is read again by the next poll if it fails
+		if chpt.deadline.IsZero() {
+			published, err := session.Checkpoints(chpt.hash)
+			if err == nil && published.Sign() != 0 {
+				chpt.deadline = time.Unix(
+					new(big.Int).Add(published, period).Int64(), 0)
+			}
+		}
+
+		if !chpt.deadline.IsZero() && w.now().After(chpt.deadline) {
+			w.logger.Error("Challenge period of checkpoint is over",
+				"hash", chpt.hash, "err", err)
+			continue
+		}
+
+		w.logger.Warn("Failed to check checkpoint", "hash", chpt.hash,
+			"err", err)
+		if first == nil {
+			first = errors.Wrapf(err, "checkpoint %s", chpt.hash.Hex())
+		}
+		pending = append(pending, chpt)
+	}
+	w.pending = pending
+	return first
+}
+
+// CheckCheckpoint checks coins of the history fixed by the checkpoint
+// and challenges wrong nonces. Honest checkpoints are added
+// to the history. It returns UIDs of challenged coins.
+// Coins which are already challenged with their last transaction
+// are not challenged again.
+func (w *Watcher) CheckCheckpoint(ctx context.Context,
+	hash common.Hash) (challenged []*big.Int, err error) {
+	defer func() {
+		checkpointsChecked.Inc(resultLabel(err))
+	}()
+
+	l := logger.FromContext(ctx, w.logger)
+
+	session := rootchain.CopySession(w.session)
+	session.CallOpts.Context = ctx
+	session.TransactOpts.Context = ctx
+
+	published, err := session.Checkpoints(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	if published.Sign() == 0 {
+		return nil, ErrCheckpointNotPublished
+	}
+
+	uids, err := w.history.UIDs()
+	if err != nil || len(uids) == 0 {
+		return nil, err
+	}
+
+	chpt, err := w.operator.GetCheckpointsBlockContext(ctx, hash)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get checkpoint block")
+	}
+
+	// a checkpoint published with the legacy order of Merkle tree
+	// levels is proved with the legacy order
+	chpt, err = checkpoints.MatchHash(chpt, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	if chpt == nil {
+		return nil, ErrWrongCheckpoint
+	}
+
+	for _, uid := range uids {
+		if !chpt.Contains(uid) {
+			continue
+		}
+
+		last, nonce, err := w.history.Last(uid)
+		if err != nil {
+			return challenged, err
+		}
+
+		wrongNonce := chpt.GetNonce(uid)
+		if wrongNonce.Cmp(nonce) <= 0 {
+			// the honest checkpoint answers challenges
+			// of older transactions of the coin
+			err := w.history.AddCheckpoint(uid, CheckpointRecord{
+				Hash:  hash,
+				Nonce: wrongNonce,
+				Proof: chpt.CreateProof(uid),
+			})
+			if err != nil {
+				return challenged, err
+			}
+			continue
+		}
+
+		exists, err := session.CheckpointIsChallenge(uid, hash, last.Tx)
+		if err != nil {
+			return challenged, err
+		}
+
+		if exists {
+			continue
+		}
+
+		l.Warn("Checkpoint fixes wrong nonce", "hash", hash, "uid", uid,
+			"nonce", wrongNonce, "expected", nonce)
+
+		tx, err := session.ChallengeCheckpoint(uid, hash,
+			chpt.CreateProof(uid), wrongNonce, last.Tx, last.Proof,
+			new(big.Int).SetUint64(last.Block))
+		if err == nil {
+			err = w.mine(ctx, tx)
+		}
+		checkpointChallenges.Inc(resultLabel(err))
+
+		if err != nil {
+			return challenged, errors.Wrapf(err,
+				"failed to challenge uid %s", uid)
+		}
+
+		l.Info("Checkpoint challenged", "hash", hash, "uid", uid,
+			"tx", tx.Hash())
+		challenged = append(challenged, uid)
+	}
+	return challenged, nil
+}
+
+// mine waits until the transaction is mined and checks its status.
+func (w *Watcher) mine(ctx context.Context, tx *types.Transaction) error {
+	tr, err := w.backend.Mine(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	if tr.Status != types.ReceiptStatusSuccessful {
+		return ErrTransactionFailed
+	}
+	return nil
+}
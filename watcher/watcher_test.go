@@ -0,0 +1,516 @@
+package watcher
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"math/big"
+	"net/http/httptest"
+	"net/rpc"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/SmartMeshFoundation/Spectrum/accounts/abi/bind"
+	"github.com/SmartMeshFoundation/Spectrum/common"
+	"github.com/SmartMeshFoundation/Spectrum/core/types"
+	"github.com/pkg/errors"
+
+	"github.com/SmartMeshFoundation/SmartPlasma/blockchan/account"
+	"github.com/SmartMeshFoundation/SmartPlasma/blockchan/backend"
+	"github.com/SmartMeshFoundation/SmartPlasma/blockchan/block/transactions"
+	"github.com/SmartMeshFoundation/SmartPlasma/blockchan/transaction"
+	"github.com/SmartMeshFoundation/SmartPlasma/contract/erc20token"
+	"github.com/SmartMeshFoundation/SmartPlasma/contract/mediator"
+	"github.com/SmartMeshFoundation/SmartPlasma/contract/rootchain"
+	"github.com/SmartMeshFoundation/SmartPlasma/database/bolt"
+	"github.com/SmartMeshFoundation/SmartPlasma/service"
+	"github.com/SmartMeshFoundation/SmartPlasma/transport"
+	"github.com/SmartMeshFoundation/SmartPlasma/transport/handlers"
+)
+
+var (
+	zero  = big.NewInt(0)
+	one   = big.NewInt(1)
+	two   = big.NewInt(2)
+	three = big.NewInt(3)
+	four  = big.NewInt(4)
+)
+
+type instance struct {
+	server    *httptest.Server
+	backend   backend.Backend
+	accounts  []*account.PlasmaTransactOpts
+	rootChain common.Address
+	mediator  common.Address
+	token     common.Address
+	session   *rootchain.RootChainSession
+	service   *service.Service
+	blocks    uint64
+	dir       string
+	closers   []func()
+}
+
+func newInstance(t *testing.T, numberAcc int) *instance {
+	accounts := account.GenAccounts(numberAcc)
+	owner := accounts[0]
+
+	server := backend.NewSimulatedBackend(account.Addresses(accounts))
+
+	mediatorAddr, med, err := mediator.Deploy(owner.TransactOpts, server)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rootChain, err := med.RootChain(&bind.CallOptsWithNumber{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, _, err := erc20token.Deploy(owner.TransactOpts, server)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	session, err := rootchain.NewRootChainSession(
+		*owner.TransactOpts, rootChain, server)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := ioutil.TempDir("", "watcher")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	i := &instance{
+		backend:   server,
+		accounts:  accounts,
+		rootChain: rootChain,
+		mediator:  mediatorAddr,
+		token:     token,
+		session:   session,
+		dir:       dir,
+	}
+
+	i.service = service.NewService(session, server,
+		i.newDB(t, bolt.BlocksBucket), i.newDB(t, bolt.CheckpointsBucket),
+		nil, nil, false)
+	i.closers = append(i.closers, func() { i.service.Close() })
+
+	rpcServer := rpc.NewServer()
+	rpcServer.RegisterName("SmartPlasma",
+		handlers.NewSmartPlasma(100, i.service))
+
+	i.server = httptest.NewServer(rpcServer)
+	i.closers = append(i.closers, i.server.Close)
+	return i
+}
+
+// newDB opens the database in the directory of the instance,
+// it is closed with the instance.
+func (i *instance) newDB(t *testing.T, bucket string) *bolt.DB {
+	db, err := bolt.NewDB(filepath.Join(i.dir, bucket), bucket, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	i.closers = append(i.closers, func() { db.Close() })
+	return db
+}
+
+// Close closes clients, the server, the service and databases
+// of the instance in reverse order and removes its directory.
+func (i *instance) Close() {
+	for j := len(i.closers) - 1; j >= 0; j-- {
+		i.closers[j]()
+	}
+	os.RemoveAll(i.dir)
+}
+
+func (i *instance) client(t *testing.T) *transport.Client {
+	cli := transport.NewClient(100, i.accounts[0])
+	if err := cli.ConnectString(i.server.URL[7:]); err != nil {
+		t.Fatal(err)
+	}
+	i.closers = append(i.closers, func() { cli.Close() })
+	return cli
+}
+
+func (i *instance) watcher(t *testing.T,
+	user *account.PlasmaTransactOpts, history *History) *Watcher {
+	w, err := NewWatcher(user, i.backend, i.rootChain, i.client(t), history)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the simulated chain starts at the zero time
+	w.now = func() time.Time { return time.Unix(0, 0) }
+	return w
+}
+
+func (i *instance) userSession(t *testing.T,
+	user *account.PlasmaTransactOpts) *rootchain.RootChainSession {
+	session, err := rootchain.NewRootChainSession(
+		*user.TransactOpts, i.rootChain, i.backend)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return session
+}
+
+// deposit deposits a token to Mediator contract from the account
+// and returns UID of the coin.
+func (i *instance) deposit(t *testing.T,
+	user *account.PlasmaTransactOpts) *big.Int {
+	owner := i.accounts[0]
+	ownerToken, err := erc20token.NewExampleTokenSession(
+		*owner.TransactOpts, i.token, i.backend)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	userToken, err := erc20token.NewExampleTokenSession(
+		*user.TransactOpts, i.token, i.backend)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	med, err := mediator.NewMediatorSession(
+		*user.TransactOpts, i.mediator, i.backend)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	uid, err := rootchain.GenerateNextUID(i.session, user.From, i.token)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tx, err := ownerToken.Mint(user.From, one)
+	if err != nil {
+		t.Fatal(err)
+	}
+	i.mustSucceed(t, tx, "failed to mint tokens")
+
+	tx, err = userToken.IncreaseApproval(i.mediator, one)
+	if err != nil {
+		t.Fatal(err)
+	}
+	i.mustSucceed(t, tx, "failed to approve tokens")
+
+	tx, err = med.Deposit(i.token, one)
+	if err != nil {
+		t.Fatal(err)
+	}
+	i.mustSucceed(t, tx, "failed to deposit")
+	return uid
+}
+
+func (i *instance) mustSucceed(t *testing.T, tx *types.Transaction,
+	msg string) {
+	if !i.backend.GoodTransaction(tx) {
+		t.Fatal(msg)
+	}
+}
+
+// timeMachine moves the simulated clock after the challenge period.
+func (i *instance) timeMachine(t *testing.T) {
+	period, err := i.session.ChallengePeriod()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	adjustment := time.Duration(period.Int64()+1) * time.Second
+	err = i.backend.(backend.Simulator).AdjustTime(adjustment)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testTx(t *testing.T, prevBlock, uid, nonce *big.Int,
+	newOwner common.Address,
+	signer *account.PlasmaTransactOpts) *transaction.Transaction {
+	unsignedTx, err := transaction.NewTransaction(
+		prevBlock, uid, one, nonce, newOwner)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tx, err := signer.PlasmaSigner(signer.From, unsignedTx,
+		transaction.LegacyDomain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tx
+}
+
+func rawTx(t *testing.T, tx *transaction.Transaction) []byte {
+	buf := bytes.NewBuffer(nil)
+	if err := tx.EncodeRLP(buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// publishTx publishes the block with the transaction
+// and returns the record of the transaction.
+func (i *instance) publishTx(t *testing.T,
+	tx *transaction.Transaction) CoinRecord {
+	if err := i.service.AcceptTransaction(tx); err != nil {
+		t.Fatal(err)
+	}
+
+	hash, err := i.service.BuildBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ethTx, err := i.service.SendBlockHash(context.Background(), hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !i.backend.GoodTransaction(ethTx) {
+		t.Fatal("failed to publish block")
+	}
+
+	i.blocks++
+	err = i.service.SaveBlockToDB(i.blocks, i.service.CurrentBlock())
+	if err != nil {
+		t.Fatal(err)
+	}
+	i.service.InitBlock()
+
+	proof, err := i.service.CreateProof(tx.UID(), i.blocks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return CoinRecord{Tx: rawTx(t, tx), Proof: proof, Block: i.blocks}
+}
+
+// publishCheckpoint publishes the checkpoint of the coin with the nonce
+// of the transaction in the block.
+func (i *instance) publishCheckpoint(t *testing.T, uid, nonce *big.Int,
+	block uint64) common.Hash {
+	if err := i.service.AcceptUIDState(uid, nonce, block); err != nil {
+		t.Fatal(err)
+	}
+
+	hash, err := i.service.BuildCheckpoint()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = i.service.SaveCheckpointToDB(i.service.CurrentCheckpoint())
+	if err != nil {
+		t.Fatal(err)
+	}
+	i.service.InitCheckpoint()
+
+	tx, err := i.service.SendChptHash(context.Background(), hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !i.backend.GoodTransaction(tx) {
+		t.Fatal("failed to publish checkpoint")
+	}
+	return hash
+}
+
+// forgeCheckpoint saves the block with a forged transaction of the coin
+// without publishing it and publishes the checkpoint of the forged nonce.
+func (i *instance) forgeCheckpoint(t *testing.T, uid,
+	nonce *big.Int) common.Hash {
+	owner := i.accounts[0]
+	blk := transactions.NewBlock()
+	err := blk.AddTx(testTx(t, big.NewInt(int64(i.blocks)), uid, nonce,
+		owner.From, owner))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := blk.Build(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := i.service.SaveBlockToDB(i.blocks+1, blk); err != nil {
+		t.Fatal(err)
+	}
+	return i.publishCheckpoint(t, uid, nonce, i.blocks+1)
+}
+
+func (i *instance) challenges(t *testing.T, uid *big.Int,
+	hash common.Hash) uint64 {
+	length, err := i.session.CheckpointChallengesLength(uid, hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return length.Uint64()
+}
+
+func TestWatcherChallenge(t *testing.T) {
+	i := newInstance(t, 3)
+	defer i.Close()
+
+	user1 := i.accounts[1]
+	uid := big.NewInt(7)
+	history := NewHistory(i.newDB(t, "history"))
+
+	record0 := i.publishTx(t, testTx(t, zero, uid, zero, user1.From, user1))
+	if err := history.Add(uid, record0); err != nil {
+		t.Fatal(err)
+	}
+
+	// the honest checkpoint is not challenged
+	honest := i.publishCheckpoint(t, uid, zero, 1)
+
+	// the operator checkpoints the nonce of a forged transaction
+	fraud := i.forgeCheckpoint(t, uid, three)
+
+	w := i.watcher(t, user1, history)
+	if err := w.Poll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if i.challenges(t, uid, honest) != 0 {
+		t.Fatal("honest checkpoint is challenged")
+	}
+
+	if i.challenges(t, uid, fraud) != 1 {
+		t.Fatal("fraudulent checkpoint is not challenged")
+	}
+
+	challenge, err := i.session.GetCheckpointChallenge(uid, fraud, zero)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(challenge.ChallengeTx, record0.Tx) ||
+		challenge.ChallengeBlock.Uint64() != record0.Block {
+		t.Fatal("wrong challenge")
+	}
+
+	// the challenge is not sent again
+	challenged, err := w.CheckCheckpoint(context.Background(), fraud)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(challenged) != 0 || i.challenges(t, uid, fraud) != 1 {
+		t.Fatal("checkpoint is challenged again")
+	}
+}
+
+func TestWatcherUnavailableCheckpoint(t *testing.T) {
+	i := newInstance(t, 2)
+	defer i.Close()
+
+	user1 := i.accounts[1]
+	uid := big.NewInt(7)
+	history := NewHistory(i.newDB(t, "history"))
+
+	record0 := i.publishTx(t, testTx(t, zero, uid, zero, user1.From, user1))
+	if err := history.Add(uid, record0); err != nil {
+		t.Fatal(err)
+	}
+
+	// the operator publishes the hash without the checkpoint block
+	tx, err := i.session.NewCheckpoint(common.Hash{1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !i.backend.GoodTransaction(tx) {
+		t.Fatal("failed to publish checkpoint")
+	}
+
+	w := i.watcher(t, user1, history)
+	for n := 0; n < 2; n++ {
+		err := w.Poll(context.Background())
+		if errors.Cause(err) != ErrWrongCheckpoint {
+			t.Fatalf("expected error %s, got %v", ErrWrongCheckpoint, err)
+		}
+	}
+
+	w.mtx.Lock()
+	pending := len(w.pending)
+	w.mtx.Unlock()
+
+	if pending != 1 {
+		t.Fatalf("expected 1 pending checkpoint, got %d", pending)
+	}
+
+	// the checkpoint is dropped after its challenge period in the contract
+	published, err := i.session.Checkpoints(common.Hash{1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	period, err := i.session.ChallengePeriod()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w.now = func() time.Time {
+		return time.Unix(published.Int64()+period.Int64()+1, 0)
+	}
+
+	if err := w.Poll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	w.mtx.Lock()
+	pending = len(w.pending)
+	w.mtx.Unlock()
+
+	if pending != 0 {
+		t.Fatalf("expected no pending checkpoints, got %d", pending)
+	}
+
+	if _, err := w.CheckCheckpoint(context.Background(),
+		common.Hash{2}); err != ErrCheckpointNotPublished {
+		t.Fatalf("expected error %s, got %v", ErrCheckpointNotPublished, err)
+	}
+}
+
+func TestRunWatcher(t *testing.T) {
+	i := newInstance(t, 2)
+	defer i.Close()
+
+	user1 := i.accounts[1]
+	uid := big.NewInt(7)
+	history := NewHistory(i.newDB(t, "history"))
+
+	record0 := i.publishTx(t, testTx(t, zero, uid, zero, user1.From, user1))
+	if err := history.Add(uid, record0); err != nil {
+		t.Fatal(err)
+	}
+
+	w := i.watcher(t, user1, history)
+	w.SetInterval(10 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	done := make(chan error)
+	go func() {
+		done <- w.Run(ctx)
+	}()
+
+	fraud := i.forgeCheckpoint(t, uid, two)
+
+	for i.challenges(t, uid, fraud) != 1 {
+		select {
+		case err := <-done:
+			t.Fatalf("checkpoint is not challenged: %v", err)
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	cancel()
+
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("expected error %s, got %v", context.Canceled, err)
+	}
+}